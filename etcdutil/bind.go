@@ -0,0 +1,81 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"log"
+	"reflect"
+	"sync/atomic"
+)
+
+// Binding holds the latest value of a struct bound to a subtree with
+// Bind.
+type Binding struct {
+	u        *EtcdUtil
+	key      string
+	defaults reflect.Value // The struct new values start from.
+	v        atomic.Value  // The current pointer to the struct.
+	onChange func(old, new interface{})
+}
+
+// Bind loads the keys under prefix+key into dst the same way as
+// UnmarshalKeys and then watches the subtree, reloading it whenever
+// something changes. Because the watch starts from the index of the
+// initial load, no changes are missed in between.
+//
+// dst must be a pointer to a struct. Its values when Bind is called
+// are used as the defaults for every reload. It's populated by the
+// initial load but isn't updated after that. Each reload creates a
+// new struct that atomically replaces the one returned by Current.
+// If the new struct is different from the old one, onChange (if
+// not nil) is called with pointers to the old and new structs.
+// Reloads that fail are logged and the old struct is kept.
+//
+// The watch continues until Close() is called.
+func (u *EtcdUtil) Bind(key string, dst interface{},
+	onChange func(old, new interface{})) (*Binding, error) {
+	if err := checkStruct(dst); err != nil {
+		return nil, err
+	}
+	b := &Binding{u: u, key: key, onChange: onChange}
+	b.defaults = reflect.New(reflect.TypeOf(dst).Elem()).Elem()
+	b.defaults.Set(reflect.ValueOf(dst).Elem())
+
+	i, err := u.UnmarshalKeys(key, dst)
+	if err != nil {
+		return nil, err
+	}
+	cur := reflect.New(b.defaults.Type())
+	cur.Elem().Set(reflect.ValueOf(dst).Elem())
+	b.v.Store(cur.Interface())
+	u.Watch(key, i+1, true, func(k, v string) { b.reload() })
+	return b, nil
+}
+
+// Current returns a pointer to the latest struct. It shouldn't be
+// modified, as it's shared with other callers.
+func (b *Binding) Current() interface{} {
+	return b.v.Load()
+}
+
+// reload reads the subtree into a new struct and swaps it in.
+func (b *Binding) reload() {
+	n := reflect.New(b.defaults.Type())
+	n.Elem().Set(b.defaults)
+	if _, err := b.u.UnmarshalKeys(b.key, n.Interface()); err != nil {
+		log.Printf("Bind(%v): %v\n", b.key, err)
+		return
+	}
+	old := b.v.Load()
+	if reflect.DeepEqual(old, n.Interface()) {
+		return
+	}
+	b.v.Store(n.Interface())
+	if b.onChange != nil {
+		b.onChange(old, n.Interface())
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestBind(t *testing.T) {
+	type config struct {
+		Host string `etcd:"host"`
+		Port int    `etcd:"port"`
+	}
+	e := newFake(etcd.Nodes{
+		&etcd.Node{Key: "/myport/test/db/host", Value: "a"},
+		&etcd.Node{Key: "/myport/test/db/port", Value: "1"},
+	})
+	ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+	defer ec.Close()
+
+	if _, err := ec.Bind("db", config{}, nil); err == nil {
+		t.Errorf("Bind() of non-pointer didn't fail")
+	}
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if _, err := ec.Bind("db", &config{}, nil); err != etcd.ErrWatchStoppedByUser {
+		t.Errorf("Bind() didn't return etcd error: %v", err)
+	}
+
+	type change struct{ old, new *config }
+	changes := make(chan change, 1)
+	dst := &config{Port: 80}
+	b, err := ec.Bind("db", dst, func(old, new interface{}) {
+		changes <- change{old.(*config), new.(*config)}
+	})
+	if err != nil {
+		t.Fatalf("Bind() failed: %v", err)
+	}
+	if *dst != (config{"a", 1}) || *b.Current().(*config) != *dst {
+		t.Fatalf("initial load wrong: %v %v", dst, b.Current())
+	}
+
+	// A change is reloaded and reported.
+	e.Set("/myport/test/db/host", "b", 0)
+	select {
+	case c := <-changes:
+		if *c.old != (config{"a", 1}) || *c.new != (config{"b", 1}) {
+			t.Errorf("wrong change: %v %v", c.old, c.new)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("change not reported")
+	}
+	if *b.Current().(*config) != (config{"b", 1}) {
+		t.Errorf("current not updated: %v", b.Current())
+	}
+
+	// Removed keys go back to the default and bad values are ignored.
+	e.Delete("/myport/test/db/port", false)
+	if c := <-changes; *c.new != (config{"b", 80}) {
+		t.Errorf("default not restored: %v", c.new)
+	}
+	e.Set("/myport/test/db/port", "x", 0)
+	e.Set("/myport/test/db/host", "b", 0)
+	select {
+	case c := <-changes:
+		t.Errorf("unexpected change: %v %v", c.old, c.new)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if *b.Current().(*config) != (config{"b", 80}) {
+		t.Errorf("current changed: %v", b.Current())
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// cache holds the values read by Get when caching is enabled.
+type cache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry // Keyed by the full etcd key.
+}
+
+// cacheEntry is a value in the cache.
+type cacheEntry struct {
+	value string
+	index uint64
+	at    time.Time // When it was read from etcd.
+	stale bool      // Whether it was last served because etcd failed.
+}
+
+// EnableCache makes Get (and all of the Get* functions) cache the
+// values they read from etcd for the given ttl. The cache is kept up
+// to date by watching the prefix, so changes are seen right away.
+//
+// If reading a value from etcd fails for any reason other than the
+// key not existing, the last known value is returned instead of an
+// error. This keeps services running (and MustGet* from panicking)
+// during short etcd outages. IsStale reports when this happens.
+//
+// It should be called before the util is used. The watch stops when
+// Close() is called.
+func (u *EtcdUtil) EnableCache(ttl time.Duration) {
+	u.cache = &cache{ttl: ttl, entries: map[string]*cacheEntry{}}
+	u.Watch("", 0, true, func(key, value string) {
+		u.cache.mu.Lock()
+		defer u.cache.mu.Unlock()
+		delete(u.cache.entries, key)
+	})
+}
+
+// IsStale returns true if the last value returned for the key by the
+// Get* functions was served from the cache because etcd couldn't be
+// read.
+func (u *EtcdUtil) IsStale(key string) bool {
+	if u.cache == nil {
+		return false
+	}
+	k := strings.Join([]string{u.p, key}, "/")
+	u.cache.mu.Lock()
+	defer u.cache.mu.Unlock()
+	e, ok := u.cache.entries[k]
+	return ok && e.stale
+}
+
+// get returns the cached value for the key if it's fresh.
+func (c *cache) get(k string) (string, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[k]
+	if !ok || e.stale || time.Since(e.at) >= c.ttl {
+		return "", 0, false
+	}
+	return e.value, e.index, true
+}
+
+// set caches a value read from etcd.
+func (c *cache) set(k, value string, index uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[k] = &cacheEntry{value: value, index: index, at: time.Now()}
+}
+
+// stale returns the last known value for the key and marks it stale.
+func (c *cache) stale(k string) (string, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[k]
+	if !ok {
+		return "", 0, false
+	}
+	e.stale = true
+	return e.value, e.index, true
+}
+
+// remove forgets the key.
+func (c *cache) remove(k string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, k)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestCache(t *testing.T) {
+	e := newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "a"}})
+	ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+	defer ec.Close()
+	ec.EnableCache(time.Hour)
+
+	get := func(exp string) {
+		if v, _, err := ec.Get("key0", "def"); v != exp || err != nil {
+			t.Errorf("Get() = %v, %v but wanted %v", v, err, exp)
+		}
+	}
+	get("a")
+
+	// The value is cached until a change is seen. The cache's watch
+	// only sees changes after it has started, so keep changing it.
+	if v, _, ok := ec.cache.get("/myport/test/key0"); !ok || v != "a" {
+		t.Errorf("value wasn't cached: %v, %v", v, ok)
+	}
+	deadline := time.Now().Add(time.Second)
+	for v, _, _ := ec.Get("key0", ""); v != "b"; v, _, _ = ec.Get("key0", "") {
+		if time.Now().After(deadline) {
+			t.Fatalf("watch didn't invalidate the cache")
+		}
+		e.Set("/myport/test/key0", "b", 0)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacheStale(t *testing.T) {
+	e := newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "1"}})
+	ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+	defer ec.Close()
+	if ec.IsStale("key0") {
+		t.Errorf("IsStale() without cache returned true")
+	}
+	ec.EnableCache(0)
+
+	// Wait for the cache's watch to start so it doesn't get the errors
+	// meant for Get.
+	deadline := time.Now().Add(time.Second)
+	for {
+		ec.Get("key0", "")
+		e.Set("/myport/test/key0", "1", 0)
+		time.Sleep(time.Millisecond)
+		if !cached(ec, "/myport/test/key0") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("watch didn't start")
+		}
+	}
+
+	// Without anything cached, errors are returned.
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if _, _, err := ec.Get("key0", "def"); err != etcd.ErrWatchStoppedByUser {
+		t.Errorf("Get() didn't return error: %v", err)
+	}
+
+	// The last known value is served during an outage.
+	if v, _ := ec.MustGetInt("key0"); v != 1 || ec.IsStale("key0") {
+		t.Errorf("MustGetInt() = %v (stale %v)", v, ec.IsStale("key0"))
+	}
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if v, _ := ec.MustGetInt("key0"); v != 1 || !ec.IsStale("key0") {
+		t.Errorf("MustGetInt() during outage = %v (stale %v)", v, ec.IsStale("key0"))
+	}
+	e.Set("/myport/test/key0", "2", 0)
+	if v, _ := ec.MustGetInt("key0"); v != 2 || ec.IsStale("key0") {
+		t.Errorf("MustGetInt() after outage = %v (stale %v)", v, ec.IsStale("key0"))
+	}
+
+	// Removed keys aren't served.
+	e.Delete("/myport/test/key0", false)
+	if v, _, err := ec.Get("key0", "def"); v != "def" || err == nil {
+		t.Errorf("Get() of removed key = %v, %v", v, err)
+	}
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if v, _, err := ec.Get("key0", "def"); v != "def" || err == nil {
+		t.Errorf("Get() of removed key during outage = %v, %v", v, err)
+	}
+}
+
+// cached returns true if the full key is in the cache.
+func cached(ec *EtcdUtil, k string) bool {
+	ec.cache.mu.Lock()
+	defer ec.cache.mu.Unlock()
+	_, ok := ec.cache.entries[k]
+	return ok
+}
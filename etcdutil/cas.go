@@ -0,0 +1,91 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+// ErrCompareFailed is returned by the CompareAnd* operations when the
+// key's current value or index didn't match the one given.
+var ErrCompareFailed = errors.New("compare failed")
+
+// etcd's error codes used by the package.
+const (
+	ecodeKeyNotFound = 100
+	ecodeTestFailed  = 101
+	ecodeNodeExist   = 105
+)
+
+// GetForUpdate is like Get but the uint64 returned is the modified
+// index of the key rather than the etcd index. It's the index the
+// CompareAnd* operations expect.
+func (u *EtcdUtil) GetForUpdate(key string) (string, uint64, error) {
+	k := strings.Join([]string{u.p, key}, "/")
+	r, err := u.c.Get(k, false, false)
+	if err != nil {
+		return "", 0, err
+	}
+	return r.Node.Value, r.Node.ModifiedIndex, nil
+}
+
+// CompareAndSwap sets the value of prefix+key only if its current
+// value is prevValue and its modified index is prevIndex. An empty
+// prevValue or a zero prevIndex isn't compared, but one of them must
+// be given. If the compare fails, ErrCompareFailed is returned.
+// Otherwise the new modified index of the key is returned so it can
+// be used in subsequent compares.
+func (u *EtcdUtil) CompareAndSwap(key, value, prevValue string,
+	prevIndex uint64) (uint64, error) {
+	k := strings.Join([]string{u.p, key}, "/")
+	r, err := u.c.CompareAndSwap(k, value, 0, prevValue, prevIndex)
+	if err != nil {
+		return 0, compareError(err)
+	}
+	return r.Node.ModifiedIndex, nil
+}
+
+// CompareAndSwapJSON is like CompareAndSwap but the new value is src
+// encoded as JSON and only the modified index is compared. This is
+// typically used with an index from GetForUpdate or a previous
+// CompareAndSwapJSON after decoding and changing the value.
+func (u *EtcdUtil) CompareAndSwapJSON(key string, src interface{},
+	prevIndex uint64) (uint64, error) {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return 0, err
+	}
+	return u.CompareAndSwap(key, string(b), "", prevIndex)
+}
+
+// CompareAndDelete deletes prefix+key only if its current value is
+// prevValue and its modified index is prevIndex. The values are
+// compared the same way as CompareAndSwap.
+func (u *EtcdUtil) CompareAndDelete(key, prevValue string, prevIndex uint64) error {
+	k := strings.Join([]string{u.p, key}, "/")
+	_, err := u.c.CompareAndDelete(k, prevValue, prevIndex)
+	return compareError(err)
+}
+
+// compareError converts etcd's failed compare error to
+// ErrCompareFailed.
+func compareError(err error) error {
+	if isEtcdError(err, ecodeTestFailed) {
+		return ErrCompareFailed
+	}
+	return err
+}
+
+// isEtcdError returns true if err is an etcd error with the given code.
+func isEtcdError(err error, code int) bool {
+	e, ok := err.(*etcd.EtcdError)
+	return ok && e.ErrorCode == code
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	e := newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}})
+	ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+
+	v, i, err := ec.GetForUpdate("key0")
+	if err != nil || v != "val0" || i != 1 {
+		t.Fatalf("GetForUpdate() = %v, %v, %v", v, i, err)
+	}
+	tests := []struct {
+		value     string // The new value.
+		prevValue string // The value to compare.
+		prevIndex uint64 // The index to compare.
+		index     uint64 // The expected index.
+		err       error  // The expected error.
+	}{
+		// Swap by value.
+		{value: "val1", prevValue: "val0", index: 2},
+		// Wrong value.
+		{value: "val2", prevValue: "val0", err: ErrCompareFailed},
+		// Swap by index.
+		{value: "val2", prevIndex: 2, index: 3},
+		// Wrong index.
+		{value: "val3", prevIndex: 2, err: ErrCompareFailed},
+		// Both.
+		{value: "val3", prevValue: "val2", prevIndex: 3, index: 4},
+	}
+	for k, test := range tests {
+		i, err := ec.CompareAndSwap("key0", test.value, test.prevValue, test.prevIndex)
+		if err != test.err {
+			t.Errorf("Test %v: wanted error '%v' but got '%v'", k, test.err, err)
+		}
+		if i != test.index {
+			t.Errorf("Test %v: wanted index %v but got %v", k, test.index, i)
+		}
+	}
+
+	// The JSON variant.
+	i, err = ec.CompareAndSwapJSON("key0", map[string]int{"a": 1}, 4)
+	v = values(e, "/myport/test")["/myport/test/key0"]
+	if err != nil || i != 5 || v != `{"a":1}` {
+		t.Errorf("CompareAndSwapJSON() = %v, %v (%v)", i, err, v)
+	}
+	if _, err := ec.CompareAndSwapJSON("key0", func() {}, 5); err == nil {
+		t.Errorf("CompareAndSwapJSON() of unencodable value didn't fail")
+	}
+
+	// Other errors are returned as is.
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if _, err := ec.CompareAndSwap("key0", "x", "", 5); err != etcd.ErrWatchStoppedByUser {
+		t.Errorf("CompareAndSwap() didn't return error: %v", err)
+	}
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if _, _, err := ec.GetForUpdate("key0"); err != etcd.ErrWatchStoppedByUser {
+		t.Errorf("GetForUpdate() didn't return error: %v", err)
+	}
+}
+
+func TestCompareAndDelete(t *testing.T) {
+	e := newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}})
+	ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+	if err := ec.CompareAndDelete("key0", "val1", 0); err != ErrCompareFailed {
+		t.Errorf("CompareAndDelete() with wrong value: %v", err)
+	}
+	if err := ec.CompareAndDelete("key0", "", 2); err != ErrCompareFailed {
+		t.Errorf("CompareAndDelete() with wrong index: %v", err)
+	}
+	if err := ec.CompareAndDelete("key0", "val0", 1); err != nil {
+		t.Errorf("CompareAndDelete() failed: %v", err)
+	}
+	if m := values(e, "/myport/test"); len(m) != 0 {
+		t.Errorf("key wasn't deleted: %v", m)
+	}
+	err := ec.CompareAndDelete("key0", "val0", 1)
+	if ee, ok := err.(*etcd.EtcdError); !ok || ee.ErrorCode != 100 {
+		t.Errorf("CompareAndDelete() of missing key: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Election is a candidate in a leader election. Instantiate it with
+// Elect.
+type Election struct {
+	u   *EtcdUtil
+	k   string // The full election key.
+	id  string // The candidate ID.
+	ttl time.Duration
+
+	leaders chan string
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Elect enters the candidate into the election with the given name.
+// The election is held on prefix+name, whose value is the ID of the
+// current leader. The leader refreshes the key every third of the ttl.
+// If it fails to (e.g. the process dies), the key expires and another
+// candidate takes over.
+//
+// Candidates run until Resign() or Close() is called.
+func (u *EtcdUtil) Elect(name, candidateID string, ttl time.Duration) *Election {
+	e := &Election{
+		u:       u,
+		k:       strings.Join([]string{u.p, name}, "/"),
+		id:      candidateID,
+		ttl:     ttl,
+		leaders: make(chan string, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Leaders returns a channel on which the ID of the leader is sent
+// whenever it changes. An empty ID means there is currently no
+// leader. Only the latest change is kept if the channel isn't read
+// from. The channel is closed once the candidate has stopped.
+func (e *Election) Leaders() <-chan string {
+	return e.leaders
+}
+
+// Resign withdraws the candidate from the election. If it's the
+// leader, the election key is removed so another candidate can take
+// over right away.
+func (e *Election) Resign() {
+	e.once.Do(func() { close(e.stop) })
+	<-e.done
+}
+
+// run campaigns until the candidate is stopped.
+func (e *Election) run() {
+	defer close(e.done)
+	defer close(e.leaders)
+	leader := ""
+	every := refreshEvery(e.ttl)
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		l, err := e.campaign(leader)
+		if err != nil {
+			log.Printf("Elect(%v): %v - Retrying in %v\n", e.k, err, every)
+		}
+		if l != leader {
+			leader = l
+			e.send(leader)
+		}
+		select {
+		case <-e.u.s:
+			return
+		case <-e.stop:
+			if leader == e.id {
+				e.u.c.CompareAndDelete(e.k, e.id, 0)
+			}
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// campaign refreshes the key if the candidate is the leader or tries
+// to become the leader otherwise. It returns the leader afterwards.
+func (e *Election) campaign(leader string) (string, error) {
+	ttl := ttlSeconds(e.ttl)
+	if leader == e.id {
+		_, err := e.u.c.CompareAndSwap(e.k, e.id, ttl, e.id, 0)
+		if err == nil {
+			return e.id, nil
+		}
+		if !isEtcdError(err, ecodeTestFailed) && !isEtcdError(err, ecodeKeyNotFound) {
+			// We can't tell, so we are no longer the leader to be safe.
+			return "", err
+		}
+	}
+	if _, err := e.u.c.Create(e.k, e.id, ttl); err == nil {
+		return e.id, nil
+	}
+	r, err := e.u.c.Get(e.k, false, false)
+	if isEtcdError(err, ecodeKeyNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return r.Node.Value, nil
+}
+
+// send sends the leader on the leaders channel, replacing any value
+// that hasn't been read.
+func (e *Election) send(leader string) {
+	select {
+	case e.leaders <- leader:
+	default:
+		select {
+		case <-e.leaders:
+		default:
+		}
+		e.leaders <- leader
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestElect(t *testing.T) {
+	e := newFake(nil)
+	ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+
+	// The first candidate becomes the leader and the second sees it. A
+	// tiny ttl is treated like a second.
+	a := ec.Elect("leader", "a", 30*time.Millisecond)
+	waitLeader(t, a, "a")
+	b := ec.Elect("leader", "b", time.Nanosecond)
+	waitLeader(t, b, "a")
+
+	// When the leader resigns, the other takes over.
+	a.Resign()
+	a.Resign()
+	for range a.Leaders() {
+	}
+	waitLeader(t, b, "b")
+
+	// If someone else takes the key, leadership is lost.
+	e.Set("/myport/test/leader", "c", 1)
+	waitLeader(t, b, "c")
+
+	// Close stops the candidates.
+	close(ec.s)
+	for range b.Leaders() {
+	}
+	b.Resign()
+}
+
+// waitLeader waits for the given leader to be sent on the election's
+// channel.
+func waitLeader(t *testing.T, e *Election, exp string) {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case l := <-e.Leaders():
+			if l == exp {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("%v never saw leader %v", e.id, exp)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/go-etcd/etcd"
@@ -26,29 +27,47 @@ var (
 	startWait = 1 * time.Second
 )
 
-// ec is the interface to functions we need for our etcd client. It's
-// primarily used to make testing without etcd possible.
-type ec interface {
+// EtcdClient is the interface to functions we need for our etcd
+// client. It's satisfied by *etcd.Client and is primarily used to make
+// testing without etcd possible (see the etcdutiltest package).
+type EtcdClient interface {
 	Close()
 	Get(string, bool, bool) (*etcd.Response, error)
+	Set(string, string, uint64) (*etcd.Response, error)
+	Create(string, string, uint64) (*etcd.Response, error)
+	Delete(string, bool) (*etcd.Response, error)
+	CompareAndSwap(string, string, uint64, string, uint64) (*etcd.Response, error)
+	CompareAndDelete(string, string, uint64) (*etcd.Response, error)
 	Watch(string, uint64, bool, chan *etcd.Response, chan bool) (*etcd.Response, error)
 }
 
 // EtcdUtil is the primary structure used in the package. Instantiate
-// it with New or NewFromString.
+// it with New, NewFromString or NewFromClient.
 type EtcdUtil struct {
-	c ec        // The etcd client.
-	p string    // The prefix.
-	s chan bool // the watch stop channel.
+	c     EtcdClient // The etcd client.
+	p     string     // The prefix.
+	s     chan bool  // the watch stop channel.
+	f     Fallback   // The local sources of values.
+	cache *cache     // The cache of values if enabled.
+
+	mu   sync.Mutex        // protects regs.
+	regs map[string]func() // The stop functions of registered services.
 }
 
 // New creates a utilitiy structure that connects to etcd on the
 // following machines. The prefix will be prepended to all keys during
 // any requests.
 func New(machines []string, prefix string) *EtcdUtil {
+	return NewFromClient(etcd.NewClient(machines), prefix)
+}
+
+// NewFromClient is like New but uses the given client. This is useful
+// for configuring the client beyond the list of machines or for
+// testing with a fake client.
+func NewFromClient(c EtcdClient, prefix string) *EtcdUtil {
 	return &EtcdUtil{
 		p: prefix,
-		c: etcd.NewClient(machines),
+		c: c,
 		s: make(chan bool),
 	}
 }
@@ -68,13 +87,38 @@ func (u *EtcdUtil) Client() *etcd.Client {
 }
 
 // Get returns the value for the given prefix+key or the default value
-// given.
+// given. If a Fallback has been set, its sources are also checked (see
+// SetFallback). Values from them have an index of 0. If caching is
+// enabled, values may come from the cache (see EnableCache).
 func (u *EtcdUtil) Get(key, def string) (string, uint64, error) {
+	if u.f.Override {
+		if v, ok := u.f.local(key); ok {
+			return v, 0, nil
+		}
+	}
 	k := strings.Join([]string{u.p, key}, "/")
+	if u.cache != nil {
+		if v, i, ok := u.cache.get(k); ok {
+			return v, i, nil
+		}
+	}
 	r, err := u.c.Get(k, false, false)
 	if err != nil {
+		if u.cache != nil {
+			if isEtcdError(err, ecodeKeyNotFound) {
+				u.cache.remove(k)
+			} else if v, i, ok := u.cache.stale(k); ok {
+				return v, i, nil
+			}
+		}
+		if v, ok := u.f.local(key); ok {
+			return v, 0, nil
+		}
 		return def, 0, err
 	}
+	if u.cache != nil {
+		u.cache.set(k, r.Node.Value, r.EtcdIndex)
+	}
 	return r.Node.Value, r.EtcdIndex, nil
 }
 
@@ -131,6 +175,120 @@ func (u *EtcdUtil) MustGetDuration(key string) (time.Duration, uint64) {
 	return v, i
 }
 
+// GetBool is like Get but returns a boolean. The values accepted are
+// those of strconv.ParseBool.
+func (u *EtcdUtil) GetBool(key string, def bool) (bool, uint64, error) {
+	s, i, err := u.Get(key, "")
+	if err != nil {
+		return def, i, err
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return def, i, err
+	}
+	return b, i, nil
+}
+
+// MustGetBool is like MustGet but returns a boolean.
+func (u *EtcdUtil) MustGetBool(key string) (bool, uint64) {
+	v, i, err := u.GetBool(key, false)
+	if err != nil {
+		panic(fmt.Sprintf("MustGetBool(%v): %v\n", key, err))
+	}
+	return v, i
+}
+
+// GetFloat64 is like Get but returns a float64.
+func (u *EtcdUtil) GetFloat64(key string, def float64) (float64, uint64, error) {
+	s, i, err := u.Get(key, "")
+	if err != nil {
+		return def, i, err
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return def, i, err
+	}
+	return f, i, nil
+}
+
+// MustGetFloat64 is like MustGet but returns a float64.
+func (u *EtcdUtil) MustGetFloat64(key string) (float64, uint64) {
+	v, i, err := u.GetFloat64(key, 0)
+	if err != nil {
+		panic(fmt.Sprintf("MustGetFloat64(%v): %v\n", key, err))
+	}
+	return v, i
+}
+
+// GetStrings is like Get but returns a list of strings. If the value
+// starts with '[', it's decoded as a JSON list. Otherwise, it's split
+// on commas and the surrounding whitespace of each item is removed.
+// An empty value is an empty list.
+func (u *EtcdUtil) GetStrings(key string, def []string) ([]string, uint64, error) {
+	s, i, err := u.Get(key, "")
+	if err != nil {
+		return def, i, err
+	}
+	l, err := parseStrings(s)
+	if err != nil {
+		return def, i, err
+	}
+	return l, i, nil
+}
+
+// parseStrings parses a list of strings in the format GetStrings
+// describes.
+func parseStrings(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") {
+		var l []string
+		if err := json.Unmarshal([]byte(s), &l); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+	if s == "" {
+		return []string{}, nil
+	}
+	l := strings.Split(s, ",")
+	for x := range l {
+		l[x] = strings.TrimSpace(l[x])
+	}
+	return l, nil
+}
+
+// MustGetStrings is like MustGet but returns a list of strings.
+func (u *EtcdUtil) MustGetStrings(key string) ([]string, uint64) {
+	v, i, err := u.GetStrings(key, nil)
+	if err != nil {
+		panic(fmt.Sprintf("MustGetStrings(%v): %v\n", key, err))
+	}
+	return v, i
+}
+
+// GetTime is like Get but returns a time. The value should be in the
+// RFC3339 format.
+func (u *EtcdUtil) GetTime(key string, def time.Time) (time.Time, uint64, error) {
+	s, i, err := u.Get(key, "")
+	if err != nil {
+		return def, i, err
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return def, i, err
+	}
+	return t, i, nil
+}
+
+// MustGetTime is like MustGet but returns a time.
+func (u *EtcdUtil) MustGetTime(key string) (time.Time, uint64) {
+	v, i, err := u.GetTime(key, time.Time{})
+	if err != nil {
+		panic(fmt.Sprintf("MustGetTime(%v): %v\n", key, err))
+	}
+	return v, i
+}
+
 // GetJSON is like get but decodes the JSON to dst.
 func (u *EtcdUtil) GetJSON(key string, dst interface{}) (uint64, error) {
 	s, i, err := u.Get(key, "")
@@ -170,6 +328,14 @@ func (u *EtcdUtil) Close() {
 // Watching will continue to retry until Close() is called. Multiple
 // Watch's may be started.
 func (u *EtcdUtil) Watch(key string, waitIndex uint64, recursive bool, f func(key, value string)) {
+	u.watch(key, waitIndex, recursive, func(r *etcd.Response) {
+		f(r.Node.Key, r.Node.Value)
+	})
+}
+
+// watch does the work for Watch and WatchFull. It calls f with each
+// response.
+func (u *EtcdUtil) watch(key string, waitIndex uint64, recursive bool, f func(r *etcd.Response)) {
 	k := strings.Join([]string{u.p, key}, "/")
 	c := make(chan *etcd.Response)
 
@@ -180,14 +346,14 @@ func (u *EtcdUtil) Watch(key string, waitIndex uint64, recursive bool, f func(ke
 			case <-u.s:
 				return
 			case r := <-c:
-				f(r.Node.Key, r.Node.Value)
+				f(r)
 			}
 		}
 	}()
 
 	// This is the goroutine that watches until Close() is called.
+	wait := startWait
 	go func() {
-		wait := startWait
 		for {
 			_, err := u.c.Watch(k, waitIndex, recursive, c, u.s)
 			if err == etcd.ErrWatchStoppedByUser {
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/coreos/go-etcd/etcd"
+	"github.com/icub3d/gop/etcdutil/etcdutiltest"
 )
 
 var subSubNodes = etcd.Nodes{
@@ -74,7 +75,7 @@ func TestNew(t *testing.T) {
 }
 
 func TestClient(t *testing.T) {
-	ec := &EtcdUtil{c: &ecs{}}
+	ec := &EtcdUtil{c: etcdutiltest.NewFake()}
 	if ec.Client() != nil {
 		t.Errorf("Client(): expected nil for non etcd.Client")
 	}
@@ -89,11 +90,11 @@ func TestClient(t *testing.T) {
 
 func TestGet(t *testing.T) {
 	tests := []struct {
-		key string // The key to search for.
-		def string // The default value.
-		val string // The value we expect to get back.
-		err error  // The error we expect to be returned.
-		e   ecs    // The fake etcd client.
+		key string             // The key to search for.
+		def string             // The default value.
+		val string             // The value we expect to get back.
+		err error              // The error we expect to be returned.
+		e   *etcdutiltest.Fake // The fake etcd client.
 	}{
 		// A normal get.
 		{
@@ -101,9 +102,7 @@ func TestGet(t *testing.T) {
 			def: "bad",
 			val: "val0",
 			err: nil,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}}),
 		},
 
 		// An error condition.
@@ -112,16 +111,13 @@ func TestGet(t *testing.T) {
 			def: "good",
 			val: "val0",
 			err: etcd.ErrWatchStoppedByUser,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}},
-				err:   etcd.ErrWatchStoppedByUser,
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}}, etcd.ErrWatchStoppedByUser),
 		},
 	}
 
 	for k, test := range tests {
 		// Setup the util and call Get.
-		ec := &EtcdUtil{p: "/myport/test", c: &test.e, s: make(chan bool)}
+		ec := &EtcdUtil{p: "/myport/test", c: test.e, s: make(chan bool)}
 		val, _, err := ec.Get(test.key, test.def)
 		if test.err != nil {
 			// If we are expecting an error, we need to test for it and the
@@ -149,28 +145,23 @@ func TestGet(t *testing.T) {
 
 func TestMustGet(t *testing.T) {
 	tests := []struct {
-		key string // The key to search for.
-		val string // The expected value.
-		p   bool   // Whether or not a panic is expected.
-		e   ecs    // The fake etcd client.
+		key string             // The key to search for.
+		val string             // The expected value.
+		p   bool               // Whether or not a panic is expected.
+		e   *etcdutiltest.Fake // The fake etcd client.
 	}{
 		// Normal get.
 		{
 			key: "key0",
 			val: "val0",
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}}),
 		},
 		// An error condition.
 		{
 			key: "key0",
 			val: "val0",
 			p:   true,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}},
-				err:   etcd.ErrWatchStoppedByUser,
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}}, etcd.ErrWatchStoppedByUser),
 		},
 	}
 
@@ -185,7 +176,7 @@ func TestMustGet(t *testing.T) {
 	for k, test := range tests {
 		// Reset our panic state, setup the util, and call MustGet.
 		p = false
-		ec := &EtcdUtil{p: "/myport/test", c: &test.e, s: make(chan bool)}
+		ec := &EtcdUtil{p: "/myport/test", c: test.e, s: make(chan bool)}
 		val, _ := ec.MustGet(test.key)
 		// Make sure we did/didn't panic based on the test.
 		if test.p && !p {
@@ -205,40 +196,33 @@ func TestGetInt(t *testing.T) {
 		def int
 		val int
 		err error
-		e   ecs
+		e   *etcdutiltest.Fake
 	}{
 		{
 			key: "key0",
 			def: -1,
 			val: 1234,
 			err: nil,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "1234"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "1234"}}),
 		},
 		{
 			key: "key0",
 			def: -1,
 			val: -1,
 			err: etcd.ErrWatchStoppedByUser,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "1234"}},
-				err:   etcd.ErrWatchStoppedByUser,
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "1234"}}, etcd.ErrWatchStoppedByUser),
 		},
 		{
 			key: "key0",
 			def: -1,
 			val: -1,
 			err: errors.New("strconv.ParseInt: parsing \"$@$@#\": invalid syntax"),
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "$@$@#"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "$@$@#"}}),
 		},
 	}
 
 	for k, test := range tests {
-		ec := &EtcdUtil{p: "/myport/test", c: &test.e, s: make(chan bool)}
+		ec := &EtcdUtil{p: "/myport/test", c: test.e, s: make(chan bool)}
 		val, _, err := ec.GetInt(test.key, test.def)
 		if test.err != nil {
 			if err.Error() != test.err.Error() {
@@ -265,29 +249,22 @@ func TestMustGetInt(t *testing.T) {
 		key string
 		val int
 		p   bool
-		e   ecs
+		e   *etcdutiltest.Fake
 	}{
 		{
 			key: "key0",
 			val: 123,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "123"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "123"}}),
 		},
 		{
 			key: "key0",
 			p:   true,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}},
-				err:   etcd.ErrWatchStoppedByUser,
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}}, etcd.ErrWatchStoppedByUser),
 		},
 		{
 			key: "key0",
 			p:   true,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "#&#&#&#"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "#&#&#&#"}}),
 		},
 	}
 
@@ -300,7 +277,7 @@ func TestMustGetInt(t *testing.T) {
 
 	for k, test := range tests {
 		p = false
-		ec := &EtcdUtil{p: "/myport/test", c: &test.e, s: make(chan bool)}
+		ec := &EtcdUtil{p: "/myport/test", c: test.e, s: make(chan bool)}
 		val, _ := ec.MustGetInt(test.key)
 		if test.p && !p {
 			t.Errorf("Test %v: expected panic, but didn't get it.", k)
@@ -318,40 +295,33 @@ func TestGetDuration(t *testing.T) {
 		def time.Duration
 		val time.Duration
 		err error
-		e   ecs
+		e   *etcdutiltest.Fake
 	}{
 		{
 			key: "key0",
 			def: -1,
 			val: 2 * time.Minute,
 			err: nil,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "2m"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "2m"}}),
 		},
 		{
 			key: "key0",
 			def: -1,
 			val: -1,
 			err: etcd.ErrWatchStoppedByUser,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "2m"}},
-				err:   etcd.ErrWatchStoppedByUser,
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "2m"}}, etcd.ErrWatchStoppedByUser),
 		},
 		{
 			key: "key0",
 			def: -1,
 			val: -1,
 			err: errors.New("time: invalid duration $@$@#"),
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "$@$@#"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "$@$@#"}}),
 		},
 	}
 
 	for k, test := range tests {
-		ec := &EtcdUtil{p: "/myport/test", c: &test.e, s: make(chan bool)}
+		ec := &EtcdUtil{p: "/myport/test", c: test.e, s: make(chan bool)}
 		val, _, err := ec.GetDuration(test.key, test.def)
 		if test.err != nil {
 			if err.Error() != test.err.Error() {
@@ -378,29 +348,22 @@ func TestMustGetDuration(t *testing.T) {
 		key string
 		val time.Duration
 		p   bool
-		e   ecs
+		e   *etcdutiltest.Fake
 	}{
 		{
 			key: "key0",
 			val: 2 * time.Minute,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "2m"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "2m"}}),
 		},
 		{
 			key: "key0",
 			p:   true,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}},
-				err:   etcd.ErrWatchStoppedByUser,
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}}, etcd.ErrWatchStoppedByUser),
 		},
 		{
 			key: "key0",
 			p:   true,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "#&#&#&#"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "#&#&#&#"}}),
 		},
 	}
 
@@ -413,7 +376,7 @@ func TestMustGetDuration(t *testing.T) {
 
 	for k, test := range tests {
 		p = false
-		ec := &EtcdUtil{p: "/myport/test", c: &test.e, s: make(chan bool)}
+		ec := &EtcdUtil{p: "/myport/test", c: test.e, s: make(chan bool)}
 		val, _ := ec.MustGetDuration(test.key)
 		if test.p && !p {
 			t.Errorf("Test %v: expected panic, but didn't get it.", k)
@@ -425,6 +388,117 @@ func TestMustGetDuration(t *testing.T) {
 	}
 }
 
+func TestGetTyped(t *testing.T) {
+	now := time.Date(2015, 6, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		val string                                  // The value in etcd.
+		get func(ec *EtcdUtil) (interface{}, error) // The getter to call.
+		exp interface{}                             // The expected value.
+		err bool                                    // Whether an error is expected.
+	}{
+		// Bools.
+		{val: "true", exp: true, get: func(ec *EtcdUtil) (interface{}, error) {
+			v, _, err := ec.GetBool("key0", false)
+			return v, err
+		}},
+		{val: "0", exp: false, get: func(ec *EtcdUtil) (interface{}, error) {
+			v, _, err := ec.GetBool("key0", true)
+			return v, err
+		}},
+		{val: "maybe", exp: true, err: true, get: func(ec *EtcdUtil) (interface{}, error) {
+			v, _, err := ec.GetBool("key0", true)
+			return v, err
+		}},
+		// Floats.
+		{val: "1.5e3", exp: 1500.0, get: func(ec *EtcdUtil) (interface{}, error) {
+			v, _, err := ec.GetFloat64("key0", -1)
+			return v, err
+		}},
+		{val: "one", exp: -1.0, err: true, get: func(ec *EtcdUtil) (interface{}, error) {
+			v, _, err := ec.GetFloat64("key0", -1)
+			return v, err
+		}},
+		// Strings.
+		{val: "a, b ,c", exp: []string{"a", "b", "c"}, get: func(ec *EtcdUtil) (interface{}, error) {
+			v, _, err := ec.GetStrings("key0", nil)
+			return v, err
+		}},
+		{val: ` ["a,b", "c"]`, exp: []string{"a,b", "c"}, get: func(ec *EtcdUtil) (interface{}, error) {
+			v, _, err := ec.GetStrings("key0", nil)
+			return v, err
+		}},
+		{val: "", exp: []string{}, get: func(ec *EtcdUtil) (interface{}, error) {
+			v, _, err := ec.GetStrings("key0", nil)
+			return v, err
+		}},
+		{val: "[a", exp: []string{"def"}, err: true, get: func(ec *EtcdUtil) (interface{}, error) {
+			v, _, err := ec.GetStrings("key0", []string{"def"})
+			return v, err
+		}},
+		// Times.
+		{val: "2015-06-01T12:30:00Z", exp: now, get: func(ec *EtcdUtil) (interface{}, error) {
+			v, _, err := ec.GetTime("key0", time.Time{})
+			return v, err
+		}},
+		{val: "yesterday", exp: now, err: true, get: func(ec *EtcdUtil) (interface{}, error) {
+			v, _, err := ec.GetTime("key0", now)
+			return v, err
+		}},
+	}
+
+	for k, test := range tests {
+		e := newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: test.val}})
+		ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+		v, err := test.get(ec)
+		if test.err != (err != nil) {
+			t.Errorf("Test %v: wanted error %v but got '%v'", k, test.err, err)
+		}
+		if !reflect.DeepEqual(v, test.exp) {
+			t.Errorf("Test %v: Expected value %v but got %v", k, test.exp, v)
+		}
+
+		// Errors from etcd should return the default as well.
+		e.FailNext(etcd.ErrWatchStoppedByUser)
+		if _, err := test.get(ec); err != etcd.ErrWatchStoppedByUser {
+			t.Errorf("Test %v: wanted etcd error but got '%v'", k, err)
+		}
+	}
+}
+
+func TestMustGetTyped(t *testing.T) {
+	tests := []struct {
+		val string          // The value in etcd.
+		get func(*EtcdUtil) // The getter to call.
+		p   bool            // Whether or not a panic is expected.
+	}{
+		{val: "true", get: func(ec *EtcdUtil) { ec.MustGetBool("key0") }},
+		{val: "maybe", p: true, get: func(ec *EtcdUtil) { ec.MustGetBool("key0") }},
+		{val: "1.5", get: func(ec *EtcdUtil) { ec.MustGetFloat64("key0") }},
+		{val: "one", p: true, get: func(ec *EtcdUtil) { ec.MustGetFloat64("key0") }},
+		{val: "a,b", get: func(ec *EtcdUtil) { ec.MustGetStrings("key0") }},
+		{val: "[a", p: true, get: func(ec *EtcdUtil) { ec.MustGetStrings("key0") }},
+		{val: "2015-06-01T12:30:00Z", get: func(ec *EtcdUtil) { ec.MustGetTime("key0") }},
+		{val: "yesterday", p: true, get: func(ec *EtcdUtil) { ec.MustGetTime("key0") }},
+	}
+
+	for k, test := range tests {
+		e := newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: test.val}})
+		ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+		p := func() (p bool) {
+			defer func() {
+				if r := recover(); r != nil {
+					p = true
+				}
+			}()
+			test.get(ec)
+			return false
+		}()
+		if p != test.p {
+			t.Errorf("Test %v: wanted panic %v but got %v", k, test.p, p)
+		}
+	}
+}
+
 func TestGetJSON(t *testing.T) {
 	type tv struct {
 		Name string
@@ -435,35 +509,28 @@ func TestGetJSON(t *testing.T) {
 		key string
 		val tv
 		err error
-		e   ecs
+		e   *etcdutiltest.Fake
 	}{
 		{
 			key: "key0",
 			val: tv{Name: "Test", Age: 33},
 			err: nil,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: `{"Name": "Test", "Age": 33}`}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: `{"Name": "Test", "Age": 33}`}}),
 		},
 		{
 			key: "key0",
 			err: etcd.ErrWatchStoppedByUser,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: `{"Name": "Test", "Age": 33}`}},
-				err:   etcd.ErrWatchStoppedByUser,
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: `{"Name": "Test", "Age": 33}`}}, etcd.ErrWatchStoppedByUser),
 		},
 		{
 			key: "key0",
 			err: errors.New("invalid character '$' looking for beginning of value"),
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "$@$@#"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "$@$@#"}}),
 		},
 	}
 
 	for k, test := range tests {
-		ec := &EtcdUtil{p: "/myport/test", c: &test.e, s: make(chan bool)}
+		ec := &EtcdUtil{p: "/myport/test", c: test.e, s: make(chan bool)}
 		mtv := tv{}
 		_, err := ec.GetJSON(test.key, &mtv)
 		if test.err != nil {
@@ -496,29 +563,22 @@ func TestMustGetJSON(t *testing.T) {
 		key string
 		val tv
 		p   bool
-		e   ecs
+		e   *etcdutiltest.Fake
 	}{
 		{
 			key: "key0",
 			val: tv{Name: "Test", Age: 33},
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: `{"Name": "Test", "Age": 33}`}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: `{"Name": "Test", "Age": 33}`}}),
 		},
 		{
 			key: "key0",
 			p:   true,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}},
-				err:   etcd.ErrWatchStoppedByUser,
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "val0"}}, etcd.ErrWatchStoppedByUser),
 		},
 		{
 			key: "key0",
 			p:   true,
-			e: ecs{
-				nodes: etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "#&#&#&#"}},
-			},
+			e:   newFake(etcd.Nodes{&etcd.Node{Key: "/myport/test/key0", Value: "#&#&#&#"}}),
 		},
 	}
 
@@ -531,7 +591,7 @@ func TestMustGetJSON(t *testing.T) {
 
 	for k, test := range tests {
 		p = false
-		ec := &EtcdUtil{p: "/myport/test", c: &test.e, s: make(chan bool)}
+		ec := &EtcdUtil{p: "/myport/test", c: test.e, s: make(chan bool)}
 		val := tv{}
 		ec.MustGetJSON(test.key, &val)
 		if test.p && !p {
@@ -547,10 +607,10 @@ func TestMustGetJSON(t *testing.T) {
 func TestWatch(t *testing.T) {
 	// Chaing the startWait so our test doesn't last forever.
 	startWait = 1 * time.Millisecond
-	e := ecs{nodes: testNodes, c: make(chan *etcd.Response), r: make(chan ret)}
+	e := newFake(testNodes)
 	ec := &EtcdUtil{
 		p: "/myport/test",
-		c: &e,
+		c: e,
 		s: make(chan bool),
 	}
 
@@ -564,26 +624,23 @@ func TestWatch(t *testing.T) {
 		wg.Done()
 	}
 
-	// Start the watch.
-	ec.Watch("/myport/test/k0-0", 0, true, f)
-
-	// Send a return value so we can test the retry.
-	e.r <- ret{nil, errors.New("retry")}
-	time.Sleep(5 * time.Millisecond)
+	// Start the watch. The first try fails so we can test the retry.
+	e.FailNext(errors.New("retry"))
+	ec.Watch("k0-0", e.Index()+1, true, f)
 
-	// Send a could responses to our Watch.
-	e.c <- &etcd.Response{
+	// Send a couple responses to our Watch.
+	e.Send(&etcd.Response{
 		Node: &etcd.Node{
 			Key:   "/myport/test/k0-0/k1-0",
 			Value: "val1",
 		},
-	}
-	e.c <- &etcd.Response{
+	})
+	e.Send(&etcd.Response{
 		Node: &etcd.Node{
 			Key:   "/myport/test/k0-0/k1-1",
 			Value: "val2",
 		},
-	}
+	})
 
 	// Cleanup and check the results.
 	wg.Wait()
@@ -600,10 +657,10 @@ func TestWatch(t *testing.T) {
 func TestWatchRetryClose(t *testing.T) {
 	// We do the same above, we just need to check the return works while we are in the retry loop.
 	startWait = 1 * time.Second
-	e := ecs{nodes: testNodes, c: make(chan *etcd.Response), r: make(chan ret)}
+	e := newFake(testNodes, errors.New("retry"))
 	ec := &EtcdUtil{
 		p: "/myport/test",
-		c: &e,
+		c: e,
 		s: make(chan bool),
 	}
 
@@ -612,38 +669,31 @@ func TestWatchRetryClose(t *testing.T) {
 		res = append(res, key+"|"+val)
 	}
 
-	ec.Watch("/myport/test/k0-0", 0, true, f)
-
-	// Send a return value so we can test the retry.
-	e.r <- ret{nil, errors.New("retry")}
+	// The first try fails, so it's in the retry loop.
+	ec.Watch("k0-0", 0, true, f)
 	go ec.Close()
 	time.Sleep(5 * time.Millisecond)
 }
 
 func TestWalk(t *testing.T) {
 	tests := []struct {
-		key      string   // The key to walk through
-		e        ecs      // The fake etcd client.
-		err      error    // The error to return.
-		errCount int      // When to return the above error.
-		exp      []string // The expected results.
-		expErr   error    // The expected error.
+		key      string             // The key to walk through
+		e        *etcdutiltest.Fake // The fake etcd client.
+		err      error              // The error to return.
+		errCount int                // When to return the above error.
+		exp      []string           // The expected results.
+		expErr   error              // The expected error.
 	}{
 		// Failed get.
 		{
-			key: "doesn't matter",
-			e: ecs{
-				nodes: testNodes,
-				err:   etcd.ErrWatchStoppedByUser,
-			},
+			key:    "doesn't matter",
+			e:      newFake(testNodes, etcd.ErrWatchStoppedByUser),
 			expErr: etcd.ErrWatchStoppedByUser,
 		},
 		// Stop part way through.
 		{
-			key: "k0-0",
-			e: ecs{
-				nodes: testNodes,
-			},
+			key:      "k0-0",
+			e:        newFake(testNodes),
 			err:      etcd.ErrWatchStoppedByUser,
 			errCount: 2,
 			exp: []string{
@@ -655,9 +705,7 @@ func TestWalk(t *testing.T) {
 		// No errors.
 		{
 			key: "k0-0",
-			e: ecs{
-				nodes: testNodes,
-			},
+			e:   newFake(testNodes),
 			exp: []string{
 				"/myport/test/k0-0/k1-0/k2-0|v2-0",
 				"/myport/test/k0-0/k1-0/k2-1|v2-1",
@@ -669,9 +717,7 @@ func TestWalk(t *testing.T) {
 		// Find a deep key.
 		{
 			key: "k0-0/k1-0/k2-0",
-			e: ecs{
-				nodes: testNodes,
-			},
+			e:   newFake(testNodes),
 			exp: []string{
 				"/myport/test/k0-0/k1-0/k2-0|v2-0",
 			},
@@ -679,7 +725,7 @@ func TestWalk(t *testing.T) {
 	}
 
 	for k, test := range tests {
-		ec := &EtcdUtil{p: "/myport/test", c: &test.e, s: make(chan bool)}
+		ec := &EtcdUtil{p: "/myport/test", c: test.e, s: make(chan bool)}
 		count := 0
 		var res []string
 		wf := func(key, value string) error {
@@ -704,71 +750,42 @@ func TestWalk(t *testing.T) {
 	}
 }
 
-// ret is used to send on a channel to force a return.
-type ret struct {
-	r   *etcd.Response
-	err error
-}
-
-type ecs struct {
-	nodes etcd.Nodes // The nodes we are going to search through.
-	err   error      // The error to return on the next call.
-	c     chan *etcd.Response
-	r     chan ret
-}
-
-func (e *ecs) Close() {
-	e.nodes = nil
+// newFake returns a fake etcd client with the values of the given
+// nodes. The errors are returned by the next calls to it.
+func newFake(nodes etcd.Nodes, errs ...error) *etcdutiltest.Fake {
+	f := etcdutiltest.NewFake()
+	setNodes(f, nodes)
+	f.FailNext(errs...)
+	return f
 }
 
-// We can ignore sort because we don't really use it in the
-// util. We'll also ignore recur becase the test should setup the
-// nodes for us. We just need to find it.
-func (e *ecs) Get(key string, sort, recur bool) (*etcd.Response, error) {
-	if e.err != nil {
-		err := e.err
-		e.err = nil
-		return nil, err
-	}
-
-	// Our testing doesn't require anything else.
-	return &etcd.Response{
-		Node: findNode(key, e.nodes),
-	}, nil
-}
-
-func findNode(key string, nodes etcd.Nodes) *etcd.Node {
-	for _, node := range nodes {
-		if node.Key == key {
-			return node
-		} else if len(node.Nodes) > 0 {
-			found := findNode(key, node.Nodes)
-			if found != nil {
-				return found
-			}
+// setNodes sets the values of the nodes and their children.
+func setNodes(f *etcdutiltest.Fake, nodes etcd.Nodes) {
+	for _, n := range nodes {
+		if n.Dir {
+			setNodes(f, n.Nodes)
+			continue
 		}
+		f.Set(n.Key, n.Value, uint64(n.TTL))
 	}
-	return nil
 }
 
-// We can ignore the index because we don't really use it. We can
-// ignore recur as well because the test should setup the nodes for
-// us.
-func (e *ecs) Watch(key string, index uint64, recur bool, out chan *etcd.Response, stop chan bool) (*etcd.Response, error) {
-	for {
-		select {
-		case r := <-e.r:
-			return r.r, r.err
-		case <-stop:
-			return nil, etcd.ErrWatchStoppedByUser
-		case r := <-e.c:
-			select {
-			case r := <-e.r:
-				return r.r, r.err
-			case <-stop:
-				return nil, etcd.ErrWatchStoppedByUser
-			case out <- r:
-			}
+// values returns the values under the given key in the fake.
+func values(f *etcdutiltest.Fake, key string) map[string]string {
+	m := map[string]string{}
+	r, err := f.Get(key, true, true)
+	if err != nil {
+		return m
+	}
+	var walk func(n *etcd.Node)
+	walk = func(n *etcd.Node) {
+		if !n.Dir {
+			m[n.Key] = n.Value
+		}
+		for _, c := range n.Nodes {
+			walk(c)
 		}
 	}
+	walk(r.Node)
+	return m
 }
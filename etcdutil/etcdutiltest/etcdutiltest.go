@@ -0,0 +1,417 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+// Package etcdutiltest provides an in-memory etcd client for testing
+// code that uses etcdutil without running etcd. For example:
+//
+//	f := etcdutiltest.NewFake()
+//	f.Set("/myapp/db/host", "localhost", 0)
+//	u := etcdutil.NewFromClient(f, "/myapp")
+//	host, _ := u.MustGet("db/host")
+//
+// Changes made through the fake are sent to watches like etcd would.
+// Errors can be injected with FailNext and arbitrary watch events with
+// Send.
+package etcdutiltest
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+// etcd's error codes.
+const (
+	ecodeKeyNotFound  = 100
+	ecodeTestFailed   = 101
+	ecodeNotFile      = 102
+	ecodeNodeExist    = 105
+	ecodeMissingValue = 201
+)
+
+// Fake is an in-memory etcd client. It satisfies etcdutil.EtcdClient.
+// Instantiate it with NewFake.
+type Fake struct {
+	mu      sync.Mutex
+	entries map[string]*entry // The values keyed by their full key.
+	index   uint64            // The etcd index.
+	errs    []error           // The errors to return next.
+	history []*etcd.Response  // Every event so watches can catch up.
+	changed chan struct{}     // Closed and replaced on every event.
+	closed  bool
+}
+
+// entry is a value in the fake.
+type entry struct {
+	value    string
+	ttl      int64
+	created  uint64
+	modified uint64
+}
+
+// NewFake creates an empty fake.
+func NewFake() *Fake {
+	return &Fake{
+		entries: map[string]*entry{},
+		changed: make(chan struct{}),
+	}
+}
+
+// FailNext makes the next calls to the fake's client functions return
+// the given errors, one per call, instead of doing anything. Watches
+// that are already running aren't affected.
+func (f *Fake) FailNext(errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errs = append(f.errs, errs...)
+}
+
+// Send sends the response to the watches of its node's key as if it
+// were a change in etcd. The fake's values aren't changed. If the
+// response's EtcdIndex is 0, it's given the next index.
+func (f *Fake) Send(r *etcd.Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.EtcdIndex == 0 {
+		f.index++
+		r.EtcdIndex = f.index
+	}
+	f.notify(r)
+}
+
+// Expire removes the key as if its ttl passed. It returns false if the
+// key doesn't exist.
+func (f *Fake) Expire(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	k := clean(key)
+	e, ok := f.entries[k]
+	if !ok {
+		return false
+	}
+	delete(f.entries, k)
+	f.index++
+	f.notify(f.response("expire", &etcd.Node{Key: k, CreatedIndex: e.created,
+		ModifiedIndex: f.index}, e.node(k)))
+	return true
+}
+
+// Index returns the current etcd index.
+func (f *Fake) Index() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.index
+}
+
+// Closed returns true if Close has been called.
+func (f *Fake) Closed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+// Close marks the fake closed. It can still be used afterwards.
+func (f *Fake) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+}
+
+// Get returns the node for the key. Directories are sorted by key
+// regardless of sort. Their children only contain their own children
+// if recursive is true.
+func (f *Fake) Get(key string, sort, recursive bool) (*etcd.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.nextErr(); err != nil {
+		return nil, err
+	}
+	k := clean(key)
+	if e, ok := f.entries[k]; ok {
+		return f.response("get", e.node(k), nil), nil
+	}
+	if !f.isDir(k) {
+		return nil, f.error(ecodeKeyNotFound, "Key not found", k)
+	}
+	return f.response("get", f.dir(k, recursive), nil), nil
+}
+
+// Set sets the value of the key.
+func (f *Fake) Set(key, value string, ttl uint64) (*etcd.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.nextErr(); err != nil {
+		return nil, err
+	}
+	return f.set("set", clean(key), value, ttl)
+}
+
+// Create sets the value of the key if it doesn't exist.
+func (f *Fake) Create(key, value string, ttl uint64) (*etcd.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.nextErr(); err != nil {
+		return nil, err
+	}
+	k := clean(key)
+	if _, ok := f.entries[k]; ok || f.isDir(k) {
+		return nil, f.error(ecodeNodeExist, "Key already exists", k)
+	}
+	return f.set("create", k, value, ttl)
+}
+
+// Delete removes the key. Directories are only removed if recursive
+// is true.
+func (f *Fake) Delete(key string, recursive bool) (*etcd.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.nextErr(); err != nil {
+		return nil, err
+	}
+	k := clean(key)
+	if _, ok := f.entries[k]; ok {
+		return f.remove("delete", k), nil
+	}
+	if !f.isDir(k) {
+		return nil, f.error(ecodeKeyNotFound, "Key not found", k)
+	}
+	if !recursive {
+		return nil, f.error(ecodeNotFile, "Not a file", k)
+	}
+	for ek := range f.entries {
+		if strings.HasPrefix(ek, dirPrefix(k)) {
+			delete(f.entries, ek)
+		}
+	}
+	f.index++
+	r := f.response("delete", &etcd.Node{Key: k, Dir: true, ModifiedIndex: f.index},
+		&etcd.Node{Key: k, Dir: true})
+	f.notify(r)
+	return r, nil
+}
+
+// CompareAndSwap sets the value of the key if its value and modified
+// index match.
+func (f *Fake) CompareAndSwap(key, value string, ttl uint64, prevValue string,
+	prevIndex uint64) (*etcd.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.nextErr(); err != nil {
+		return nil, err
+	}
+	k := clean(key)
+	if err := f.compare(k, prevValue, prevIndex); err != nil {
+		return nil, err
+	}
+	return f.set("compareAndSwap", k, value, ttl)
+}
+
+// CompareAndDelete removes the key if its value and modified index
+// match.
+func (f *Fake) CompareAndDelete(key, prevValue string, prevIndex uint64) (*etcd.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.nextErr(); err != nil {
+		return nil, err
+	}
+	k := clean(key)
+	if err := f.compare(k, prevValue, prevIndex); err != nil {
+		return nil, err
+	}
+	return f.remove("compareAndDelete", k), nil
+}
+
+// Watch waits for changes to the key (or the keys under it if
+// recursive is true) starting at waitIndex. If waitIndex is 0, only
+// new changes are returned. If receiver is nil, the first change is
+// returned. Otherwise, changes are sent to it until stop is closed.
+func (f *Fake) Watch(key string, waitIndex uint64, recursive bool,
+	receiver chan *etcd.Response, stop chan bool) (*etcd.Response, error) {
+	f.mu.Lock()
+	if err := f.nextErr(); err != nil {
+		f.mu.Unlock()
+		return nil, err
+	}
+	k := clean(key)
+	next := waitIndex
+	if next == 0 {
+		next = f.index + 1
+	}
+	f.mu.Unlock()
+	for {
+		f.mu.Lock()
+		var r *etcd.Response
+		for _, h := range f.history {
+			if h.EtcdIndex >= next && matches(k, h.Node.Key, recursive) {
+				r = h
+				break
+			}
+		}
+		changed := f.changed
+		f.mu.Unlock()
+
+		if r == nil {
+			select {
+			case <-stop:
+				return nil, etcd.ErrWatchStoppedByUser
+			case <-changed:
+			}
+			continue
+		}
+		next = r.EtcdIndex + 1
+		if receiver == nil {
+			return r, nil
+		}
+		select {
+		case <-stop:
+			return nil, etcd.ErrWatchStoppedByUser
+		case receiver <- r:
+		}
+	}
+}
+
+// nextErr returns the next error from FailNext if there is one.
+func (f *Fake) nextErr() error {
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+// set sets the value of the key and notifies the watches.
+func (f *Fake) set(action, k, value string, ttl uint64) (*etcd.Response, error) {
+	if f.isDir(k) {
+		return nil, f.error(ecodeNotFile, "Not a file", k)
+	}
+	f.index++
+	var prev *etcd.Node
+	e := &entry{value: value, ttl: int64(ttl), created: f.index, modified: f.index}
+	if old, ok := f.entries[k]; ok {
+		prev = old.node(k)
+		e.created = old.created
+	}
+	f.entries[k] = e
+	r := f.response(action, e.node(k), prev)
+	f.notify(r)
+	return r, nil
+}
+
+// remove deletes the key and notifies the watches.
+func (f *Fake) remove(action, k string) *etcd.Response {
+	e := f.entries[k]
+	delete(f.entries, k)
+	f.index++
+	r := f.response(action, &etcd.Node{Key: k, CreatedIndex: e.created,
+		ModifiedIndex: f.index}, e.node(k))
+	f.notify(r)
+	return r
+}
+
+// compare checks the key against the values for CompareAndSwap and
+// CompareAndDelete.
+func (f *Fake) compare(k, prevValue string, prevIndex uint64) error {
+	if prevValue == "" && prevIndex == 0 {
+		return f.error(ecodeMissingValue, "You must give either prevValue or prevIndex.", k)
+	}
+	e, ok := f.entries[k]
+	if !ok {
+		return f.error(ecodeKeyNotFound, "Key not found", k)
+	}
+	if (prevValue != "" && prevValue != e.value) ||
+		(prevIndex != 0 && prevIndex != e.modified) {
+		return f.error(ecodeTestFailed, "Compare failed",
+			fmt.Sprintf("[%v != %v] [%v != %v]", prevValue, e.value, prevIndex, e.modified))
+	}
+	return nil
+}
+
+// notify records the event and wakes up the watches.
+func (f *Fake) notify(r *etcd.Response) {
+	f.history = append(f.history, r)
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// response creates a response with the current index.
+func (f *Fake) response(action string, n, prev *etcd.Node) *etcd.Response {
+	return &etcd.Response{Action: action, Node: n, PrevNode: prev, EtcdIndex: f.index}
+}
+
+// error creates an etcd error with the current index.
+func (f *Fake) error(code int, message, cause string) error {
+	return &etcd.EtcdError{ErrorCode: code, Message: message, Cause: cause, Index: f.index}
+}
+
+// isDir returns true if there are keys under k.
+func (f *Fake) isDir(k string) bool {
+	for ek := range f.entries {
+		if strings.HasPrefix(ek, dirPrefix(k)) {
+			return true
+		}
+	}
+	return k == "/"
+}
+
+// dir returns the node for the directory k. Sub-directories only
+// contain their children if recursive is true.
+func (f *Fake) dir(k string, recursive bool) *etcd.Node {
+	n := &etcd.Node{Key: k, Dir: true}
+	seen := map[string]bool{}
+	for ek, e := range f.entries {
+		if !strings.HasPrefix(ek, dirPrefix(k)) {
+			continue
+		}
+		rest := ek[len(dirPrefix(k)):]
+		i := strings.Index(rest, "/")
+		if i < 0 {
+			n.Nodes = append(n.Nodes, e.node(ek))
+			continue
+		}
+		ck := dirPrefix(k) + rest[:i]
+		if seen[ck] {
+			continue
+		}
+		seen[ck] = true
+		if recursive {
+			n.Nodes = append(n.Nodes, f.dir(ck, true))
+		} else {
+			n.Nodes = append(n.Nodes, &etcd.Node{Key: ck, Dir: true})
+		}
+	}
+	sort.Sort(n.Nodes)
+	return n
+}
+
+// node returns the node for the entry.
+func (e *entry) node(k string) *etcd.Node {
+	return &etcd.Node{Key: k, Value: e.value, TTL: e.ttl,
+		CreatedIndex: e.created, ModifiedIndex: e.modified}
+}
+
+// clean normalizes the key.
+func clean(key string) string {
+	return path.Clean("/" + key)
+}
+
+// dirPrefix returns the prefix of the keys under the directory k.
+func dirPrefix(k string) string {
+	if k == "/" {
+		return k
+	}
+	return k + "/"
+}
+
+// matches returns true if a change to key should be seen by a watch
+// of k.
+func matches(k, key string, recursive bool) bool {
+	return key == k || (recursive && strings.HasPrefix(key, dirPrefix(k)))
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutiltest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/icub3d/gop/etcdutil"
+)
+
+var _ etcdutil.EtcdClient = (*Fake)(nil)
+
+func TestFake(t *testing.T) {
+	f := NewFake()
+	u := etcdutil.NewFromClient(f, "/myapp")
+	f.Set("/myapp/db/host", "localhost", 0)
+	f.Set("myapp/db/pool/size/", "10", 0)
+	f.Set("/other", "x", 0)
+
+	if v, _ := u.MustGet("db/host"); v != "localhost" {
+		t.Errorf("MustGet() = %v", v)
+	}
+	m, i, err := u.GetAll("db")
+	exp := map[string]string{"host": "localhost", "pool/size": "10"}
+	if err != nil || i != 3 || !reflect.DeepEqual(m, exp) {
+		t.Errorf("GetAll() = %v, %v, %v", m, i, err)
+	}
+
+	// Directories.
+	r, _ := f.Get("/myapp", false, false)
+	if len(r.Node.Nodes) != 1 || !r.Node.Nodes[0].Dir || r.Node.Nodes[0].Nodes != nil {
+		t.Errorf("non-recursive Get() of directory = %v", r.Node.Nodes)
+	}
+	r, _ = f.Get("/", true, true)
+	if len(r.Node.Nodes) != 2 || r.Node.Nodes[0].Key != "/myapp" ||
+		len(r.Node.Nodes[0].Nodes[0].Nodes) != 2 {
+		t.Errorf("recursive Get() of root = %v", r.Node.Nodes)
+	}
+	if _, err := f.Set("/myapp/db", "x", 0); !isCode(err, ecodeNotFile) {
+		t.Errorf("Set() of directory = %v", err)
+	}
+	if _, err := f.Delete("/myapp/db", false); !isCode(err, ecodeNotFile) {
+		t.Errorf("non-recursive Delete() of directory = %v", err)
+	}
+	if _, err := f.Delete("/myapp/db", true); err != nil {
+		t.Errorf("recursive Delete() of directory = %v", err)
+	}
+	if _, _, err := u.Get("db/host", ""); !isCode(err, ecodeKeyNotFound) {
+		t.Errorf("Get() of removed key = %v", err)
+	}
+	if _, err := f.Delete("/myapp/db", true); !isCode(err, ecodeKeyNotFound) {
+		t.Errorf("Delete() of missing key = %v", err)
+	}
+
+	// Create and compares.
+	if _, err := f.Create("/other", "y", 0); !isCode(err, ecodeNodeExist) {
+		t.Errorf("Create() of existing key = %v", err)
+	}
+	if _, err := f.Create("/new", "y", 5); err != nil {
+		t.Errorf("Create() failed: %v", err)
+	}
+	_, mi, _ := u.GetForUpdate("../other")
+	if _, err := u.CompareAndSwap("../other", "z", "y", 0); err != etcdutil.ErrCompareFailed {
+		t.Errorf("CompareAndSwap() with wrong value = %v", err)
+	}
+	if _, err := u.CompareAndSwap("../other", "z", "x", mi); err != nil {
+		t.Errorf("CompareAndSwap() failed: %v", err)
+	}
+	if err := u.CompareAndDelete("../other", "", mi); err != etcdutil.ErrCompareFailed {
+		t.Errorf("CompareAndDelete() with old index = %v", err)
+	}
+	if _, err := f.CompareAndDelete("/other", "", 0); !isCode(err, ecodeMissingValue) {
+		t.Errorf("CompareAndDelete() without values = %v", err)
+	}
+	if err := u.CompareAndDelete("../other", "z", 0); err != nil {
+		t.Errorf("CompareAndDelete() failed: %v", err)
+	}
+
+	// Injected errors.
+	e := errors.New("injected")
+	f.FailNext(e, e)
+	if _, _, err := u.Get("x", ""); err != e {
+		t.Errorf("first injected error = %v", err)
+	}
+	if _, err := f.Set("/x", "", 0); err != e {
+		t.Errorf("second injected error = %v", err)
+	}
+	if _, err := f.Set("/x", "", 0); err != nil {
+		t.Errorf("error after injected ones = %v", err)
+	}
+
+	u.Close()
+	if !f.Closed() {
+		t.Errorf("Closed() = false after Close()")
+	}
+}
+
+func TestFakeWatch(t *testing.T) {
+	f := NewFake()
+	u := etcdutil.NewFromClient(f, "/myapp")
+	defer u.Close()
+	f.Set("/myapp/a", "1", 0)
+	start := f.Index()
+	f.Set("/myapp/b", "2", 0)
+	f.Set("/other", "x", 0)
+
+	type change struct{ key, value string }
+	changes := make(chan change, 10)
+	// Past changes are seen from the wait index.
+	u.Watch("", start, true, func(key, value string) {
+		changes <- change{key, value}
+	})
+	expect := func(exp ...change) {
+		for _, e := range exp {
+			select {
+			case c := <-changes:
+				if c != e {
+					t.Errorf("got change %v but wanted %v", c, e)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("didn't get change %v", e)
+			}
+		}
+	}
+	expect(change{"/myapp/a", "1"}, change{"/myapp/b", "2"})
+
+	// New changes, expiries and scripted events.
+	f.Set("/myapp/a", "3", 0)
+	if !f.Expire("/myapp/b") || f.Expire("/myapp/b") {
+		t.Errorf("Expire() returned the wrong values")
+	}
+	f.Send(&etcd.Response{Action: "set", Node: &etcd.Node{Key: "/myapp/c", Value: "4"}})
+	expect(change{"/myapp/a", "3"}, change{"/myapp/b", ""}, change{"/myapp/c", "4"})
+
+	// A watch without a receiver returns the first change.
+	next := f.Index() + 1
+	go f.Set("/myapp/d", "5", 0)
+	r, err := f.Watch("/myapp/d", next, false, nil, nil)
+	if err != nil || r.Action != "set" || r.Node.Value != "5" {
+		t.Errorf("Watch() = %v, %v", r, err)
+	}
+	stop := make(chan bool)
+	close(stop)
+	if _, err := f.Watch("/myapp/e", 0, false, nil, stop); err != etcd.ErrWatchStoppedByUser {
+		t.Errorf("stopped Watch() = %v", err)
+	}
+}
+
+// isCode returns true if err is an etcd error with the given code.
+func isCode(err error, code int) bool {
+	e, ok := err.(*etcd.EtcdError)
+	return ok && e.ErrorCode == code
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"flag"
+	"os"
+	"strings"
+)
+
+// Fallback configures local sources of values for the Get* functions.
+// This makes it possible to run without etcd (e.g. during local
+// development) or to override values from etcd.
+type Fallback struct {
+	// EnvPrefix enables looking up keys in the environment. The
+	// variable for a key is EnvPrefix, an underscore, and the key in
+	// upper case with anything other than letters and numbers replaced
+	// by underscores. For example, with the prefix "MYAPP", the key
+	// "db/host" is read from MYAPP_DB_HOST. If empty, the environment
+	// isn't used.
+	EnvPrefix string
+
+	// Flags enables looking up keys in the given flag set. The flag
+	// for a key is the key with slashes replaced by dashes (e.g.
+	// "db-host"). Only flags that were set on the command line are
+	// used. If nil, flags aren't used.
+	Flags *flag.FlagSet
+
+	// Override gives the local sources precedence over etcd. By
+	// default, they are only used when the value can't be read from
+	// etcd.
+	Override bool
+}
+
+// SetFallback sets the local sources the Get* functions use. Flags
+// take precedence over the environment. If a value isn't found in
+// etcd or any of the local sources, the default given to the Get*
+// function is used. It should be called before the util is used.
+func (u *EtcdUtil) SetFallback(f Fallback) {
+	u.f = f
+}
+
+// local looks for the key in the local sources.
+func (f Fallback) local(key string) (string, bool) {
+	if f.Flags != nil {
+		name := strings.Replace(strings.Trim(key, "/"), "/", "-", -1)
+		value, found := "", false
+		f.Flags.Visit(func(fl *flag.Flag) {
+			if fl.Name == name {
+				value, found = fl.Value.String(), true
+			}
+		})
+		if found {
+			return value, true
+		}
+	}
+	if f.EnvPrefix != "" {
+		return os.LookupEnv(f.EnvPrefix + "_" + envName(key))
+	}
+	return "", false
+}
+
+// envName converts the key to the form used for environment
+// variables.
+func envName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, strings.Trim(key, "/"))
+}
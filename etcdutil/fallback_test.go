@@ -0,0 +1,75 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestFallback(t *testing.T) {
+	os.Setenv("ETCDUTILTEST_DB_HOST", "env")
+	os.Setenv("ETCDUTILTEST_DB_PORT", "env")
+	os.Setenv("ETCDUTILTEST_DB_USER_NAME", "env")
+	defer os.Unsetenv("ETCDUTILTEST_DB_HOST")
+	defer os.Unsetenv("ETCDUTILTEST_DB_PORT")
+	defer os.Unsetenv("ETCDUTILTEST_DB_USER_NAME")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.String("db-host", "unset", "")
+	fs.String("db-name", "unset", "")
+	if err := fs.Parse([]string{"-db-host=flag"}); err != nil {
+		t.Fatalf("parsing flags: %v", err)
+	}
+	nodes := etcd.Nodes{&etcd.Node{Key: "/myport/test/db/host", Value: "etcd"}}
+
+	tests := []struct {
+		key string   // The key to get.
+		f   Fallback // The fallback to use.
+		exp string   // The expected value.
+		err bool     // Whether an error is expected.
+	}{
+		// No fallback.
+		{key: "db/host", exp: "etcd"},
+		{key: "db/port", exp: "def", err: true},
+		// The etcd value takes precedence.
+		{key: "db/host", f: Fallback{EnvPrefix: "ETCDUTILTEST", Flags: fs}, exp: "etcd"},
+		// Flags and then the environment.
+		{key: "/db/host/", f: Fallback{EnvPrefix: "ETCDUTILTEST", Flags: fs, Override: true}, exp: "flag"},
+		{key: "db/port", f: Fallback{EnvPrefix: "ETCDUTILTEST", Flags: fs}, exp: "env"},
+		{key: "db/user-name", f: Fallback{EnvPrefix: "ETCDUTILTEST"}, exp: "env"},
+		{key: "db/host", f: Fallback{EnvPrefix: "ETCDUTILTEST", Override: true}, exp: "env"},
+		// Unset flags aren't used.
+		{key: "db/name", f: Fallback{Flags: fs}, exp: "def", err: true},
+		{key: "db/name", f: Fallback{Flags: fs, Override: true}, exp: "def", err: true},
+	}
+	for k, test := range tests {
+		ec := &EtcdUtil{p: "/myport/test", c: newFake(nodes), s: make(chan bool)}
+		ec.SetFallback(test.f)
+		v, _, err := ec.Get(test.key, "def")
+		if test.err != (err != nil) {
+			t.Errorf("Test %v: wanted error %v but got '%v'", k, test.err, err)
+		}
+		if v != test.exp {
+			t.Errorf("Test %v: Expected value %v but got %v", k, test.exp, v)
+		}
+	}
+
+	// It works without etcd and for the typed getters.
+	os.Setenv("ETCDUTILTEST_PORT", "5432")
+	defer os.Unsetenv("ETCDUTILTEST_PORT")
+	ec := &EtcdUtil{p: "/myport/test", c: newFake(nil, etcd.ErrWatchStoppedByUser),
+		s: make(chan bool)}
+	ec.SetFallback(Fallback{EnvPrefix: "ETCDUTILTEST"})
+	if v, i, err := ec.GetInt("port", 0); v != 5432 || i != 0 || err != nil {
+		t.Errorf("GetInt() = %v, %v, %v", v, i, err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// GetAll returns all the values under prefix+key. The keys in the map
+// are relative to prefix+key, so a directory subtree like
+// /prefix/db/host and /prefix/db/pool/size becomes "host" and
+// "pool/size" for GetAll("db").
+func (u *EtcdUtil) GetAll(key string) (map[string]string, uint64, error) {
+	m := map[string]string{}
+	base := strings.Join([]string{u.p, key}, "/")
+	i, err := u.Walk(key, false, func(k, v string) error {
+		m[relativeKey(base, k)] = v
+		return nil
+	})
+	if err != nil {
+		return nil, i, err
+	}
+	return m, i, nil
+}
+
+// GetAllJSON is like GetAll but decodes each value as JSON into dst,
+// which must be a pointer to a map with string keys (e.g.
+// *map[string]Config). If the map is nil, a new one is made.
+func (u *EtcdUtil) GetAllJSON(key string, dst interface{}) (uint64, error) {
+	p := reflect.ValueOf(dst)
+	if p.Kind() != reflect.Ptr || p.IsNil() || p.Elem().Kind() != reflect.Map ||
+		p.Elem().Type().Key().Kind() != reflect.String {
+		return 0, fmt.Errorf("GetAllJSON(%v): dst must be a pointer to a map with string keys, not %T", key, dst)
+	}
+	m := p.Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+	base := strings.Join([]string{u.p, key}, "/")
+	return u.Walk(key, false, func(k, v string) error {
+		e := reflect.New(m.Type().Elem())
+		if err := json.Unmarshal([]byte(v), e.Interface()); err != nil {
+			return fmt.Errorf("GetAllJSON(%v): decoding %v: %v", key, k, err)
+		}
+		m.SetMapIndex(reflect.ValueOf(relativeKey(base, k)).Convert(m.Type().Key()), e.Elem())
+		return nil
+	})
+}
+
+// relativeKey removes base from the beginning of k along with the
+// separating slash.
+func relativeKey(base, k string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(k, base), "/")
+}
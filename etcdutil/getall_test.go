@@ -0,0 +1,80 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestGetAll(t *testing.T) {
+	ec := &EtcdUtil{p: "/myport/test", c: newFake(testNodes), s: make(chan bool)}
+	m, _, err := ec.GetAll("k0-0")
+	if err != nil {
+		t.Fatalf("GetAll() failed: %v", err)
+	}
+	exp := map[string]string{
+		"k1-0/k2-0": "v2-0",
+		"k1-0/k2-1": "v2-1",
+		"k1-0/k2-2": "v2-2",
+		"k1-1":      "v1-1",
+		"k1-2":      "v1-2",
+	}
+	if !reflect.DeepEqual(m, exp) {
+		t.Errorf("Expecting %v but got %v", exp, m)
+	}
+
+	ec = &EtcdUtil{p: "/myport/test", c: newFake(testNodes,
+		etcd.ErrWatchStoppedByUser), s: make(chan bool)}
+	if _, _, err := ec.GetAll("k0-0"); err != etcd.ErrWatchStoppedByUser {
+		t.Errorf("GetAll() didn't return error: %v", err)
+	}
+}
+
+func TestGetAllJSON(t *testing.T) {
+	type server struct {
+		Host string
+		Port int
+	}
+	nodes := etcd.Nodes{
+		&etcd.Node{Key: "/myport/test/servers", Dir: true, Nodes: etcd.Nodes{
+			&etcd.Node{Key: "/myport/test/servers/a", Value: `{"Host":"a","Port":1}`},
+			&etcd.Node{Key: "/myport/test/servers/b", Value: `{"Host":"b","Port":2}`},
+		}},
+		&etcd.Node{Key: "/myport/test/bad", Dir: true, Nodes: etcd.Nodes{
+			&etcd.Node{Key: "/myport/test/bad/a", Value: `{`},
+		}},
+	}
+	ec := &EtcdUtil{p: "/myport/test", c: newFake(nodes), s: make(chan bool)}
+
+	var m map[string]server
+	if _, err := ec.GetAllJSON("servers", &m); err != nil {
+		t.Fatalf("GetAllJSON() failed: %v", err)
+	}
+	exp := map[string]server{"a": {"a", 1}, "b": {"b", 2}}
+	if !reflect.DeepEqual(m, exp) {
+		t.Errorf("Expecting %v but got %v", exp, m)
+	}
+
+	// Pointer values work as well.
+	mp := map[string]*server{}
+	if _, err := ec.GetAllJSON("servers", &mp); err != nil || *mp["b"] != exp["b"] {
+		t.Errorf("GetAllJSON() into pointers failed: %v %v", mp, err)
+	}
+
+	for k, dst := range []interface{}{m, (*map[string]int)(nil), &[]string{},
+		&map[int]string{}} {
+		if _, err := ec.GetAllJSON("servers", dst); err == nil {
+			t.Errorf("Test %v: expected error for dst %T", k, dst)
+		}
+	}
+	if _, err := ec.GetAllJSON("bad", &m); err == nil {
+		t.Errorf("GetAllJSON() of bad JSON didn't fail")
+	}
+}
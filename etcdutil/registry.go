@@ -0,0 +1,97 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// serviceKey returns the key for the service, or an instance of it if
+// instanceID isn't empty, relative to the prefix.
+func serviceKey(service, instanceID string) string {
+	if instanceID == "" {
+		return "services/" + service
+	}
+	return "services/" + service + "/" + instanceID
+}
+
+// Register adds an instance of a service to the registry. The
+// registry is kept under prefix+/services/<service>/<instanceID>
+// whose value is the instance's address. The key is refreshed in the
+// background like KeepAlive, so if the process dies, the instance is
+// removed once the ttl has passed. Registering an instance again
+// replaces the previous registration.
+func (u *EtcdUtil) Register(service, instanceID, address string, ttl time.Duration) error {
+	k := serviceKey(service, instanceID)
+	stop, err := u.KeepAlive(k, address, ttl)
+	if err != nil {
+		return err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.regs == nil {
+		u.regs = map[string]func(){}
+	}
+	if old, ok := u.regs[k]; ok {
+		old()
+	}
+	u.regs[k] = stop
+	return nil
+}
+
+// Deregister removes an instance of a service from the registry and
+// stops refreshing it.
+func (u *EtcdUtil) Deregister(service, instanceID string) error {
+	k := serviceKey(service, instanceID)
+	u.mu.Lock()
+	if stop, ok := u.regs[k]; ok {
+		stop()
+		delete(u.regs, k)
+	}
+	u.mu.Unlock()
+	_, err := u.c.Delete(strings.Join([]string{u.p, k}, "/"), false)
+	if isEtcdError(err, ecodeKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Discover returns the live instances of a service as a map of their
+// IDs to their addresses. If there are none, the map is empty.
+func (u *EtcdUtil) Discover(service string) (map[string]string, uint64, error) {
+	m, i, err := u.GetAll(serviceKey(service, ""))
+	if isEtcdError(err, ecodeKeyNotFound) {
+		return map[string]string{}, i, nil
+	}
+	return m, i, err
+}
+
+// WatchService calls f with the live instances of a service (as
+// returned by Discover) and then again whenever they change. Because
+// the watch starts from the index of the initial lookup, no changes
+// are missed in between. Lookups that fail after the first are logged
+// and f isn't called for them.
+//
+// The watch continues until Close() is called.
+func (u *EtcdUtil) WatchService(service string, f func(instances map[string]string)) error {
+	m, i, err := u.Discover(service)
+	if err != nil {
+		return err
+	}
+	f(m)
+	u.Watch(serviceKey(service, ""), i+1, true, func(key, value string) {
+		m, _, err := u.Discover(service)
+		if err != nil {
+			log.Printf("WatchService(%v): %v\n", service, err)
+			return
+		}
+		f(m)
+	})
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestRegistry(t *testing.T) {
+	e := newFake(nil)
+	ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+	defer ec.Close()
+
+	if m, _, err := ec.Discover("db"); err != nil || len(m) != 0 {
+		t.Errorf("Discover() of unknown service = %v, %v", m, err)
+	}
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if err := ec.Register("web", "a", "10.0.0.1:80", time.Minute); err == nil {
+		t.Errorf("Register() didn't return error")
+	}
+	for _, id := range []string{"a", "b", "b"} {
+		if err := ec.Register("web", id, "10.0.0."+id+":80", time.Minute); err != nil {
+			t.Fatalf("Register(%v) failed: %v", id, err)
+		}
+	}
+	exp := map[string]string{"a": "10.0.0.a:80", "b": "10.0.0.b:80"}
+	if m, _, err := ec.Discover("web"); err != nil || !reflect.DeepEqual(m, exp) {
+		t.Errorf("Discover() = %v, %v", m, err)
+	}
+
+	instances := make(chan map[string]string, 1)
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if err := ec.WatchService("web", func(m map[string]string) {}); err == nil {
+		t.Errorf("WatchService() didn't return error")
+	}
+	if err := ec.WatchService("web", func(m map[string]string) { instances <- m }); err != nil {
+		t.Fatalf("WatchService() failed: %v", err)
+	}
+	if m := <-instances; !reflect.DeepEqual(m, exp) {
+		t.Errorf("initial instances = %v", m)
+	}
+
+	// Deregistering removes the instance.
+	if err := ec.Deregister("web", "b"); err != nil {
+		t.Errorf("Deregister() failed: %v", err)
+	}
+	if err := ec.Deregister("web", "b"); err != nil {
+		t.Errorf("Deregister() of missing instance failed: %v", err)
+	}
+	select {
+	case m := <-instances:
+		if !reflect.DeepEqual(m, map[string]string{"a": "10.0.0.a:80"}) {
+			t.Errorf("instances after Deregister() = %v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("change not seen")
+	}
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if err := ec.Deregister("web", "a"); err != etcd.ErrWatchStoppedByUser {
+		t.Errorf("Deregister() didn't return error: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ttlSeconds converts the ttl to the whole seconds etcd uses. Partial
+// seconds are rounded up so the key never expires early.
+func ttlSeconds(ttl time.Duration) uint64 {
+	s := uint64((ttl + time.Second - 1) / time.Second)
+	if s < 1 {
+		s = 1
+	}
+	return s
+}
+
+// refreshEvery returns how often a key with the ttl is refreshed. It's
+// a third of the ttl etcd actually uses, so a tiny ttl isn't refreshed
+// far more often than needed.
+func refreshEvery(ttl time.Duration) time.Duration {
+	return time.Duration(ttlSeconds(ttl)) * time.Second / 3
+}
+
+// SetWithTTL sets prefix+key to the given value. The key is removed
+// by etcd once the ttl has passed unless it's set again. Any ttl less
+// than a second is treated as a second. The modified index of the key
+// is returned.
+func (u *EtcdUtil) SetWithTTL(key, value string, ttl time.Duration) (uint64, error) {
+	k := strings.Join([]string{u.p, key}, "/")
+	r, err := u.c.Set(k, value, ttlSeconds(ttl))
+	if err != nil {
+		return 0, err
+	}
+	return r.Node.ModifiedIndex, nil
+}
+
+// KeepAlive sets prefix+key to the given value with the given ttl and
+// then refreshes it in the background so it doesn't expire. This is
+// useful for presence or heartbeat keys. The key is refreshed every
+// third of the ttl until the returned stop function is called or
+// Close() is called. After that, the key expires normally. Failed
+// refreshes are logged and retried at the next interval.
+//
+// An error is returned if the key can't be set initially. In that
+// case, nothing is started.
+func (u *EtcdUtil) KeepAlive(key, value string, ttl time.Duration) (func(), error) {
+	if _, err := u.SetWithTTL(key, value, ttl); err != nil {
+		return nil, err
+	}
+	k := strings.Join([]string{u.p, key}, "/")
+	stop := make(chan struct{})
+	every := refreshEvery(ttl)
+	go func() {
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-u.s:
+				return
+			case <-stop:
+				return
+			case <-t.C:
+				if _, err := u.c.Set(k, value, ttlSeconds(ttl)); err != nil {
+					log.Printf("KeepAlive(%v): %v - Retrying in %v\n", k, err, every)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(stop) }) }, nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestSetWithTTL(t *testing.T) {
+	e := newFake(nil)
+	ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+	tests := []struct {
+		ttl time.Duration // The ttl given.
+		exp int64         // The ttl expected in etcd.
+	}{
+		{ttl: 0, exp: 1},
+		{ttl: 10 * time.Millisecond, exp: 1},
+		{ttl: time.Second, exp: 1},
+		{ttl: 1500 * time.Millisecond, exp: 2},
+		{ttl: time.Minute, exp: 60},
+	}
+	for k, test := range tests {
+		i, err := ec.SetWithTTL("key0", "val0", test.ttl)
+		if err != nil {
+			t.Errorf("Test %v: unexpected error: %v", k, err)
+			continue
+		}
+		if i != uint64(k+1) {
+			t.Errorf("Test %v: wanted index %v but got %v", k, k+1, i)
+		}
+		r, err := e.Get("/myport/test/key0", false, false)
+		if err != nil || r.Node.Value != "val0" || r.Node.TTL != test.exp {
+			t.Errorf("Test %v: wanted ttl %v but got %v, %v", k, test.exp, r, err)
+		}
+	}
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if _, err := ec.SetWithTTL("key0", "val0", time.Second); err != etcd.ErrWatchStoppedByUser {
+		t.Errorf("SetWithTTL() didn't return error: %v", err)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	e := newFake(nil)
+	ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+
+	// A failed initial set.
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if _, err := ec.KeepAlive("key0", "val0", 30*time.Millisecond); err == nil {
+		t.Fatalf("KeepAlive() didn't return error")
+	}
+
+	// The key should be refreshed until stop is called. A failed
+	// refresh is retried.
+	stop, err := ec.KeepAlive("key0", "val0", 30*time.Millisecond)
+	if err != nil {
+		t.Fatalf("KeepAlive() failed: %v", err)
+	}
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	time.Sleep(750 * time.Millisecond)
+	stop()
+	stop()
+	i := e.Index()
+	if i < 2 {
+		t.Errorf("key not refreshed: %v", i)
+	}
+	time.Sleep(400 * time.Millisecond)
+	if e.Index() != i {
+		t.Errorf("key refreshed after stop: %v %v", i, e.Index())
+	}
+
+	// Close stops them as well. A tiny ttl is refreshed like a second.
+	if _, err := ec.KeepAlive("key0", "val0", time.Nanosecond); err != nil {
+		t.Fatalf("KeepAlive() failed: %v", err)
+	}
+	close(ec.s)
+	time.Sleep(5 * time.Millisecond)
+	i = e.Index()
+	time.Sleep(400 * time.Millisecond)
+	if e.Index() != i {
+		t.Errorf("key refreshed after close: %v %v", i, e.Index())
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+// ErrPartialCommit is returned by Txn.Commit when an operation failed
+// and the operations before it couldn't be undone.
+var ErrPartialCommit = errors.New("transaction partially committed")
+
+// TxnOp is an operation performed by a Txn. Create them with OpSet
+// and OpDelete.
+type TxnOp struct {
+	key    string
+	value  string
+	delete bool
+}
+
+// OpSet is an operation that sets prefix+key to the value.
+func OpSet(key, value string) TxnOp {
+	return TxnOp{key: key, value: value}
+}
+
+// OpDelete is an operation that deletes prefix+key.
+func OpDelete(key string) TxnOp {
+	return TxnOp{key: key, delete: true}
+}
+
+// txnCmp is a guard of a Txn. An empty value or zero index isn't
+// compared.
+type txnCmp struct {
+	key   string
+	value string
+	index uint64
+}
+
+// Txn changes related keys together based on guards. Instantiate it
+// with Txn, add guards with If*, add operations with Then and Else
+// and then call Commit. For example:
+//
+//	ok, err := u.Txn().
+//		IfValue("db/primary", "a").
+//		Then(OpSet("db/primary", "b"), OpDelete("db/failover")).
+//		Commit()
+//
+// etcd v2, which this package uses, has no multi-key transactions, so
+// they are emulated. The guards are checked and the keys of the
+// operations are read. The operations are then performed one at a
+// time as compare-and-swaps against what was read, so they fail with
+// ErrCompareFailed if another writer changed a key in between. If an
+// operation fails, the ones before it are undone the same way, so
+// either all of them are performed or none are. Readers may see some
+// of the changes before the rest are performed or undone, guards on
+// keys that aren't changed are only checked before the operations
+// and the ttls of the keys that are restored are lost.
+type Txn struct {
+	u     *EtcdUtil
+	ifs   []txnCmp
+	thens []TxnOp
+	elses []TxnOp
+}
+
+// Txn starts a new transaction.
+func (u *EtcdUtil) Txn() *Txn {
+	return &Txn{u: u}
+}
+
+// IfValue adds a guard that prefix+key has the given value.
+func (t *Txn) IfValue(key, value string) *Txn {
+	t.ifs = append(t.ifs, txnCmp{key: key, value: value})
+	return t
+}
+
+// IfIndex adds a guard that prefix+key has the given modified index
+// (see GetForUpdate).
+func (t *Txn) IfIndex(key string, index uint64) *Txn {
+	t.ifs = append(t.ifs, txnCmp{key: key, index: index})
+	return t
+}
+
+// Then adds operations that are performed if all the guards pass.
+func (t *Txn) Then(ops ...TxnOp) *Txn {
+	t.thens = append(t.thens, ops...)
+	return t
+}
+
+// Else adds operations that are performed if any guard fails.
+func (t *Txn) Else(ops ...TxnOp) *Txn {
+	t.elses = append(t.elses, ops...)
+	return t
+}
+
+// Commit checks the guards and performs the Then or Else operations.
+// It returns true if the guards passed. A guard on a key that doesn't
+// exist fails. If an operation fails, its error is returned after the
+// operations before it are undone. If they can't be undone,
+// ErrPartialCommit is returned instead.
+func (t *Txn) Commit() (bool, error) {
+	seen := map[string]*txnKey{}
+	ok := true
+	for _, c := range t.ifs {
+		k, err := t.read(c.key, seen)
+		if err != nil {
+			return false, err
+		}
+		if !k.exists || (c.value != "" && c.value != k.value) ||
+			(c.index != 0 && c.index != k.index) {
+			ok = false
+		}
+	}
+	ops := t.thens
+	if !ok {
+		ops = t.elses
+	}
+	orig := map[string]txnKey{} // The keys before the operations.
+	for _, op := range ops {
+		k, err := t.read(op.key, seen)
+		if err != nil {
+			return ok, err
+		}
+		orig[op.key] = *k
+	}
+	for _, op := range ops {
+		if err := t.do(op, seen[op.key]); err != nil {
+			if !t.undo(orig, seen) {
+				err = ErrPartialCommit
+			}
+			return ok, err
+		}
+	}
+	return ok, nil
+}
+
+// txnKey is what a Txn knows about a key. The index is its modified
+// index if it exists.
+type txnKey struct {
+	exists bool
+	value  string
+	index  uint64
+}
+
+// read gets the key unless it was already read.
+func (t *Txn) read(key string, seen map[string]*txnKey) (*txnKey, error) {
+	if k, ok := seen[key]; ok {
+		return k, nil
+	}
+	k := &txnKey{}
+	r, err := t.u.c.Get(t.key(key), false, false)
+	if err == nil {
+		k.exists, k.value, k.index = true, r.Node.Value, r.Node.ModifiedIndex
+	} else if !isEtcdError(err, ecodeKeyNotFound) {
+		return nil, err
+	}
+	seen[key] = k
+	return k, nil
+}
+
+// do performs the operation if the key hasn't changed since it was
+// last seen and records the change.
+func (t *Txn) do(op TxnOp, k *txnKey) error {
+	if op.delete {
+		return t.set(t.key(op.key), k, txnKey{})
+	}
+	return t.set(t.key(op.key), k, txnKey{exists: true, value: op.value})
+}
+
+// undo restores the keys that were changed. It returns false if any
+// of them couldn't be restored.
+func (t *Txn) undo(orig map[string]txnKey, seen map[string]*txnKey) bool {
+	ok := true
+	for key, o := range orig {
+		k := seen[key]
+		if k.exists == o.exists && k.index == o.index {
+			continue
+		}
+		if err := t.set(t.key(key), k, o); err != nil {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// set changes the key from k to the given state with a
+// compare-and-swap and updates k. Deleting a key that doesn't exist
+// fails like etcd would.
+func (t *Txn) set(path string, k *txnKey, to txnKey) error {
+	var (
+		r   *etcd.Response
+		err error
+	)
+	switch {
+	case !to.exists && !k.exists:
+		return &etcd.EtcdError{ErrorCode: ecodeKeyNotFound,
+			Message: "Key not found", Cause: path}
+	case !to.exists:
+		_, err = t.u.c.CompareAndDelete(path, "", k.index)
+	case !k.exists:
+		r, err = t.u.c.Create(path, to.value, 0)
+	default:
+		r, err = t.u.c.CompareAndSwap(path, to.value, 0, "", k.index)
+	}
+	if isEtcdError(err, ecodeNodeExist) ||
+		(k.exists && isEtcdError(err, ecodeKeyNotFound)) {
+		// Someone else created or deleted it.
+		return ErrCompareFailed
+	} else if err != nil {
+		return compareError(err)
+	}
+	*k = to
+	if r != nil {
+		k.index = r.Node.ModifiedIndex
+	}
+	return nil
+}
+
+// key returns prefix+key.
+func (t *Txn) key(key string) string {
+	return strings.Join([]string{t.u.p, key}, "/")
+}
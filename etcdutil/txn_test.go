@@ -0,0 +1,117 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/icub3d/gop/etcdutil/etcdutiltest"
+)
+
+func TestTxn(t *testing.T) {
+	newEC := func() (*etcdutiltest.Fake, *EtcdUtil) {
+		// a is modified at index 1 and b at 2.
+		e := newFake(etcd.Nodes{
+			&etcd.Node{Key: "/myport/test/a", Value: "1"},
+			&etcd.Node{Key: "/myport/test/b", Value: "2"},
+		})
+		return e, &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+	}
+
+	tests := []struct {
+		txn  func(*Txn) *Txn   // Builds the transaction.
+		ok   bool              // Whether the guards should pass.
+		code int               // The expected etcd error code, if any.
+		exp  map[string]string // The expected values afterwards.
+	}{
+		// Guards pass.
+		{
+			txn: func(t *Txn) *Txn {
+				return t.IfValue("a", "1").IfIndex("b", 2).
+					Then(OpSet("a", "3"), OpSet("a", "4"), OpDelete("b"), OpSet("c", "5")).
+					Else(OpSet("c", "bad"))
+			},
+			ok:  true,
+			exp: map[string]string{"/myport/test/a": "4", "/myport/test/c": "5"},
+		},
+		// A guard fails.
+		{
+			txn: func(t *Txn) *Txn {
+				return t.IfValue("a", "1").IfIndex("b", 3).
+					Then(OpSet("c", "bad")).
+					Else(OpSet("c", "5"), OpDelete("a"))
+			},
+			exp: map[string]string{"/myport/test/b": "2", "/myport/test/c": "5"},
+		},
+		// A guard on a missing key fails.
+		{
+			txn: func(t *Txn) *Txn {
+				return t.IfValue("c", "1").Then(OpSet("c", "bad"))
+			},
+			exp: map[string]string{"/myport/test/a": "1", "/myport/test/b": "2"},
+		},
+		// No guards.
+		{
+			txn: func(t *Txn) *Txn {
+				return t.Then(OpDelete("a"))
+			},
+			ok:  true,
+			exp: map[string]string{"/myport/test/b": "2"},
+		},
+		// A failed operation undoes the ones before it.
+		{
+			txn: func(t *Txn) *Txn {
+				return t.Then(OpSet("a", "3"), OpDelete("b"), OpSet("d", "4"),
+					OpDelete("c"), OpSet("b", "bad"))
+			},
+			ok:   true,
+			code: ecodeKeyNotFound,
+			exp:  map[string]string{"/myport/test/a": "1", "/myport/test/b": "2"},
+		},
+	}
+	for k, test := range tests {
+		e, ec := newEC()
+		ok, err := test.txn(ec.Txn()).Commit()
+		if ok != test.ok || (err != nil || test.code != 0) && !isEtcdError(err, test.code) {
+			t.Errorf("Test %v: Commit() = %v, %v", k, ok, err)
+		}
+		if m := values(e, "/myport/test"); !reflect.DeepEqual(m, test.exp) {
+			t.Errorf("Test %v: Expected values %v but got %v", k, test.exp, m)
+		}
+	}
+
+	// A key that changes after it's read isn't overwritten and the
+	// operations before it are undone.
+	changed := &etcd.EtcdError{ErrorCode: ecodeTestFailed}
+	e, ec := newEC()
+	e.FailNext(nil, nil, nil, changed)
+	ok, err := ec.Txn().IfValue("a", "1").Then(OpSet("a", "3"), OpSet("b", "4")).Commit()
+	if !ok || err != ErrCompareFailed {
+		t.Errorf("Commit() of changed key = %v, %v", ok, err)
+	}
+	exp := map[string]string{"/myport/test/a": "1", "/myport/test/b": "2"}
+	if m := values(e, "/myport/test"); !reflect.DeepEqual(m, exp) {
+		t.Errorf("Expected values %v but got %v", exp, m)
+	}
+
+	// The operations can't be undone.
+	e, ec = newEC()
+	e.FailNext(nil, nil, nil, changed, changed)
+	_, err = ec.Txn().Then(OpSet("a", "3"), OpSet("b", "4")).Commit()
+	if err != ErrPartialCommit {
+		t.Errorf("Commit() that couldn't be undone = %v", err)
+	}
+
+	// Errors reading the keys.
+	txn := ec.Txn().IfValue("a", "1").Then(OpSet("a", "3"))
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	if _, err := txn.Commit(); err != etcd.ErrWatchStoppedByUser {
+		t.Errorf("Commit() didn't return error: %v", err)
+	}
+}
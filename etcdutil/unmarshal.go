@@ -0,0 +1,157 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// UnmarshalKeys populates the fields of the struct dst points to from
+// the keys under prefix+key. Each field is read from the child key
+// named by its etcd tag or the field's name if it has no tag. Fields
+// tagged with "-" and unexported fields are ignored. For example:
+//
+//	type DB struct {
+//		Host    string        `etcd:"host"`
+//		Timeout time.Duration `etcd:"timeout"`
+//		Pool    struct {
+//			Size int `etcd:"size"`
+//		} `etcd:"pool"`
+//	}
+//
+// would be populated from prefix+key/host, prefix+key/timeout and
+// prefix+key/pool/size. Values are converted the same way as the
+// Get* functions for strings, bools, numbers, durations, times and
+// string slices. Nested structs are read from the sub-directory of
+// the same name. Any other type is decoded as JSON. Fields whose keys
+// don't exist are left unchanged, so dst can hold the defaults.
+func (u *EtcdUtil) UnmarshalKeys(key string, dst interface{}) (uint64, error) {
+	m, i, err := u.GetAll(key)
+	if err != nil {
+		return i, err
+	}
+	return i, unmarshalKeys(m, dst)
+}
+
+// unmarshalKeys populates dst from the map of relative keys GetAll
+// returns.
+func unmarshalKeys(m map[string]string, dst interface{}) error {
+	if err := checkStruct(dst); err != nil {
+		return err
+	}
+	return unmarshalStruct(m, "", reflect.ValueOf(dst).Elem())
+}
+
+// checkStruct returns an error if dst isn't a pointer to a struct.
+func checkStruct(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dst must be a pointer to a struct, not %T", dst)
+	}
+	return nil
+}
+
+// unmarshalStruct populates the fields of the struct v from the keys
+// in m that start with the given directory.
+func unmarshalStruct(m map[string]string, dir string, v reflect.Value) error {
+	t := v.Type()
+	for x := 0; x < t.NumField(); x++ {
+		f := t.Field(x)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Tag.Get("etcd")
+		if name == "-" {
+			continue
+		} else if name == "" {
+			name = f.Name
+		}
+		fv := v.Field(x)
+		if f.Type.Kind() == reflect.Struct && f.Type != timeType {
+			if err := unmarshalStruct(m, dir+name+"/", fv); err != nil {
+				return err
+			}
+			continue
+		}
+		s, ok := m[dir+name]
+		if !ok {
+			continue
+		}
+		if err := setValue(fv, s); err != nil {
+			return fmt.Errorf("%v%v: %v", dir, name, err)
+		}
+	}
+	return nil
+}
+
+// setValue converts s to the type of v and sets it.
+func setValue(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Type() == timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		if v.Type() == reflect.TypeOf([]string(nil)) {
+			l, err := parseStrings(s)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(l))
+			return nil
+		}
+		return json.Unmarshal([]byte(s), v.Addr().Interface())
+	}
+	return nil
+}
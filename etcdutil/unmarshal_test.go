@@ -0,0 +1,117 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+// ut is the struct used to test UnmarshalKeys.
+type ut struct {
+	Host    string        `etcd:"host"`
+	Port    uint16        `etcd:"port"`
+	Timeout time.Duration `etcd:"timeout"`
+	Debug   bool          `etcd:"debug"`
+	Ratio   float64       `etcd:"ratio"`
+	Tags    []string      `etcd:"tags"`
+	Started time.Time     `etcd:"started"`
+	Extra   map[string]int
+	Pool    struct {
+		Size int8 `etcd:"size"`
+		Idle int  `etcd:"idle"`
+	} `etcd:"pool"`
+	Ignored string `etcd:"-"`
+	private string
+}
+
+func TestUnmarshalKeys(t *testing.T) {
+	nodes := func(kvs ...string) etcd.Nodes {
+		var ns etcd.Nodes
+		for x := 0; x < len(kvs); x += 2 {
+			ns = append(ns, &etcd.Node{Key: "/myport/test/db/" + kvs[x], Value: kvs[x+1]})
+		}
+		return etcd.Nodes{&etcd.Node{Key: "/myport/test/db", Dir: true, Nodes: ns}}
+	}
+	good := ut{
+		Host:    "localhost",
+		Port:    5432,
+		Timeout: 3 * time.Second,
+		Debug:   true,
+		Ratio:   0.5,
+		Tags:    []string{"a", "b"},
+		Started: time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC),
+		Extra:   map[string]int{"a": 1},
+		Ignored: "default",
+	}
+	good.Pool.Size = 10
+	good.Pool.Idle = 2
+
+	tests := []struct {
+		nodes etcd.Nodes // The nodes in etcd.
+		exp   ut         // The expected struct.
+		err   bool       // Whether an error is expected.
+	}{
+		// Everything set.
+		{
+			nodes: nodes("host", "localhost", "port", "5432", "timeout", "3s",
+				"debug", "true", "ratio", "0.5", "tags", "a,b",
+				"started", "2015-06-01T00:00:00Z", "Extra", `{"a":1}`,
+				"pool/size", "10", "pool/idle", "2", "-", "x", "private", "x"),
+			exp: good,
+		},
+		// Missing keys keep their defaults.
+		{
+			nodes: nodes("host", "localhost"),
+			exp:   ut{Host: "localhost", Port: 1, Ignored: "default"},
+		},
+		// Bad values.
+		{nodes: nodes("port", "70000"), err: true},
+		{nodes: nodes("timeout", "3"), err: true},
+		{nodes: nodes("debug", "maybe"), err: true},
+		{nodes: nodes("ratio", "half"), err: true},
+		{nodes: nodes("tags", "[a"), err: true},
+		{nodes: nodes("started", "today"), err: true},
+		{nodes: nodes("Extra", "{"), err: true},
+		{nodes: nodes("pool/size", "1000"), err: true},
+		{nodes: nodes("pool/idle", "x"), err: true},
+	}
+
+	for k, test := range tests {
+		ec := &EtcdUtil{p: "/myport/test", c: newFake(test.nodes), s: make(chan bool)}
+		v := ut{Port: 1, Ignored: "default"}
+		_, err := ec.UnmarshalKeys("db", &v)
+		if test.err {
+			if err == nil {
+				t.Errorf("Test %v: expected error but got none", k)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %v: got unexpected non-nil err: %v", k, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, test.exp) {
+			t.Errorf("Test %v: Expected value %+v but got %+v", k, test.exp, v)
+		}
+	}
+
+	// Errors from etcd and bad destinations.
+	ec := &EtcdUtil{p: "/myport/test", c: newFake(nodes("host", "localhost"),
+		etcd.ErrWatchStoppedByUser), s: make(chan bool)}
+	if _, err := ec.UnmarshalKeys("db", &ut{}); err != etcd.ErrWatchStoppedByUser {
+		t.Errorf("UnmarshalKeys() didn't return etcd error: %v", err)
+	}
+	for k, dst := range []interface{}{ut{}, (*ut)(nil), new(int)} {
+		if _, err := ec.UnmarshalKeys("db", dst); err == nil {
+			t.Errorf("Test %v: expected error for dst %T", k, dst)
+		}
+	}
+}
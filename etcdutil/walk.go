@@ -0,0 +1,198 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/icub3d/gop/gopool"
+	"golang.org/x/net/context"
+)
+
+// WalkConcurrent is like Walk but each directory is fetched separately
+// instead of the entire tree at once, which may time out for large
+// trees. Up to goroutines directories are fetched at the same time, so
+// the calls to f are in no particular order. They are never made
+// concurrently though. The first error from etcd or f halts the walk
+// and is returned. The index returned is the smallest one of the
+// fetches, so watching from it won't miss any changes made during the
+// walk.
+func (u *EtcdUtil) WalkConcurrent(key string, goroutines int, f func(key, value string) error) (uint64, error) {
+	if goroutines < 1 {
+		goroutines = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms := gopool.NewManagedSource(gopool.NewFIFOQueue("walk"), false, nil, ctx)
+	w := &walker{
+		u:    u,
+		f:    f,
+		ctx:  ctx,
+		done: cancel,
+		add:  ms.Add,
+		g:    gopool.NewGroup("walk", goroutines, false, ctx, cancel, ms.Source),
+	}
+	w.dir(strings.Join([]string{u.p, key}, "/"))
+	err := w.g.Wait()
+	ms.Wait()
+	return w.index, err
+}
+
+// walker tracks the state of a WalkConcurrent.
+type walker struct {
+	u    *EtcdUtil
+	f    func(key, value string) error
+	ctx  context.Context
+	done context.CancelFunc
+	add  chan<- gopool.Task
+	g    *gopool.Group
+
+	mu      sync.Mutex // protects pending and index.
+	pending int        // The number of directories not yet walked.
+	index   uint64     // The smallest index seen.
+
+	fmu sync.Mutex // serializes the calls to f.
+}
+
+// dir queues the given directory to be walked.
+func (w *walker) dir(key string) {
+	w.mu.Lock()
+	w.pending++
+	w.mu.Unlock()
+	select {
+	case w.add <- w.g.Task(&walkTask{w: w, key: key}):
+	case <-w.ctx.Done():
+	}
+}
+
+// finish marks a directory as walked. Once all of them are, the walk is
+// stopped.
+func (w *walker) finish(index uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if index != 0 && (w.index == 0 || index < w.index) {
+		w.index = index
+	}
+	w.pending--
+	if w.pending == 0 {
+		w.done()
+	}
+}
+
+// call calls f with the node's key and value.
+func (w *walker) call(n *etcd.Node) error {
+	w.fmu.Lock()
+	defer w.fmu.Unlock()
+	return w.f(n.Key, n.Value)
+}
+
+// walkTask fetches a single directory. Its values are given to f and
+// its sub-directories are queued.
+type walkTask struct {
+	w   *walker
+	key string
+}
+
+func (t *walkTask) String() string {
+	return "walk " + t.key
+}
+
+func (t *walkTask) Run(ctx context.Context) error {
+	r, err := t.w.u.c.Get(t.key, false, false)
+	if err != nil {
+		return err
+	}
+	defer t.w.finish(r.EtcdIndex)
+	if !r.Node.Dir {
+		return t.w.call(r.Node)
+	}
+	for _, n := range r.Node.Nodes {
+		if n.Dir {
+			t.w.dir(n.Key)
+		} else if err := t.w.call(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkFrom is like Walk with sorted set but each directory is fetched
+// separately and only the keys after the given one are given to f. An
+// empty after walks everything. It returns the last key given to f
+// without an error, so a walk that failed part way through can be
+// resumed by calling WalkFrom again with it. The index returned is the
+// smallest one of the fetches.
+func (u *EtcdUtil) WalkFrom(key, after string, f func(key, value string) error) (string, uint64, error) {
+	w := &sortedWalker{f: f, last: after}
+	err := w.walk(u.c, strings.Join([]string{u.p, key}, "/"))
+	return w.last, w.index, err
+}
+
+// sortedWalker tracks the state of a WalkFrom.
+type sortedWalker struct {
+	f     func(key, value string) error
+	last  string
+	index uint64
+}
+
+func (w *sortedWalker) walk(c EtcdClient, key string) error {
+	r, err := c.Get(key, true, false)
+	if err != nil {
+		return err
+	}
+	if w.index == 0 || (r.EtcdIndex != 0 && r.EtcdIndex < w.index) {
+		w.index = r.EtcdIndex
+	}
+	if !r.Node.Dir {
+		return w.call(r.Node)
+	}
+	for _, n := range r.Node.Nodes {
+		if !n.Dir {
+			if err := w.call(n); err != nil {
+				return err
+			}
+			continue
+		}
+		// Skip the directories that were completely walked.
+		if w.last != "" && keyLess(n.Key, w.last) &&
+			!strings.HasPrefix(w.last, n.Key+"/") {
+			continue
+		}
+		if err := w.walk(c, n.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// call calls f with the node's key and value if it's after the last key.
+func (w *sortedWalker) call(n *etcd.Node) error {
+	if w.last != "" && !keyLess(w.last, n.Key) {
+		return nil
+	}
+	if err := w.f(n.Key, n.Value); err != nil {
+		return err
+	}
+	w.last = n.Key
+	return nil
+}
+
+// keyLess returns true if a comes before b in a sorted walk. Keys are
+// compared by their parts, so everything in a directory comes before
+// the keys after it.
+func keyLess(a, b string) bool {
+	as := strings.Split(strings.Trim(a, "/"), "/")
+	bs := strings.Split(strings.Trim(b, "/"), "/")
+	for x := 0; x < len(as) && x < len(bs); x++ {
+		if as[x] != bs[x] {
+			return as[x] < bs[x]
+		}
+	}
+	return len(as) < len(bs)
+}
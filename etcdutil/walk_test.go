@@ -0,0 +1,117 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+var walkAll = []string{
+	"/myport/test/k0-0/k1-0/k2-0|v2-0",
+	"/myport/test/k0-0/k1-0/k2-1|v2-1",
+	"/myport/test/k0-0/k1-0/k2-2|v2-2",
+	"/myport/test/k0-0/k1-1|v1-1",
+	"/myport/test/k0-0/k1-2|v1-2",
+}
+
+func TestWalkConcurrent(t *testing.T) {
+	e := newFake(testNodes)
+	ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+	var res []string
+	i, err := ec.WalkConcurrent("k0-0", 3, func(key, value string) error {
+		res = append(res, key+"|"+value)
+		return nil
+	})
+	sort.Strings(res)
+	if err != nil || i != e.Index() || !reflect.DeepEqual(res, walkAll) {
+		t.Errorf("WalkConcurrent() = %v, %v, %v", i, err, res)
+	}
+
+	// A single key.
+	res = nil
+	_, err = ec.WalkConcurrent("k0-1", 0, func(key, value string) error {
+		res = append(res, key+"|"+value)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(res, []string{"/myport/test/k0-1|v0-1"}) {
+		t.Errorf("WalkConcurrent() of a key = %v, %v", err, res)
+	}
+
+	// Errors from f and etcd.
+	fe := errors.New("f")
+	_, err = ec.WalkConcurrent("k0-0", 2, func(key, value string) error {
+		return fe
+	})
+	if err != fe {
+		t.Errorf("WalkConcurrent() didn't return error from f: %v", err)
+	}
+	e.FailNext(etcd.ErrWatchStoppedByUser)
+	_, err = ec.WalkConcurrent("k0-0", 2, func(key, value string) error {
+		return nil
+	})
+	if err != etcd.ErrWatchStoppedByUser {
+		t.Errorf("WalkConcurrent() didn't return error from etcd: %v", err)
+	}
+}
+
+func TestWalkFrom(t *testing.T) {
+	ec := &EtcdUtil{p: "/myport/test", c: newFake(testNodes), s: make(chan bool)}
+	tests := []struct {
+		after    string   // Where to start.
+		errCount int      // When f fails, if ever.
+		exp      []string // The expected results.
+		last     string   // The expected last key.
+	}{
+		{errCount: -1, exp: walkAll, last: "/myport/test/k0-0/k1-2"},
+		{errCount: 2, exp: walkAll[:2], last: "/myport/test/k0-0/k1-0/k2-1"},
+		{after: "/myport/test/k0-0/k1-0/k2-1", errCount: -1, exp: walkAll[2:],
+			last: "/myport/test/k0-0/k1-2"},
+		{after: "/myport/test/k0-0/k1-0/k2-2", errCount: 1, exp: walkAll[3:4],
+			last: "/myport/test/k0-0/k1-1"},
+		{after: "/myport/test/k0-0/k1-2", errCount: -1,
+			last: "/myport/test/k0-0/k1-2"},
+	}
+	fe := errors.New("f")
+	for k, test := range tests {
+		var res []string
+		last, _, err := ec.WalkFrom("k0-0", test.after, func(key, value string) error {
+			if len(res) == test.errCount {
+				return fe
+			}
+			res = append(res, key+"|"+value)
+			return nil
+		})
+		if (err != nil) != (test.errCount >= 0) || last != test.last ||
+			!reflect.DeepEqual(res, test.exp) {
+			t.Errorf("Test %v: WalkFrom() = %v, %v, %v", k, last, err, res)
+		}
+	}
+}
+
+func TestKeyLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  bool
+	}{
+		{"/a", "/b", true},
+		{"/b", "/a", false},
+		{"/a/z", "/a-b", true},
+		{"/a", "/a/b", true},
+		{"/a/b", "/a", false},
+		{"/a", "/a", false},
+	}
+	for k, test := range tests {
+		if r := keyLess(test.a, test.b); r != test.exp {
+			t.Errorf("Test %v: keyLess(%v, %v) = %v", k, test.a, test.b, r)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"github.com/coreos/go-etcd/etcd"
+)
+
+// The actions of a WatchEvent. They are the actions etcd reports.
+const (
+	ActionSet              = "set"
+	ActionCreate           = "create"
+	ActionUpdate           = "update"
+	ActionDelete           = "delete"
+	ActionExpire           = "expire"
+	ActionCompareAndSwap   = "compareAndSwap"
+	ActionCompareAndDelete = "compareAndDelete"
+)
+
+// WatchEvent describes a change seen by WatchFull.
+type WatchEvent struct {
+	// Action is what happened to the key (e.g. ActionSet or
+	// ActionDelete).
+	Action string
+
+	// Key is the full key that changed and Value is its new value. The
+	// value is empty if the key was removed.
+	Key   string
+	Value string
+
+	// Dir is true if the key is a directory.
+	Dir bool
+
+	// HasPrev is true if etcd reported the previous value of the key,
+	// which is PrevValue. It's false for new keys.
+	HasPrev   bool
+	PrevValue string
+
+	// CreatedIndex and ModifiedIndex are the indexes of the key.
+	// ModifiedIndex can be used as the prevIndex of the CompareAnd*
+	// functions.
+	CreatedIndex  uint64
+	ModifiedIndex uint64
+
+	// EtcdIndex is the index of etcd when the change was reported.
+	EtcdIndex uint64
+}
+
+// Removed returns true if the event removed the key.
+func (e WatchEvent) Removed() bool {
+	return e.Action == ActionDelete || e.Action == ActionExpire ||
+		e.Action == ActionCompareAndDelete
+}
+
+// WatchFull is like Watch but f gets all of the details of each
+// change, so deletes can be told apart from sets and previous values
+// aren't lost.
+func (u *EtcdUtil) WatchFull(key string, waitIndex uint64, recursive bool, f func(e WatchEvent)) {
+	u.watch(key, waitIndex, recursive, func(r *etcd.Response) {
+		f(newWatchEvent(r))
+	})
+}
+
+// newWatchEvent converts an etcd response to a WatchEvent.
+func newWatchEvent(r *etcd.Response) WatchEvent {
+	e := WatchEvent{
+		Action:        r.Action,
+		Key:           r.Node.Key,
+		Value:         r.Node.Value,
+		Dir:           r.Node.Dir,
+		CreatedIndex:  r.Node.CreatedIndex,
+		ModifiedIndex: r.Node.ModifiedIndex,
+		EtcdIndex:     r.EtcdIndex,
+	}
+	if r.PrevNode != nil {
+		e.HasPrev = true
+		e.PrevValue = r.PrevNode.Value
+	}
+	return e
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestWatchFull(t *testing.T) {
+	e := newFake(testNodes)
+	ec := &EtcdUtil{
+		p: "/myport/test",
+		c: e,
+		s: make(chan bool),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	var res []WatchEvent
+	ec.WatchFull("k0-0", e.Index()+1, true, func(ev WatchEvent) {
+		res = append(res, ev)
+		wg.Done()
+	})
+
+	// The six values in testNodes are at indexes 1-6.
+	e.Create("/myport/test/k0-0/a", "1", 0)
+	e.Set("/myport/test/k0-0/a", "2", 0)
+	e.Expire("/myport/test/k0-0/a")
+
+	wg.Wait()
+	ec.Close()
+	exp := []WatchEvent{
+		{Action: ActionCreate, Key: "/myport/test/k0-0/a", Value: "1",
+			CreatedIndex: 7, ModifiedIndex: 7, EtcdIndex: 7},
+		{Action: ActionSet, Key: "/myport/test/k0-0/a", Value: "2", HasPrev: true, PrevValue: "1",
+			CreatedIndex: 7, ModifiedIndex: 8, EtcdIndex: 8},
+		{Action: ActionExpire, Key: "/myport/test/k0-0/a", HasPrev: true, PrevValue: "2",
+			CreatedIndex: 7, ModifiedIndex: 9, EtcdIndex: 9},
+	}
+	if !reflect.DeepEqual(res, exp) {
+		t.Errorf("Expecting %v but got %v", exp, res)
+	}
+	for k, ev := range res {
+		if ev.Removed() != (k == 2) {
+			t.Errorf("Event %v: Removed() = %v", k, ev.Removed())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"reflect"
+)
+
+// ErrNilPrototype is returned by WatchJSON when it isn't given a
+// prototype.
+var ErrNilPrototype = errors.New("nil prototype")
+
+// WatchJSON is like Watch but each changed value is decoded from JSON
+// before f is called. The value given to f is a new value with the
+// same type as prototype. If prototype is a pointer, f gets a pointer
+// to a new value of the type it points to. For example, with
+// &Config{}, f gets a *Config that can be type asserted. When a key
+// is deleted or expires, f gets a nil value.
+//
+// Values that can't be decoded are logged and f isn't called for
+// them. It returns ErrNilPrototype if prototype is nil.
+func (u *EtcdUtil) WatchJSON(key string, waitIndex uint64, recursive bool,
+	prototype interface{}, f func(key string, v interface{})) error {
+	if prototype == nil {
+		return ErrNilPrototype
+	}
+	t := reflect.TypeOf(prototype)
+	ptr := t.Kind() == reflect.Ptr
+	if ptr {
+		t = t.Elem()
+	}
+	u.WatchFull(key, waitIndex, recursive, func(e WatchEvent) {
+		if e.Removed() {
+			f(e.Key, nil)
+			return
+		}
+		v := reflect.New(t)
+		if err := json.Unmarshal([]byte(e.Value), v.Interface()); err != nil {
+			log.Printf("WatchJSON(%v): decoding %q: %v\n", e.Key, e.Value, err)
+			return
+		}
+		if !ptr {
+			v = v.Elem()
+		}
+		f(e.Key, v.Interface())
+	})
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWatchJSON(t *testing.T) {
+	type config struct {
+		A int
+		B string
+	}
+	tests := []struct {
+		prototype interface{}   // The prototype given.
+		exp       []interface{} // The values we expect.
+	}{
+		{
+			prototype: &config{},
+			exp:       []interface{}{&config{A: 1, B: "b"}, &config{A: 2}, nil},
+		},
+		{
+			prototype: config{},
+			exp:       []interface{}{config{A: 1, B: "b"}, config{A: 2}, nil},
+		},
+		{
+			prototype: map[string]interface{}{},
+			exp: []interface{}{
+				map[string]interface{}{"A": 1.0, "B": "b"},
+				map[string]interface{}{"A": 2.0},
+				nil,
+			},
+		},
+	}
+	vals := []string{`{"A":1,"B":"b"}`, `bad json`, `{"A":2}`}
+	for k, test := range tests {
+		e := newFake(nil)
+		ec := &EtcdUtil{p: "/myport/test", c: e, s: make(chan bool)}
+		res := make(chan interface{})
+		err := ec.WatchJSON("config", 1, false, test.prototype, func(key string, v interface{}) {
+			if key != "/myport/test/config" {
+				t.Errorf("Test %v: got wrong key: %v", k, key)
+			}
+			res <- v
+		})
+		if err != nil {
+			t.Fatalf("Test %v: WatchJSON(): %v", k, err)
+		}
+		for _, v := range vals {
+			e.Set("/myport/test/config", v, 0)
+		}
+		e.Delete("/myport/test/config", false)
+		// The bad value should be skipped and the delete gives nil.
+		for _, exp := range test.exp {
+			v := <-res
+			if !reflect.DeepEqual(v, exp) {
+				t.Errorf("Test %v: wanted %#v but got %#v", k, exp, v)
+			}
+		}
+		ec.Close()
+	}
+
+	ec := &EtcdUtil{p: "/myport/test", c: newFake(nil), s: make(chan bool)}
+	if err := ec.WatchJSON("config", 1, false, nil, nil); err != ErrNilPrototype {
+		t.Errorf("WatchJSON() with nil prototype = %v", err)
+	}
+}
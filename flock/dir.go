@@ -0,0 +1,80 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// DirLockName is the name of the lock file LockDir creates in a
+// directory.
+const DirLockName = ".flock"
+
+// DirLock is an exclusive lock on a directory returned by LockDir.
+type DirLock struct {
+	f    *Flock
+	name string
+}
+
+// LockDir waits for an exclusive lock on the given directory, creating
+// it if needed. The lock is on the file named DirLockName in it, which
+// is created while it's held and removed by Unlock. All processes using
+// the directory should lock it this way.
+func LockDir(path string) (*DirLock, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	name := filepath.Join(path, DirLockName)
+	for {
+		f, err := New(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.LockExclusiveWait(); err != nil {
+			f.Close()
+			return nil, err
+		}
+		// The holder we waited on may have removed the file, in which
+		// case someone else may lock a new one. Only the lock on the
+		// file that's in the directory counts.
+		same, err := sameFile(f, name)
+		if err != nil && !os.IsNotExist(err) {
+			f.Close()
+			return nil, err
+		}
+		if same {
+			return &DirLock{f: f, name: name}, nil
+		}
+		f.Close()
+	}
+}
+
+// Unlock removes the lock file and releases the lock.
+func (d *DirLock) Unlock() error {
+	err := os.Remove(d.name)
+	d.f.Unlock()
+	if cerr := d.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// sameFile returns true if the open file of the lock is the one with
+// the given name.
+func sameFile(f *Flock, name string) (bool, error) {
+	var open, named unix.Stat_t
+	if err := unix.Fstat(int(f.f.Fd()), &open); err != nil {
+		return false, err
+	}
+	if err := unix.Stat(name, &named); err != nil {
+		return false, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return open.Dev == named.Dev && open.Ino == named.Ino, nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockDir(t *testing.T) {
+	dir := "/tmp/flock_test_dir/data"
+	defer os.RemoveAll("/tmp/flock_test_dir")
+	os.RemoveAll("/tmp/flock_test_dir")
+
+	d, err := LockDir(dir)
+	if err != nil {
+		t.Fatalf("LockDir(%q): %v", dir, err)
+	}
+	other, err := New(filepath.Join(dir, DirLockName))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	if err := other.LockShared(); err != ErrWouldBlock {
+		t.Errorf("LockShared() while held = %v", err)
+	}
+	other.Close()
+	if err := d.Unlock(); err != nil {
+		t.Errorf("Unlock(): %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, DirLockName)); !os.IsNotExist(err) {
+		t.Errorf("lock file not removed: %v", err)
+	}
+
+	// Only one holds it at a time even though the file is removed and
+	// created again.
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders int
+	)
+	for x := 0; x < 20; x++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d, err := LockDir(dir)
+			if err != nil {
+				t.Errorf("LockDir(): %v", err)
+				return
+			}
+			mu.Lock()
+			holders++
+			if holders > 1 {
+				t.Errorf("%v holders of the lock", holders)
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			if err := d.Unlock(); err != nil {
+				t.Errorf("Unlock(): %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
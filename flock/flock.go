@@ -11,7 +11,10 @@ package flock
 import (
 	"errors"
 	"os"
+	"sync/atomic"
+	"time"
 
+	"golang.org/x/net/context"
 	"golang.org/x/sys/unix"
 )
 
@@ -19,9 +22,21 @@ import (
 // have blocked.
 var ErrWouldBlock = errors.New("would block")
 
+// ErrTimeout is returned by the locks with a timeout when the lock
+// couldn't be acquired in time.
+var ErrTimeout = errors.New("timeout")
+
+// The shortest and longest times to wait between attempts when waiting
+// for a lock with a timeout or a context.
+const (
+	minPoll = time.Millisecond
+	maxPoll = 50 * time.Millisecond
+)
+
 // Flock is a file based lock mechanism.
 type Flock struct {
-	f *os.File
+	f     *os.File
+	state int32 // The LockState, accessed atomically.
 }
 
 // New creates a new Flock for the given path name.
@@ -61,6 +76,34 @@ func (f *Flock) LockExclusive() error {
 	return f.call(unix.LOCK_EX | unix.LOCK_NB)
 }
 
+// LockSharedTimeout attempts to get a shared lock and waits up to d
+// for it. If it can't be acquired in that time, the returned error is
+// ErrTimeout.
+func (f *Flock) LockSharedTimeout(d time.Duration) error {
+	return f.timeout(unix.LOCK_SH, d)
+}
+
+// LockExclusiveTimeout attempts to get an exclusive lock and waits up
+// to d for it. If it can't be acquired in that time, the returned
+// error is ErrTimeout.
+func (f *Flock) LockExclusiveTimeout(d time.Duration) error {
+	return f.timeout(unix.LOCK_EX, d)
+}
+
+// LockSharedContext attempts to get a shared lock and waits until it's
+// acquired or the context is done. In that case, the context's error is
+// returned.
+func (f *Flock) LockSharedContext(ctx context.Context) error {
+	return f.wait(ctx, unix.LOCK_SH)
+}
+
+// LockExclusiveContext attempts to get an exclusive lock and waits
+// until it's acquired or the context is done. In that case, the
+// context's error is returned.
+func (f *Flock) LockExclusiveContext(ctx context.Context) error {
+	return f.wait(ctx, unix.LOCK_EX)
+}
+
 // Unlock attempts to release the lock you have
 func (f *Flock) Unlock() error {
 	return f.call(unix.LOCK_UN)
@@ -72,10 +115,80 @@ func (f *Flock) Close() error {
 	return f.f.Close()
 }
 
+// WithExclusive opens the lock file with the given path name, waits
+// for an exclusive lock on it and calls fn. The lock is released and the
+// file closed when fn returns, even if it panics. It returns the error
+// from fn or from getting the lock.
+func WithExclusive(name string, fn func() error) error {
+	return with(name, unix.LOCK_EX, fn)
+}
+
+// WithShared is like WithExclusive but it gets a shared lock.
+func WithShared(name string, fn func() error) error {
+	return with(name, unix.LOCK_SH, fn)
+}
+
+func with(name string, flags int, fn func() error) error {
+	f, err := New(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.call(flags); err != nil {
+		return err
+	}
+	defer f.Unlock()
+	return fn()
+}
+
 func (f *Flock) call(flags int) error {
 	err := unix.Flock(int(f.f.Fd()), flags)
 	if err == unix.EWOULDBLOCK {
 		return ErrWouldBlock
+	} else if err != nil {
+		return err
+	}
+	s := Unlocked
+	switch flags &^ unix.LOCK_NB {
+	case unix.LOCK_SH:
+		s = Shared
+	case unix.LOCK_EX:
+		s = Exclusive
+	}
+	atomic.StoreInt32(&f.state, int32(s))
+	return nil
+}
+
+// timeout tries to get the lock until d passes.
+func (f *Flock) timeout(flags int, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	err := f.wait(ctx, flags)
+	if err == context.DeadlineExceeded {
+		return ErrTimeout
 	}
 	return err
 }
+
+// wait tries to get the lock until the context is done. The lock can't
+// be waited on with a context, so it's polled with a growing delay.
+func (f *Flock) wait(ctx context.Context, flags int) error {
+	poll := minPoll
+	t := time.NewTimer(poll)
+	defer t.Stop()
+	for {
+		err := f.call(flags | unix.LOCK_NB)
+		if err != ErrWouldBlock {
+			return err
+		}
+		t.Reset(poll)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		if poll *= 2; poll > maxPoll {
+			poll = maxPoll
+		}
+	}
+}
@@ -11,9 +11,9 @@ import (
 	"os"
 	"testing"
 	"time"
-)
 
-var ErrTimeout = errors.New("timeout")
+	"golang.org/x/net/context"
+)
 
 // LockorTimeout is a helper function. It calls the given locker
 // function and returns the error it got back from it. If the lock
@@ -188,3 +188,147 @@ func TestUnlock(t *testing.T) {
 		t.Errorf("unlocking returned some errors: %v | %v", errs[0], errs[1])
 	}
 }
+
+func TestTimeoutLocks(t *testing.T) {
+	defer os.Remove("/tmp/flock_test_timeout")
+
+	flocks := make([]*Flock, 2)
+	for x := 0; x < 2; x++ {
+		f, err := New("/tmp/flock_test_timeout")
+		if err != nil {
+			t.Fatalf(`f[%v] = New("/tmp/flock_test_timeout"): %v`, x, err)
+		}
+		defer f.Close()
+		defer f.Unlock()
+		flocks[x] = f
+	}
+
+	// Shared locks don't wait.
+	if err := flocks[0].LockSharedTimeout(10 * time.Millisecond); err != nil {
+		t.Fatalf("LockSharedTimeout(): %v", err)
+	}
+	if err := flocks[1].LockSharedTimeout(10 * time.Millisecond); err != nil {
+		t.Fatalf("LockSharedTimeout(): %v", err)
+	}
+
+	// An exclusive lock times out while the other is held.
+	start := time.Now()
+	if err := flocks[1].LockExclusiveTimeout(20 * time.Millisecond); err != ErrTimeout {
+		t.Errorf("LockExclusiveTimeout() = %v", err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("LockExclusiveTimeout() returned after %v", d)
+	}
+
+	// It's acquired once the other is released.
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		flocks[0].Unlock()
+		close(done)
+	}()
+	if err := flocks[1].LockExclusiveTimeout(time.Second); err != nil {
+		t.Errorf("LockExclusiveTimeout() = %v", err)
+	}
+	<-done
+}
+
+func TestContextLocks(t *testing.T) {
+	defer os.Remove("/tmp/flock_test_context")
+
+	flocks := make([]*Flock, 2)
+	for x := 0; x < 2; x++ {
+		f, err := New("/tmp/flock_test_context")
+		if err != nil {
+			t.Fatalf(`f[%v] = New("/tmp/flock_test_context"): %v`, x, err)
+		}
+		defer f.Close()
+		defer f.Unlock()
+		flocks[x] = f
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := flocks[0].LockSharedContext(ctx); err != nil {
+		t.Fatalf("LockSharedContext(): %v", err)
+	}
+
+	// Waiting stops when the context is cancelled.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if err := flocks[1].LockExclusiveContext(ctx); err != context.Canceled {
+		t.Errorf("LockExclusiveContext() = %v", err)
+	}
+
+	// It's acquired once the other is released.
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		flocks[0].Unlock()
+		close(done)
+	}()
+	if err := flocks[1].LockExclusiveContext(context.Background()); err != nil {
+		t.Errorf("LockExclusiveContext() = %v", err)
+	}
+	<-done
+}
+
+func TestWithLocks(t *testing.T) {
+	name := "/tmp/flock_test_with"
+	defer os.Remove(name)
+
+	other, err := New(name)
+	if err != nil {
+		t.Fatalf(`New(%q): %v`, name, err)
+	}
+	defer other.Close()
+
+	// The lock is held while fn runs and its error is returned.
+	ferr := errors.New("fn failed")
+	err = WithExclusive(name, func() error {
+		if err := other.LockShared(); err != ErrWouldBlock {
+			t.Errorf("LockShared() in WithExclusive() = %v", err)
+		}
+		return ferr
+	})
+	if err != ferr {
+		t.Errorf("WithExclusive() = %v", err)
+	}
+	err = WithShared(name, func() error {
+		if err := other.LockShared(); err != nil {
+			t.Errorf("LockShared() in WithShared() = %v", err)
+		}
+		if err := other.Unlock(); err != nil {
+			t.Errorf("Unlock() in WithShared() = %v", err)
+		}
+		if err := other.LockExclusive(); err != ErrWouldBlock {
+			t.Errorf("LockExclusive() in WithShared() = %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("WithShared() = %v", err)
+	}
+
+	// It's released if fn panics.
+	func() {
+		defer func() {
+			if r := recover(); r != "oops" {
+				t.Errorf("recover() = %v", r)
+			}
+		}()
+		WithExclusive(name, func() error { panic("oops") })
+	}()
+	if err := other.LockExclusive(); err != nil {
+		t.Errorf("LockExclusive() after panic = %v", err)
+	}
+	other.Unlock()
+
+	if err := WithShared("/tmp/flock_test_no_such_dir/lock", func() error {
+		t.Errorf("fn called without a lock")
+		return nil
+	}); err == nil {
+		t.Errorf("WithShared() of bad path didn't fail")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"sync"
+	"time"
+)
+
+// Lease is a PIDLock whose holder rewrites the time in the file every
+// interval while it holds the lock. A holder that dies releases the
+// lock, but one that hangs doesn't. Observers can use IsStale to detect
+// this and recover, for example, by killing the holder.
+type Lease struct {
+	p        *PIDLock
+	interval time.Duration
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+	err  error // The first error from a heartbeat.
+}
+
+// NewLease creates a new Lease for the given path name. The holder
+// updates the time every interval.
+func NewLease(name string, interval time.Duration) (*Lease, error) {
+	p, err := NewPIDLock(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Lease{p: p, interval: interval}, nil
+}
+
+// Lock attempts to get the lock and starts the heartbeat. It won't
+// block if another process holds it. In this case, the return error is
+// ErrWouldBlock.
+func (l *Lease) Lock() error {
+	if err := l.p.Lock(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	l.err = nil
+	go l.heartbeat(l.stop, l.done)
+	return nil
+}
+
+// Unlock stops the heartbeat, empties the file and releases the lock.
+// If a heartbeat failed while the lock was held, its error is returned.
+func (l *Lease) Unlock() error {
+	l.mu.Lock()
+	stop, done := l.stop, l.done
+	l.stop = nil
+	l.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	l.mu.Lock()
+	herr := l.err
+	l.mu.Unlock()
+	if err := l.p.Unlock(); err != nil {
+		return err
+	}
+	return herr
+}
+
+// Close closes the open file. This should be called when the lease is
+// no longer needed.
+func (l *Lease) Close() error {
+	return l.p.Close()
+}
+
+// Holder returns the pid of the process that holds the lock and the
+// time of its last heartbeat. If the file is empty, the returned error
+// is ErrNoHolder.
+func (l *Lease) Holder() (int, time.Time, error) {
+	return l.p.Holder()
+}
+
+// IsStale returns true if the lock is held but its holder hasn't
+// updated the time within maxAge. A lock that isn't held is never
+// stale.
+func (l *Lease) IsStale(maxAge time.Duration) (bool, error) {
+	_, at, err := l.p.Holder()
+	if err == ErrNoHolder {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if time.Since(at) <= maxAge {
+		return false, nil
+	}
+	return l.p.held()
+}
+
+// heartbeat rewrites the time every interval until stop is closed.
+func (l *Lease) heartbeat(stop, done chan struct{}) {
+	defer close(done)
+	t := time.NewTicker(l.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		if err := l.p.write(); err != nil {
+			l.mu.Lock()
+			if l.err == nil {
+				l.err = err
+			}
+			l.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLease(t *testing.T) {
+	name := "/tmp/flock_test_lease"
+	defer os.Remove(name)
+	os.Remove(name)
+
+	holder, err := NewLease(name, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewLease(%q): %v", name, err)
+	}
+	defer holder.Close()
+	observer, err := NewLease(name, time.Hour)
+	if err != nil {
+		t.Fatalf("NewLease(%q): %v", name, err)
+	}
+	defer observer.Close()
+
+	if stale, err := observer.IsStale(time.Nanosecond); stale || err != nil {
+		t.Errorf("IsStale() of empty file = %v, %v", stale, err)
+	}
+	if err := holder.Lock(); err != nil {
+		t.Fatalf("Lock(): %v", err)
+	}
+	if err := observer.Lock(); err != ErrWouldBlock {
+		t.Errorf("Lock() while held = %v", err)
+	}
+	_, first, err := observer.Holder()
+	if err != nil {
+		t.Fatalf("Holder(): %v", err)
+	}
+
+	// The time is updated while it's held.
+	time.Sleep(50 * time.Millisecond)
+	pid, last, err := observer.Holder()
+	if err != nil || pid != os.Getpid() || !last.After(first) {
+		t.Errorf("Holder() = %v, %v, %v (first %v)", pid, last, err, first)
+	}
+	if stale, err := observer.IsStale(time.Second); stale || err != nil {
+		t.Errorf("IsStale() while held = %v, %v", stale, err)
+	}
+	if err := holder.Unlock(); err != nil {
+		t.Fatalf("Unlock(): %v", err)
+	}
+	if stale, err := observer.IsStale(time.Nanosecond); stale || err != nil {
+		t.Errorf("IsStale() after Unlock() = %v, %v", stale, err)
+	}
+
+	// A holder that stopped updating the time is stale. An old time in
+	// a file that isn't locked isn't.
+	old := time.Now().Add(-time.Hour).Format(time.RFC3339Nano)
+	if err := ioutil.WriteFile(name, []byte("1\n"+old+"\n"), 0644); err != nil {
+		t.Fatalf("WriteFile(): %v", err)
+	}
+	if stale, err := observer.IsStale(time.Minute); stale || err != nil {
+		t.Errorf("IsStale() of unlocked file = %v, %v", stale, err)
+	}
+	other, err := New(name)
+	if err != nil {
+		t.Fatalf("New(%q): %v", name, err)
+	}
+	defer other.Close()
+	if err := other.LockExclusive(); err != nil {
+		t.Fatalf("LockExclusive(): %v", err)
+	}
+	if stale, err := observer.IsStale(time.Minute); !stale || err != nil {
+		t.Errorf("IsStale() of hung holder = %v, %v", stale, err)
+	}
+	other.Unlock()
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/sys/unix"
+)
+
+// Locks is a set of held locks returned by LockAll.
+type Locks []*Flock
+
+// LockAll opens the lock files with the given path names and waits for
+// a lock on each of them, exclusive or shared. The paths are locked in
+// sorted order, so processes locking overlapping sets of files can't
+// deadlock against each other. A path given more than once is only
+// locked once. If any of them fails, the locks already acquired are
+// released and the error is returned.
+func LockAll(exclusive bool, names ...string) (Locks, error) {
+	paths := make([]string, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		p, err := filepath.Abs(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	flags := unix.LOCK_SH
+	if exclusive {
+		flags = unix.LOCK_EX
+	}
+	l := make(Locks, 0, len(paths))
+	for _, p := range paths {
+		f, err := New(p)
+		if err != nil {
+			l.Release()
+			return nil, err
+		}
+		if err := f.call(flags); err != nil {
+			f.Close()
+			l.Release()
+			return nil, err
+		}
+		l = append(l, f)
+	}
+	return l, nil
+}
+
+// Release unlocks and closes all of the locks in the reverse order they
+// were acquired. It returns the first error.
+func (l Locks) Release() error {
+	var first error
+	for x := len(l) - 1; x >= 0; x-- {
+		err := l[x].Unlock()
+		if cerr := l[x].Close(); err == nil {
+			err = cerr
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
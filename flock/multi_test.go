@@ -0,0 +1,78 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestLockAll(t *testing.T) {
+	names := []string{"/tmp/flock_test_all_b", "/tmp/flock_test_all_a",
+		"/tmp/flock_test_all_c"}
+	for _, name := range names {
+		defer os.Remove(name)
+	}
+
+	l, err := LockAll(true, names[0], names[1], names[2], names[0])
+	if err != nil {
+		t.Fatalf("LockAll(): %v", err)
+	}
+	if len(l) != 3 {
+		t.Fatalf("LockAll() returned %v locks", len(l))
+	}
+	for x, exp := range []string{names[1], names[0], names[2]} {
+		if n := l[x].f.Name(); n != exp {
+			t.Errorf("lock %v is %v, expected %v", x, n, exp)
+		}
+	}
+	other, err := New(names[2])
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	defer other.Close()
+	if err := other.LockShared(); err != ErrWouldBlock {
+		t.Errorf("LockShared() while held = %v", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Errorf("Release(): %v", err)
+	}
+	if err := other.LockExclusive(); err != nil {
+		t.Errorf("LockExclusive() after Release() = %v", err)
+	}
+	other.Unlock()
+
+	// The locks already acquired are released on failure.
+	if _, err := LockAll(true, names[0], "/tmp/flock_test_no_such_dir/lock"); err == nil {
+		t.Errorf("LockAll() of bad path didn't fail")
+	}
+	if err := other.LockExclusive(); err != nil {
+		t.Errorf("LockExclusive() after failure = %v", err)
+	}
+	other.Unlock()
+
+	// Opposite orders don't deadlock.
+	var wg sync.WaitGroup
+	for x := 0; x < 10; x++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+			ns := names
+			if x%2 == 1 {
+				ns = []string{names[2], names[1], names[0]}
+			}
+			l, err := LockAll(true, ns...)
+			if err != nil {
+				t.Errorf("LockAll(): %v", err)
+				return
+			}
+			l.Release()
+		}(x)
+	}
+	wg.Wait()
+}
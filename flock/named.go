@@ -0,0 +1,86 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// NamedLock is a set of exclusive locks that are accessible by a name
+// like nlock.NamedLock, but the locks are held on files, so they work
+// across processes as well as goroutines. Each name has a lock file in
+// the directory. The files aren't removed, since another process may
+// be waiting on them.
+type NamedLock struct {
+	dir string
+
+	mu   sync.Mutex
+	held map[string]*Flock
+}
+
+// NewNamedLock creates a NamedLock with the lock files in the given
+// directory, creating it if needed.
+func NewNamedLock(dir string) (*NamedLock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &NamedLock{dir: dir, held: map[string]*Flock{}}, nil
+}
+
+// Lock locks the given name. If name is already locked, it blocks
+// until the lock is available. It panics if the lock file can't be
+// opened or locked.
+func (nl *NamedLock) Lock(name string) {
+	if err := nl.LockContext(context.Background(), name); err != nil {
+		panic(fmt.Sprintf("flock: locking %q: %v", name, err))
+	}
+}
+
+// LockContext locks the given name. If name is already locked, it
+// waits until the lock is available or the context is done. In that
+// case, the context's error is returned. The lock is tried once even if
+// the context is already done.
+func (nl *NamedLock) LockContext(ctx context.Context, name string) error {
+	f, err := New(nl.path(name))
+	if err != nil {
+		return err
+	}
+	if err := f.LockExclusiveContext(ctx); err != nil {
+		f.Close()
+		return err
+	}
+	nl.mu.Lock()
+	nl.held[name] = f
+	nl.mu.Unlock()
+	return nil
+}
+
+// Unlock unlocks the given name. Nothing happens if the name isn't
+// locked by this NamedLock.
+func (nl *NamedLock) Unlock(name string) {
+	nl.mu.Lock()
+	f, ok := nl.held[name]
+	delete(nl.held, name)
+	nl.mu.Unlock()
+	if !ok {
+		return
+	}
+	f.Unlock()
+	f.Close()
+}
+
+// path returns the name of the lock file for the given name. The name
+// is escaped so it can't refer to another directory.
+func (nl *NamedLock) path(name string) string {
+	return filepath.Join(nl.dir, url.QueryEscape(name)+".lock")
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestNamedLock(t *testing.T) {
+	dir := "/tmp/flock_test_named"
+	defer os.RemoveAll(dir)
+
+	nl, err := NewNamedLock(dir)
+	if err != nil {
+		t.Fatalf("NewNamedLock(%q): %v", dir, err)
+	}
+	nl.Lock("a")
+	nl.Lock("b/../c")
+	nl.Unlock("d")
+	nl.Unlock("a")
+	nl.Unlock("b/../c")
+
+	// The files are under the directory no matter the name.
+	if _, err := os.Stat(dir + "/b%2F..%2Fc.lock"); err != nil {
+		t.Errorf("lock file not found: %v", err)
+	}
+
+	// Another NamedLock, as in another process, waits for the name.
+	other, err := NewNamedLock(dir)
+	if err != nil {
+		t.Fatalf("NewNamedLock(%q): %v", dir, err)
+	}
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders int
+	)
+	for x := 0; x < 10; x++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+			l := nl
+			if x%2 == 1 {
+				l = other
+			}
+			l.Lock("a")
+			mu.Lock()
+			holders++
+			if holders > 1 {
+				t.Errorf("%v holders of the lock", holders)
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			l.Unlock("a")
+		}(x)
+	}
+	wg.Wait()
+}
+
+func TestNamedLockContext(t *testing.T) {
+	dir := "/tmp/flock_test_named_context"
+	defer os.RemoveAll(dir)
+
+	nl, err := NewNamedLock(dir)
+	if err != nil {
+		t.Fatalf("NewNamedLock(%q): %v", dir, err)
+	}
+	other, err := NewNamedLock(dir)
+	if err != nil {
+		t.Fatalf("NewNamedLock(%q): %v", dir, err)
+	}
+	if err := nl.LockContext(context.Background(), "a"); err != nil {
+		t.Fatalf("LockContext(): %v", err)
+	}
+
+	// A done context still tries once.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := other.LockContext(ctx, "b"); err != nil {
+		t.Errorf("LockContext() of free name with done context: %v", err)
+	}
+	other.Unlock("b")
+	if err := other.LockContext(ctx, "a"); err != context.Canceled {
+		t.Errorf("LockContext() of locked name = %v, wanted %v", err, context.Canceled)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := other.LockContext(ctx, "a"); err != context.DeadlineExceeded {
+		t.Errorf("LockContext() = %v, wanted %v", err, context.DeadlineExceeded)
+	}
+	nl.Unlock("a")
+	if err := other.LockContext(context.Background(), "a"); err != nil {
+		t.Errorf("LockContext() after Unlock(): %v", err)
+	}
+	other.Unlock("a")
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrNoHolder is returned by Holder when the pid file is empty.
+var ErrNoHolder = errors.New("no holder")
+
+// pidTimeFormat is RFC3339 with a fixed number of digits.
+const pidTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// PIDLock is a pid file guarded by an exclusive lock. The holder of the
+// lock writes its pid and the time it got the lock to the file, so other
+// processes can tell who holds it. The lock is released by the system
+// if the holder dies, but the file is left behind. Stale detects this.
+type PIDLock struct {
+	name string
+	f    *Flock
+}
+
+// NewPIDLock creates a new PIDLock for the given path name.
+func NewPIDLock(name string) (*PIDLock, error) {
+	// The file isn't opened for appending, so it can be rewritten in
+	// place.
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &PIDLock{name: name, f: &Flock{f: f}}, nil
+}
+
+// Lock attempts to get the lock and write our pid to the file. It
+// won't block if another process holds it. In this case, the return
+// error is ErrWouldBlock. A stale file is overwritten.
+func (p *PIDLock) Lock() error {
+	if err := p.f.LockExclusive(); err != nil {
+		return err
+	}
+	if err := p.write(); err != nil {
+		p.f.Unlock()
+		return err
+	}
+	return nil
+}
+
+// write replaces the contents of the file with our pid and the current
+// time. It's written over the old contents before the file is
+// truncated, so readers never see an empty file. The time has a fixed
+// width so rewrites don't leave parts of the old one behind.
+func (p *PIDLock) write() error {
+	b := []byte(fmt.Sprintf("%d\n%s\n", os.Getpid(),
+		time.Now().Format(pidTimeFormat)))
+	if _, err := p.f.f.WriteAt(b, 0); err != nil {
+		return err
+	}
+	if err := p.f.f.Truncate(int64(len(b))); err != nil {
+		return err
+	}
+	return p.f.f.Sync()
+}
+
+// Unlock empties the file and releases the lock.
+func (p *PIDLock) Unlock() error {
+	if err := p.f.f.Truncate(0); err != nil {
+		return err
+	}
+	return p.f.Unlock()
+}
+
+// Close closes the open file. This should be called when the lock is
+// no longer needed.
+func (p *PIDLock) Close() error {
+	return p.f.Close()
+}
+
+// Holder returns the pid of the process that last got the lock and when
+// it got it. If the file is empty, the returned error is ErrNoHolder.
+// The holder may have since died (see Stale).
+func (p *PIDLock) Holder() (int, time.Time, error) {
+	b, err := ioutil.ReadFile(p.name)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if lines[0] == "" {
+		return 0, time.Time{}, ErrNoHolder
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("invalid pid file %v: %v", p.name, err)
+	}
+	var start time.Time
+	if len(lines) > 1 {
+		start, err = time.Parse(time.RFC3339Nano, strings.TrimSpace(lines[1]))
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("invalid pid file %v: %v", p.name, err)
+		}
+	}
+	return pid, start, nil
+}
+
+// Stale returns true if the file names a holder but the lock isn't
+// held, which happens when the holder died without unlocking. The
+// holder's pid may have been reused by then, so the lock is checked
+// rather than only the process.
+func (p *PIDLock) Stale() (bool, error) {
+	pid, _, err := p.Holder()
+	if err == ErrNoHolder {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if unix.Kill(pid, 0) == unix.ESRCH {
+		return true, nil
+	}
+	held, err := p.held()
+	return !held, err
+}
+
+// held returns true if some process holds the lock. A separate open
+// file conflicts with the lock even in the holder's own process.
+func (p *PIDLock) held() (bool, error) {
+	f, err := New(p.name)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	switch err := f.LockShared(); err {
+	case nil:
+		f.Unlock()
+		return false, nil
+	case ErrWouldBlock:
+		return true, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPIDLock(t *testing.T) {
+	name := "/tmp/flock_test_pidlock"
+	defer os.Remove(name)
+	os.Remove(name)
+
+	locks := make([]*PIDLock, 2)
+	for x := 0; x < 2; x++ {
+		p, err := NewPIDLock(name)
+		if err != nil {
+			t.Fatalf(`NewPIDLock(%q): %v`, name, err)
+		}
+		defer p.Close()
+		locks[x] = p
+	}
+
+	if _, _, err := locks[0].Holder(); err != ErrNoHolder {
+		t.Errorf("Holder() of empty file = %v", err)
+	}
+	if stale, err := locks[0].Stale(); stale || err != nil {
+		t.Errorf("Stale() of empty file = %v, %v", stale, err)
+	}
+
+	start := time.Now()
+	if err := locks[0].Lock(); err != nil {
+		t.Fatalf("Lock(): %v", err)
+	}
+	if err := locks[1].Lock(); err != ErrWouldBlock {
+		t.Errorf("Lock() while held = %v", err)
+	}
+	pid, at, err := locks[1].Holder()
+	if err != nil || pid != os.Getpid() || at.Before(start.Truncate(time.Second)) {
+		t.Errorf("Holder() = %v, %v, %v", pid, at, err)
+	}
+	if stale, err := locks[1].Stale(); stale || err != nil {
+		t.Errorf("Stale() while held = %v, %v", stale, err)
+	}
+	if err := locks[0].Unlock(); err != nil {
+		t.Fatalf("Unlock(): %v", err)
+	}
+	if _, _, err := locks[1].Holder(); err != ErrNoHolder {
+		t.Errorf("Holder() after Unlock() = %v", err)
+	}
+
+	// A file left by a holder that died is stale and is taken over.
+	if err := ioutil.WriteFile(name, []byte("999999999\n"), 0644); err != nil {
+		t.Fatalf("WriteFile(): %v", err)
+	}
+	if stale, err := locks[1].Stale(); !stale || err != nil {
+		t.Errorf("Stale() of dead holder = %v, %v", stale, err)
+	}
+	if err := ioutil.WriteFile(name, []byte("1\n"), 0644); err != nil {
+		t.Fatalf("WriteFile(): %v", err)
+	}
+	if stale, err := locks[1].Stale(); !stale || err != nil {
+		t.Errorf("Stale() of unlocked file = %v, %v", stale, err)
+	}
+	if err := locks[1].Lock(); err != nil {
+		t.Fatalf("Lock() of stale file: %v", err)
+	}
+	if pid, _, err := locks[0].Holder(); pid != os.Getpid() || err != nil {
+		t.Errorf("Holder() after taking over = %v, %v", pid, err)
+	}
+	locks[1].Unlock()
+
+	if err := ioutil.WriteFile(name, []byte("x\n"), 0644); err != nil {
+		t.Fatalf("WriteFile(): %v", err)
+	}
+	if _, _, err := locks[0].Holder(); err == nil {
+		t.Errorf("Holder() of invalid file didn't fail")
+	}
+}
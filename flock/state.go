@@ -0,0 +1,46 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import "sync/atomic"
+
+// LockState is the kind of lock a Flock holds.
+type LockState int32
+
+// The states of a Flock.
+const (
+	Unlocked LockState = iota
+	Shared
+	Exclusive
+)
+
+func (s LockState) String() string {
+	switch s {
+	case Unlocked:
+		return "unlocked"
+	case Shared:
+		return "shared"
+	case Exclusive:
+		return "exclusive"
+	}
+	return "unknown"
+}
+
+// State returns the kind of lock this Flock holds.
+func (f *Flock) State() LockState {
+	return LockState(atomic.LoadInt32(&f.state))
+}
+
+// TestLock returns the pid of a process holding a lock on the file that
+// conflicts with getting a lock of the given kind, or 0 if there is
+// none. Any lock conflicts with an exclusive one and only an exclusive
+// one conflicts with a shared one. Locks held by this process are
+// ignored; use State for them. Since another process may get or release
+// a lock at any time, it's only meant for diagnostics.
+func (f *Flock) TestLock(exclusive bool) (int, error) {
+	return testLock(f, exclusive)
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// procLocks lists the locks held on the system.
+const procLocks = "/proc/locks"
+
+// testLock finds the lock in /proc/locks. Linux keeps flock locks apart
+// from fcntl locks, so fcntl(F_GETLK) can't see them. Each line looks
+// like:
+//
+//	1: FLOCK  ADVISORY  WRITE 1234 08:01:5678 0 EOF
+//
+// where the fields after the type are the pid and the file's device
+// and inode. Processes waiting for a lock are listed with "->" and are
+// skipped.
+func testLock(f *Flock, exclusive bool) (int, error) {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(f.f.Fd()), &st); err != nil {
+		return 0, err
+	}
+	id := fmt.Sprintf("%02x:%02x:%d", unix.Major(uint64(st.Dev)),
+		unix.Minor(uint64(st.Dev)), st.Ino)
+
+	pl, err := os.Open(procLocks)
+	if err != nil {
+		return 0, err
+	}
+	defer pl.Close()
+	self := os.Getpid()
+	s := bufio.NewScanner(pl)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 6 || fields[1] != "FLOCK" {
+			continue
+		}
+		if fields[5] != id || (!exclusive && fields[3] != "WRITE") {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[4])
+		if err != nil {
+			return 0, fmt.Errorf("invalid line in %v: %q", procLocks, s.Text())
+		}
+		if pid != self {
+			return pid, nil
+		}
+	}
+	return 0, s.Err()
+}
@@ -0,0 +1,32 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+//go:build !linux
+// +build !linux
+
+package flock
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// testLock asks with fcntl(F_GETLK). On the BSDs, flock locks are the
+// same as fcntl locks of the whole file, so they're reported.
+func testLock(f *Flock, exclusive bool) (int, error) {
+	lk := unix.Flock_t{Type: unix.F_RDLCK}
+	if exclusive {
+		lk.Type = unix.F_WRLCK
+	}
+	if err := unix.FcntlFlock(f.f.Fd(), unix.F_GETLK, &lk); err != nil {
+		return 0, err
+	}
+	if lk.Type == unix.F_UNLCK || int(lk.Pid) == os.Getpid() {
+		return 0, nil
+	}
+	return int(lk.Pid), nil
+}
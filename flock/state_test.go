@@ -0,0 +1,150 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flock
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestHelperLock isn't a real test. It's run by TestTestLock in another
+// process to hold a lock until its stdin is closed.
+func TestHelperLock(t *testing.T) {
+	name := os.Getenv("FLOCK_HELPER_FILE")
+	if name == "" {
+		return
+	}
+	f, err := New(name)
+	if err != nil {
+		os.Exit(1)
+	}
+	if os.Getenv("FLOCK_HELPER_SHARED") != "" {
+		err = f.LockSharedWait()
+	} else {
+		err = f.LockExclusiveWait()
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+	os.Stdout.WriteString("locked\n")
+	ioutil.ReadAll(os.Stdin)
+	os.Exit(0)
+}
+
+// holdLock runs TestHelperLock to hold a lock on the file in another
+// process. It returns the process and a function that releases the
+// lock.
+func holdLock(t *testing.T, name string, shared bool) (*exec.Cmd, func()) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperLock$")
+	cmd.Env = append(os.Environ(), "FLOCK_HELPER_FILE="+name)
+	if shared {
+		cmd.Env = append(cmd.Env, "FLOCK_HELPER_SHARED=1")
+	}
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("StdinPipe(): %v", err)
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe(): %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start(): %v", err)
+	}
+	if l, err := bufio.NewReader(out).ReadString('\n'); l != "locked\n" {
+		t.Fatalf("helper didn't get the lock: %q %v", l, err)
+	}
+	return cmd, func() {
+		in.Close()
+		cmd.Wait()
+	}
+}
+
+func TestState(t *testing.T) {
+	name := "/tmp/flock_test_state"
+	defer os.Remove(name)
+
+	f, err := New(name)
+	if err != nil {
+		t.Fatalf("New(%q): %v", name, err)
+	}
+	defer f.Close()
+	steps := []struct {
+		lock func() error
+		exp  LockState
+	}{
+		{f.LockShared, Shared},
+		{f.LockExclusive, Exclusive},
+		{f.Unlock, Unlocked},
+		{f.LockExclusiveWait, Exclusive},
+		{f.LockSharedWait, Shared},
+		{f.Unlock, Unlocked},
+	}
+	if s := f.State(); s != Unlocked {
+		t.Errorf("State() of new lock = %v", s)
+	}
+	for k, step := range steps {
+		if err := step.lock(); err != nil {
+			t.Fatalf("Step %v: %v", k, err)
+		}
+		if s := f.State(); s != step.exp {
+			t.Errorf("Step %v: State() = %v, expected %v", k, s, step.exp)
+		}
+	}
+
+	// A failed lock doesn't change it.
+	other, err := New(name)
+	if err != nil {
+		t.Fatalf("New(%q): %v", name, err)
+	}
+	defer other.Close()
+	other.LockExclusive()
+	if err := f.LockShared(); err != ErrWouldBlock {
+		t.Errorf("LockShared() while held = %v", err)
+	}
+	if s := f.State(); s != Unlocked {
+		t.Errorf("State() after failed lock = %v", s)
+	}
+	if s := LockState(7).String(); s != "unknown" {
+		t.Errorf("String() of unknown state = %v", s)
+	}
+}
+
+func TestTestLock(t *testing.T) {
+	name := "/tmp/flock_test_testlock"
+	defer os.Remove(name)
+
+	f, err := New(name)
+	if err != nil {
+		t.Fatalf("New(%q): %v", name, err)
+	}
+	defer f.Close()
+	// Our own locks are ignored.
+	f.LockExclusive()
+	if pid, err := f.TestLock(true); pid != 0 || err != nil {
+		t.Errorf("TestLock() without other process = %v, %v", pid, err)
+	}
+	f.Unlock()
+
+	cmd, release := holdLock(t, name, true)
+	if pid, err := f.TestLock(true); pid != cmd.Process.Pid || err != nil {
+		t.Errorf("TestLock(true) with shared lock = %v, %v", pid, err)
+	}
+	if pid, err := f.TestLock(false); pid != 0 || err != nil {
+		t.Errorf("TestLock(false) with shared lock = %v, %v", pid, err)
+	}
+	release()
+
+	cmd, release = holdLock(t, name, false)
+	defer release()
+	if pid, err := f.TestLock(false); pid != cmd.Process.Pid || err != nil {
+		t.Errorf("TestLock(false) with exclusive lock = %v, %v", pid, err)
+	}
+}
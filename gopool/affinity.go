@@ -0,0 +1,188 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// KeyedTask is a Task that has a key. An AffinityPool runs all of the
+// tasks with the same key on the same goroutine in the order they were
+// received.
+type KeyedTask interface {
+	Task
+	Key() string
+}
+
+// NewKeyedTask returns a KeyedTask with the given task and key.
+func NewKeyedTask(t Task, key string) KeyedTask {
+	return &kt{
+		k: key,
+		t: t,
+	}
+}
+
+// kt is an internal implementation of the KeyedTask.
+type kt struct {
+	k string
+	t Task
+}
+
+func (t *kt) String() string        { return t.t.String() }
+func (t *kt) Key() string           { return t.k }
+func (t *kt) Run(c context.Context) { t.t.Run(c) }
+
+// affinityBuffer is the number of tasks that can be waiting for each
+// worker in an AffinityPool before the dispatcher blocks.
+const affinityBuffer = 16
+
+// AffinityPool is like a GoPool but each goroutine has its own queue.
+// Tasks are assigned to a goroutine by hashing their key, so tasks
+// with the same key are run in order while tasks with different keys
+// can run in parallel. Tasks that aren't KeyedTasks are assigned to
+// goroutines in a round-robin fashion.
+//
+// Because a slow task holds up the other tasks assigned to its
+// goroutine, the dispatcher blocks once a goroutine has too many
+// tasks waiting.
+type AffinityPool struct {
+	name   string
+	src    <-chan Task
+	wg     sync.WaitGroup
+	ctx    context.Context
+	opts   options
+	queues []chan Task
+}
+
+// NewAffinityPool creates a new AffinityPool with the given number of
+// goroutines. The arguments are the same as New().
+func NewAffinityPool(name string, goroutines int, verbose bool,
+	ctx context.Context, src <-chan Task, opts ...Option) *AffinityPool {
+	if goroutines < 1 {
+		goroutines = 1
+	}
+	p := &AffinityPool{
+		name:   name,
+		src:    src,
+		ctx:    ctx,
+		opts:   newOptions(verbose, opts),
+		queues: make([]chan Task, goroutines),
+	}
+	p.wg.Add(goroutines + 1)
+	for x := range p.queues {
+		p.queues[x] = make(chan Task, affinityBuffer)
+		go p.worker(x, p.queues[x])
+	}
+	go p.dispatch()
+	return p
+}
+
+// Wait blocks until all of the workers have stopped. This won't ever
+// return if the context for this gopool is never done and the source
+// is never closed.
+func (p *AffinityPool) Wait() {
+	p.wg.Wait()
+}
+
+// String implements the fmt.Stringer interface. It just prints the
+// name given to NewAffinityPool().
+func (p *AffinityPool) String() string {
+	return p.name
+}
+
+// dispatch gets tasks from the source and sends them to the queue of
+// the worker that should run them. When the source is closed, the
+// worker queues are closed.
+func (p *AffinityPool) dispatch() {
+	defer p.wg.Done()
+	defer func() {
+		for _, q := range p.queues {
+			close(q)
+		}
+	}()
+	next := 0
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case t, ok := <-p.src:
+			if !ok {
+				p.opts.printf("[gopool %v] input source closed: stopping", p)
+				return
+			}
+			var x int
+			if k, ok := t.(KeyedTask); ok {
+				h := fnv.New32a()
+				h.Write([]byte(k.Key()))
+				x = int(h.Sum32() % uint32(len(p.queues)))
+			} else {
+				x = next
+				next = (next + 1) % len(p.queues)
+			}
+			select {
+			case <-p.ctx.Done():
+				return
+			case p.queues[x] <- t:
+			}
+		}
+	}
+}
+
+// worker runs the tasks from its queue until the context is done or
+// its queue is closed.
+func (p *AffinityPool) worker(ID int, q <-chan Task) {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.ctx.Done():
+			p.opts.verbosef("[gopool %v %v] stop channel closed: stopping", p, ID)
+			p.stopped(ID, p.ctx.Err())
+			return
+		case t, ok := <-q:
+			if !ok {
+				p.opts.verbosef("[gopool %v %v] queue closed: stopping", p, ID)
+				// The dispatcher closes the queues when the context is
+				// done or the source is closed.
+				err := p.ctx.Err()
+				if err == nil {
+					err = ErrSourceClosed
+				}
+				p.stopped(ID, err)
+				return
+			}
+			p.run(ID, t)
+		}
+	}
+}
+
+// run performs a single task for the given worker.
+func (p *AffinityPool) run(ID int, t Task) {
+	p.opts.verbosef("[gopool %v %v] starting task: %v", p, ID, t)
+	p.opts.emit(Event{Type: EventTaskStarted, Name: p.name, Worker: ID, Task: t})
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			p.opts.emit(Event{Type: EventTaskFailed, Name: p.name, Worker: ID,
+				Task: t, Duration: time.Now().Sub(start), Err: &PanicError{Value: r}})
+			panic(r)
+		}
+	}()
+	t.Run(p.ctx)
+	d := time.Now().Sub(start)
+	p.opts.verbosef("[gopool %v %v] finished task (duration %v): %v", p, ID, d, t)
+	p.opts.emit(Event{Type: EventTaskFinished, Name: p.name, Worker: ID,
+		Task: t, Duration: d})
+}
+
+// stopped records that the given worker stopped.
+func (p *AffinityPool) stopped(ID int, err error) {
+	p.opts.emit(Event{Type: EventWorkerStopped, Name: p.name, Worker: ID, Err: err})
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"bytes"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestAffinityPool(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+
+	var l sync.Mutex
+	seen := map[string][]int{}
+	workers := map[string]map[int]bool{}
+	src := make(chan Task)
+	pool := NewAffinityPool("test-pool", 4, true, context.Background(), src)
+	if pool.String() != "test-pool" {
+		t.Errorf(`pool.String() != "test-pool": %v`, pool.String())
+	}
+
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	for x := 0; x < 600; x++ {
+		key := keys[x%len(keys)]
+		i := x
+		src <- NewKeyedTask(&tt{i: x, f: func(int) {
+			l.Lock()
+			defer l.Unlock()
+			seen[key] = append(seen[key], i)
+		}}, key)
+	}
+	// Unkeyed tasks should still run.
+	done := 0
+	for x := 0; x < 10; x++ {
+		src <- &tt{i: 1000 + x, f: func(int) {
+			l.Lock()
+			defer l.Unlock()
+			done++
+		}}
+	}
+	close(src)
+	pool.Wait()
+
+	for _, key := range keys {
+		if len(seen[key]) != 100 {
+			t.Errorf("key %v: got %v tasks, expected 100", key, len(seen[key]))
+		}
+		for x := 1; x < len(seen[key]); x++ {
+			if seen[key][x] < seen[key][x-1] {
+				t.Errorf("key %v: tasks out of order: %v", key, seen[key])
+				break
+			}
+		}
+	}
+	if done != 10 {
+		t.Errorf("got %v unkeyed tasks, expected 10", done)
+	}
+
+	// Each key should only have been run by one worker.
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if !strings.Contains(line, "starting task") {
+			continue
+		}
+		parts := strings.Fields(line)
+		id, _ := strconv.Atoi(strings.TrimSuffix(parts[4], "]"))
+		n, _ := strconv.Atoi(parts[len(parts)-1])
+		if n >= 600 {
+			continue
+		}
+		key := keys[n%len(keys)]
+		if workers[key] == nil {
+			workers[key] = map[int]bool{}
+		}
+		workers[key][id] = true
+	}
+	for _, key := range keys {
+		if len(workers[key]) != 1 {
+			t.Errorf("key %v was run by %v workers", key, len(workers[key]))
+		}
+	}
+	if !strings.Contains(buf.String(), "input source closed") {
+		t.Errorf("didn't log input source closed")
+	}
+}
+
+func TestAffinityPoolStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := NewAffinityPool("test-pool", 0, false, ctx, make(chan Task))
+	cancel()
+	pool.Wait()
+}
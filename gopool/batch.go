@@ -0,0 +1,103 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// BatchTask processes groups of tasks at once. This is useful when
+// the work is much cheaper in bulk (e.g. database inserts).
+type BatchTask interface {
+	fmt.Stringer
+
+	// Run performs the work for the batch of tasks. The context
+	// should be handled the same way as in Task.Run.
+	Run(ctx context.Context, batch []Task)
+}
+
+// batch is a Task that gives a group of tasks to a BatchTask.
+type batch struct {
+	bt    BatchTask
+	tasks []Task
+}
+
+func (b *batch) String() string {
+	return fmt.Sprintf("%v (batch of %d)", b.bt, len(b.tasks))
+}
+
+func (b *batch) Run(ctx context.Context) {
+	b.bt.Run(ctx, b.tasks)
+}
+
+// NewBatcher groups the tasks from src into batches and returns a
+// channel of tasks that give each batch to the BatchTask. The returned
+// channel can be given to New() so batches are processed by a gopool.
+// A batch is sent once it has size tasks or wait has passed since its
+// first task was received, whichever comes first.
+//
+// When src is closed, any partial batch is sent and the returned
+// channel is closed. When the context is done, the returned channel
+// is closed and any partial batch is dropped.
+func NewBatcher(size int, wait time.Duration, bt BatchTask,
+	ctx context.Context, src <-chan Task) <-chan Task {
+	if size < 1 {
+		size = 1
+	}
+	out := make(chan Task)
+	go func() {
+		defer close(out)
+		var tasks []Task
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		send := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			if len(tasks) == 0 {
+				return true
+			}
+			b := &batch{bt: bt, tasks: tasks}
+			tasks = nil
+			select {
+			case out <- b:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timeout:
+				if !send() {
+					return
+				}
+			case t, ok := <-src:
+				if !ok {
+					send()
+					return
+				}
+				tasks = append(tasks, t)
+				if len(tasks) >= size {
+					if !send() {
+						return
+					}
+				} else if timer == nil {
+					timer = time.NewTimer(wait)
+					timeout = timer.C
+				}
+			}
+		}
+	}()
+	return out
+}
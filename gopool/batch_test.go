@@ -0,0 +1,75 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestBatcher(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	bt := &bt{}
+	src := make(chan Task)
+	pool := New("test-pool", 2, false, context.Background(),
+		NewBatcher(3, 20*time.Millisecond, bt, context.Background(), src))
+
+	// Two full batches and then a partial one that times out.
+	for x := 0; x < 7; x++ {
+		src <- &tt{i: x}
+	}
+	time.Sleep(50 * time.Millisecond)
+	bt.Lock()
+	if !reflect.DeepEqual(bt.sizes, []int{3, 3, 1}) &&
+		!reflect.DeepEqual(bt.sizes, []int{3, 1, 3}) {
+		t.Errorf("unexpected batch sizes: %v", bt.sizes)
+	}
+	bt.Unlock()
+
+	// A partial batch is sent when the source is closed.
+	src <- &tt{i: 8}
+	src <- &tt{i: 9}
+	close(src)
+	pool.Wait()
+	if bt.total != 9 || len(bt.sizes) != 4 || bt.sizes[3] != 2 {
+		t.Errorf("unexpected batches after close: %v %v", bt.total, bt.sizes)
+	}
+}
+
+func TestBatcherStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	src := make(chan Task)
+	out := NewBatcher(0, time.Hour, &bt{}, ctx, src)
+	src <- &tt{i: 1}
+	cancel()
+	for range out {
+	}
+	if s := (&batch{bt: &bt{}, tasks: []Task{&tt{}}}).String(); s != "bt (batch of 1)" {
+		t.Errorf("unexpected batch String(): %v", s)
+	}
+}
+
+// bt is a helper BatchTask that records the batches it gets.
+type bt struct {
+	sync.Mutex
+	sizes []int
+	total int
+}
+
+func (b *bt) String() string { return "bt" }
+func (b *bt) Run(ctx context.Context, batch []Task) {
+	b.Lock()
+	defer b.Unlock()
+	b.sizes = append(b.sizes, len(batch))
+	b.total += len(batch)
+}
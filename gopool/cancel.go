@@ -0,0 +1,346 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"reflect"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// idTasks tracks the IdentifiedTasks with the same ID in a
+// ManagedSource so they can be cancelled. The tasks themselves are
+// given to the Sourcer as is and are recognized by origin when they
+// come back out of it, so only the ones that were queued when Cancel
+// was called are dropped.
+type idTasks struct {
+	queued    map[Task]int             // The tasks in the Sourcer and their counts.
+	cancelled map[Task]int             // How many of the queued tasks were cancelled.
+	running   map[*cancelTask]struct{} // The tasks sent to a gopool.
+}
+
+// cancelTask wraps an IdentifiedTask when it's sent to a gopool so it
+// runs with a context that Cancel can cancel.
+type cancelTask struct {
+	Task
+	ms *ManagedSource
+	id string
+
+	mu        sync.Mutex
+	cancelled bool
+	cancel    context.CancelFunc
+}
+
+// Run implements Task.Run. The wrapped task is run with a context
+// that is cancelled if Cancel is called while it's running.
+func (t *cancelTask) Run(ctx context.Context) {
+	defer t.ms.finish(t)
+	t.mu.Lock()
+	if t.cancelled {
+		t.mu.Unlock()
+		return
+	}
+	ctx, t.cancel = context.WithCancel(ctx)
+	t.mu.Unlock()
+	defer t.cancel()
+	t.Task.Run(ctx)
+}
+
+// stop cancels the task and returns whether it already was.
+func (t *cancelTask) stop() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	was := t.cancelled
+	t.cancelled = true
+	if t.cancel != nil {
+		t.cancel()
+	}
+	return was
+}
+
+// Cancel withdraws the tasks with the given ID. Tasks that haven't
+// been sent to a gopool yet are dropped when they come up and
+// acknowledged if their Sourcer requires it (e.g. a DiskQueue). The
+// contexts of tasks that are running are cancelled. Tasks with the ID
+// that are added after Cancel is called aren't affected. Only
+// IdentifiedTasks added through the managed source's Add channel or
+// TryAdd can be cancelled and they must be comparable (e.g. pointers).
+// It returns false if there were no such tasks.
+func (ms *ManagedSource) Cancel(id string) bool {
+	// Make sure the tasks that were just sent to Add are tracked.
+	ms.state(context.Background())
+	ms.mu.Lock()
+	ts := ms.tasks[id]
+	var running []*cancelTask
+	found := false
+	if ts != nil {
+		for t, n := range ts.queued {
+			ts.cancelled[t] = n
+		}
+		for t := range ts.running {
+			running = append(running, t)
+		}
+		found = len(ts.queued) > 0 || len(running) > 0
+	}
+	ms.mu.Unlock()
+	for _, t := range running {
+		t.stop()
+	}
+	return found
+}
+
+// ids returns the tracking of the given ID, creating it if needed. The
+// caller must hold ms.mu.
+func (ms *ManagedSource) ids(id string) *idTasks {
+	ts := ms.tasks[id]
+	if ts == nil {
+		ts = &idTasks{
+			queued:    map[Task]int{},
+			cancelled: map[Task]int{},
+			running:   map[*cancelTask]struct{}{},
+		}
+		ms.tasks[id] = ts
+	}
+	return ts
+}
+
+// clean stops tracking the ID if it has no tasks. The caller must hold
+// ms.mu.
+func (ms *ManagedSource) clean(id string) {
+	if ts := ms.tasks[id]; ts != nil && len(ts.queued) == 0 && len(ts.running) == 0 {
+		delete(ms.tasks, id)
+	}
+}
+
+// track records that an IdentifiedTask was added to the Sourcer.
+func (ms *ManagedSource) track(t Task) {
+	i, ok := t.(IdentifiedTask)
+	if !ok {
+		return
+	}
+	o := origin(t)
+	if !reflect.TypeOf(o).Comparable() {
+		return
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.ids(i.ID()).queued[o]++
+}
+
+// forget stops tracking a task that the Sourcer dropped.
+func (ms *ManagedSource) forget(t Task) {
+	i, ok := t.(IdentifiedTask)
+	if !ok {
+		return
+	}
+	o := origin(t)
+	if !reflect.TypeOf(o).Comparable() {
+		return
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ts := ms.tasks[i.ID()]; ts != nil {
+		ms.untrack(ts, o)
+		ms.clean(i.ID())
+	}
+}
+
+// untrack removes one of the queued instances of the task. It returns
+// true if it was cancelled. The caller must hold ms.mu.
+func (ms *ManagedSource) untrack(ts *idTasks, o Task) bool {
+	if ts.queued[o] == 0 {
+		// Tasks that weren't added through the managed source, e.g.
+		// ones a DiskQueue replayed, weren't tracked.
+		return false
+	}
+	if ts.queued[o]--; ts.queued[o] == 0 {
+		delete(ts.queued, o)
+	}
+	if ts.cancelled[o] == 0 {
+		return false
+	}
+	if ts.cancelled[o]--; ts.cancelled[o] == 0 {
+		delete(ts.cancelled, o)
+	}
+	return true
+}
+
+// take is called with each task taken from the Sourcer. If it was
+// cancelled, it returns true and the task should be discarded.
+// Otherwise it returns the task to send to the gopool, which is
+// wrapped in a cancelTask if it's an IdentifiedTask. The cancelTask is
+// tracked as running from then on, so it can be cancelled before the
+// gopool gets to it.
+func (ms *ManagedSource) take(t Task) (Task, *cancelTask, bool) {
+	i, ok := t.(IdentifiedTask)
+	if !ok {
+		return t, nil, false
+	}
+	id := i.ID()
+	o := origin(t)
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ts := ms.ids(id)
+	if reflect.TypeOf(o).Comparable() && ms.untrack(ts, o) {
+		ms.clean(id)
+		return nil, nil, true
+	}
+	ct := &cancelTask{Task: t, ms: ms, id: id}
+	ts.running[ct] = struct{}{}
+	return forward(ct, t), ct, false
+}
+
+// requeue undoes take for a task that is being added back to the
+// Sourcer. It returns false if the task was cancelled in the meantime
+// and should be dropped instead.
+func (ms *ManagedSource) requeue(ct *cancelTask) bool {
+	if ct == nil {
+		return true
+	}
+	cancelled := ct.stop()
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ts := ms.ids(ct.id)
+	delete(ts.running, ct)
+	if o := origin(ct.Task); !cancelled && reflect.TypeOf(o).Comparable() {
+		ts.queued[o]++
+	}
+	ms.clean(ct.id)
+	return !cancelled
+}
+
+// finish stops tracking a task once it has run.
+func (ms *ManagedSource) finish(t *cancelTask) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ts := ms.tasks[t.id]; ts != nil {
+		delete(ts.running, t)
+		ms.clean(t.id)
+	}
+}
+
+// These are the optional methods of tasks that wrappers forward.
+type (
+	identifier  interface{ ID() string }
+	keyer       interface{ Key() string }
+	prioritizer interface{ Priority() int }
+)
+
+// wrapped is embedded by the tasks forward returns so unwrap can get
+// the wrapper back.
+type wrapped struct {
+	Task
+}
+
+func (w *wrapped) unwrap() Task { return w.Task }
+
+// unwrap returns the wrapper that forward was given for t or t itself.
+func unwrap(t Task) Task {
+	if u, ok := t.(interface {
+		unwrap() Task
+	}); ok {
+		return u.unwrap()
+	}
+	return t
+}
+
+// inner is implemented by the wrappers Sourcers put around the tasks
+// they're given, e.g. a diskTask. task returns the wrapped task.
+type inner interface {
+	task() Task
+}
+
+// discarder is implemented by the wrappers that need to know when a
+// task is dropped instead of run, e.g. so a diskTask is acknowledged.
+type discarder interface {
+	discard()
+}
+
+// origin returns the task that was given to the Sourcer t came from,
+// unwrapping any wrappers.
+func origin(t Task) Task {
+	for {
+		t = unwrap(t)
+		i, ok := t.(inner)
+		if !ok {
+			return t
+		}
+		t = i.task()
+	}
+}
+
+// discard tells the wrappers of a task taken from a Sourcer that it's
+// being dropped.
+func discard(t Task) {
+	for {
+		t = unwrap(t)
+		if d, ok := t.(discarder); ok {
+			d.discard()
+		}
+		i, ok := t.(inner)
+		if !ok {
+			return
+		}
+		t = i.task()
+	}
+}
+
+// forward returns w with the ID, Key and Priority methods of t if t
+// has them. This keeps a task that's wrapped by gopool from losing
+// the optional interfaces that Sourcers and pools look for, e.g. the
+// KeyedTask interface of an AffinityPool. t doesn't have to be a Task,
+// e.g. it's an ErrorTask for a Group. Use unwrap to get w back.
+func forward(w Task, t interface{}) Task {
+	i, iok := t.(identifier)
+	k, kok := t.(keyer)
+	p, pok := t.(prioritizer)
+	ww := &wrapped{w}
+	switch {
+	case iok && kok && pok:
+		return &struct {
+			*wrapped
+			identifier
+			keyer
+			prioritizer
+		}{ww, i, k, p}
+	case iok && kok:
+		return &struct {
+			*wrapped
+			identifier
+			keyer
+		}{ww, i, k}
+	case iok && pok:
+		return &struct {
+			*wrapped
+			identifier
+			prioritizer
+		}{ww, i, p}
+	case kok && pok:
+		return &struct {
+			*wrapped
+			keyer
+			prioritizer
+		}{ww, k, p}
+	case iok:
+		return &struct {
+			*wrapped
+			identifier
+		}{ww, i}
+	case kok:
+		return &struct {
+			*wrapped
+			keyer
+		}{ww, k}
+	case pok:
+		return &struct {
+			*wrapped
+			prioritizer
+		}{ww, p}
+	}
+	return w
+}
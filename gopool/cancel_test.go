@@ -0,0 +1,297 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestManagedSourceCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms := NewManagedSource(NewFIFOQueue("test"), false, nil, ctx)
+
+	// Queue a task that blocks until it's cancelled and a few others.
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	ms.Add <- NewIdentifiedTask(&ft{f: func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+	}}, "running")
+	ms.Add <- NewIdentifiedTask(&sct{name: "a"}, "a")
+	ms.Add <- &ipt{sct: sct{name: "b", w: &bytes.Buffer{}}, id: "b", p: 10}
+	ms.Add <- NewIdentifiedTask(&sct{name: "c"}, "c")
+	ms.Add <- &sct{name: "d"}
+	if ms.Cancel("z") {
+		t.Errorf("Cancel() of unknown ID returned true")
+	}
+	if !ms.Cancel("a") {
+		t.Errorf("Cancel() of queued task returned false")
+	}
+
+	// Start the running one.
+	r := <-ms.Source
+	go r.Run(ctx)
+	<-started
+	if !ms.Cancel("running") {
+		t.Errorf("Cancel() of running task returned false")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("running task's context wasn't cancelled")
+	}
+
+	// a should have been dropped. b should keep its priority.
+	b := <-ms.Source
+	if b.String() != "b" || b.(PriorityTask).Priority() != 10 {
+		t.Errorf("didn't get b with its priority: %v", b)
+	}
+	b.Run(ctx)
+	for _, e := range []string{"c", "d"} {
+		if c := <-ms.Source; c.String() != e {
+			t.Errorf("didn't get %v: %v", e, c)
+		}
+	}
+	select {
+	case c := <-ms.Source:
+		t.Errorf("got cancelled task: %v", c)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if ms.Len() != 0 {
+		t.Errorf("ms.Len() != 0: %v", ms.Len())
+	}
+}
+
+func TestManagedSourceCancelWrapped(t *testing.T) {
+	// The Sourcers that wrap their tasks still drop the cancelled ones.
+	d := NewDedupSource(NewFIFOQueue("test"), 0, false)
+	m := NewMultiSource("test", NewFIFOQueue("a"), NewFIFOQueue("b"))
+	for _, s := range []Sourcer{d, m} {
+		ctx, cancel := context.WithCancel(context.Background())
+		ms := NewManagedSource(s, false, nil, ctx)
+		ms.Add <- &sct{name: "first"}
+		ms.Add <- NewIdentifiedTask(&sct{name: "a"}, "a")
+		ms.Add <- NewIdentifiedTask(&sct{name: "b"}, "b")
+		if !ms.Cancel("a") {
+			t.Errorf("%T: Cancel() of queued task returned false", s)
+		}
+		for _, e := range []string{"first", "b"} {
+			if c := <-ms.Source; c.String() != e {
+				t.Errorf("%T: didn't get %v: %v", s, e, c)
+			}
+		}
+		select {
+		case c := <-ms.Source:
+			t.Errorf("%T: got cancelled task: %v", s, c)
+		case <-time.After(10 * time.Millisecond):
+		}
+		cancel()
+		ms.Wait()
+	}
+	if d.Queued("a") {
+		t.Errorf("dropped task still queued in the DedupSource")
+	}
+}
+
+func TestManagedSourceCancelLater(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms := NewManagedSource(NewLIFOQueue("test"), false, nil, ctx)
+
+	// A task with the same ID added after Cancel runs even though it
+	// comes out of the Sourcer first.
+	ms.Add <- &sct{name: "first"}
+	ms.Add <- NewIdentifiedTask(&sct{name: "x1"}, "x")
+	ms.Cancel("x")
+	ms.Add <- NewIdentifiedTask(&sct{name: "x2"}, "x")
+	for _, e := range []string{"first", "x2"} {
+		if c := <-ms.Source; c.String() != e {
+			t.Errorf("didn't get %v: %v", e, c)
+		}
+	}
+	select {
+	case c := <-ms.Source:
+		t.Errorf("got cancelled task: %v", c)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// ft is a helper task that runs a function.
+type ft struct {
+	f func(context.Context)
+}
+
+func (t *ft) String() string          { return "ft" }
+func (t *ft) Run(ctx context.Context) { t.f(ctx) }
+
+// kit is a helper task that has an ID and a key.
+type kit struct {
+	sct
+	id, key string
+}
+
+func (t *kit) ID() string  { return t.id }
+func (t *kit) Key() string { return t.key }
+
+func TestManagedSourceCancelAffinity(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms := NewManagedSource(NewFIFOQueue("test"), false, nil, ctx)
+
+	// The keys survive being made cancellable.
+	ms.Add <- &kit{sct: sct{name: "a", w: &bytes.Buffer{}}, id: "a", key: "k"}
+	got := <-ms.Source
+	if k, ok := got.(KeyedTask); !ok || k.Key() != "k" {
+		t.Fatalf("task from the managed source lost its key: %T", got)
+	}
+	if i, ok := got.(IdentifiedTask); !ok || i.ID() != "a" {
+		t.Fatalf("task from the managed source lost its ID: %T", got)
+	}
+	got.Run(ctx)
+
+	// An AffinityPool runs the tasks for each key in order.
+	var l sync.Mutex
+	seen := map[string][]int{}
+	keys := []string{"a", "b", "c"}
+	for x := 0; x < 300; x++ {
+		key, i := keys[x%len(keys)], x
+		ms.Add <- &kit{
+			sct: sct{name: strconv.Itoa(x), w: funcWriter(func() {
+				l.Lock()
+				defer l.Unlock()
+				seen[key] = append(seen[key], i)
+			})},
+			id:  "id" + strconv.Itoa(x%10),
+			key: key,
+		}
+	}
+	// The tasks with this ID are dropped whatever their key.
+	ms.Cancel("id0")
+	pool := NewAffinityPool("test-pool", 4, false, ctx, ms.Source)
+	for {
+		l.Lock()
+		n := len(seen["a"]) + len(seen["b"]) + len(seen["c"])
+		l.Unlock()
+		if n == 270 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	pool.Wait()
+	for _, key := range keys {
+		for x := 1; x < len(seen[key]); x++ {
+			if seen[key][x] < seen[key][x-1] {
+				t.Errorf("key %v: tasks out of order: %v", key, seen[key])
+				break
+			}
+		}
+		for _, i := range seen[key] {
+			if i%10 == 0 {
+				t.Errorf("key %v: ran cancelled task %v", key, i)
+			}
+		}
+	}
+}
+
+// funcWriter is a helper io.Writer that calls f for each write.
+type funcWriter func()
+
+func (f funcWriter) Write(p []byte) (int, error) {
+	f()
+	return len(p), nil
+}
+
+// idCodec is a helper Codec for ipt tasks that records the types it's
+// asked to encode.
+type idCodec struct {
+	w     *bytes.Buffer
+	types []string
+}
+
+func (c *idCodec) Encode(t Task) ([]byte, error) {
+	c.types = append(c.types, fmt.Sprintf("%T", t))
+	i, ok := t.(IdentifiedTask)
+	if !ok {
+		return nil, fmt.Errorf("not identified: %T", t)
+	}
+	return []byte(i.ID() + ":" + t.String()), nil
+}
+
+func (c *idCodec) Decode(b []byte) (Task, error) {
+	parts := strings.SplitN(string(b), ":", 2)
+	return &ipt{sct: sct{name: parts[1], w: c.w}, id: parts[0]}, nil
+}
+
+func TestManagedSourceCancelCodec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopool")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	out := &bytes.Buffer{}
+	c := &idCodec{w: out}
+	q, err := NewDiskQueue("test", filepath.Join(dir, "queue"), c)
+	if err != nil {
+		t.Fatalf("NewDiskQueue() failed: %v", err)
+	}
+	defer q.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ms := NewManagedSource(q, false, nil, ctx)
+	for _, name := range []string{"a", "b", "c"} {
+		ms.Add <- &ipt{sct: sct{name: name, w: out}, id: name}
+	}
+	if !ms.Cancel("b") {
+		t.Errorf("Cancel() of queued task returned false")
+	}
+	for _, e := range []string{"a", "c"} {
+		got := <-ms.Source
+		if i, ok := got.(IdentifiedTask); !ok || i.ID() != e {
+			t.Errorf("didn't get %v: %v", e, got)
+		}
+		got.Run(ctx)
+	}
+	cancel()
+	ms.Wait()
+
+	// The codec only saw the tasks that were added.
+	for _, typ := range c.types {
+		if typ != "*gopool.ipt" {
+			t.Errorf("codec was given a %v", typ)
+		}
+	}
+	if out.String() != "ac" {
+		t.Errorf(`tasks run != "ac": %v`, out.String())
+	}
+	if q.Len() != 0 {
+		t.Errorf("q.Len() != 0: %v", q.Len())
+	}
+
+	// The dropped task was acknowledged, so it isn't replayed.
+	q.Close()
+	q, err = NewDiskQueue("test", filepath.Join(dir, "queue"), c)
+	if err != nil {
+		t.Fatalf("NewDiskQueue() failed: %v", err)
+	}
+	if q.Len() != 0 {
+		t.Errorf("q.Len() != 0 after reopening: %v", q.Len())
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule determines when a recurring task should be run.
+type Schedule interface {
+	// Next returns the first time after t that the task should be run.
+	Next(t time.Time) time.Time
+}
+
+// Every returns a Schedule that runs at fixed intervals of d. Values
+// of d less than a millisecond are rounded up to a millisecond.
+func Every(d time.Duration) Schedule {
+	if d < time.Millisecond {
+		d = time.Millisecond
+	}
+	return every(d)
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cron is a Schedule parsed from a cron expression. Each field is a
+// bit set of the allowed values.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+}
+
+// The bounds of each of the cron fields.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// The descriptors supported by ParseCron and their equivalent
+// expressions.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard five field cron expression (minute,
+// hour, day of month, month and day of week). Each field can be a *,
+// a value, a range (1-5) or a list of them (1,3-5) and each may have
+// a step (*/15). Like most crons, if both the day of month and day of
+// week are restricted, a day matching either will run.
+//
+// The descriptors @yearly, @annually, @monthly, @weekly, @daily,
+// @midnight and @hourly are also supported as is "@every <duration>"
+// which is the same as calling Every with the parsed duration.
+func ParseCron(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron spec '%v': %v", spec, err)
+		}
+		return Every(d), nil
+	}
+	if e, ok := cronDescriptors[spec]; ok {
+		spec = e
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron spec '%v': expected %v fields",
+			spec, len(cronFields))
+	}
+	bits := make([]uint64, len(parts))
+	for x, part := range parts {
+		b, err := parseCronField(part, cronFields[x].min, cronFields[x].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron spec '%v': %v: %v", spec,
+				cronFields[x].name, err)
+		}
+		bits[x] = b
+	}
+	return &cron{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+	}, nil
+}
+
+// parseCronField turns a single field of a cron expression into a
+// bit set.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			s, err := strconv.Atoi(item[i+1:])
+			if err != nil || s < 1 {
+				return 0, fmt.Errorf("invalid step '%v'", item[i+1:])
+			}
+			step = s
+			item = item[:i]
+		}
+		lo, hi := min, max
+		if item != "*" {
+			r := strings.SplitN(item, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(r[0]); err != nil {
+				return 0, fmt.Errorf("invalid value '%v'", r[0])
+			}
+			hi = lo
+			if len(r) == 2 {
+				if hi, err = strconv.Atoi(r[1]); err != nil {
+					return 0, fmt.Errorf("invalid value '%v'", r[1])
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("'%v' out of range %v-%v", item, min, max)
+		}
+		for x := lo; x <= hi; x += step {
+			bits |= 1 << uint(x)
+		}
+	}
+	return bits, nil
+}
+
+// all returns true if every value of the field is allowed.
+func (c *cron) all(field uint64, min, max int) bool {
+	for x := min; x <= max; x++ {
+		if field&(1<<uint(x)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// dayMatches returns true if the day of the given time is allowed.
+func (c *cron) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.all(c.dom, 1, 31) || c.all(c.dow, 0, 6) {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// Next implements Schedule.Next. It returns the zero time if no time
+// within the next five years matches.
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second -
+		time.Duration(t.Nanosecond()))
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
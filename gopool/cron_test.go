@@ -0,0 +1,54 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	start := time.Date(2015, time.June, 10, 13, 21, 45, 0, time.UTC)
+	tests := []struct {
+		spec string
+		err  bool
+		exp  time.Time
+	}{
+		{"* * * * *", false, time.Date(2015, time.June, 10, 13, 22, 0, 0, time.UTC)},
+		{"*/15 * * * *", false, time.Date(2015, time.June, 10, 13, 30, 0, 0, time.UTC)},
+		{"5 * * * *", false, time.Date(2015, time.June, 10, 14, 5, 0, 0, time.UTC)},
+		{"0 9-17 * * *", false, time.Date(2015, time.June, 10, 14, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", false, time.Date(2015, time.June, 15, 0, 0, 0, 0, time.UTC)},
+		{"30 8 * * 1", false, time.Date(2015, time.June, 15, 8, 30, 0, 0, time.UTC)},
+		{"0 0 1 * 5", false, time.Date(2015, time.June, 12, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", false, time.Date(2016, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", false, time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", false, time.Date(2015, time.June, 10, 14, 0, 0, 0, time.UTC)},
+		{"@every 90s", false, time.Date(2015, time.June, 10, 13, 23, 15, 0, time.UTC)},
+		{"0 0 31 2 *", false, time.Time{}},
+		{"@every nope", true, time.Time{}},
+		{"* * * *", true, time.Time{}},
+		{"60 * * * *", true, time.Time{}},
+		{"a * * * *", true, time.Time{}},
+		{"1-a * * * *", true, time.Time{}},
+		{"*/0 * * * *", true, time.Time{}},
+		{"5-1 * * * *", true, time.Time{}},
+	}
+	for k, test := range tests {
+		s, err := ParseCron(test.spec)
+		if (err != nil) != test.err {
+			t.Errorf("Test %v (%v): unexpected error result: %v", k, test.spec, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if n := s.Next(start); !n.Equal(test.exp) {
+			t.Errorf("Test %v (%v): Next() = %v, expected %v", k, test.spec, n, test.exp)
+		}
+	}
+}
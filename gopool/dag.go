@@ -0,0 +1,291 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+var (
+	// ErrDuplicateTask is returned when a task is added to a DAGSource
+	// with an ID that is already used.
+	ErrDuplicateTask = errors.New("duplicate task id")
+
+	// ErrUnknownDependency is returned when a task is added to a
+	// DAGSource with a dependency that hasn't been added yet.
+	ErrUnknownDependency = errors.New("unknown dependency")
+
+	// ErrDependencyFailed is the error given to tasks in a DAGSource
+	// that weren't run because a dependency failed.
+	ErrDependencyFailed = errors.New("dependency failed")
+)
+
+// DAGPolicy determines what happens to the descendants of a task in
+// a DAGSource that fails.
+type DAGPolicy int
+
+const (
+	// DAGSkip marks the descendants of a failed task as skipped.
+	DAGSkip DAGPolicy = iota
+
+	// DAGFail marks the descendants of a failed task as failed with
+	// ErrDependencyFailed.
+	DAGFail
+)
+
+// DAGState is the state of a task in a DAGSource.
+type DAGState int
+
+// The states a task in a DAGSource can be in.
+const (
+	DAGWaiting DAGState = iota
+	DAGReady
+	DAGRunning
+	DAGSucceeded
+	DAGFailed
+	DAGSkipped
+)
+
+var dagStateNames = []string{"waiting", "ready", "running", "succeeded",
+	"failed", "skipped"}
+
+func (s DAGState) String() string {
+	if int(s) < len(dagStateNames) {
+		return dagStateNames[s]
+	}
+	return fmt.Sprintf("DAGState(%d)", int(s))
+}
+
+// finished returns true if the task won't be run (again).
+func (s DAGState) finished() bool {
+	return s == DAGSucceeded || s == DAGFailed || s == DAGSkipped
+}
+
+// DAGSource is an implementation of a Sourcer where tasks can depend
+// on other tasks. A task is only returned by Next once all of its
+// dependencies have run successfully. Tasks that are ready are done in
+// the order they became ready.
+//
+// Since tasks become ready as other tasks finish, the channel returned
+// by Wakeup() should be given to the ManagedSource using the
+// DAGSource.
+type DAGSource struct {
+	name   string
+	policy DAGPolicy
+	wakeup chan struct{}
+
+	mu    sync.Mutex
+	cond  *sync.Cond
+	tasks map[string]*dagTask
+	ready []*dagTask
+	left  int
+}
+
+// dagTask is a task in a DAGSource.
+type dagTask struct {
+	d        *DAGSource
+	id       string
+	t        ErrorTask
+	state    DAGState
+	err      error
+	waiting  int
+	children []*dagTask
+}
+
+func (t *dagTask) String() string { return t.t.String() }
+func (t *dagTask) Run(ctx context.Context) {
+	t.d.finish(t, runErrorTask(ctx, t.t))
+}
+
+// NewDAGSource creates a new DAGSource that uses the given policy when
+// a task fails.
+func NewDAGSource(name string, policy DAGPolicy) *DAGSource {
+	d := &DAGSource{
+		name:   name,
+		policy: policy,
+		wakeup: make(chan struct{}, 1),
+		tasks:  map[string]*dagTask{},
+	}
+	d.cond = sync.NewCond(&d.mu)
+	return d
+}
+
+func (d *DAGSource) String() string {
+	return d.name
+}
+
+// Wakeup returns the channel that is signaled when tasks become
+// ready. It should be given to NewManagedSource.
+func (d *DAGSource) Wakeup() chan struct{} {
+	return d.wakeup
+}
+
+// AddTask adds a task with the given ID that depends on the tasks with
+// the given IDs. The dependencies must already have been added, which
+// guarantees there are no cycles. If a dependency has already failed,
+// the task is skipped or failed right away according to the policy.
+func (d *DAGSource) AddTask(id string, t ErrorTask, deps ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.tasks[id]; ok {
+		return ErrDuplicateTask
+	}
+	for _, dep := range deps {
+		if _, ok := d.tasks[dep]; !ok {
+			return ErrUnknownDependency
+		}
+	}
+	dt := &dagTask{d: d, id: id, t: t}
+	d.tasks[id] = dt
+	d.left++
+	failed := false
+	for _, dep := range deps {
+		p := d.tasks[dep]
+		switch p.state {
+		case DAGSucceeded:
+		case DAGFailed, DAGSkipped:
+			failed = true
+		default:
+			p.children = append(p.children, dt)
+			dt.waiting++
+		}
+	}
+	if failed {
+		d.propagate(dt)
+	} else if dt.waiting == 0 {
+		d.makeReady(dt)
+	}
+	return nil
+}
+
+// Next implements Sourcer.Next.
+func (d *DAGSource) Next() Task {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.ready) < 1 {
+		return nil
+	}
+	t := d.ready[0]
+	d.ready[0] = nil
+	d.ready = d.ready[1:]
+	t.state = DAGRunning
+	return t
+}
+
+// Add implements Sourcer.Add. Tasks that weren't taken from this
+// DAGSource are added with no dependencies using their String() as
+// their ID. If that ID is already used, the task is dropped.
+func (d *DAGSource) Add(t Task) {
+	if dt, ok := t.(*dagTask); ok && dt.d == d {
+		// It's being put back (e.g. the ManagedSource is stopping).
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		dt.state = DAGReady
+		d.ready = append([]*dagTask{dt}, d.ready...)
+		return
+	}
+	d.AddTask(t.String(), &taskAdapter{t})
+}
+
+// Status returns the state of the task with the given ID and the
+// error it failed with, if any. It returns ErrUnknownDependency if
+// there is no such task.
+func (d *DAGSource) Status(id string) (DAGState, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t, ok := d.tasks[id]
+	if !ok {
+		return 0, ErrUnknownDependency
+	}
+	return t.state, t.err
+}
+
+// Wait blocks until all of the tasks that have been added have
+// succeeded, failed or been skipped.
+func (d *DAGSource) Wait() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for d.left > 0 {
+		d.cond.Wait()
+	}
+}
+
+// finish records the result of running a task and updates its
+// children.
+func (d *DAGSource) finish(t *dagTask, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil {
+		t.state = DAGFailed
+		t.err = err
+		d.done()
+		for _, c := range t.children {
+			d.propagate(c)
+		}
+		return
+	}
+	t.state = DAGSucceeded
+	d.done()
+	for _, c := range t.children {
+		c.waiting--
+		if c.waiting == 0 && c.state == DAGWaiting {
+			d.makeReady(c)
+		}
+	}
+}
+
+// propagate marks the task and its descendants as skipped or failed.
+// The caller must hold d.mu.
+func (d *DAGSource) propagate(t *dagTask) {
+	if t.state.finished() {
+		return
+	}
+	if d.policy == DAGFail {
+		t.state = DAGFailed
+		t.err = ErrDependencyFailed
+	} else {
+		t.state = DAGSkipped
+	}
+	d.done()
+	for _, c := range t.children {
+		d.propagate(c)
+	}
+}
+
+// makeReady queues a task and signals the wakeup channel so a
+// ManagedSource that found no tasks looks again. The caller must hold
+// d.mu.
+func (d *DAGSource) makeReady(t *dagTask) {
+	t.state = DAGReady
+	d.ready = append(d.ready, t)
+	select {
+	case d.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// done records that a task has finished. The caller must hold d.mu.
+func (d *DAGSource) done() {
+	d.left--
+	if d.left == 0 {
+		d.cond.Broadcast()
+	}
+}
+
+// taskAdapter turns a Task into an ErrorTask that never fails.
+type taskAdapter struct {
+	Task
+}
+
+func (t *taskAdapter) Run(ctx context.Context) error {
+	t.Task.Run(ctx)
+	return nil
+}
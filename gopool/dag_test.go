@@ -0,0 +1,159 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestDAGSource(t *testing.T) {
+	tests := []struct {
+		policy DAGPolicy
+		state  DAGState
+		err    error
+	}{
+		{DAGSkip, DAGSkipped, nil},
+		{DAGFail, DAGFailed, ErrDependencyFailed},
+	}
+	for k, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		d := NewDAGSource("test", test.policy)
+		if d.String() != "test" {
+			t.Errorf(`d.String() != "test": %v`, d.String())
+		}
+		ms := NewManagedSource(d, false, d.Wakeup(), ctx)
+		New("test-pool", 4, false, ctx, ms.Source)
+
+		var l sync.Mutex
+		order := map[string]int{}
+		n := 0
+		mk := func(id string, fail bool) ErrorTask {
+			return &dt{id: id, fail: fail, f: func(id string) {
+				l.Lock()
+				defer l.Unlock()
+				order[id] = n
+				n++
+			}}
+		}
+
+		// a -> (b, c) -> d and e (fails) -> f -> g.
+		adds := []struct {
+			id   string
+			fail bool
+			deps []string
+			err  error
+		}{
+			{"a", false, nil, nil},
+			{"b", false, []string{"a"}, nil},
+			{"c", false, []string{"a"}, nil},
+			{"d", false, []string{"b", "c"}, nil},
+			{"e", true, nil, nil},
+			{"f", false, []string{"e", "a"}, nil},
+			{"g", false, []string{"f"}, nil},
+			{"a", false, nil, ErrDuplicateTask},
+			{"h", false, []string{"z"}, ErrUnknownDependency},
+		}
+		for _, add := range adds {
+			if err := d.AddTask(add.id, mk(add.id, add.fail), add.deps...); err != add.err {
+				t.Errorf("Test %v: AddTask(%v) = %v, expected %v", k, add.id, err, add.err)
+			}
+		}
+		d.Wait()
+
+		// Adding a task depending on a failed one finishes right away.
+		if err := d.AddTask("i", mk("i", false), "e"); err != nil {
+			t.Errorf("Test %v: AddTask(i) failed: %v", k, err)
+		}
+		d.Wait()
+		cancel()
+
+		l.Lock()
+		if order["a"] > order["b"] || order["a"] > order["c"] ||
+			order["b"] > order["d"] || order["c"] > order["d"] {
+			t.Errorf("Test %v: tasks run out of order: %v", k, order)
+		}
+		l.Unlock()
+		for _, id := range []string{"a", "b", "c", "d"} {
+			if s, err := d.Status(id); s != DAGSucceeded || err != nil {
+				t.Errorf("Test %v: Status(%v) = %v, %v", k, id, s, err)
+			}
+		}
+		if s, err := d.Status("e"); s != DAGFailed || err == nil {
+			t.Errorf("Test %v: Status(e) = %v, %v", k, s, err)
+		}
+		for _, id := range []string{"f", "g", "i"} {
+			if s, err := d.Status(id); s != test.state || err != test.err {
+				t.Errorf("Test %v: Status(%v) = %v, %v", k, id, s, err)
+			}
+		}
+		if _, err := d.Status("z"); err != ErrUnknownDependency {
+			t.Errorf("Test %v: Status(z) didn't fail: %v", k, err)
+		}
+	}
+}
+
+func TestDAGSourceAdd(t *testing.T) {
+	d := NewDAGSource("test", DAGSkip)
+	d.Add(&sct{name: "a"})
+	a := d.Next()
+	if a == nil || a.String() != "a" {
+		t.Fatalf("didn't get added task: %v", a)
+	}
+	if s, _ := d.Status("a"); s != DAGRunning {
+		t.Errorf("Status(a) != running: %v", s)
+	}
+	d.Add(a)
+	if s, _ := d.Status("a"); s != DAGReady {
+		t.Errorf("Status(a) != ready after putting back: %v", s)
+	}
+	if DAGState(42).String() != "DAGState(42)" {
+		t.Errorf("unexpected string for unknown state: %v", DAGState(42))
+	}
+}
+
+func TestDAGSourceAddIdle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := NewDAGSource("test", DAGSkip)
+	ms := NewManagedSource(d, false, d.Wakeup(), ctx)
+	p := New("test-pool", 1, false, ctx, ms.Source)
+	if err := p.WaitIdle(ctx, ms); err != nil {
+		t.Fatalf("WaitIdle() failed: %v", err)
+	}
+
+	// The managed source has found no tasks, so it only looks again
+	// when it's woken up.
+	ran := make(chan string, 1)
+	d.AddTask("a", &dt{id: "a", f: func(id string) { ran <- id }})
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatalf("task added to an idle source never ran")
+	}
+	d.Wait()
+}
+
+// dt is a helper ErrorTask for DAGs.
+type dt struct {
+	id   string
+	fail bool
+	f    func(string)
+}
+
+func (t *dt) String() string { return t.id }
+func (t *dt) Run(ctx context.Context) error {
+	t.f(t.id)
+	if t.fail {
+		return errors.New(t.id)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// IdentifiedTask is a Task that has an ID. Tasks with the same ID are
+// considered to be duplicates of each other.
+type IdentifiedTask interface {
+	Task
+	ID() string
+}
+
+// NewIdentifiedTask returns an IdentifiedTask with the given task
+// and ID.
+func NewIdentifiedTask(t Task, id string) IdentifiedTask {
+	return &it{
+		id: id,
+		t:  t,
+	}
+}
+
+// it is an internal implementation of the IdentifiedTask.
+type it struct {
+	id string
+	t  Task
+}
+
+func (t *it) String() string        { return t.t.String() }
+func (t *it) ID() string            { return t.id }
+func (t *it) Run(c context.Context) { t.t.Run(c) }
+
+// DedupSource wraps a Sourcer and drops tasks that are duplicates of
+// tasks already in the Sourcer. Optionally, tasks that completed
+// within a TTL are also considered duplicates. Tasks that aren't
+// IdentifiedTasks are never considered duplicates.
+//
+// A task stops being queued once it starts running, so a duplicate
+// added while a task is running will be run again. This is usually
+// what you want when tasks are triggered by changes (e.g. from an
+// etcdutil.Watch) and the running task may have missed the latest
+// change.
+type DedupSource struct {
+	s        Sourcer
+	ttl      time.Duration
+	coalesce bool
+
+	mu        sync.Mutex
+	queued    map[string]*dedupTask
+	completed map[string]time.Time
+}
+
+// dedupTask wraps the tasks in a DedupSource so we know when they
+// start and finish. It's forwarded so it keeps the ID, Key and
+// Priority of the first task added with its ID.
+type dedupTask struct {
+	d  *DedupSource
+	id string
+	t  Task
+}
+
+func (t *dedupTask) String() string { return t.task().String() }
+
+func (t *dedupTask) Run(ctx context.Context) {
+	t.d.mu.Lock()
+	if t.d.queued[t.id] == t {
+		delete(t.d.queued, t.id)
+	}
+	task := t.t
+	t.d.mu.Unlock()
+
+	task.Run(ctx)
+
+	if t.d.ttl > 0 {
+		t.d.mu.Lock()
+		t.d.completed[t.id] = time.Now()
+		t.d.mu.Unlock()
+	}
+}
+
+// task returns the wrapped task, which may be changed by coalescing.
+func (t *dedupTask) task() Task {
+	t.d.mu.Lock()
+	defer t.d.mu.Unlock()
+	return t.t
+}
+
+// discard stops the task being queued when it's dropped instead of
+// run.
+func (t *dedupTask) discard() {
+	t.d.mu.Lock()
+	defer t.d.mu.Unlock()
+	if t.d.queued[t.id] == t {
+		delete(t.d.queued, t.id)
+	}
+}
+
+// NewDedupSource wraps the given Sourcer. If ttl is greater than zero,
+// tasks that completed within that time are also dropped. If coalesce
+// is true, a duplicate task replaces the queued one instead of being
+// dropped. This is useful if the newer task has more recent
+// information. The queued task keeps its place in the Sourcer.
+func NewDedupSource(s Sourcer, ttl time.Duration, coalesce bool) *DedupSource {
+	return &DedupSource{
+		s:         s,
+		ttl:       ttl,
+		coalesce:  coalesce,
+		queued:    map[string]*dedupTask{},
+		completed: map[string]time.Time{},
+	}
+}
+
+// String implements the fmt.Stringer interface. It uses the String()
+// of the wrapped Sourcer.
+func (d *DedupSource) String() string {
+	return d.s.String()
+}
+
+// Next implements Sourcer.Next.
+func (d *DedupSource) Next() Task {
+	return d.s.Next()
+}
+
+// Add implements Sourcer.Add. Duplicates are dropped or coalesced.
+func (d *DedupSource) Add(t Task) {
+	d.add(t)
+}
+
+// add does the work for Add. It returns the task that was dropped or
+// replaced by coalescing, if any, so a ManagedSource can stop counting
+// it.
+func (d *DedupSource) add(t Task) Task {
+	if dt, ok := unwrap(t).(*dedupTask); ok && dt.d == d {
+		// It's being put back (e.g. the ManagedSource is stopping).
+		d.s.Add(t)
+		return nil
+	}
+	i, ok := t.(IdentifiedTask)
+	if !ok {
+		d.s.Add(t)
+		return nil
+	}
+	id := i.ID()
+
+	d.mu.Lock()
+	now := time.Now()
+	for k, c := range d.completed {
+		if now.Sub(c) >= d.ttl {
+			delete(d.completed, k)
+		}
+	}
+	if _, ok := d.completed[id]; ok {
+		d.mu.Unlock()
+		return t
+	}
+	if q, ok := d.queued[id]; ok {
+		if d.coalesce {
+			q.t, t = t, q.t
+		}
+		d.mu.Unlock()
+		return t
+	}
+	dt := &dedupTask{d: d, id: id, t: t}
+	d.queued[id] = dt
+	d.mu.Unlock()
+
+	d.s.Add(forward(dt, t))
+	return nil
+}
+
+// Queued returns true if a task with the given ID is waiting to be
+// run.
+func (d *DedupSource) Queued(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.queued[id]
+	return ok
+}
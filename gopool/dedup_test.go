@@ -0,0 +1,103 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestDedupSource(t *testing.T) {
+	tests := []struct {
+		ttl      time.Duration
+		coalesce bool
+		exp      string
+	}{
+		{0, false, "a1b1cca2"},
+		{0, true, "a3b2cca2"},
+		{time.Hour, false, "a1b1cc"},
+	}
+	for k, test := range tests {
+		buf := &bytes.Buffer{}
+		d := NewDedupSource(NewFIFOQueue("test"), test.ttl, test.coalesce)
+		if d.String() != "test" {
+			t.Errorf(`Test %v: d.String() != "test": %v`, k, d.String())
+		}
+		d.Add(NewIdentifiedTask(&sct{name: "a1", w: buf}, "a"))
+		d.Add(&ipt{sct: sct{name: "b1", w: buf}, id: "b", p: -1})
+		d.Add(NewIdentifiedTask(&sct{name: "a2", w: buf}, "a"))
+		d.Add(NewIdentifiedTask(&sct{name: "b2", w: buf}, "b"))
+		d.Add(NewIdentifiedTask(&sct{name: "a3", w: buf}, "a"))
+		d.Add(&sct{name: "c", w: buf})
+		d.Add(&sct{name: "c", w: buf})
+		if !d.Queued("a") || d.Queued("z") {
+			t.Errorf("Test %v: Queued() returned the wrong value", k)
+		}
+
+		// Run a and b checking their IDs and priorities.
+		a := d.Next()
+		if _, ok := a.(PriorityTask); ok {
+			t.Errorf("Test %v: a has a priority", k)
+		}
+		if i, ok := a.(IdentifiedTask); !ok || i.ID() != "a" {
+			t.Errorf("Test %v: a lost its ID", k)
+		}
+		a.Run(nil)
+		if d.Queued("a") {
+			t.Errorf("Test %v: a still queued after running", k)
+		}
+		b := d.Next()
+		if p := b.(PriorityTask).Priority(); p != -1 && !test.coalesce {
+			t.Errorf("Test %v: b.Priority() != -1: %v", k, p)
+		}
+		b.Run(nil)
+
+		// Run the rest and then try adding a again.
+		for c := d.Next(); c != nil; c = d.Next() {
+			c.Run(nil)
+		}
+		d.Add(NewIdentifiedTask(&sct{name: "a2", w: buf}, "a"))
+		for c := d.Next(); c != nil; c = d.Next() {
+			c.Run(nil)
+		}
+		if buf.String() != test.exp {
+			t.Errorf("Test %v: expected %v but got %v", k, test.exp, buf.String())
+		}
+	}
+}
+
+// ipt is a helper task that has both an ID and a priority.
+type ipt struct {
+	sct
+	id string
+	p  int
+}
+
+func (t *ipt) ID() string    { return t.id }
+func (t *ipt) Priority() int { return t.p }
+
+func TestDedupSourceBounded(t *testing.T) {
+	// The duplicates don't take up room in a bounded managed source.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, coalesce := range []bool{false, true} {
+		d := NewDedupSource(NewFIFOQueue("test"), 0, coalesce)
+		ms := NewBoundedManagedSource(d, 2, false, nil, ctx)
+		for x := 0; x < 10; x++ {
+			if err := ms.TryAdd(NewIdentifiedTask(&sct{name: "a"}, "a")); err != nil {
+				t.Fatalf("coalesce %v: TryAdd() of duplicate %v: %v", coalesce, x, err)
+			}
+			ms.state(ctx)
+		}
+		if l := ms.Len(); l != 1 {
+			t.Errorf("coalesce %v: ms.Len() != 1: %v", coalesce, l)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// Codec converts tasks to and from bytes so they can be stored by a
+// DiskQueue.
+type Codec interface {
+	// Encode returns the serialized form of the task.
+	Encode(t Task) ([]byte, error)
+
+	// Decode returns the task for the serialized form returned by
+	// Encode.
+	Decode(b []byte) (Task, error)
+}
+
+// The types of records in a DiskQueue log.
+const (
+	recordAdd byte = iota + 1
+	recordAck
+)
+
+// The size of a record header: type (1), id (8) and length (4).
+const recordHeaderSize = 13
+
+// DiskQueue is an implementation of a Sourcer that persists its tasks
+// to an append-only log file so queued work survives a restart. Tasks
+// are done in the order they are added.
+//
+// Delivery is at-least-once. A task is only removed from the log once
+// it has finished running, so tasks that were running when the process
+// stopped are run again when the queue is reopened.
+type DiskQueue struct {
+	name  string
+	path  string
+	codec Codec
+	opts  options
+
+	mu       sync.Mutex
+	f        *os.File
+	nextID   uint64
+	queue    []*diskTask
+	inflight map[uint64]*diskTask
+}
+
+// diskTask is a task that was stored in a DiskQueue. It acknowledges
+// the task once it has run.
+type diskTask struct {
+	q    *DiskQueue
+	id   uint64
+	data []byte
+	t    Task
+}
+
+func (t *diskTask) String() string { return t.t.String() }
+func (t *diskTask) Run(ctx context.Context) {
+	t.t.Run(ctx)
+	t.q.ack(t)
+}
+
+func (t *diskTask) task() Task { return t.t }
+func (t *diskTask) discard()   { t.q.ack(t) }
+
+// NewDiskQueue opens or creates the log file at the given path and
+// uses the codec to serialize tasks. Any tasks in the log that were
+// not acknowledged are queued again. Errors are logged to the default
+// logger unless WithLogger is given.
+func NewDiskQueue(name, path string, codec Codec,
+	opts ...Option) (*DiskQueue, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	q := &DiskQueue{
+		name:     name,
+		path:     path,
+		codec:    codec,
+		opts:     newOptions(false, opts),
+		f:        f,
+		inflight: map[uint64]*diskTask{},
+	}
+	if err := q.replay(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *DiskQueue) String() string {
+	return q.name
+}
+
+// Len returns the number of tasks waiting in the queue. It does not
+// include tasks that are currently running.
+func (q *DiskQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue)
+}
+
+// Next implements Sourcer.Next.
+func (q *DiskQueue) Next() Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.queue) < 1 {
+		return nil
+	}
+	t := q.queue[0]
+	q.queue[0] = nil
+	q.queue = q.queue[1:]
+	q.inflight[t.id] = t
+	return forward(t, t.t)
+}
+
+// Add implements Sourcer.Add. The task is written to the log before
+// it is queued. If it can't be encoded or written, it is still queued
+// but won't survive a restart and the error is logged.
+func (q *DiskQueue) Add(t Task) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Tasks from this queue are being put back (e.g. the
+	// ManagedSource is stopping), so they are already in the log.
+	if dt, ok := unwrap(t).(*diskTask); ok && dt.q == q {
+		delete(q.inflight, dt.id)
+		q.queue = append([]*diskTask{dt}, q.queue...)
+		return
+	}
+
+	dt := &diskTask{q: q, id: q.nextID, t: t}
+	q.nextID++
+	data, err := q.codec.Encode(t)
+	if err != nil {
+		q.opts.printf("[diskqueue %v] encoding task %v: %v", q, t, err)
+	} else if err := q.write(recordAdd, dt.id, data); err != nil {
+		q.opts.printf("[diskqueue %v] writing task %v: %v", q, t, err)
+	} else {
+		dt.data = data
+	}
+	q.queue = append(q.queue, dt)
+}
+
+// Compact rewrites the log so that it only contains the tasks that
+// are queued or running.
+func (q *DiskQueue) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.compact()
+}
+
+// Close compacts and closes the log file. The queue shouldn't be used
+// after it is closed.
+func (q *DiskQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	err := q.compact()
+	if cerr := q.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ack removes the task from the log once it has run.
+func (q *DiskQueue) ack(t *diskTask) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inflight, t.id)
+	if t.data == nil {
+		return
+	}
+	var err error
+	if len(q.queue) == 0 && len(q.inflight) == 0 {
+		// Nothing is left, so we can just start over.
+		err = q.compact()
+	} else {
+		err = q.write(recordAck, t.id, nil)
+	}
+	if err != nil {
+		q.opts.printf("[diskqueue %v] acknowledging task %v: %v", q, t, err)
+	}
+}
+
+// write appends a record to the log. The caller must hold q.mu.
+func (q *DiskQueue) write(typ byte, id uint64, data []byte) error {
+	_, err := q.f.Write(encodeRecord(typ, id, data))
+	return err
+}
+
+// compact does the work for Compact. The new log is written to a
+// temporary file that replaces the old one once it's synced, so a
+// crash while compacting doesn't lose the queue. The caller must hold
+// q.mu.
+func (q *DiskQueue) compact() error {
+	pending := make([]*diskTask, 0, len(q.inflight)+len(q.queue))
+	for _, t := range q.inflight {
+		pending = append(pending, t)
+	}
+	sort.Sort(byID(pending))
+	pending = append(pending, q.queue...)
+
+	buf := []byte{}
+	for _, t := range pending {
+		if t.data != nil {
+			buf = append(buf, encodeRecord(recordAdd, t.id, t.data)...)
+		}
+	}
+	tmp := q.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, q.path)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	q.f.Close()
+	q.f = f
+	return nil
+}
+
+// replay reads the log and queues the tasks that weren't
+// acknowledged. A torn record at the end of the log (e.g. from a crash
+// during a write) is discarded.
+func (q *DiskQueue) replay() error {
+	tasks := map[uint64][]byte{}
+	order := []uint64{}
+	r := bufio.NewReader(q.f)
+	var offset int64
+	for {
+		typ, id, data, err := decodeRecord(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			q.opts.printf("[diskqueue %v] discarding log after offset %v: %v", q,
+				offset, err)
+			break
+		}
+		offset += int64(recordHeaderSize + len(data) + 4)
+		switch typ {
+		case recordAdd:
+			tasks[id] = data
+			order = append(order, id)
+		case recordAck:
+			delete(tasks, id)
+		}
+		if id >= q.nextID {
+			q.nextID = id + 1
+		}
+	}
+	for _, id := range order {
+		data, ok := tasks[id]
+		if !ok {
+			continue
+		}
+		t, err := q.codec.Decode(data)
+		if err != nil {
+			q.opts.printf("[diskqueue %v] decoding task %v: %v", q, id, err)
+			continue
+		}
+		q.queue = append(q.queue, &diskTask{q: q, id: id, data: data, t: t})
+	}
+	return q.compact()
+}
+
+// encodeRecord returns the bytes for a record: the header followed by
+// the data and a CRC32 of both.
+func encodeRecord(typ byte, id uint64, data []byte) []byte {
+	b := make([]byte, recordHeaderSize+len(data)+4)
+	b[0] = typ
+	binary.BigEndian.PutUint64(b[1:], id)
+	binary.BigEndian.PutUint32(b[9:], uint32(len(data)))
+	copy(b[recordHeaderSize:], data)
+	n := recordHeaderSize + len(data)
+	binary.BigEndian.PutUint32(b[n:], crc32.ChecksumIEEE(b[:n]))
+	return b
+}
+
+// errCorrupt is used when a record is incomplete or its checksum
+// doesn't match.
+var errCorrupt = errors.New("corrupt record")
+
+// The largest record we'll try to read. Anything larger is assumed to
+// be a corrupt length.
+const maxRecordSize = 1 << 28
+
+// decodeRecord reads the next record from r. It returns io.EOF if
+// there are no more records.
+func decodeRecord(r io.Reader) (byte, uint64, []byte, error) {
+	h := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, h); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errCorrupt
+		}
+		return 0, 0, nil, err
+	}
+	l := binary.BigEndian.Uint32(h[9:])
+	if l > maxRecordSize {
+		return 0, 0, nil, errCorrupt
+	}
+	rest := make([]byte, l+4)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return 0, 0, nil, errCorrupt
+	}
+	n := len(rest) - 4
+	crc := crc32.ChecksumIEEE(h)
+	crc = crc32.Update(crc, crc32.IEEETable, rest[:n])
+	if crc != binary.BigEndian.Uint32(rest[n:]) {
+		return 0, 0, nil, errCorrupt
+	}
+	return h[0], binary.BigEndian.Uint64(h[1:]), rest[:n], nil
+}
+
+// byID sorts disk tasks by their ID, which is the order they were
+// added.
+type byID []*diskTask
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Less(i, j int) bool { return s[i].id < s[j].id }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,148 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestDiskQueue(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	dir, err := ioutil.TempDir("", "gopool")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "queue")
+	out := &bytes.Buffer{}
+	c := &sctCodec{w: out}
+
+	q, err := NewDiskQueue("test", path, c)
+	if err != nil {
+		t.Fatalf("NewDiskQueue() failed: %v", err)
+	}
+	if q.String() != "test" {
+		t.Errorf(`q.String() != "test": %v`, q.String())
+	}
+	if q.Next() != nil {
+		t.Fatalf("q.Next() != nil on empty queue")
+	}
+	for _, name := range []string{"a", "b", "c", "d", "bad"} {
+		q.Add(&sct{name: name, w: out})
+	}
+	if !strings.Contains(buf.String(), "encoding task bad") {
+		t.Errorf("didn't log encoding error: %v", buf.String())
+	}
+
+	// Run a, take b but don't run it and then put c back like a
+	// ManagedSource would.
+	q.Next().Run(context.Background())
+	q.Next()
+	cc := q.Next()
+	q.Add(cc)
+	if q.Len() != 3 {
+		t.Errorf("q.Len() != 3: %v", q.Len())
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	// Append a torn record to make sure it's ignored.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.Write(encodeRecord(recordAdd, 100, []byte("torn"))[:10])
+	f.Close()
+
+	// Reopen and we should get the unfinished tasks in order.
+	q, err = NewDiskQueue("test", path, c)
+	if err != nil {
+		t.Fatalf("NewDiskQueue() failed on reopen: %v", err)
+	}
+	for c := q.Next(); c != nil; c = q.Next() {
+		c.Run(context.Background())
+	}
+	if out.String() != "abcd" {
+		t.Errorf(`tasks run != "abcd": %v`, out.String())
+	}
+
+	// Everything is done, so the log should be empty.
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Errorf("log not empty after running everything: %v %v", fi.Size(), err)
+	}
+	q.Close()
+
+	// A failed compaction leaves the log alone.
+	q, err = NewDiskQueue("test", path, c)
+	if err != nil {
+		t.Fatalf("NewDiskQueue() failed on reopen: %v", err)
+	}
+	q.Add(&sct{name: "e", w: out})
+	os.Mkdir(path+".tmp", 0755)
+	if err := q.Close(); err == nil {
+		t.Errorf("Close() didn't fail to compact")
+	}
+	os.Remove(path + ".tmp")
+	q, err = NewDiskQueue("test", path, c)
+	if err != nil {
+		t.Fatalf("NewDiskQueue() failed on reopen: %v", err)
+	}
+	if q.Len() != 1 {
+		t.Errorf("q.Len() != 1 after failed compaction: %v", q.Len())
+	}
+	q.Close()
+
+	// Bad files.
+	if _, err := NewDiskQueue("test", dir, c); err == nil {
+		t.Errorf("NewDiskQueue() on a directory didn't fail")
+	}
+}
+
+func TestDecodeRecord(t *testing.T) {
+	b := encodeRecord(recordAck, 42, []byte("hello"))
+	typ, id, data, err := decodeRecord(bytes.NewReader(b))
+	if err != nil || typ != recordAck || id != 42 || string(data) != "hello" {
+		t.Errorf("decodeRecord() = %v %v %v %v", typ, id, string(data), err)
+	}
+	b[len(b)-1]++
+	if _, _, _, err := decodeRecord(bytes.NewReader(b)); err != errCorrupt {
+		t.Errorf("decodeRecord() with bad checksum didn't fail: %v", err)
+	}
+	if _, _, _, err := decodeRecord(bytes.NewReader(b[:3])); err != errCorrupt {
+		t.Errorf("decodeRecord() with short header didn't fail: %v", err)
+	}
+	if _, _, _, err := decodeRecord(bytes.NewReader(b[:15])); err != errCorrupt {
+		t.Errorf("decodeRecord() with short data didn't fail: %v", err)
+	}
+}
+
+// sctCodec is a helper Codec for sct tasks.
+type sctCodec struct {
+	w *bytes.Buffer
+}
+
+func (c *sctCodec) Encode(t Task) ([]byte, error) {
+	if t.String() == "bad" {
+		return nil, errors.New("bad")
+	}
+	return []byte(t.String()), nil
+}
+
+func (c *sctCodec) Decode(b []byte) (Task, error) {
+	if string(b) == "undecodable" {
+		return nil, errors.New("undecodable")
+	}
+	return &sct{name: string(b), w: c.w}, nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Logger is where gopools and managed sources log messages. The
+// standard library's *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// EventType is the type of an Event.
+type EventType int
+
+// The types of events sent to a Listener.
+const (
+	// EventTaskStarted is sent when a worker starts a task.
+	EventTaskStarted EventType = iota
+
+	// EventTaskFinished is sent when a worker finishes a task.
+	EventTaskFinished
+
+	// EventTaskFailed is sent when a task panics. The panic is
+	// continued after the event is sent.
+	EventTaskFailed
+
+	// EventWorkerStopped is sent when a worker stops.
+	EventWorkerStopped
+
+	// EventTaskAdded is sent when a managed source adds a task to its
+	// Sourcer.
+	EventTaskAdded
+
+	// EventTaskSent is sent when a managed source or Scheduler sends a
+	// task to a gopool.
+	EventTaskSent
+
+	// EventTaskDropped is sent when a managed source drops a task that
+	// was cancelled.
+	EventTaskDropped
+
+	// EventSourceStopped is sent when a managed source stops.
+	EventSourceStopped
+)
+
+var eventTypeNames = []string{"task started", "task finished", "task failed",
+	"worker stopped", "task added", "task sent", "task dropped",
+	"source stopped"}
+
+func (t EventType) String() string {
+	if int(t) < len(eventTypeNames) {
+		return eventTypeNames[t]
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event describes something that happened in a gopool or managed
+// source.
+type Event struct {
+	Type EventType
+
+	// Name is the name of the gopool or the Sourcer of the managed
+	// source.
+	Name string
+
+	// Worker is the ID of the worker for gopool events.
+	Worker int
+
+	// Task is the task the event is about, if any.
+	Task Task
+
+	// Duration is how long the task ran for EventTaskFinished and
+	// EventTaskFailed.
+	Duration time.Duration
+
+	// Err is the reason for EventTaskFailed and EventWorkerStopped.
+	Err error
+}
+
+// Listener is notified of events in gopools and managed sources.
+// HandleEvent is called synchronously from the worker or managed
+// source goroutine, so it should return quickly.
+type Listener interface {
+	HandleEvent(e Event)
+}
+
+// ListenerFunc is an adapter to allow the use of ordinary functions
+// as Listeners.
+type ListenerFunc func(e Event)
+
+// HandleEvent calls f(e).
+func (f ListenerFunc) HandleEvent(e Event) {
+	f(e)
+}
+
+// options configure the logging of gopools and managed sources.
+type options struct {
+	verbose  bool
+	logger   Logger
+	listener Listener
+}
+
+// Option configures the logging of a gopool, managed source or the
+// other types in this package. It's given to their constructors.
+type Option func(*options)
+
+// WithLogger logs messages to the given Logger instead of the standard
+// logger from the log package.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithListener notifies the given Listener of events.
+func WithListener(l Listener) Option {
+	return func(o *options) {
+		o.listener = l
+	}
+}
+
+// newOptions returns the options for verbose with opts applied.
+func newOptions(verbose bool, opts []Option) options {
+	o := options{verbose: verbose}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// The reasons a worker stopped for EventWorkerStopped.
+var (
+	// ErrShrunk is used when a worker stopped because the pool was
+	// shrunk.
+	ErrShrunk = fmt.Errorf("pool shrunk")
+
+	// ErrSourceClosed is used when a worker stopped because its source
+	// channel was closed.
+	ErrSourceClosed = fmt.Errorf("input source closed")
+)
+
+// printf logs the message.
+func (o options) printf(format string, v ...interface{}) {
+	if o.logger != nil {
+		o.logger.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// verbosef logs the message if verbose is set.
+func (o options) verbosef(format string, v ...interface{}) {
+	if o.verbose {
+		o.printf(format, v...)
+	}
+}
+
+// emit sends the event to the listener if there is one.
+func (o options) emit(e Event) {
+	if o.listener != nil {
+		o.listener.HandleEvent(e)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestOptions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := &el{}
+	opts := []Option{WithLogger(log.New(buf, "", 0)), WithListener(l)}
+	ctx, cancel := context.WithCancel(context.Background())
+	ms := NewManagedSource(NewFIFOQueue("q"), true, nil, ctx, opts...)
+	p := New("p", 1, true, ctx, ms.Source, opts...)
+	ms.Add <- &sct{name: "a", w: &bytes.Buffer{}}
+	if err := p.WaitIdle(context.Background(), ms); err != nil {
+		t.Fatalf("WaitIdle() failed: %v", err)
+	}
+	cancel()
+	p.Wait()
+	ms.Wait()
+
+	for _, e := range []string{"[source q] added task a", "[source q] sent task a",
+		"[gopool p 0] starting task: a", "[gopool p 0] finished task",
+		"[gopool p 0] stop channel closed: stopping", "[source q] stop requested"} {
+		if !strings.Contains(buf.String(), e) {
+			t.Errorf("logger missing %q: %v", e, buf.String())
+		}
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	got := map[EventType]Event{}
+	for _, e := range l.events {
+		got[e.Type] = e
+	}
+	for _, typ := range []EventType{EventTaskAdded, EventTaskSent,
+		EventTaskStarted, EventTaskFinished, EventWorkerStopped,
+		EventSourceStopped} {
+		e, ok := got[typ]
+		if !ok {
+			t.Errorf("missing %v event: %v", typ, l.events)
+			continue
+		}
+		if typ == EventWorkerStopped || typ == EventSourceStopped {
+			if e.Err != context.Canceled {
+				t.Errorf("%v event has wrong error: %v", typ, e.Err)
+			}
+		} else if e.Task.String() != "a" {
+			t.Errorf("%v event has wrong task: %v", typ, e.Task)
+		}
+	}
+	if got[EventTaskStarted].Name != "p" || got[EventTaskAdded].Name != "q" {
+		t.Errorf("events have wrong names: %v", l.events)
+	}
+}
+
+func TestOptionsTaskFailed(t *testing.T) {
+	var failed Event
+	l := ListenerFunc(func(e Event) {
+		if e.Type == EventTaskFailed {
+			failed = e
+		}
+	})
+	p := &GoPool{name: "p", ctx: context.Background(),
+		opts: newOptions(false, []Option{WithListener(l)})}
+	func() {
+		defer func() {
+			if r := recover(); r != "oops" {
+				t.Errorf("panic not continued: %v", r)
+			}
+		}()
+		p.run(0, &ft{f: func(ctx context.Context) { panic("oops") }})
+	}()
+	if pe, ok := failed.Err.(*PanicError); !ok || pe.Value != "oops" {
+		t.Errorf("task failed event has wrong error: %v", failed.Err)
+	}
+	if EventSourceStopped.String() != "source stopped" ||
+		EventType(100).String() != "EventType(100)" {
+		t.Errorf("unexpected EventType.String()")
+	}
+}
+
+func TestOptionsOthers(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := &el{}
+	opts := []Option{WithLogger(log.New(buf, "", 0)), WithListener(l)}
+
+	// An AffinityPool logs and sends events like a GoPool.
+	src := make(chan Task)
+	p := NewAffinityPool("p", 1, true, context.Background(), src, opts...)
+	src <- &sct{name: "a", w: &bytes.Buffer{}}
+	close(src)
+	p.Wait()
+
+	// A Scheduler sends an event for each task it sends.
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan Task)
+	s := NewScheduler("s", true, out, ctx, opts...)
+	s.Add(Every(time.Millisecond), &sct{name: "b"}, CatchUpSkip)
+	<-out
+	cancel()
+	s.Wait()
+
+	// Sourcers log their errors.
+	dir, err := ioutil.TempDir("", "gopool-events")
+	if err != nil {
+		t.Fatalf("TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	q, err := NewDiskQueue("q", filepath.Join(dir, "queue"),
+		&sctCodec{w: &bytes.Buffer{}}, opts...)
+	if err != nil {
+		t.Fatalf("NewDiskQueue() failed: %v", err)
+	}
+	q.Add(&sct{name: "bad"})
+	q.Close()
+
+	for _, e := range []string{"[gopool p 0] starting task: a",
+		"[gopool p 0] finished task", "[gopool p] input source closed",
+		"[scheduler s 0] sent task b", "[diskqueue q] encoding task bad"} {
+		if !strings.Contains(buf.String(), e) {
+			t.Errorf("logger missing %q: %v", e, buf.String())
+		}
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	got := map[EventType]Event{}
+	for _, e := range l.events {
+		if _, ok := got[e.Type]; !ok {
+			got[e.Type] = e
+		}
+	}
+	for typ, name := range map[EventType]string{EventTaskStarted: "p",
+		EventTaskFinished: "p", EventWorkerStopped: "p", EventTaskSent: "s"} {
+		if e, ok := got[typ]; !ok || e.Name != name {
+			t.Errorf("wrong %v event: %v", typ, l.events)
+		}
+	}
+	if got[EventWorkerStopped].Err != ErrSourceClosed {
+		t.Errorf("worker stopped event has wrong error: %v",
+			got[EventWorkerStopped].Err)
+	}
+}
+
+// el is a helper Listener that records the events it gets.
+type el struct {
+	sync.Mutex
+	events []Event
+}
+
+func (l *el) HandleEvent(e Event) {
+	l.Lock()
+	defer l.Unlock()
+	l.events = append(l.events, e)
+}
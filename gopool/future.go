@@ -0,0 +1,78 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// ResultTask is like a Task but its Run method returns a value and
+// an error. It is used with a Future to get the results of some work
+// performed by a gopool.
+type ResultTask interface {
+	fmt.Stringer
+
+	// Run performs the work for this task and returns its result. The
+	// context should be handled the same way as in Task.Run.
+	Run(context.Context) (interface{}, error)
+}
+
+// Future is a Task that wraps a ResultTask. Once a worker has run
+// it, the result is available through Wait() and the Done() channel
+// is closed.
+type Future struct {
+	t     ResultTask
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// NewFuture creates a Future for the given ResultTask. The returned
+// Future can be sent to a gopool like any other Task.
+func NewFuture(t ResultTask) *Future {
+	return &Future{
+		t:    t,
+		done: make(chan struct{}),
+	}
+}
+
+// String implements the fmt.Stringer interface. It uses the String()
+// of the wrapped ResultTask.
+func (f *Future) String() string {
+	return f.t.String()
+}
+
+// Run implements Task.Run. It runs the wrapped ResultTask and stores
+// the results. It should only be called once.
+func (f *Future) Run(ctx context.Context) {
+	f.value, f.err = f.t.Run(ctx)
+	close(f.done)
+}
+
+// Done returns a channel that is closed once the task has been run.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the task has been run and then returns its
+// results. If the task is never run (e.g. the gopool was stopped
+// first), this never returns. Use Done() if you need to select on
+// other things as well.
+func (f *Future) Wait() (interface{}, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// Submit creates a Future for the given ResultTask and adds it to
+// the managed source.
+func (ms *ManagedSource) Submit(t ResultTask) *Future {
+	f := NewFuture(t)
+	ms.Add <- f
+	return f
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestFuture(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms := NewManagedSource(NewPriorityQueue("test"), false, nil, ctx)
+	New("test-pool", 3, false, ctx, ms.Source)
+
+	// Submit a bunch of tasks and make sure we get all the results.
+	fs := []*Future{}
+	for x := 0; x < 10; x++ {
+		fs = append(fs, ms.Submit(&rt{i: x}))
+	}
+	for x, f := range fs {
+		<-f.Done()
+		v, err := f.Wait()
+		if x%3 == 0 {
+			if err == nil || err.Error() != strconv.Itoa(x) {
+				t.Errorf("expected error %v from future but got: %v", x, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error from future %v: %v", x, err)
+		}
+		if v.(int) != x*x {
+			t.Errorf("future %v returned %v, expected %v", x, v, x*x)
+		}
+		if f.String() != strconv.Itoa(x) {
+			t.Errorf("f.String() != %v: %v", x, f.String())
+		}
+	}
+}
+
+// rt is a helper ResultTask that squares its number and fails for
+// multiples of three.
+type rt struct {
+	i int
+}
+
+func (t *rt) String() string { return strconv.Itoa(t.i) }
+func (t *rt) Run(ctx context.Context) (interface{}, error) {
+	if t.i%3 == 0 {
+		return nil, errors.New(strconv.Itoa(t.i))
+	}
+	return t.i * t.i, nil
+}
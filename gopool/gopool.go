@@ -15,7 +15,6 @@ package gopool
 import (
 	"errors"
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
@@ -40,11 +39,29 @@ type Task interface {
 // GoPool is a group of goroutines that work on Tasks. Each goroutine
 // gets work from a channel until the context signals that it's done.
 type GoPool struct {
-	name    string
-	src     <-chan Task
-	wg      sync.WaitGroup
-	ctx     context.Context
-	verbose bool
+	name string
+	src  <-chan Task
+	wg   sync.WaitGroup
+	ctx  context.Context
+	opts options
+
+	// mu protects the fields below which track the running workers so
+	// the pool can be resized and their activity so WaitIdle can tell
+	// when the pool is idle.
+	mu       sync.Mutex
+	workers  []*worker            // The workers that haven't been shrunk.
+	live     map[*worker]struct{} // The workers that haven't stopped.
+	nextID   int
+	received uint64
+	busy     int
+	changed  chan struct{}
+}
+
+// worker holds the channels of a worker goroutine.
+type worker struct {
+	quit chan struct{} // Closed when the pool is shrunk.
+	ping chan struct{} // Received from while waiting for a task.
+	done chan struct{} // Closed when the worker stops.
 }
 
 // New creates a new GoPool with the given number of goroutines. The
@@ -58,28 +75,181 @@ type GoPool struct {
 // The src channel is where the goroutines look for tasks. If verbose
 // is true, information about the work being performed is logged to
 // the default logger. Otherwise only unexpected closures or errors
-// are logged.
+// are logged. The opts can send the messages to another logger with
+// WithLogger or events to a listener with WithListener.
 func New(name string, goroutines int, verbose bool, ctx context.Context,
-	src <-chan Task) *GoPool {
+	src <-chan Task, opts ...Option) *GoPool {
 	p := &GoPool{
-		name:    name,
-		src:     src,
-		ctx:     ctx,
-		verbose: verbose,
-	}
-	for x := 0; x < goroutines; x++ {
-		go p.worker(x)
+		name: name,
+		src:  src,
+		ctx:  ctx,
+		opts: newOptions(verbose, opts),
+		live: map[*worker]struct{}{},
 	}
-	p.wg.Add(goroutines)
+	p.Grow(goroutines)
 	return p
 }
 
+// Size returns the number of workers currently in the pool. Workers
+// that have been asked to stop by Shrink() or Resize() but are still
+// finishing their current task are not counted.
+func (p *GoPool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.workers)
+}
+
+// Resize changes the number of workers in the pool to n. If n is
+// larger than the current size, new workers are started. If it is
+// smaller, the extra workers are signaled to stop once they finish
+// their current task. A negative n is treated as zero.
+func (p *GoPool) Resize(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resize(n)
+}
+
+// Grow adds n workers to the pool.
+func (p *GoPool) Grow(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resize(len(p.workers) + n)
+}
+
+// Shrink signals n workers to stop once they finish their current
+// task. If n is larger than the size of the pool, all of the workers
+// are stopped.
+func (p *GoPool) Shrink(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resize(len(p.workers) - n)
+}
+
+// resize does the work for Resize. The caller must hold p.mu.
+func (p *GoPool) resize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	for len(p.workers) < n {
+		w := &worker{
+			quit: make(chan struct{}),
+			ping: make(chan struct{}),
+			done: make(chan struct{}),
+		}
+		p.workers = append(p.workers, w)
+		p.live[w] = struct{}{}
+		p.wg.Add(1)
+		go p.worker(p.nextID, w)
+		p.nextID++
+	}
+	for len(p.workers) > n {
+		last := len(p.workers) - 1
+		close(p.workers[last].quit)
+		p.workers = p.workers[:last]
+	}
+}
+
 // Wait blocks until all of the workers have stopped. This won't ever
 // return if the context for this gopool is never done.
 func (p *GoPool) Wait() {
 	p.wg.Wait()
 }
 
+// WaitIdle blocks until the pool is idle. The pool is idle when none
+// of the workers are running a task and ms, the managed source feeding
+// the pool, has no tasks to send. For this to work, ms should be the
+// only thing sending tasks to the pool. If ms is nil, the pool's
+// source channel must be empty instead and the tasks that were sent on
+// it before WaitIdle was called are waited for. The pool keeps running
+// afterwards, so tasks can be added and WaitIdle called again.
+//
+// If the context is done first, its error is returned. If the managed
+// source stops, ErrStopped is returned.
+func (p *GoPool) WaitIdle(ctx context.Context, ms *ManagedSource) error {
+	for {
+		// Get the changed channel before looking at the state so we
+		// don't miss any changes.
+		p.mu.Lock()
+		if p.changed == nil {
+			p.changed = make(chan struct{})
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		idle := len(p.src) == 0
+		var sent uint64
+		if ms != nil {
+			s, err := ms.state(ctx)
+			if err != nil {
+				return err
+			}
+			idle, sent = s.empty, s.sent
+		}
+		p.mu.Lock()
+		idle = idle && p.busy == 0 && (ms == nil || p.received == sent)
+		received := p.received
+		p.mu.Unlock()
+		if idle && ms != nil {
+			return nil
+		}
+		if idle {
+			// Without a managed source, we can't tell if a worker
+			// received a task but hasn't started it yet, so we wait
+			// for every worker to get back to waiting for a task. If
+			// none of them received one in the meantime, it's idle.
+			if err := p.ping(ctx); err != nil {
+				return err
+			}
+			p.mu.Lock()
+			idle = len(p.src) == 0 && p.busy == 0 && p.received == received
+			p.mu.Unlock()
+			if idle {
+				return nil
+			}
+			continue
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ping waits until each worker is waiting for a task or has stopped.
+func (p *GoPool) ping(ctx context.Context) error {
+	p.mu.Lock()
+	ws := make([]*worker, 0, len(p.live))
+	for w := range p.live {
+		ws = append(ws, w)
+	}
+	p.mu.Unlock()
+	for _, w := range ws {
+		select {
+		case w.ping <- struct{}{}:
+		case <-w.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// track records that a worker received a task (received is 1) and
+// changes the number of busy workers by busy. Anything waiting in
+// WaitIdle is notified of the change.
+func (p *GoPool) track(received, busy int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.received += uint64(received)
+	p.busy += busy
+	if p.changed != nil {
+		close(p.changed)
+		p.changed = nil
+	}
+}
+
 // String implements the fmt.Stringer interface. It just prints the
 // name given to New().
 func (p *GoPool) String() string {
@@ -87,32 +257,71 @@ func (p *GoPool) String() string {
 }
 
 // Worker is the function each goroutine uses to get and perform
-// tasks. It stops when the stop channel is closed. It also stops if
-// the source channel is closed but logs a message in addition.
-func (p *GoPool) worker(ID int) {
+// tasks. It stops when the stop channel is closed or when the pool
+// is shrunk and its quit channel is closed. While it waits for a
+// task, it also answers pings from WaitIdle. It also stops if the
+// source channel is closed but logs a message in addition.
+func (p *GoPool) worker(ID int, w *worker) {
+	defer func() {
+		p.mu.Lock()
+		delete(p.live, w)
+		p.mu.Unlock()
+		close(w.done)
+	}()
 	for {
+		// Check for shrinking first so a worker that was stopped while
+		// it was busy doesn't take another task.
+		select {
+		case <-w.quit:
+			p.opts.verbosef("[gopool %v %v] pool shrunk: stopping", p, ID)
+			p.stopped(ID, ErrShrunk)
+			return
+		default:
+		}
 		select {
+		case <-w.quit:
+			p.opts.verbosef("[gopool %v %v] pool shrunk: stopping", p, ID)
+			p.stopped(ID, ErrShrunk)
+			return
+		case <-w.ping:
 		case <-p.ctx.Done():
-			if p.verbose {
-				log.Printf("[gopool %v %v] stop channel closed: stopping", p, ID)
-			}
-			p.wg.Done()
+			p.opts.verbosef("[gopool %v %v] stop channel closed: stopping", p, ID)
+			p.stopped(ID, p.ctx.Err())
 			return
 		case t, ok := <-p.src:
 			if !ok {
-				log.Printf("[gopool %v %v] input source closed: stopping", p, ID)
-				p.wg.Done()
+				p.opts.printf("[gopool %v %v] input source closed: stopping", p, ID)
+				p.stopped(ID, ErrSourceClosed)
 				return
 			}
-			if p.verbose {
-				log.Printf("[gopool %v %v] starting task: %v", p, ID, t)
-			}
-			start := time.Now()
-			t.Run(p.ctx)
-			if p.verbose {
-				log.Printf("[gopool %v %v] finished task (duration %v): %v", p, ID,
-					time.Now().Sub(start), t)
-			}
+			p.track(1, 1)
+			p.run(ID, t)
+			p.track(0, -1)
 		}
 	}
 }
+
+// run performs a single task for the given worker.
+func (p *GoPool) run(ID int, t Task) {
+	p.opts.verbosef("[gopool %v %v] starting task: %v", p, ID, t)
+	p.opts.emit(Event{Type: EventTaskStarted, Name: p.name, Worker: ID, Task: t})
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			p.opts.emit(Event{Type: EventTaskFailed, Name: p.name, Worker: ID,
+				Task: t, Duration: time.Now().Sub(start), Err: &PanicError{Value: r}})
+			panic(r)
+		}
+	}()
+	t.Run(p.ctx)
+	d := time.Now().Sub(start)
+	p.opts.verbosef("[gopool %v %v] finished task (duration %v): %v", p, ID, d, t)
+	p.opts.emit(Event{Type: EventTaskFinished, Name: p.name, Worker: ID,
+		Task: t, Duration: d})
+}
+
+// stopped records that the given worker stopped.
+func (p *GoPool) stopped(ID int, err error) {
+	p.opts.emit(Event{Type: EventWorkerStopped, Name: p.name, Worker: ID, Err: err})
+	p.wg.Done()
+}
@@ -46,15 +46,15 @@ func TestGoPool(t *testing.T) {
 		ms.Add <- &tt{f: ai, i: x}
 		exp = append(exp, x)
 	}
-	for pq.q.Len() > 1 {
-		time.Sleep(20 * time.Millisecond)
+	if err := pool.WaitIdle(ctx, ms); err != nil {
+		t.Fatalf("WaitIdle() failed: %v", err)
 	}
 	for x := 500; x < 1000; x++ {
 		ms.Add <- &tt{f: ai, i: x}
 		exp = append(exp, x)
 	}
-	for pq.q.Len() > 1 {
-		time.Sleep(20 * time.Millisecond)
+	if err := pool.WaitIdle(ctx, ms); err != nil {
+		t.Fatalf("WaitIdle() failed: %v", err)
 	}
 
 	// Cleanup
@@ -78,6 +78,67 @@ func TestGoPool(t *testing.T) {
 	}
 }
 
+func TestGoPoolWaitIdle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	src := make(chan Task, 10)
+	pool := New("test-pool", 2, false, ctx, src)
+
+	// Without a managed source, we wait for the channel to drain.
+	var l sync.Mutex
+	n := 0
+	for x := 0; x < 10; x++ {
+		src <- &tt{f: func(int) {
+			time.Sleep(time.Millisecond)
+			l.Lock()
+			n++
+			l.Unlock()
+		}, i: x}
+	}
+	if err := pool.WaitIdle(ctx, nil); err != nil {
+		t.Fatalf("WaitIdle() failed: %v", err)
+	}
+	l.Lock()
+	if n != 10 {
+		t.Errorf("WaitIdle() returned before all tasks finished: %v", n)
+	}
+	l.Unlock()
+
+	// A task a worker received but hasn't started yet is waited for.
+	usrc := make(chan Task)
+	upool := New("test-pool", 2, false, ctx, usrc)
+	for x := 0; x < 100; x++ {
+		ran := false
+		usrc <- &tt{f: func(int) { ran = true }, i: x}
+		if err := upool.WaitIdle(ctx, nil); err != nil {
+			t.Fatalf("WaitIdle() failed: %v", err)
+		}
+		if !ran {
+			t.Fatalf("WaitIdle() returned before the received task ran")
+		}
+	}
+
+	// A context that ends first.
+	release := make(chan struct{})
+	started := make(chan struct{})
+	src <- &tt{f: func(int) { close(started); <-release }}
+	<-started
+	wctx, wcancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	if err := pool.WaitIdle(wctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("WaitIdle() didn't time out: %v", err)
+	}
+	wcancel()
+	close(release)
+
+	// A stopped managed source.
+	ms := NewManagedSource(NewFIFOQueue("test"), false, nil, ctx)
+	cancel()
+	ms.Wait()
+	if err := pool.WaitIdle(context.Background(), ms); err != ErrStopped {
+		t.Errorf("WaitIdle() with a stopped source didn't fail: %v", err)
+	}
+	pool.Wait()
+}
+
 func TestGoPoolInputSourceClosed(t *testing.T) {
 	// TODO this isn't very rigorous and relies on time.Sleep.
 	buf := &bytes.Buffer{}
@@ -110,3 +171,72 @@ func (t *tt) String() string { return strconv.Itoa(t.i) }
 func (t *tt) Run(ctx context.Context) {
 	t.f(t.i)
 }
+
+func TestGoPoolResize(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+
+	src := make(chan Task)
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := New("test-pool", 2, true, ctx, src)
+	if pool.Size() != 2 {
+		t.Errorf("pool.Size() != 2 after New(): %v", pool.Size())
+	}
+
+	// Grow and make sure all of the workers can take tasks at the same
+	// time.
+	pool.Grow(3)
+	if pool.Size() != 5 {
+		t.Errorf("pool.Size() != 5 after Grow(3): %v", pool.Size())
+	}
+	var wg sync.WaitGroup
+	release := make(chan struct{})
+	wg.Add(5)
+	for x := 0; x < 5; x++ {
+		src <- &tt{f: func(int) { wg.Done(); <-release }, i: x}
+	}
+	wg.Wait()
+	close(release)
+
+	// Shrink and wait for the workers to report that they stopped.
+	pool.Shrink(4)
+	if pool.Size() != 1 {
+		t.Errorf("pool.Size() != 1 after Shrink(4): %v", pool.Size())
+	}
+	pool.Resize(-1)
+	if pool.Size() != 0 {
+		t.Errorf("pool.Size() != 0 after Resize(-1): %v", pool.Size())
+	}
+	pool.Wait()
+	cancel()
+
+	log := buf.String()
+	for x := 0; x < 5; x++ {
+		e := fmt.Sprintf("[gopool test-pool %d] pool shrunk: stopping", x)
+		if !strings.Contains(log, e) {
+			t.Errorf("Didn't get an entry for: %v", e)
+		}
+	}
+}
+
+func TestGoPoolShrinkBusy(t *testing.T) {
+	// A worker that's shrunk while busy stops after its task even if
+	// there are more tasks waiting.
+	for x := 0; x < 20; x++ {
+		src := make(chan Task, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		pool := New("test-pool", 1, false, ctx, src)
+		started, release := make(chan struct{}), make(chan struct{})
+		src <- &tt{f: func(int) { close(started); <-release }}
+		<-started
+		ran := false
+		src <- &tt{f: func(int) { ran = true }}
+		pool.Shrink(1)
+		close(release)
+		pool.Wait()
+		cancel()
+		if ran {
+			t.Fatalf("shrunk worker took another task")
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// Group is a GoPool that collects the errors of its tasks, similar to
+// errgroup but with the queueing and priorities of managed sources.
+// Tasks that can fail are wrapped with Task() before being given to
+// the group's source.
+type Group struct {
+	*GoPool
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewGroup creates a new Group. The arguments are the same as New()
+// except for cancel. If cancel is non-nil, it is
+// called when the first task fails. It's typically the cancel
+// function of ctx so the remaining tasks are abandoned and the
+// workers stop, like errgroup.WithContext.
+func NewGroup(name string, goroutines int, verbose bool, ctx context.Context,
+	cancel context.CancelFunc, src <-chan Task, opts ...Option) *Group {
+	return &Group{
+		GoPool: New(name, goroutines, verbose, ctx, src, opts...),
+		cancel: cancel,
+	}
+}
+
+// Task wraps the given ErrorTask so its error is recorded by the group
+// when it's run. A panic in the task is recorded as a PanicError. If
+// the task has ID(), Key() or Priority() methods, the returned task
+// has them too.
+func (g *Group) Task(t ErrorTask) Task {
+	return forward(&groupTask{ErrorTask: t, g: g}, t)
+}
+
+// Wait blocks until all of the workers have stopped, which happens
+// when the context is done or the source is closed. It returns the
+// first error recorded by the group, if any.
+func (g *Group) Wait() error {
+	g.GoPool.Wait()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.errs) == 0 {
+		return nil
+	}
+	return g.errs[0]
+}
+
+// Errors returns all of the errors recorded so far in the order they
+// happened.
+func (g *Group) Errors() []error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]error(nil), g.errs...)
+}
+
+// fail records the error and cancels the context if it's the first.
+func (g *Group) fail(err error) {
+	g.mu.Lock()
+	g.errs = append(g.errs, err)
+	first := len(g.errs) == 1
+	g.mu.Unlock()
+	if first && g.cancel != nil {
+		g.cancel()
+	}
+}
+
+// groupTask adapts an ErrorTask to a Task that records its error in a
+// Group.
+type groupTask struct {
+	ErrorTask
+	g *Group
+}
+
+// Run implements Task.Run.
+func (t *groupTask) Run(ctx context.Context) {
+	if err := runErrorTask(ctx, t.ErrorTask); err != nil {
+		t.g.fail(err)
+	}
+}
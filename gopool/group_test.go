@@ -0,0 +1,79 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestGroup(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	src := make(chan Task)
+	g := NewGroup("test", 2, false, context.Background(), nil, src)
+	e1 := fmt.Errorf("one")
+	src <- g.Task(&gt{name: "a"})
+	src <- g.Task(&gt{name: "b", err: e1})
+	if err := g.WaitIdle(context.Background(), nil); err != nil {
+		t.Fatalf("WaitIdle() failed: %v", err)
+	}
+	src <- g.Task(&gt{name: "c", panics: true})
+	close(src)
+	if err := g.Wait(); err != e1 {
+		t.Errorf("Wait() didn't return the first error: %v", err)
+	}
+	errs := g.Errors()
+	if len(errs) != 2 || errs[0] != e1 {
+		t.Fatalf("unexpected Errors(): %v", errs)
+	}
+	if pe, ok := errs[1].(*PanicError); !ok || pe.Value != "c" {
+		t.Errorf("panic not recorded: %v", errs[1])
+	}
+	if p := g.Task(&gt{p: 5}).(PriorityTask).Priority(); p != 5 {
+		t.Errorf("priority not delegated: %v", p)
+	}
+}
+
+func TestGroupCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms := NewManagedSource(NewFIFOQueue("test"), false, nil, ctx)
+	g := NewGroup("test", 1, false, ctx, cancel, ms.Source)
+
+	// The source is never closed so the group only stops because the
+	// failing task cancels the context.
+	ms.Add <- g.Task(&gt{name: "b"})
+	ms.Add <- g.Task(&gt{name: "a", err: fmt.Errorf("a")})
+	if err := g.Wait(); err == nil || err.Error() != "a" {
+		t.Errorf("Wait() returned wrong error: %v", err)
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("context not cancelled on error: %v", ctx.Err())
+	}
+	ms.Wait()
+}
+
+// gt is a helper ErrorTask that returns err or panics.
+type gt struct {
+	name   string
+	err    error
+	panics bool
+	p      int
+}
+
+func (t *gt) String() string { return t.name }
+func (t *gt) Priority() int  { return t.p }
+func (t *gt) Run(ctx context.Context) error {
+	if t.panics {
+		panic(t.name)
+	}
+	return t.err
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"sort"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// WeightedSource is a Sourcer and its weight in a MultiSource.
+type WeightedSource struct {
+	Sourcer
+	Weight int
+}
+
+// MultiSource is an implementation of a Sourcer that multiplexes
+// several Sourcers fairly. Each call to Next() takes a task from the
+// source that is most owed one according to its weight (smooth
+// weighted round-robin). Empty sources are skipped and don't build up
+// credit, so neither a single large source nor one that was idle and
+// is then filled can starve the others (e.g. one tenant's giant
+// queue).
+//
+// The individual sources should be filled using AddTo(), which is
+// safe to call concurrently. Since this doesn't go through the
+// ManagedSource, the channel returned by Wakeup() should be given to
+// the ManagedSource using the MultiSource.
+type MultiSource struct {
+	name   string
+	wakeup chan struct{}
+
+	mu      sync.Mutex
+	sources []WeightedSource
+	current []int
+	total   int
+}
+
+// multiTask remembers which source a task came from so it can be put
+// back. It's forwarded so it keeps the optional methods of the task.
+type multiTask struct {
+	Task
+	m     *MultiSource
+	index int
+}
+
+// NewMultiSource creates a MultiSource that uses round-robin between
+// the given sources.
+func NewMultiSource(name string, ss ...Sourcer) *MultiSource {
+	ws := make([]WeightedSource, len(ss))
+	for x, s := range ss {
+		ws[x] = WeightedSource{Sourcer: s, Weight: 1}
+	}
+	return NewWeightedMultiSource(name, ws...)
+}
+
+// NewWeightedMultiSource creates a MultiSource where each source gets
+// a share of the tasks proportional to its weight. A source with a
+// weight of 2 gets twice as many tasks as one with a weight of 1 as
+// long as both have tasks. Weights less than 1 are treated as 1.
+func NewWeightedMultiSource(name string, ws ...WeightedSource) *MultiSource {
+	m := &MultiSource{
+		name:    name,
+		wakeup:  make(chan struct{}, 1),
+		sources: ws,
+		current: make([]int, len(ws)),
+	}
+	for x := range m.sources {
+		if m.sources[x].Weight < 1 {
+			m.sources[x].Weight = 1
+		}
+		m.total += m.sources[x].Weight
+	}
+	return m
+}
+
+func (m *MultiSource) String() string {
+	return m.name
+}
+
+// Wakeup returns the channel that is signaled when tasks are added
+// with AddTo(). It should be given to NewManagedSource.
+func (m *MultiSource) Wakeup() chan struct{} {
+	return m.wakeup
+}
+
+// Next implements Sourcer.Next.
+func (m *MultiSource) Next() Task {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order := make([]int, len(m.sources))
+	for x, s := range m.sources {
+		m.current[x] += s.Weight
+		order[x] = x
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return m.current[order[i]] > m.current[order[j]]
+	})
+	for _, x := range order {
+		if t := m.sources[x].Next(); t != nil {
+			m.current[x] -= m.total
+			if m.current[x] < -m.total {
+				// It's paying for the empty sources, which would
+				// otherwise let it run up a debt while it's the
+				// only one with tasks.
+				m.current[x] = -m.total
+			}
+			return forward(&multiTask{Task: t, m: m, index: x}, t)
+		}
+		// An empty source isn't owed anything. Otherwise it would
+		// build up credit while it's idle and starve the others once
+		// it's filled.
+		m.current[x] = 0
+	}
+	// Everything is empty, so nobody is owed anything.
+	for x := range m.current {
+		m.current[x] = 0
+	}
+	return nil
+}
+
+// Add implements Sourcer.Add. Tasks that were taken from this
+// MultiSource are put back into the source they came from. Other
+// tasks are added to the first source.
+func (m *MultiSource) Add(t Task) {
+	if mt, ok := unwrap(t).(*multiTask); ok && mt.m == m {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.sources[mt.index].Add(mt.Task)
+		return
+	}
+	m.AddTo(0, t)
+}
+
+// AddTo adds the task to the source at the given index (in the order
+// given when the MultiSource was created) and wakes up the
+// ManagedSource.
+func (m *MultiSource) AddTo(index int, t Task) {
+	m.mu.Lock()
+	m.sources[index].Add(t)
+	m.mu.Unlock()
+	select {
+	case m.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Run implements Task.Run for the wrapped task.
+func (t *multiTask) Run(ctx context.Context) {
+	t.Task.Run(ctx)
+}
+
+func (t *multiTask) task() Task { return t.Task }
@@ -0,0 +1,106 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestMultiSource(t *testing.T) {
+	buf := &bytes.Buffer{}
+	m := NewMultiSource("test", NewFIFOQueue("a"), NewFIFOQueue("b"),
+		NewFIFOQueue("c"))
+	if m.String() != "test" {
+		t.Errorf(`m.String() != "test": %v`, m.String())
+	}
+	if m.Next() != nil {
+		t.Fatalf("m.Next() != nil on empty sources")
+	}
+	// a has lots, b a few and c none.
+	for x := 0; x < 5; x++ {
+		m.AddTo(0, &sct{name: "a", w: buf})
+	}
+	m.AddTo(1, &sct{name: "b", w: buf})
+	m.Add(&sct{name: "a", w: buf})
+	m.AddTo(1, &sct{name: "b", w: buf})
+
+	// Put one back and make sure it goes to the right source.
+	m.Add(m.Next())
+	for c := m.Next(); c != nil; c = m.Next() {
+		c.Run(nil)
+	}
+	if buf.String() != "babaaaaa" && buf.String() != "ababaaaa" {
+		t.Errorf("tasks not multiplexed fairly: %v", buf.String())
+	}
+
+	// The tasks keep their optional methods.
+	m.AddTo(2, NewIdentifiedTask(&sct{name: "c"}, "c"))
+	if i, ok := m.Next().(IdentifiedTask); !ok || i.ID() != "c" {
+		t.Errorf("task lost its ID")
+	}
+}
+
+func TestWeightedMultiSource(t *testing.T) {
+	buf := &bytes.Buffer{}
+	m := NewWeightedMultiSource("test",
+		WeightedSource{Sourcer: NewFIFOQueue("a"), Weight: 3},
+		WeightedSource{Sourcer: NewFIFOQueue("b"), Weight: 0})
+	for x := 0; x < 8; x++ {
+		m.AddTo(0, &sct{name: "a", w: buf})
+		m.AddTo(1, &sct{name: "b", w: buf})
+	}
+	for x := 0; x < 8; x++ {
+		m.Next().Run(nil)
+	}
+	if buf.String() != "aabaaaba" {
+		t.Errorf("tasks not weighted properly: %v", buf.String())
+	}
+}
+
+func TestMultiSourceIdle(t *testing.T) {
+	// b is empty while a is busy and then gets lots of tasks. It
+	// shouldn't get a long run of them because it was idle.
+	buf := &bytes.Buffer{}
+	m := NewMultiSource("test", NewFIFOQueue("a"), NewFIFOQueue("b"))
+	for x := 0; x < 40; x++ {
+		m.AddTo(0, &sct{name: "a", w: buf})
+	}
+	for x := 0; x < 20; x++ {
+		m.Next().Run(nil)
+	}
+	for x := 0; x < 20; x++ {
+		m.AddTo(1, &sct{name: "b", w: buf})
+	}
+	buf.Reset()
+	for x := 0; x < 10; x++ {
+		m.Next().Run(nil)
+	}
+	if strings.Contains(buf.String(), "bbb") || strings.Count(buf.String(), "a") < 4 {
+		t.Errorf("idle source starved the others when filled: %v", buf.String())
+	}
+}
+
+func TestMultiSourceWakeup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewMultiSource("test", NewFIFOQueue("a"), NewFIFOQueue("b"))
+	ms := NewManagedSource(m, false, m.Wakeup(), ctx)
+	m.AddTo(1, &sct{name: "b"})
+	select {
+	case c := <-ms.Source:
+		if c.String() != "b" {
+			t.Errorf("got wrong task: %v", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("AddTo() didn't wake up the managed source")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// StageTask is a task in a Pipeline. It is like a Task but its Run
+// method returns the tasks that should be run by the next stage of
+// the pipeline.
+type StageTask interface {
+	fmt.Stringer
+
+	// Run performs the work for this task and returns the tasks for
+	// the next stage. The context should be handled the same way as in
+	// Task.Run. Tasks returned by the last stage are dropped.
+	Run(context.Context) []StageTask
+}
+
+// Stage describes a stage of a Pipeline.
+type Stage struct {
+	// Name is used as the name of the stage's gopool.
+	Name string
+
+	// Goroutines is the number of goroutines working on the stage.
+	Goroutines int
+
+	// Buffer is the number of tasks that can be waiting for the stage
+	// before the previous stage blocks.
+	Buffer int
+
+	// Options are given to New() when creating the stage's gopool.
+	Options []Option
+}
+
+// Pipeline connects several gopools so that the tasks output by one
+// stage are run by the next. Each stage has its own concurrency and
+// buffering.
+type Pipeline struct {
+	ctx    context.Context
+	stages []*pipelineStage
+}
+
+// pipelineStage is a running stage of a Pipeline.
+type pipelineStage struct {
+	in   chan Task
+	pool *GoPool
+}
+
+// NewPipeline creates and starts a pipeline with the given stages.
+// All of the stages stop when the context is done. Use Shutdown() to
+// stop the pipeline after all of the tasks have been run.
+func NewPipeline(ctx context.Context, verbose bool, stages ...Stage) *Pipeline {
+	p := &Pipeline{ctx: ctx}
+	for _, s := range stages {
+		in := make(chan Task, s.Buffer)
+		p.stages = append(p.stages, &pipelineStage{
+			in:   in,
+			pool: New(s.Name, s.Goroutines, verbose, ctx, in, s.Options...),
+		})
+	}
+	return p
+}
+
+// Add sends the task to the first stage of the pipeline. It blocks if
+// the first stage's buffer is full. It returns ErrStopped if the
+// context is done first. It shouldn't be called after Shutdown().
+func (p *Pipeline) Add(t StageTask) error {
+	return p.send(0, t)
+}
+
+// Shutdown stops the pipeline after all of the tasks that were added
+// have made it through. Each stage is drained before the next stage
+// is stopped. It returns when all of the stages have stopped.
+func (p *Pipeline) Shutdown() {
+	for _, s := range p.stages {
+		close(s.in)
+		s.pool.Wait()
+	}
+}
+
+// Wait blocks until all of the stages have stopped.
+func (p *Pipeline) Wait() {
+	for _, s := range p.stages {
+		s.pool.Wait()
+	}
+}
+
+// send sends the task to the given stage.
+func (p *Pipeline) send(stage int, t StageTask) error {
+	if stage >= len(p.stages) {
+		return nil
+	}
+	select {
+	case p.stages[stage].in <- &pipelineTask{p: p, stage: stage, t: t}:
+		return nil
+	case <-p.ctx.Done():
+		return ErrStopped
+	}
+}
+
+// pipelineTask adapts a StageTask to a Task for a stage's gopool.
+type pipelineTask struct {
+	p     *Pipeline
+	stage int
+	t     StageTask
+}
+
+func (t *pipelineTask) String() string { return t.t.String() }
+func (t *pipelineTask) Run(ctx context.Context) {
+	for _, next := range t.t.Run(ctx) {
+		if t.p.send(t.stage+1, next) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"bytes"
+	"log"
+	"strconv"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestPipeline(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	var l sync.Mutex
+	sum := 0
+	p := NewPipeline(context.Background(), false,
+		Stage{Name: "split", Goroutines: 2, Buffer: 5},
+		Stage{Name: "square", Goroutines: 4, Buffer: 10},
+		Stage{Name: "sum", Goroutines: 1},
+	)
+
+	// Each number is split into itself and its negative, squared and
+	// then summed.
+	for x := 1; x <= 100; x++ {
+		err := p.Add(&st{i: x, f: func(i int) []StageTask {
+			square := func(i int) []StageTask {
+				return []StageTask{&st{i: i * i, f: func(i int) []StageTask {
+					l.Lock()
+					defer l.Unlock()
+					sum += i
+					// These should be dropped.
+					return []StageTask{&st{i: i}}
+				}}}
+			}
+			return []StageTask{&st{i: i, f: square}, &st{i: -i, f: square}}
+		}})
+		if err != nil {
+			t.Fatalf("Add() failed: %v", err)
+		}
+	}
+	p.Shutdown()
+	if sum != 676700 {
+		t.Errorf("sum != 676700: %v", sum)
+	}
+}
+
+func TestPipelineStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewPipeline(ctx, false,
+		Stage{Name: "a", Goroutines: 1},
+		Stage{Name: "b", Goroutines: 1},
+	)
+	block := make(chan struct{})
+	p.Add(&st{f: func(int) []StageTask {
+		<-block
+		return nil
+	}})
+	cancel()
+	if err := p.Add(&st{}); err != ErrStopped {
+		t.Errorf("Add() after stopping didn't return ErrStopped: %v", err)
+	}
+	close(block)
+	p.Wait()
+}
+
+// st is a helper StageTask that calls a function with its number.
+type st struct {
+	i int
+	f func(int) []StageTask
+}
+
+func (t *st) String() string { return strconv.Itoa(t.i) }
+func (t *st) Run(ctx context.Context) []StageTask {
+	if t.f == nil {
+		return nil
+	}
+	return t.f(t.i)
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// RedisConn is the minimal interface a redis client needs to satisfy
+// to be used by RedisSource. It matches the Do method of common redis
+// clients (e.g. redigo's Conn). Bulk string replies should be returned
+// as []byte or string, integer replies as int64 and array replies as
+// []interface{}. A nil reply should be returned as nil.
+//
+// RedisSource calls Do from the goroutine running its ManagedSource
+// and from the workers acknowledging tasks, so the connection must be
+// safe for concurrent use (e.g. backed by a pool).
+type RedisConn interface {
+	Do(cmd string, args ...interface{}) (interface{}, error)
+}
+
+// RedisSource is an implementation of a Sourcer that uses a redis list
+// as its queue. This allows gopools to be the consumers of an existing
+// redis job queue and lets several processes share the same queue.
+//
+// Producers LPUSH serialized tasks onto the list (Add does this
+// using the Codec). When a task is taken with Next, it is atomically
+// moved to a processing list and given a deadline by a Lua script, so
+// redis 2.6 or later is needed. Once it has run it
+// is acknowledged and removed. If it isn't acknowledged before the
+// visibility timeout (e.g. the process died), it is moved back to the
+// queue. Delivery is therefore at-least-once.
+//
+// Since the serialized tasks are used to identify them in the
+// processing list, tasks that are queued at the same time should
+// serialize to unique values.
+type RedisSource struct {
+	name       string
+	conn       RedisConn
+	codec      Codec
+	key        string
+	visibility time.Duration
+	opts       options
+}
+
+// nextScript moves the next task to the processing list and sets its
+// deadline in one step, so a task is never processing without a
+// deadline.
+const nextScript = `
+local v = redis.call('RPOPLPUSH', KEYS[1], KEYS[2])
+if v then
+	redis.call('ZADD', KEYS[3], ARGV[1], v)
+end
+return v
+`
+
+// redisTask is a task taken from a RedisSource. It acknowledges the
+// task once it has run.
+type redisTask struct {
+	s    *RedisSource
+	data []byte
+	t    Task
+}
+
+func (t *redisTask) String() string { return t.t.String() }
+func (t *redisTask) Run(ctx context.Context) {
+	t.t.Run(ctx)
+	t.discard()
+}
+
+func (t *redisTask) task() Task { return t.t }
+
+// discard acknowledges the task. It's also used when the task is
+// dropped instead of run.
+func (t *redisTask) discard() {
+	if err := t.s.ack(t.data); err != nil {
+		t.s.opts.printf("[redis %v] acknowledging task %v: %v", t.s, t, err)
+	}
+}
+
+// NewRedisSource creates a RedisSource using the list at the given
+// key. The keys <key>:processing and <key>:deadlines are used to track
+// tasks that are running. The visibility timeout is how long a task
+// may run before it is assumed lost and queued again. Errors are
+// logged to the default logger unless WithLogger is given.
+func NewRedisSource(name string, conn RedisConn, codec Codec, key string,
+	visibility time.Duration, opts ...Option) *RedisSource {
+	return &RedisSource{
+		name:       name,
+		conn:       conn,
+		codec:      codec,
+		key:        key,
+		visibility: visibility,
+		opts:       newOptions(false, opts),
+	}
+}
+
+func (s *RedisSource) String() string {
+	return s.name
+}
+
+// Next implements Sourcer.Next. Tasks whose visibility timeout has
+// expired are requeued before the next task is taken. Errors talking
+// to redis are logged and treated as if there were no work.
+func (s *RedisSource) Next() Task {
+	if _, err := s.Requeue(); err != nil {
+		s.opts.printf("[redis %v] requeuing expired tasks: %v", s, err)
+	}
+	for {
+		deadline := time.Now().Add(s.visibility).UnixNano()
+		reply, err := s.conn.Do("EVAL", nextScript, 3, s.key, s.processing(),
+			s.deadlines(), deadline)
+		if err != nil {
+			s.opts.printf("[redis %v] getting task: %v", s, err)
+			return nil
+		}
+		if reply == nil {
+			return nil
+		}
+		data, err := redisBytes(reply)
+		if err != nil {
+			s.opts.printf("[redis %v] getting task: %v", s, err)
+			return nil
+		}
+		t, err := s.codec.Decode(data)
+		if err != nil {
+			// There is no point in retrying something we can't decode.
+			s.opts.printf("[redis %v] decoding task %q: %v", s, data, err)
+			if err := s.ack(data); err != nil {
+				s.opts.printf("[redis %v] removing task %q: %v", s, data, err)
+			}
+			continue
+		}
+		return forward(&redisTask{s: s, data: data, t: t}, t)
+	}
+}
+
+// Add implements Sourcer.Add. If the task can't be encoded or added,
+// the error is logged and the task is dropped.
+func (s *RedisSource) Add(t Task) {
+	// Tasks from this source are being put back (e.g. the
+	// ManagedSource is stopping), so they go back to the front of the
+	// queue.
+	if rt, ok := unwrap(t).(*redisTask); ok && rt.s == s {
+		if err := s.release(rt.data); err != nil {
+			s.opts.printf("[redis %v] putting back task %v: %v", s, t, err)
+		}
+		return
+	}
+	data, err := s.codec.Encode(t)
+	if err != nil {
+		s.opts.printf("[redis %v] encoding task %v: %v", s, t, err)
+		return
+	}
+	if _, err := s.conn.Do("LPUSH", s.key, data); err != nil {
+		s.opts.printf("[redis %v] adding task %v: %v", s, t, err)
+	}
+}
+
+// Requeue moves tasks whose visibility timeout has expired back to
+// the queue. It returns the number of tasks moved. Next calls this
+// automatically.
+func (s *RedisSource) Requeue() (int, error) {
+	reply, err := s.conn.Do("ZRANGEBYSCORE", s.deadlines(), "-inf",
+		time.Now().UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply type %T", reply)
+	}
+	for x, item := range items {
+		data, err := redisBytes(item)
+		if err != nil {
+			return x, err
+		}
+		if err := s.release(data); err != nil {
+			return x, err
+		}
+	}
+	return len(items), nil
+}
+
+// release moves a task from the processing list back to the front of
+// the queue.
+func (s *RedisSource) release(data []byte) error {
+	reply, err := s.conn.Do("LREM", s.processing(), 1, data)
+	if err != nil {
+		return err
+	}
+	// Someone else may have already moved it.
+	if n, ok := reply.(int64); !ok || n > 0 {
+		if _, err := s.conn.Do("RPUSH", s.key, data); err != nil {
+			return err
+		}
+	}
+	_, err = s.conn.Do("ZREM", s.deadlines(), data)
+	return err
+}
+
+// ack removes a finished task from the processing list.
+func (s *RedisSource) ack(data []byte) error {
+	if _, err := s.conn.Do("LREM", s.processing(), 1, data); err != nil {
+		return err
+	}
+	_, err := s.conn.Do("ZREM", s.deadlines(), data)
+	return err
+}
+
+func (s *RedisSource) processing() string { return s.key + ":processing" }
+func (s *RedisSource) deadlines() string  { return s.key + ":deadlines" }
+
+// redisBytes converts a bulk string reply to bytes.
+func redisBytes(reply interface{}) ([]byte, error) {
+	switch r := reply.(type) {
+	case []byte:
+		return r, nil
+	case string:
+		return []byte(r), nil
+	}
+	return nil, fmt.Errorf("unexpected reply type %T", reply)
+}
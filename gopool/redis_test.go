@@ -0,0 +1,167 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestRedisSource(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	out := &bytes.Buffer{}
+	r := newFakeRedis()
+	s := NewRedisSource("test", r, &sctCodec{w: out}, "q", time.Hour)
+	if s.String() != "test" {
+		t.Errorf(`s.String() != "test": %v`, s.String())
+	}
+	if s.Next() != nil {
+		t.Fatalf("s.Next() != nil on empty queue")
+	}
+	for _, name := range []string{"a", "b", "c", "bad"} {
+		s.Add(&sct{name: name, w: out})
+	}
+	if !strings.Contains(buf.String(), "encoding task bad") {
+		t.Errorf("didn't log encoding error: %v", buf.String())
+	}
+
+	// Run a, take b and put it back and take c but don't run it.
+	s.Next().Run(context.Background())
+	s.Add(s.Next())
+	if c := s.Next(); c.String() != "b" {
+		t.Fatalf("didn't get b back after putting it back: %v", c)
+	}
+	c := s.Next()
+	if len(r.lists["q:processing"]) != 2 || len(r.zsets["q:deadlines"]) != 2 {
+		t.Errorf("expected two processing tasks: %v %v", r.lists, r.zsets)
+	}
+
+	// Expire the deadlines and make sure they are requeued.
+	for k := range r.zsets["q:deadlines"] {
+		r.zsets["q:deadlines"][k] = 0
+	}
+	if n, err := s.Requeue(); n != 2 || err != nil {
+		t.Errorf("Requeue() = %v, %v; expected 2, nil", n, err)
+	}
+	// Acknowledging c after it was requeued is harmless.
+	c.Run(context.Background())
+	for t := s.Next(); t != nil; t = s.Next() {
+		t.Run(context.Background())
+	}
+	if out.String() != "acbc" && out.String() != "accb" {
+		t.Errorf(`tasks run != "acbc" or "accb": %v`, out.String())
+	}
+	if len(r.lists["q"]) != 0 || len(r.lists["q:processing"]) != 0 ||
+		len(r.zsets["q:deadlines"]) != 0 {
+		t.Errorf("redis not empty after running everything: %v %v", r.lists, r.zsets)
+	}
+
+	// Undecodable tasks are dropped.
+	r.lists["q"] = []string{"undecodable"}
+	if t := s.Next(); t != nil {
+		t.Run(context.Background())
+	}
+	if !strings.Contains(buf.String(), "decoding task") {
+		t.Errorf("didn't log decoding error: %v", buf.String())
+	}
+
+	// Errors from redis.
+	r.err = errors.New("down")
+	if s.Next() != nil {
+		t.Errorf("s.Next() != nil when redis is down")
+	}
+	s.Add(&sct{name: "d"})
+	if !strings.Contains(buf.String(), "adding task d: down") {
+		t.Errorf("didn't log add error: %v", buf.String())
+	}
+}
+
+// fakeRedis implements just enough of redis to test RedisSource. The
+// only script it runs is the one Next uses.
+type fakeRedis struct {
+	sync.Mutex
+	lists map[string][]string
+	zsets map[string]map[string]int64
+	err   error
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		lists: map[string][]string{},
+		zsets: map[string]map[string]int64{},
+	}
+}
+
+func (r *fakeRedis) Do(cmd string, args ...interface{}) (interface{}, error) {
+	r.Lock()
+	defer r.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if cmd == "EVAL" && args[0] == nextScript {
+		v, _ := r.do("RPOPLPUSH", args[2], args[3])
+		if v != nil {
+			r.do("ZADD", args[4], args[5], v)
+		}
+		return v, nil
+	}
+	return r.do(cmd, args...)
+}
+
+// do runs a command. The caller must hold the lock.
+func (r *fakeRedis) do(cmd string, args ...interface{}) (interface{}, error) {
+	s := func(i int) string { return fmt.Sprintf("%s", args[i]) }
+	switch cmd {
+	case "LPUSH":
+		r.lists[s(0)] = append([]string{s(1)}, r.lists[s(0)]...)
+	case "RPUSH":
+		r.lists[s(0)] = append(r.lists[s(0)], s(1))
+	case "RPOPLPUSH":
+		l := r.lists[s(0)]
+		if len(l) == 0 {
+			return nil, nil
+		}
+		v := l[len(l)-1]
+		r.lists[s(0)] = l[:len(l)-1]
+		r.lists[s(1)] = append([]string{v}, r.lists[s(1)]...)
+		return []byte(v), nil
+	case "LREM":
+		l := r.lists[s(0)]
+		for x, v := range l {
+			if v == s(2) {
+				r.lists[s(0)] = append(l[:x:x], l[x+1:]...)
+				return int64(1), nil
+			}
+		}
+		return int64(0), nil
+	case "ZADD":
+		if r.zsets[s(0)] == nil {
+			r.zsets[s(0)] = map[string]int64{}
+		}
+		r.zsets[s(0)][s(2)] = args[1].(int64)
+	case "ZREM":
+		delete(r.zsets[s(0)], s(1))
+	case "ZRANGEBYSCORE":
+		items := []interface{}{}
+		for k, v := range r.zsets[s(0)] {
+			if v <= args[2].(int64) {
+				items = append(items, k)
+			}
+		}
+		return items, nil
+	}
+	return int64(1), nil
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ErrorTask is like a Task but its Run method can fail.
+type ErrorTask interface {
+	fmt.Stringer
+
+	// Run performs the work for this task. A non-nil error means the
+	// task failed. The context should be handled the same way as in
+	// Task.Run.
+	Run(context.Context) error
+}
+
+// PanicError is the error used when a task panics. Value is the value
+// that was given to panic().
+type PanicError struct {
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// RetryPolicy describes how failed tasks are retried. The delay
+// before the nth retry is InitialBackoff * Multiplier^(n-1) capped at
+// MaxBackoff. Jitter is a fraction (0.0-1.0) of the delay that is
+// randomly added or removed so retries don't happen in lockstep.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times a task is run before it
+	// is given to DeadLetter. Values less than 1 are treated as 1.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the largest delay between retries. Zero means
+	// there is no limit.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor by which the delay grows after each
+	// retry. Values less than 1 are treated as 2.
+	Multiplier float64
+
+	// Jitter is the fraction of the delay that is randomized.
+	Jitter float64
+
+	// DeadLetter is called with the task and its last error when it
+	// has failed MaxAttempts times or if the context is done while it
+	// is waiting to be retried. It may be nil.
+	DeadLetter func(t ErrorTask, err error)
+}
+
+// Backoff returns the delay before the given retry. The first retry
+// is 1.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	m := p.Multiplier
+	if m < 1 {
+		m = 2
+	}
+	d := float64(p.InitialBackoff) * math.Pow(m, float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	if d < 0 {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+// RetryTask is a Task that wraps an ErrorTask. If the wrapped task
+// fails or panics, it is added back to a source after a delay
+// determined by its RetryPolicy.
+type RetryTask struct {
+	t        ErrorTask
+	add      chan<- Task
+	policy   RetryPolicy
+	attempts int
+}
+
+// NewRetryTask creates a RetryTask for the given ErrorTask. Retries
+// are sent on the add channel, which is typically the Add channel of
+// the ManagedSource feeding the gopool.
+func NewRetryTask(t ErrorTask, add chan<- Task, policy RetryPolicy) *RetryTask {
+	return &RetryTask{
+		t:      t,
+		add:    add,
+		policy: policy,
+	}
+}
+
+// String implements the fmt.Stringer interface. It uses the String()
+// of the wrapped ErrorTask.
+func (t *RetryTask) String() string {
+	return t.t.String()
+}
+
+// Attempts returns the number of times the wrapped task has been run.
+func (t *RetryTask) Attempts() int {
+	return t.attempts
+}
+
+// Run implements Task.Run. It runs the wrapped task and schedules a
+// retry if it failed.
+func (t *RetryTask) Run(ctx context.Context) {
+	t.attempts++
+	err := runErrorTask(ctx, t.t)
+	if err == nil {
+		return
+	}
+	max := t.policy.MaxAttempts
+	if max < 1 {
+		max = 1
+	}
+	if t.attempts >= max {
+		t.deadLetter(err)
+		return
+	}
+	go func() {
+		select {
+		case <-time.After(t.policy.Backoff(t.attempts)):
+		case <-ctx.Done():
+			t.deadLetter(ctx.Err())
+			return
+		}
+		select {
+		case t.add <- t:
+		case <-ctx.Done():
+			t.deadLetter(ctx.Err())
+		}
+	}()
+}
+
+func (t *RetryTask) deadLetter(err error) {
+	if t.policy.DeadLetter != nil {
+		t.policy.DeadLetter(t.t, err)
+	}
+}
+
+// runErrorTask runs the given task and turns a panic into a
+// PanicError.
+func runErrorTask(ctx context.Context, t ErrorTask) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r}
+		}
+	}()
+	return t.Run(ctx)
+}
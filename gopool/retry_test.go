@@ -0,0 +1,121 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+	}
+	tests := []struct {
+		retry int
+		exp   time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 50 * time.Millisecond},
+		{10, 50 * time.Millisecond},
+	}
+	for k, test := range tests {
+		if d := p.Backoff(test.retry); d != test.exp {
+			t.Errorf("Test %v: Backoff(%v) = %v, expected %v", k, test.retry, d, test.exp)
+		}
+	}
+
+	// Make sure jitter stays within its bounds.
+	p.Jitter = 0.5
+	for x := 0; x < 100; x++ {
+		d := p.Backoff(1)
+		if d < 5*time.Millisecond || d > 15*time.Millisecond {
+			t.Fatalf("Backoff(1) with jitter out of range: %v", d)
+		}
+	}
+}
+
+func TestRetryTask(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms := NewManagedSource(NewPriorityQueue("test"), false, nil, ctx)
+	New("test-pool", 2, false, ctx, ms.Source)
+
+	type dead struct {
+		t   ErrorTask
+		err error
+	}
+	deads := make(chan dead, 2)
+	policy := RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		DeadLetter: func(t ErrorTask, err error) {
+			deads <- dead{t: t, err: err}
+		},
+	}
+
+	// This one succeeds on the last attempt.
+	ok := &et{fails: 2, done: make(chan struct{})}
+	okr := NewRetryTask(ok, ms.Add, policy)
+	ms.Add <- okr
+
+	// This one always panics.
+	bad := &et{fails: 10, panics: true}
+	ms.Add <- NewRetryTask(bad, ms.Add, policy)
+
+	select {
+	case <-ok.done:
+	case <-time.After(time.Second):
+		t.Fatalf("retried task never succeeded")
+	}
+	if okr.Attempts() != 3 {
+		t.Errorf("okr.Attempts() != 3: %v", okr.Attempts())
+	}
+
+	select {
+	case d := <-deads:
+		if d.t != bad {
+			t.Errorf("wrong task given to DeadLetter: %v", d.t)
+		}
+		if _, ok := d.err.(*PanicError); !ok {
+			t.Errorf("expected PanicError but got: %v", d.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("failed task never given to DeadLetter")
+	}
+	if bad.runs != 3 {
+		t.Errorf("bad.runs != 3: %v", bad.runs)
+	}
+}
+
+// et is a helper ErrorTask that fails the given number of times
+// before succeeding.
+type et struct {
+	fails  int
+	panics bool
+	runs   int
+	done   chan struct{}
+}
+
+func (t *et) String() string { return "et" }
+func (t *et) Run(ctx context.Context) error {
+	t.runs++
+	if t.runs <= t.fails {
+		if t.panics {
+			panic("et")
+		}
+		return errors.New("et")
+	}
+	close(t.done)
+	return nil
+}
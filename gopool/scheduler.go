@@ -0,0 +1,155 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// CatchUp determines what a Scheduler does when it falls behind on a
+// schedule. This can happen if sending the task blocks because the
+// gopool is busy or if the process was suspended.
+type CatchUp int
+
+const (
+	// CatchUpSkip skips all of the missed runs and waits for the next
+	// scheduled time.
+	CatchUpSkip CatchUp = iota
+
+	// CatchUpOnce runs the task once for all of the missed runs.
+	CatchUpOnce
+
+	// CatchUpAll runs the task once for each of the missed runs.
+	CatchUpAll
+)
+
+// Scheduler sends tasks to a gopool on a recurring schedule.
+type Scheduler struct {
+	name string
+	out  chan<- Task
+	ctx  context.Context
+	opts options
+
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	cancels map[int]context.CancelFunc
+	nextID  int
+}
+
+// NewScheduler creates a new Scheduler that sends tasks on the given
+// channel. This is typically the Add channel of a ManagedSource but
+// can also be the channel given to New(). The name is used for
+// logging purposes.
+//
+// All of the schedules are stopped when the given context is done. If
+// verbose is true, information about the tasks being sent is logged
+// to the default logger. The opts are the same as for New(). An
+// EventTaskSent is sent to the listener for each task sent.
+func NewScheduler(name string, verbose bool, out chan<- Task,
+	ctx context.Context, opts ...Option) *Scheduler {
+	return &Scheduler{
+		name:    name,
+		out:     out,
+		ctx:     ctx,
+		opts:    newOptions(verbose, opts),
+		cancels: map[int]context.CancelFunc{},
+	}
+}
+
+// String implements the fmt.Stringer interface. It just prints the
+// name given to NewScheduler().
+func (s *Scheduler) String() string {
+	return s.name
+}
+
+// Add starts sending the given task according to the given schedule.
+// The returned ID can be used to Cancel() the schedule.
+func (s *Scheduler) Add(sched Schedule, t Task, catchUp CatchUp) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID
+	s.nextID++
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.cancels[id] = cancel
+	s.wg.Add(1)
+	go s.run(id, ctx, sched, t, catchUp)
+	return id
+}
+
+// AddCron is a helper that parses the cron spec with ParseCron and
+// then calls Add.
+func (s *Scheduler) AddCron(spec string, t Task, catchUp CatchUp) (int, error) {
+	sched, err := ParseCron(spec)
+	if err != nil {
+		return 0, err
+	}
+	return s.Add(sched, t, catchUp), nil
+}
+
+// Cancel stops the schedule with the given ID. It returns false if
+// there was no such schedule.
+func (s *Scheduler) Cancel(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cancel, ok := s.cancels[id]
+	if ok {
+		cancel()
+		delete(s.cancels, id)
+	}
+	return ok
+}
+
+// Wait blocks until all of the schedules have stopped. This won't
+// ever return if the context for this scheduler is never done and
+// there are schedules that haven't been cancelled.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// run sends the task according to the schedule until the context is
+// done.
+func (s *Scheduler) run(id int, ctx context.Context, sched Schedule, t Task,
+	catchUp CatchUp) {
+	defer s.wg.Done()
+	next := sched.Next(time.Now())
+	for !next.IsZero() {
+		timer := time.NewTimer(next.Sub(time.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.opts.verbosef("[scheduler %v %v] stopping", s, id)
+			return
+		case <-timer.C:
+		}
+		select {
+		case <-ctx.Done():
+			s.opts.verbosef("[scheduler %v %v] stopping", s, id)
+			return
+		case s.out <- t:
+			s.opts.verbosef("[scheduler %v %v] sent task %v", s, id, t)
+			s.opts.emit(Event{Type: EventTaskSent, Name: s.name, Task: t})
+		}
+		now := time.Now()
+		after := sched.Next(next)
+		if after.After(now) || after.IsZero() {
+			next = after
+			continue
+		}
+		switch catchUp {
+		case CatchUpAll:
+			next = after
+		case CatchUpOnce:
+			next = now
+		default:
+			next = sched.Next(now)
+		}
+	}
+	s.opts.verbosef("[scheduler %v %v] schedule has no more runs: stopping", s, id)
+}
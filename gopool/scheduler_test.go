@@ -0,0 +1,105 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestScheduler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan Task)
+	s := NewScheduler("test", false, out, ctx)
+	if s.String() != "test" {
+		t.Errorf(`s.String() != "test": %v`, s.String())
+	}
+	a := s.Add(Every(5*time.Millisecond), &sct{name: "a"}, CatchUpSkip)
+	if _, err := s.AddCron("@every 5ms", &sct{name: "b"}, CatchUpAll); err != nil {
+		t.Fatalf("AddCron() failed: %v", err)
+	}
+	if _, err := s.AddCron("bad", &sct{name: "c"}, CatchUpAll); err == nil {
+		t.Errorf("AddCron() with a bad spec didn't fail")
+	}
+
+	// Get a few of each.
+	counts := map[string]int{}
+	for counts["a"] < 3 || counts["b"] < 3 {
+		select {
+		case task := <-out:
+			counts[task.String()]++
+		case <-time.After(time.Second):
+			t.Fatalf("didn't get tasks from scheduler: %v", counts)
+		}
+	}
+
+	// Cancel a and make sure we only get b.
+	if !s.Cancel(a) {
+		t.Errorf("Cancel(a) returned false")
+	}
+	if s.Cancel(a) {
+		t.Errorf("second Cancel(a) returned true")
+	}
+	// Drain anything a may have been waiting to send.
+	time.Sleep(10 * time.Millisecond)
+	for x := 0; x < 5; x++ {
+		select {
+		case task := <-out:
+			if task.String() != "b" && x > 0 {
+				t.Errorf("got task from cancelled schedule: %v", task)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("didn't get tasks from scheduler after cancel")
+		}
+	}
+
+	cancel()
+	s.Wait()
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+	tests := []struct {
+		catchUp CatchUp
+		min     int
+		max     int
+	}{
+		{CatchUpSkip, 0, 1},
+		{CatchUpOnce, 1, 2},
+		{CatchUpAll, 8, 12},
+	}
+	for k, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		out := make(chan Task)
+		s := NewScheduler("test", false, out, ctx)
+		s.Add(Every(5*time.Millisecond), &sct{name: "a"}, test.catchUp)
+
+		// Get the first one and then stall for about 10 runs.
+		<-out
+		time.Sleep(52 * time.Millisecond)
+		<-out
+
+		// Count how many are sent immediately.
+		count := 0
+	loop:
+		for {
+			select {
+			case <-out:
+				count++
+			case <-time.After(2 * time.Millisecond):
+				break loop
+			}
+		}
+		if count < test.min || count > test.max {
+			t.Errorf("Test %v: got %v tasks catching up, expected %v-%v", k, count,
+				test.min, test.max)
+		}
+		cancel()
+		s.Wait()
+	}
+}
@@ -8,13 +8,18 @@ package gopool
 
 import (
 	"container/heap"
+	"errors"
 	"fmt"
-	"log"
 	"sync"
+	"time"
 
 	"golang.org/x/net/context"
 )
 
+// ErrQueueFull is returned by ManagedSource.TryAdd when the managed
+// source is at capacity.
+var ErrQueueFull = errors.New("queue full")
+
 // Sourcer is the interface that allows a type to be run as a source
 // that communicates approptiately with a gopool. If this Sourcer is
 // used by a managed source, the Next() and Add() methods are
@@ -42,10 +47,23 @@ type ManagedSource struct {
 	// Source is the channel where tasks can be retrieved.
 	Source <-chan Task
 
-	// Add is the channel on which tasks can be added.
+	// Add is the channel on which tasks can be added. If the managed
+	// source is bounded, sends on this channel block while it is at
+	// capacity.
 	Add chan<- Task
 
-	wg *sync.WaitGroup
+	wg       *sync.WaitGroup
+	ctx      context.Context
+	tryAdd   chan Task
+	query    chan chan sourceState
+	capacity int
+
+	// mu protects size, the number of tasks in the Sourcer (including
+	// any that have been taken but not sent yet or reserved by TryAdd),
+	// and tasks, the IdentifiedTasks that can be cancelled by their ID.
+	mu    sync.Mutex
+	size  int
+	tasks map[string]*idTasks
 }
 
 // Wait blocks until the ManagedSource is done. If you want to ensure
@@ -55,81 +73,209 @@ func (ms *ManagedSource) Wait() {
 	ms.wg.Wait()
 }
 
+// Len returns the number of tasks in the managed source that haven't
+// been sent to a gopool yet. It only includes tasks added through the
+// managed source.
+func (ms *ManagedSource) Len() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.size
+}
+
+// TryAdd adds the task to the managed source without blocking. It
+// returns ErrQueueFull if the managed source is bounded and at
+// capacity or ErrStopped if its context is done.
+func (ms *ManagedSource) TryAdd(t Task) error {
+	ms.mu.Lock()
+	if ms.capacity > 0 && ms.size >= ms.capacity {
+		ms.mu.Unlock()
+		return ErrQueueFull
+	}
+	// Reserve our spot so the send below won't wait for room.
+	ms.size++
+	ms.mu.Unlock()
+	select {
+	case ms.tryAdd <- t:
+		return nil
+	case <-ms.ctx.Done():
+		ms.grow(-1)
+		return ErrStopped
+	}
+}
+
+// grow changes the size by n. The size never goes below zero since
+// tasks may have been added to the Sourcer directly.
+func (ms *ManagedSource) grow(n int) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.size += n
+	if ms.size < 0 {
+		ms.size = 0
+	}
+}
+
+// dropper is implemented by Sourcers that may drop the tasks given to
+// Add or replace tasks they have with them, e.g. a DedupSource. add is
+// like Add but returns the task that was dropped or replaced, if any.
+type dropper interface {
+	add(t Task) Task
+}
+
+// add adds the task to the Sourcer and tracks it. If the Sourcer
+// drops a task instead of queuing it, it's no longer counted.
+func (ms *ManagedSource) add(s Sourcer, t Task) {
+	ms.track(t)
+	d, ok := s.(dropper)
+	if !ok {
+		s.Add(t)
+		return
+	}
+	if old := d.add(t); old != nil {
+		ms.forget(old)
+		ms.grow(-1)
+	}
+}
+
+// sourceState is a snapshot of a ManagedSource used by WaitIdle.
+type sourceState struct {
+	// empty is true if there are no tasks to send.
+	empty bool
+
+	// sent is the number of tasks sent.
+	sent uint64
+}
+
+// state gets a snapshot of the managed source from its goroutine.
+// Since the goroutine handles one thing at a time, the snapshot
+// reflects any sends to Add that completed before calling this.
+func (ms *ManagedSource) state(ctx context.Context) (sourceState, error) {
+	c := make(chan sourceState, 1)
+	select {
+	case ms.query <- c:
+		return <-c, nil
+	case <-ms.ctx.Done():
+		return sourceState{}, ErrStopped
+	case <-ctx.Done():
+		return sourceState{}, ctx.Err()
+	}
+}
+
 // NewManagedSource creates a managed source using the given Sourcer and
 // starts it. If the wakeup channel is non-nil, it can be used to force
 // the goroutine to wakeup and look for new tasks. This may be useful
 // if the source may be empty but is later filled.
 //
 // If verbose is true things happening in the channel are logged to
-// the default logger.
+// the default logger. The opts are the same as for New().
 func NewManagedSource(s Sourcer, verbose bool, wakeup chan struct{},
-	ctx context.Context) *ManagedSource {
+	ctx context.Context, opts ...Option) *ManagedSource {
+	return NewBoundedManagedSource(s, 0, verbose, wakeup, ctx, opts...)
+}
+
+// NewBoundedManagedSource is like NewManagedSource but it holds at
+// most capacity tasks. Once it is full, sends on the Add channel block
+// and TryAdd returns ErrQueueFull until tasks are taken by a gopool.
+// This provides backpressure to producers. A capacity less than 1
+// means there is no limit.
+func NewBoundedManagedSource(s Sourcer, capacity int, verbose bool,
+	wakeup chan struct{}, ctx context.Context, opts ...Option) *ManagedSource {
+	o := newOptions(verbose, opts)
 	source := make(chan Task)
 	add := make(chan Task)
 	var wg sync.WaitGroup
+	ms := &ManagedSource{
+		Source:   source,
+		Add:      add,
+		wg:       &wg,
+		ctx:      ctx,
+		tryAdd:   make(chan Task),
+		query:    make(chan chan sourceState),
+		capacity: capacity,
+		tasks:    map[string]*idTasks{},
+	}
 	wg.Add(1)
 	go func() {
 		var src chan Task
-		var top Task
+		var top, out Task // The next task and what's sent for it.
+		var ct *cancelTask
+		var sent uint64
 		for {
 			// Get the top task if we don't have one.
-			if top == nil {
+			for top == nil {
 				top = s.Next()
+				if top == nil {
+					break
+				}
+				var dropped bool
+				out, ct, dropped = ms.take(top)
+				if !dropped {
+					break
+				}
+				ms.grow(-1)
+				discard(top)
+				o.verbosef("[source %v] dropped cancelled task %v", s, top)
+				o.emit(Event{Type: EventTaskDropped, Name: s.String(), Task: top})
+				top = nil
 			}
 			// Setup the src channel based on the availability of a task.
 			src = source
 			if top == nil {
-				if verbose {
-					log.Printf("[source %v] no task available, none will be sent", s)
-				}
+				o.verbosef("[source %v] no task available, none will be sent", s)
 				src = nil
 			}
+			// Stop accepting tasks on add if we are full.
+			addc := add
+			if capacity > 0 && ms.Len() >= capacity {
+				o.verbosef("[source %v] at capacity, not accepting tasks", s)
+				addc = nil
+			}
 			select {
 			case _, ok := <-wakeup:
 				if !ok {
 					wakeup = nil
-					if verbose {
-						log.Printf("[source %v] wakeup closed, no longer selecting with it", s)
-					}
+					o.verbosef("[source %v] wakeup closed, no longer selecting with it", s)
 				} else {
-					if verbose {
-						log.Printf("[source %v] got a wakeup signal", s)
-					}
+					o.verbosef("[source %v] got a wakeup signal", s)
 				}
-			case t, ok := <-add:
+			case t, ok := <-addc:
 				if !ok {
 					add = nil
-					if verbose {
-						log.Printf("[source %v] add closed, no longer selecting with it", s)
-					}
+					o.verbosef("[source %v] add closed, no longer selecting with it", s)
 				}
 				if t != nil {
-					s.Add(t)
-					if verbose {
-						log.Printf("[source %v] added task %v", s, t)
-					}
+					ms.grow(1)
+					ms.add(s, t)
+					o.verbosef("[source %v] added task %v", s, t)
+					o.emit(Event{Type: EventTaskAdded, Name: s.String(), Task: t})
 				}
+			case t := <-ms.tryAdd:
+				ms.add(s, t)
+				o.verbosef("[source %v] added task %v", s, t)
+				o.emit(Event{Type: EventTaskAdded, Name: s.String(), Task: t})
 			case <-ctx.Done():
-				if verbose {
-					log.Printf("[source %v] stop requested", s)
-				}
-				if top != nil {
+				o.verbosef("[source %v] stop requested", s)
+				if top != nil && ms.requeue(ct) {
 					s.Add(top)
-					if verbose {
-						log.Printf("[source %v] added back task %v", s, top)
-					}
+					o.verbosef("[source %v] added back task %v", s, top)
+				} else if top != nil {
+					ms.grow(-1)
 				}
+				o.emit(Event{Type: EventSourceStopped, Name: s.String(),
+					Err: ctx.Err()})
 				wg.Done()
 				return
-			case src <- top:
-				if verbose {
-					log.Printf("[source %v] sent task %v", s, top)
-				}
-				top = nil
+			case src <- out:
+				o.verbosef("[source %v] sent task %v", s, top)
+				o.emit(Event{Type: EventTaskSent, Name: s.String(), Task: top})
+				top, out, ct = nil, nil, nil
+				sent++
+				ms.grow(-1)
+			case c := <-ms.query:
+				c <- sourceState{empty: top == nil, sent: sent}
 			}
 		}
 	}()
-	return &ManagedSource{Source: source, Add: add, wg: &wg}
+	return ms
 }
 
 // PriorityTask is a Task that has a priority.
@@ -160,8 +306,22 @@ func (t *pt) Run(c context.Context) { t.t.Run(c) }
 // PriorityQueue is an implementation of a Sourcer using a priority
 // queue. Higher priority tasks will be done first.
 type PriorityQueue struct {
-	q    *pq
-	name string
+	q     *pq
+	name  string
+	aging AgingFunc
+	seq   uint64
+}
+
+// AgingFunc returns the effective priority of a task with the given
+// priority that has been waiting in a queue for the given duration.
+type AgingFunc func(priority int, waited time.Duration) int
+
+// LinearAging returns an AgingFunc that increases the priority of a
+// task by one for every step it has been waiting.
+func LinearAging(step time.Duration) AgingFunc {
+	return func(priority int, waited time.Duration) int {
+		return priority + int(waited/step)
+	}
 }
 
 // NewPriorityQueue creates a new PriorityQueue.
@@ -171,6 +331,21 @@ func NewPriorityQueue(name string) *PriorityQueue {
 	return q
 }
 
+// NewAgingPriorityQueue creates a new PriorityQueue where the priority
+// of tasks is adjusted by the aging function based on how long they
+// have been waiting. This prevents low priority tasks from starving
+// behind a steady stream of high priority tasks. Of tasks with the
+// same effective priority, the one that has waited longest is done
+// first.
+//
+// Since effective priorities change over time, Next() has to look at
+// every task in the queue.
+func NewAgingPriorityQueue(name string, aging AgingFunc) *PriorityQueue {
+	q := NewPriorityQueue(name)
+	q.aging = aging
+	return q
+}
+
 func (q *PriorityQueue) String() string {
 	return q.name
 }
@@ -180,33 +355,147 @@ func (q *PriorityQueue) Next() Task {
 	if q.q.Len() < 1 {
 		return nil
 	}
-	return heap.Pop(q.q).(Task)
+	if q.aging == nil {
+		return heap.Pop(q.q).(*pqItem).t
+	}
+	now := time.Now()
+	best, bestp := 0, 0
+	for x, i := range *q.q {
+		p := q.aging(i.t.Priority(), now.Sub(i.added))
+		if x == 0 || p > bestp || (p == bestp && i.seq < (*q.q)[best].seq) {
+			best, bestp = x, p
+		}
+	}
+	return heap.Remove(q.q, best).(*pqItem).t
 }
 
 // Add implements Sourcer.Add.
 func (q *PriorityQueue) Add(t Task) {
-	if p, ok := t.(PriorityTask); ok {
-		heap.Push(q.q, p)
-	} else {
-		heap.Push(q.q, NewPriorityTask(t, 0))
+	p, ok := t.(PriorityTask)
+	if !ok {
+		p = NewPriorityTask(t, 0)
 	}
+	heap.Push(q.q, &pqItem{t: p, added: time.Now(), seq: q.seq})
+	q.seq++
+}
+
+// pqItem is a task in our priority queue along with when it was
+// added for aging.
+type pqItem struct {
+	t     PriorityTask
+	added time.Time
+	seq   uint64
 }
 
 // Our internal representation of priority queue.
-type pq []PriorityTask
+type pq []*pqItem
 
 func (q pq) Len() int           { return len(q) }
-func (q pq) Less(i, j int) bool { return q[i].Priority() > q[j].Priority() }
+func (q pq) Less(i, j int) bool { return q[i].t.Priority() > q[j].t.Priority() }
 func (q pq) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
 
 func (q *pq) Push(x interface{}) {
-	*q = append(*q, x.(PriorityTask))
+	*q = append(*q, x.(*pqItem))
 }
 
 func (q *pq) Pop() interface{} {
 	old := *q
 	n := len(old)
 	t := old[n-1]
+	old[n-1] = nil
 	*q = old[0 : n-1]
 	return t
 }
+
+// FIFOQueue is an implementation of a Sourcer using a first-in
+// first-out queue. Tasks are done in the order they are added. It is
+// backed by a ring buffer that grows as needed.
+type FIFOQueue struct {
+	buf  []Task
+	head int
+	n    int
+	name string
+}
+
+// NewFIFOQueue creates a new FIFOQueue.
+func NewFIFOQueue(name string) *FIFOQueue {
+	return &FIFOQueue{name: name}
+}
+
+func (q *FIFOQueue) String() string {
+	return q.name
+}
+
+// Len returns the number of tasks in the queue.
+func (q *FIFOQueue) Len() int {
+	return q.n
+}
+
+// Next implements Sourcer.Next.
+func (q *FIFOQueue) Next() Task {
+	if q.n < 1 {
+		return nil
+	}
+	t := q.buf[q.head]
+	q.buf[q.head] = nil
+	q.head = (q.head + 1) % len(q.buf)
+	q.n--
+	return t
+}
+
+// Add implements Sourcer.Add.
+func (q *FIFOQueue) Add(t Task) {
+	if q.n == len(q.buf) {
+		// Grow the buffer and unwrap the tasks into it.
+		size := len(q.buf) * 2
+		if size == 0 {
+			size = 16
+		}
+		buf := make([]Task, size)
+		n := copy(buf, q.buf[q.head:])
+		copy(buf[n:], q.buf[:q.head])
+		q.buf = buf
+		q.head = 0
+	}
+	q.buf[(q.head+q.n)%len(q.buf)] = t
+	q.n++
+}
+
+// LIFOQueue is an implementation of a Sourcer using a last-in
+// first-out queue (a stack). The most recently added task is done
+// first.
+type LIFOQueue struct {
+	s    []Task
+	name string
+}
+
+// NewLIFOQueue creates a new LIFOQueue.
+func NewLIFOQueue(name string) *LIFOQueue {
+	return &LIFOQueue{name: name}
+}
+
+func (q *LIFOQueue) String() string {
+	return q.name
+}
+
+// Len returns the number of tasks in the queue.
+func (q *LIFOQueue) Len() int {
+	return len(q.s)
+}
+
+// Next implements Sourcer.Next.
+func (q *LIFOQueue) Next() Task {
+	n := len(q.s)
+	if n < 1 {
+		return nil
+	}
+	t := q.s[n-1]
+	q.s[n-1] = nil
+	q.s = q.s[:n-1]
+	return t
+}
+
+// Add implements Sourcer.Add.
+func (q *LIFOQueue) Add(t Task) {
+	q.s = append(q.s, t)
+}
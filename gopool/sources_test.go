@@ -94,6 +94,64 @@ func TestNewSource(t *testing.T) {
 	}
 }
 
+func TestBoundedManagedSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ms := NewBoundedManagedSource(NewFIFOQueue("test"), 3, false, nil, ctx)
+
+	// Fill it up.
+	ms.Add <- &sct{name: "a"}
+	if err := ms.TryAdd(&sct{name: "b"}); err != nil {
+		t.Fatalf("TryAdd(b) failed: %v", err)
+	}
+	ms.Add <- &sct{name: "c"}
+	if err := ms.TryAdd(&sct{name: "d"}); err != ErrQueueFull {
+		t.Errorf("TryAdd(d) on a full source didn't return ErrQueueFull: %v", err)
+	}
+	if ms.Len() != 3 {
+		t.Errorf("ms.Len() != 3: %v", ms.Len())
+	}
+
+	// A blocking add should wait until there is room.
+	added := make(chan struct{})
+	go func() {
+		ms.Add <- &sct{name: "e"}
+		close(added)
+	}()
+	select {
+	case <-added:
+		t.Fatalf("Add on a full source didn't block")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if c := <-ms.Source; c.String() != "a" {
+		t.Errorf("didn't get a from source: %v", c)
+	}
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Fatalf("Add didn't unblock after a task was taken")
+	}
+
+	exp := []string{"b", "c", "e"}
+	for _, e := range exp {
+		if c := <-ms.Source; c.String() != e {
+			t.Errorf("didn't get %v from source: %v", e, c)
+		}
+	}
+	// The size is updated after the send completes.
+	for x := 0; x < 100 && ms.Len() != 0; x++ {
+		time.Sleep(time.Millisecond)
+	}
+	if ms.Len() != 0 {
+		t.Errorf("ms.Len() != 0 after taking everything: %v", ms.Len())
+	}
+
+	cancel()
+	ms.Wait()
+	if err := ms.TryAdd(&sct{name: "f"}); err != ErrStopped {
+		t.Errorf("TryAdd() after stopping didn't return ErrStopped: %v", err)
+	}
+}
+
 func TestPriorityTask(t *testing.T) {
 	buf := &bytes.Buffer{}
 	c := &sct{name: "test", w: buf}
@@ -158,6 +216,90 @@ func TestPriorityQueue(t *testing.T) {
 	}
 }
 
+func TestAgingPriorityQueue(t *testing.T) {
+	q := NewAgingPriorityQueue("test", LinearAging(10*time.Millisecond))
+	if q.Next() != nil {
+		t.Fatalf("q.Next() != nil after NewAgingPriorityQueue()")
+	}
+
+	// Add some low priority tasks and let them age.
+	buf := &bytes.Buffer{}
+	q.Add(NewPriorityTask(&sct{name: "a", w: buf}, 0))
+	q.Add(NewPriorityTask(&sct{name: "b", w: buf}, 1))
+	q.Add(&sct{name: "c", w: buf})
+	time.Sleep(35 * time.Millisecond)
+
+	// These are higher priority but the old ones have aged past them.
+	q.Add(NewPriorityTask(&sct{name: "d", w: buf}, 2))
+	q.Add(NewPriorityTask(&sct{name: "e", w: buf}, 10))
+	for c := q.Next(); c != nil; c = q.Next() {
+		c.Run(nil)
+	}
+	if buf.String() != "ebacd" {
+		t.Errorf("aging wasn't properly applied. Expected ebacd, but got %v", buf.String())
+	}
+}
+
+func TestFIFOQueue(t *testing.T) {
+	q := NewFIFOQueue("test")
+	if q.String() != "test" {
+		t.Errorf(`q.String() != "test": %v`, q.String())
+	}
+	if q.Next() != nil {
+		t.Fatalf("q.Next() != nil after NewFIFOQueue()")
+	}
+
+	// Interleave adds and removes so the ring buffer wraps and grows.
+	buf := &bytes.Buffer{}
+	exp := &bytes.Buffer{}
+	next := 0
+	for x := 0; x < 100; x++ {
+		q.Add(&sct{name: strconv.Itoa(x), w: buf})
+		if x%3 == 0 {
+			q.Next().Run(nil)
+			exp.WriteString(strconv.Itoa(next))
+			next++
+		}
+	}
+	if q.Len() != 100-next {
+		t.Errorf("q.Len() != %v: %v", 100-next, q.Len())
+	}
+	for c := q.Next(); c != nil; c = q.Next() {
+		c.Run(nil)
+		exp.WriteString(strconv.Itoa(next))
+		next++
+	}
+	if buf.String() != exp.String() {
+		t.Errorf("tasks not in FIFO order:\n%v\n%v", buf.String(), exp.String())
+	}
+	if q.Len() != 0 {
+		t.Errorf("q.Len() != 0 after reading all: %v", q.Len())
+	}
+}
+
+func TestLIFOQueue(t *testing.T) {
+	q := NewLIFOQueue("test")
+	if q.String() != "test" {
+		t.Errorf(`q.String() != "test": %v`, q.String())
+	}
+	if q.Next() != nil {
+		t.Fatalf("q.Next() != nil after NewLIFOQueue()")
+	}
+	buf := &bytes.Buffer{}
+	for x := 0; x < 10; x++ {
+		q.Add(&sct{name: strconv.Itoa(x), w: buf})
+	}
+	if q.Len() != 10 {
+		t.Errorf("q.Len() != 10: %v", q.Len())
+	}
+	for c := q.Next(); c != nil; c = q.Next() {
+		c.Run(nil)
+	}
+	if buf.String() != "9876543210" {
+		t.Errorf("tasks not in LIFO order. Expected 9876543210, but got %v", buf.String())
+	}
+}
+
 // sct is a helper for testing that bascially just prints it's name to
 // w and sets the stop channel.
 type sct struct {
@@ -0,0 +1,114 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// TypedTask is like a ResultTask but its result has a specific type.
+type TypedTask[T any] interface {
+	fmt.Stringer
+
+	// Run performs the work for this task and returns its result. The
+	// context should be handled the same way as in Task.Run.
+	Run(context.Context) (T, error)
+}
+
+// NewTypedTask returns a TypedTask that runs the given function. The
+// name is returned by its String() method.
+func NewTypedTask[T any](name string, f func(context.Context) (T, error)) TypedTask[T] {
+	return &typedFunc[T]{name: name, f: f}
+}
+
+// typedFunc is an internal implementation of TypedTask.
+type typedFunc[T any] struct {
+	name string
+	f    func(context.Context) (T, error)
+}
+
+func (t *typedFunc[T]) String() string                     { return t.name }
+func (t *typedFunc[T]) Run(ctx context.Context) (T, error) { return t.f(ctx) }
+
+// Result is the result of running a TypedTask.
+type Result[T any] struct {
+	// Task is the task that was run.
+	Task TypedTask[T]
+
+	// Value and Err are the values returned by the task's Run method.
+	Value T
+	Err   error
+}
+
+// TypedPool is a GoPool that works on TypedTasks and sends their
+// results on a channel.
+type TypedPool[T any] struct {
+	*GoPool
+	results chan Result[T]
+}
+
+// NewTypedPool creates a new TypedPool. The arguments are the same as
+// New() except tasks are read from a channel of TypedTasks.
+//
+// The result of each task is sent on the Results() channel, which
+// must be read from or the workers will block. Once all of the
+// workers have stopped, the Results() channel is closed. Results
+// that can't be sent because the context is done are dropped.
+func NewTypedPool[T any](name string, goroutines int, verbose bool,
+	ctx context.Context, src <-chan TypedTask[T]) *TypedPool[T] {
+	tasks := make(chan Task)
+	p := &TypedPool[T]{
+		GoPool:  New(name, goroutines, verbose, ctx, tasks),
+		results: make(chan Result[T]),
+	}
+	go func() {
+		defer close(tasks)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case t, ok := <-src:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case tasks <- &typedTask[T]{t: t, results: p.results}:
+				}
+			}
+		}
+	}()
+	go func() {
+		p.Wait()
+		close(p.results)
+	}()
+	return p
+}
+
+// Results returns the channel on which the results of the tasks are
+// sent.
+func (p *TypedPool[T]) Results() <-chan Result[T] {
+	return p.results
+}
+
+// typedTask adapts a TypedTask to a Task for the underlying GoPool.
+type typedTask[T any] struct {
+	t       TypedTask[T]
+	results chan<- Result[T]
+}
+
+func (t *typedTask[T]) String() string { return t.t.String() }
+func (t *typedTask[T]) Run(ctx context.Context) {
+	v, err := t.t.Run(ctx)
+	select {
+	case t.results <- Result[T]{Task: t.t, Value: v, Err: err}:
+	case <-ctx.Done():
+	}
+}
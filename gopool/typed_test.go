@@ -0,0 +1,64 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package gopool
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestTypedPool(t *testing.T) {
+	src := make(chan TypedTask[int])
+	pool := NewTypedPool("test-pool", 4, false, context.Background(), src)
+	go func() {
+		for x := 0; x < 100; x++ {
+			x := x
+			src <- NewTypedTask(strconv.Itoa(x), func(ctx context.Context) (int, error) {
+				if x == 50 {
+					return 0, errors.New("fifty")
+				}
+				return x * 2, nil
+			})
+		}
+		close(src)
+	}()
+
+	// Sum them up.
+	sum, n, errs := 0, 0, 0
+	for r := range pool.Results() {
+		n++
+		if r.Err != nil {
+			if r.Task.String() != "50" {
+				t.Errorf("unexpected error from %v: %v", r.Task, r.Err)
+			}
+			errs++
+			continue
+		}
+		sum += r.Value
+	}
+	if n != 100 || errs != 1 {
+		t.Errorf("expected 100 results and 1 error but got %v and %v", n, errs)
+	}
+	if sum != 9800 {
+		t.Errorf("sum != 9800: %v", sum)
+	}
+}
+
+func TestTypedPoolStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	src := make(chan TypedTask[string])
+	pool := NewTypedPool("test-pool", 2, false, ctx, src)
+	src <- NewTypedTask("a", func(ctx context.Context) (string, error) {
+		return "a", nil
+	})
+	cancel()
+	for range pool.Results() {
+	}
+}
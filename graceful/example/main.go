@@ -11,24 +11,12 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/icub3d/gop/graceful"
 )
 
 func main() {
-
-	// Listen for the SIGTERM.
-	sigs := make(chan os.Signal)
-	signal.Notify(sigs, syscall.SIGTERM)
-	go func() {
-		<-sigs
-		log.Print("got SIGHUP, shutting down.")
-		graceful.Close()
-	}()
-
 	// Start the server.
 	fmt.Println("Using PID:", os.Getpid())
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -38,7 +26,8 @@ func main() {
 		log.Printf("%v %v", r.Method, r.URL)
 		fmt.Fprintln(w, r.Method, r.URL)
 	})
-	log.Println(graceful.ListenAndServe(":8080", nil))
+	// It's gracefully closed on SIGINT or SIGTERM.
+	log.Println(graceful.ListenAndServeWithSignals(":8080", nil))
 	// At this point, try opening a few connection in another
 	// terminal. Then in another, send a TERM kignal.
 	// For example, in terminal one:
@@ -15,28 +15,32 @@ import (
 	"net"
 	"net/http"
 	"sync"
+
+	"golang.org/x/net/context"
 )
 
 // DefaultServer is the default Server used by the functions in this
-// package. It's used as to simplify using the graceful server.
+// package. It's used as to simplify using the graceful server. It's
+// created by the first call to ListenAndServe or ListenAndServeTLS, so
+// the functions only support one server per process. Use NewServer to
+// create servers that are closed independently.
 var DefaultServer *Server
 
 // ListenAndServe listens on the TCP network address addr using the
 // DefaultServer. If the handler is nil, http.DefaultServeMux is used.
 func ListenAndServe(addr string, handler http.Handler) error {
-	h := handler
-	if h == nil {
-		h = http.DefaultServeMux
-	}
-	if DefaultServer == nil {
-		DefaultServer = NewServer(&http.Server{Addr: addr, Handler: h})
-	}
-	return DefaultServer.ListenAndServe()
+	return defaultServer(addr, handler).ListenAndServe()
 }
 
 // ListenAndServeTLS acts identically to ListenAndServe except that is
 // expects HTTPS connections using the given certificate and key.
 func ListenAndServeTLS(addr, certFile, keyFile string, handler http.Handler) error {
+	return defaultServer(addr, handler).ListenAndServeTLS(certFile, keyFile)
+}
+
+// defaultServer returns the DefaultServer, creating it with the address
+// and handler if needed.
+func defaultServer(addr string, handler http.Handler) *Server {
 	h := handler
 	if h == nil {
 		h = http.DefaultServeMux
@@ -44,25 +48,54 @@ func ListenAndServeTLS(addr, certFile, keyFile string, handler http.Handler) err
 	if DefaultServer == nil {
 		DefaultServer = NewServer(&http.Server{Addr: addr, Handler: h})
 	}
-	return DefaultServer.ListenAndServeTLS(certFile, keyFile)
+	return DefaultServer
 }
 
 // Close gracefully shuts down the DefaultServer.
 func Close() error {
+	if DefaultServer == nil {
+		return nil
+	}
 	return DefaultServer.Close()
 }
 
-// Server is net/http compatible graceful server.
+// Server is net/http compatible graceful server. It tracks its own
+// listeners and connections, so a process can have several servers,
+// e.g. on different ports, that are closed independently. A server can
+// also serve on several listeners at the same time by calling Serve for
+// each of them.
 type Server struct {
+	// OnShutdownStart is called, if not nil, when Close or Shutdown
+	// starts shutting down the server.
+	OnShutdownStart func()
+
+	// OnConnectionDrained is called, if not nil, when a connection
+	// closes while the server is shutting down with the number of
+	// connections that remain. It can be used to report the progress
+	// of the shutdown.
+	OnConnectionDrained func(remaining int)
+
 	s  *http.Server
 	wg sync.WaitGroup
-	l  net.Listener
+
+	mu        sync.Mutex                 // protects the following.
+	listeners []*listener                // The listeners being served.
+	conns     map[*gracefulConn]struct{} // The open connections.
+	closing   bool                       // Whether it's shutting down.
+}
+
+// listener is a listener being served. l is raw or raw wrapped in TLS.
+// raw was listened on addr and is what Restart passes on.
+type listener struct {
+	l, raw net.Listener
+	addr   string
 }
 
 // NewServer turns the given net/http server into a graceful server.
 func NewServer(srv *http.Server) *Server {
 	return &Server{
-		s: srv,
+		s:     srv,
+		conns: map[*gracefulConn]struct{}{},
 	}
 }
 
@@ -70,18 +103,17 @@ func NewServer(srv *http.Server) *Server {
 // that it gracefully shuts down when Close() is called. When that
 // occurs, no new connections will be allowed and existing connections
 // will be allowed to finish. This will not return until all existing
-// connections have closed.
+// connections of the server have closed.
 func (s *Server) ListenAndServe() error {
 	addr := s.s.Addr
 	if addr == "" {
 		addr = ":http"
 	}
-	var err error
-	l, err := net.Listen("tcp", addr)
+	l, err := Listen(addr)
 	if err != nil {
 		return err
 	}
-	return s.Serve(l)
+	return s.serve(addr, l, l)
 }
 
 // ListenAndServeTLS works like ListenAndServe but with TLS using the
@@ -103,29 +135,116 @@ func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
 		return err
 	}
 	config.Certificates = []tls.Certificate{cert}
-	l, err := tls.Listen("tcp", addr, config)
+	l, err := Listen(addr)
 	if err != nil {
 		return err
 	}
-	return s.Serve(l)
+	return s.serve(addr, l, tls.NewListener(l, config))
 }
 
 // Serve works like ListenAndServer but using the given listener.
 func (s *Server) Serve(l net.Listener) error {
-	s.l = l
-	err := s.s.Serve(&gracefulListener{s.l, s})
+	return s.serve(l.Addr().String(), l, l)
+}
+
+// serve serves on l, which is raw or raw wrapped in TLS. raw was
+// listened on addr.
+func (s *Server) serve(addr string, raw, l net.Listener) error {
+	sl := &listener{l: l, raw: raw, addr: addr}
+	s.mu.Lock()
+	s.listeners = append(s.listeners, sl)
+	s.closing = false
+	s.mu.Unlock()
+	err := s.s.Serve(&gracefulListener{l, s})
+	s.mu.Lock()
+	for x, o := range s.listeners {
+		if o == sl {
+			s.listeners = append(s.listeners[:x], s.listeners[x+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
 	s.wg.Wait()
 	return err
 }
 
-// Close gracefully shuts down the listener. This should be called
+// Close gracefully shuts down the listeners. This should be called
 // when the server should stop listening for new connection and finish
-// any open connections.
+// any open connections. The first error from closing a listener is
+// returned.
 func (s *Server) Close() error {
-	err := s.l.Close()
+	s.mu.Lock()
+	start := !s.closing
+	s.closing = true
+	s.mu.Unlock()
+	if start && s.OnShutdownStart != nil {
+		s.OnShutdownStart()
+	}
+	var err error
+	for _, l := range s.serving() {
+		if cerr := l.l.Close(); err == nil {
+			err = cerr
+		}
+	}
 	return err
 }
 
+// ActiveConnections returns the number of open connections, including
+// idle keep-alive connections.
+func (s *Server) ActiveConnections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Addrs returns the addresses of the listeners being served.
+func (s *Server) Addrs() []net.Addr {
+	ls := s.serving()
+	addrs := make([]net.Addr, len(ls))
+	for x, l := range ls {
+		addrs[x] = l.l.Addr()
+	}
+	return addrs
+}
+
+// serving returns a copy of the listeners being served.
+func (s *Server) serving() []*listener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*listener(nil), s.listeners...)
+}
+
+// Shutdown gracefully shuts down the server like Close but also closes
+// idle keep-alive connections and waits for the others to finish. If
+// the context is done first, the remaining connections are closed
+// forcefully and the context's error is returned. Otherwise, the error
+// from closing the listeners is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.s.SetKeepAlivesEnabled(false)
+	err := s.Close()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	conns := make([]*gracefulConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.Close()
+	}
+	<-done
+	return ctx.Err()
+}
+
 // gracefulListener implements the net.Listener interface. When accept
 // for the underlying listener returns a connection, it adds 1 to the
 // servers wait group. The connection will be a gracefulConn which
@@ -141,7 +260,11 @@ func (g *gracefulListener) Accept() (net.Conn, error) {
 		return nil, err
 	}
 	g.s.wg.Add(1)
-	return &gracefulConn{Conn: c, s: g.s}, nil
+	gc := &gracefulConn{Conn: c, s: g.s}
+	g.s.mu.Lock()
+	g.s.conns[gc] = struct{}{}
+	g.s.mu.Unlock()
+	return gc, nil
 }
 
 // gracefulConn implements the net.Conn interface. When it closes, it
@@ -154,6 +277,18 @@ type gracefulConn struct {
 
 func (g *gracefulConn) Close() error {
 	err := g.Conn.Close()
-	g.once.Do(g.s.wg.Done)
+	g.once.Do(g.done)
 	return err
 }
+
+// done removes the connection from the server's open connections.
+func (g *gracefulConn) done() {
+	g.s.mu.Lock()
+	delete(g.s.conns, g)
+	n, closing := len(g.s.conns), g.s.closing
+	g.s.mu.Unlock()
+	if closing && g.s.OnConnectionDrained != nil {
+		g.s.OnConnectionDrained(n)
+	}
+	g.s.wg.Done()
+}
@@ -8,10 +8,15 @@ package graceful
 
 import (
 	"bytes"
+	"net"
 	"net/http"
+	"reflect"
+	"sort"
 	"sync"
 	"testing"
 	"time"
+
+	"golang.org/x/net/context"
 )
 
 func TestListenAndServe(t *testing.T) {
@@ -45,7 +50,7 @@ func TestListenAndServe(t *testing.T) {
 
 	// We need to wait for the server to be setup and running.
 	for {
-		if s.l == nil {
+		if len(s.Addrs()) == 0 {
 			time.Sleep(1 * time.Millisecond)
 		} else {
 			break
@@ -89,3 +94,198 @@ func TestListenAndServe(t *testing.T) {
 		t.Errorf("failed to get all the responses: 11111 %v", r)
 	}
 }
+
+func TestShutdown(t *testing.T) {
+	// One request finishes in time and the other never does.
+	release := make(chan struct{})
+	defer close(release)
+	started := sync.WaitGroup{}
+	started.Add(2)
+	s := NewServer(&http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started.Done()
+			if r.URL.Path == "/hang" {
+				<-release
+			}
+			time.Sleep(10 * time.Millisecond)
+			w.Write([]byte("ok"))
+		}),
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): %v", err)
+	}
+	served := make(chan error)
+	go func() { served <- s.Serve(l) }()
+
+	results := make(chan error, 2)
+	for _, path := range []string{"/", "/hang"} {
+		go func(path string) {
+			resp, err := http.Get("http://" + l.Addr().String() + path)
+			if err == nil {
+				resp.Body.Close()
+			}
+			results <- err
+		}(path)
+	}
+	started.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown() = %v, wanted %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatalf("Serve() didn't return after Shutdown()")
+	}
+	var failed int
+	for x := 0; x < 2; x++ {
+		if err := <-results; err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("%v requests failed, wanted only the hanging one", failed)
+	}
+}
+
+func TestShutdownIdle(t *testing.T) {
+	s := NewServer(&http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}),
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): %v", err)
+	}
+	go s.Serve(l)
+
+	// The client keeps the connection open after the request.
+	resp, err := http.Get("http://" + l.Addr().String())
+	if err != nil {
+		t.Fatalf("http.Get(): %v", err)
+	}
+	resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown() with idle connection = %v", err)
+	}
+}
+
+func TestMultipleServers(t *testing.T) {
+	hello := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	a, b := NewServer(&http.Server{Handler: hello}), NewServer(&http.Server{Handler: hello})
+	served := make(chan error, 3)
+	listen := func(s *Server) net.Listener {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("net.Listen(): %v", err)
+		}
+		go func() { served <- s.Serve(l) }()
+		return l
+	}
+
+	// a serves on two listeners.
+	la1, la2, lb := listen(a), listen(a), listen(b)
+	for len(a.Addrs()) != 2 || len(b.Addrs()) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	for _, l := range []net.Listener{la1, la2, lb} {
+		if body := get(t, "http://"+l.Addr().String()); body != "hello" {
+			t.Errorf("GET %v = %q", l.Addr(), body)
+		}
+	}
+
+	// Closing a closes both of its listeners but b keeps serving.
+	if err := a.Close(); err != nil {
+		t.Errorf("Close(): %v", err)
+	}
+	<-served
+	<-served
+	if l := len(a.Addrs()); l != 0 {
+		t.Errorf("a has %v listeners after Close()", l)
+	}
+	for _, l := range []net.Listener{la1, la2} {
+		if _, err := http.Get("http://" + l.Addr().String()); err == nil {
+			t.Errorf("GET %v succeeded after Close()", l.Addr())
+		}
+	}
+	if body := get(t, "http://"+lb.Addr().String()); body != "hello" {
+		t.Errorf("GET of other server = %q", body)
+	}
+	b.Close()
+	<-served
+}
+
+func TestDrainHooks(t *testing.T) {
+	release := make(chan struct{})
+	s := NewServer(&http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.Write([]byte("ok"))
+		}),
+	})
+	var (
+		mu      sync.Mutex
+		starts  int
+		drained []int
+	)
+	s.OnShutdownStart = func() {
+		mu.Lock()
+		starts++
+		mu.Unlock()
+	}
+	s.OnConnectionDrained = func(remaining int) {
+		mu.Lock()
+		drained = append(drained, remaining)
+		mu.Unlock()
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): %v", err)
+	}
+	served := make(chan error)
+	go func() { served <- s.Serve(l) }()
+
+	// Connections that close before shutting down aren't reported.
+	go func() { release <- struct{}{} }()
+	get(t, "http://"+l.Addr().String())
+	for s.ActiveConnections() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	for x := 0; x < 3; x++ {
+		go func() {
+			get(t, "http://"+l.Addr().String())
+			done <- struct{}{}
+		}()
+	}
+	for s.ActiveConnections() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	s.Close()
+	s.Close()
+	close(release)
+	for x := 0; x < 3; x++ {
+		<-done
+	}
+	<-served
+
+	mu.Lock()
+	defer mu.Unlock()
+	if starts != 1 {
+		t.Errorf("OnShutdownStart called %v times, wanted 1", starts)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(drained)))
+	if !reflect.DeepEqual(drained, []int{2, 1, 0}) {
+		t.Errorf("OnConnectionDrained got %v, wanted [2 1 0]", drained)
+	}
+}
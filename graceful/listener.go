@@ -0,0 +1,83 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package graceful
+
+import (
+	"net"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// Listener is a net.Listener that tracks the connections it accepts so
+// they can be drained like those of a Server. It lets servers other
+// than net/http's, e.g. gRPC or raw TCP protocols, be shut down
+// gracefully. Instantiate it with WrapListener.
+type Listener struct {
+	net.Listener
+
+	// OnShutdownStart is called, if not nil, when Close or Shutdown
+	// starts shutting down the listener.
+	OnShutdownStart func()
+
+	// OnConnectionDrained is called, if not nil, when a connection
+	// closes while the listener is shutting down with the number of
+	// connections that remain.
+	OnConnectionDrained func(remaining int)
+
+	s *Server // Tracks the connections. It isn't served.
+}
+
+// WrapListener wraps the listener so the connections it accepts are
+// tracked. The connections must be closed by the server using them for
+// the listener to be drained.
+func WrapListener(l net.Listener) *Listener {
+	gl := &Listener{Listener: l, s: NewServer(&http.Server{})}
+	gl.s.OnShutdownStart = func() {
+		if gl.OnShutdownStart != nil {
+			gl.OnShutdownStart()
+		}
+	}
+	gl.s.OnConnectionDrained = func(remaining int) {
+		if gl.OnConnectionDrained != nil {
+			gl.OnConnectionDrained(remaining)
+		}
+	}
+	gl.s.listeners = []*listener{{l: l, raw: l, addr: l.Addr().String()}}
+	return gl
+}
+
+// Accept waits for and returns the next connection. It's tracked until
+// it's closed.
+func (l *Listener) Accept() (net.Conn, error) {
+	return (&gracefulListener{l.Listener, l.s}).Accept()
+}
+
+// Close stops accepting connections. Unlike Server.Close, it doesn't
+// wait for the open connections, as servers typically close their
+// listener before closing their connections. Use Wait for that.
+func (l *Listener) Close() error {
+	return l.s.Close()
+}
+
+// Wait waits until the connections that were accepted are closed.
+func (l *Listener) Wait() {
+	l.s.wg.Wait()
+}
+
+// Shutdown closes the listener and waits for the connections to be
+// closed. If the context is done first, the remaining connections are
+// closed forcefully and the context's error is returned. Otherwise, the
+// error from closing the listener is returned.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	return l.s.Shutdown(ctx)
+}
+
+// ActiveConnections returns the number of open connections.
+func (l *Listener) ActiveConnections() int {
+	return l.s.ActiveConnections()
+}
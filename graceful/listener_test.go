@@ -0,0 +1,97 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package graceful
+
+import (
+	"bufio"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// echo serves a line based echo protocol on the listener until it's
+// closed.
+func echo(l net.Listener) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer c.Close()
+			r := bufio.NewReader(c)
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				c.Write([]byte(line))
+			}
+		}()
+	}
+}
+
+func TestWrapListener(t *testing.T) {
+	nl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): %v", err)
+	}
+	l := WrapListener(nl)
+	var started, drained int32
+	l.OnShutdownStart = func() { atomic.AddInt32(&started, 1) }
+	l.OnConnectionDrained = func(remaining int) { atomic.AddInt32(&drained, 1) }
+	go echo(l)
+
+	dial := func() (net.Conn, *bufio.Reader) {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("net.Dial(): %v", err)
+		}
+		return c, bufio.NewReader(c)
+	}
+	a, ar := dial()
+	b, br := dial()
+	for l.ActiveConnections() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The open connections keep working after it's closed.
+	if err := l.Close(); err != nil {
+		t.Errorf("Close(): %v", err)
+	}
+	if c, err := net.Dial("tcp", l.Addr().String()); err == nil {
+		t.Errorf("net.Dial() succeeded after Close()")
+		c.Close()
+	}
+	a.Write([]byte("hello\n"))
+	if line, err := ar.ReadString('\n'); err != nil || line != "hello\n" {
+		t.Errorf("echo = %q, %v", line, err)
+	}
+	a.Close()
+	for l.ActiveConnections() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The connection that doesn't close is closed by Shutdown.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown() = %v, wanted %v", err, context.DeadlineExceeded)
+	}
+	l.Wait()
+	if _, err := br.ReadString('\n'); err == nil {
+		t.Errorf("connection open after Shutdown()")
+	}
+	b.Close()
+	s, d := atomic.LoadInt32(&started), atomic.LoadInt32(&drained)
+	if s != 1 || d != 2 {
+		t.Errorf("hooks called %v and %v times, wanted 1 and 2", s, d)
+	}
+}
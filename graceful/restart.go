@@ -0,0 +1,126 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package graceful
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// EnvListeners is the environment variable Restart uses to tell the new
+// process the addresses of the listeners it passes on. The listener for
+// the i-th address is file descriptor 3+i.
+const EnvListeners = "GRACEFUL_LISTENERS"
+
+// ErrNoFile is returned by Restart when one of the server's listeners
+// can't be passed on because it doesn't have a file, e.g. it isn't a
+// TCP or Unix listener.
+var ErrNoFile = errors.New("listener has no file")
+
+// restartArgs returns the arguments of the new process. This is for
+// testing.
+var restartArgs = func() []string { return os.Args[1:] }
+
+var (
+	inheritOnce sync.Once
+	inheritMu   sync.Mutex
+	inherited   map[string]net.Listener // The unused inherited listeners.
+)
+
+// Listen listens on the TCP network address addr. If the process was
+// started by Restart with a listener for addr, that listener is
+// returned instead, so the new process keeps accepting the connections
+// of the old one. Each inherited listener is only returned once.
+func Listen(addr string) (net.Listener, error) {
+	inheritOnce.Do(inherit)
+	inheritMu.Lock()
+	l, ok := inherited[addr]
+	delete(inherited, addr)
+	inheritMu.Unlock()
+	if ok {
+		return l, nil
+	}
+	return net.Listen("tcp", addr)
+}
+
+// inherit loads the listeners passed on by Restart. The ones that can't
+// be loaded are ignored, so Listen creates new ones.
+func inherit() {
+	inherited = map[string]net.Listener{}
+	v := os.Getenv(EnvListeners)
+	if v == "" {
+		return
+	}
+	for x, addr := range strings.Split(v, ",") {
+		f := os.NewFile(uintptr(3+x), addr)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			continue
+		}
+		inherited[addr] = l
+	}
+}
+
+// Restart starts a new copy of the program with the same arguments,
+// environment and standard files and passes it the server's listeners.
+// If the new process listens on the same addresses with Listen (e.g.
+// with ListenAndServe), it gets the listeners and starts accepting
+// connections on them. The server is then gracefully closed (see
+// Close), so it finishes the open connections but no connections are
+// refused in between. This allows upgrading the program's binary
+// without downtime. It returns the new process.
+//
+// Passing listeners isn't supported on Windows.
+func (s *Server) Restart() (*os.Process, error) {
+	var (
+		files []*os.File
+		addrs []string
+	)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, l := range s.serving() {
+		fl, ok := l.raw.(interface {
+			File() (*os.File, error)
+		})
+		if !ok {
+			return nil, ErrNoFile
+		}
+		f, err := fl.File()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		addrs = append(addrs, l.addr)
+	}
+	path, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(path, restartArgs()...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, EnvListeners+"=") {
+			cmd.Env = append(cmd.Env, e)
+		}
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%v=%v", EnvListeners,
+		strings.Join(addrs, ",")))
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd.Process, s.Close()
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package graceful
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// get returns the body of a GET of the url without keeping the
+// connection open.
+func get(t *testing.T, url string) string {
+	c := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Errorf("Get(%v): %v", url, err)
+		return ""
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return string(b)
+}
+
+func TestRestart(t *testing.T) {
+	if os.Getenv("GRACEFUL_TEST_RESTART") == "1" {
+		// This is the new process. It serves one request on the
+		// inherited listener.
+		addr := os.Getenv(EnvListeners)
+		l, err := Listen(addr)
+		if err != nil {
+			t.Fatalf("Listen(%v): %v", addr, err)
+		}
+		s := NewServer(&http.Server{})
+		s.s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("child"))
+			go s.Close()
+		})
+		s.Serve(l)
+		return
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("passing listeners isn't supported on windows")
+	}
+
+	release := make(chan struct{})
+	s := NewServer(&http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.Write([]byte("parent"))
+		}),
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): %v", err)
+	}
+	url := "http://" + l.Addr().String()
+	served := make(chan error)
+	go func() { served <- s.Serve(l) }()
+
+	// A request is open when the server is restarted.
+	parent := make(chan string)
+	go func() { parent <- get(t, url) }()
+	for {
+		s.mu.Lock()
+		n := len(s.conns)
+		s.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	os.Setenv("GRACEFUL_TEST_RESTART", "1")
+	restartArgs = func() []string { return []string{"-test.run=^TestRestart$"} }
+	p, err := s.Restart()
+	os.Unsetenv("GRACEFUL_TEST_RESTART")
+	restartArgs = func() []string { return os.Args[1:] }
+	if err != nil {
+		t.Fatalf("Restart(): %v", err)
+	}
+
+	// New requests go to the new process and the open one finishes.
+	if b := get(t, url); b != "child" {
+		t.Errorf("request after Restart() = %q, wanted child", b)
+	}
+	close(release)
+	if b := <-parent; b != "parent" {
+		t.Errorf("open request = %q, wanted parent", b)
+	}
+	<-served
+	st, err := p.Wait()
+	if err != nil || !st.Success() {
+		t.Errorf("new process exited with %v, %v", st, err)
+	}
+
+	// Only listeners with files can be passed on.
+	s = NewServer(&http.Server{})
+	s.listeners = []*listener{{raw: &gracefulListener{Listener: l}}}
+	if _, err := s.Restart(); err != ErrNoFile {
+		t.Errorf("Restart() without file = %v, wanted %v", err, ErrNoFile)
+	}
+}
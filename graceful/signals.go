@@ -0,0 +1,46 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package graceful
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/icub3d/gop/signalhandler"
+	"golang.org/x/net/context"
+)
+
+// ListenAndServeWithSignals is like ListenAndServe but the
+// DefaultServer is gracefully closed when one of the given signals is
+// received. If none are given, SIGINT and SIGTERM are used. If a signal
+// is received again while the server is closing, the open connections
+// are closed forcefully. The signals are no longer caught once it
+// returns. The DefaultServer's OnShutdownStart hook can be used to
+// report the shutdown.
+func ListenAndServeWithSignals(addr string, handler http.Handler, sigs ...os.Signal) error {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{signalhandler.SigInt, signalhandler.SigTerm}
+	}
+	s := defaultServer(addr, handler)
+	sh := signalhandler.New()
+	defer sh.Stop()
+	closing := false
+	f := func() {
+		if !closing {
+			closing = true
+			s.Close()
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s.Shutdown(ctx)
+	}
+	for _, sig := range sigs {
+		sh.Watch(sig, f)
+	}
+	return s.ListenAndServe()
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package graceful
+
+import (
+	"net/http"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestListenAndServeWithSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("processes can't send themselves signals on windows")
+	}
+	defer func() { DefaultServer = nil }()
+
+	release := make(chan struct{})
+	DefaultServer = NewServer(&http.Server{
+		Addr: "127.0.0.1:0",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.Write([]byte("ok"))
+		}),
+	})
+	served := make(chan error)
+	go func() { served <- ListenAndServeWithSignals("", nil, os.Interrupt) }()
+	for len(DefaultServer.Addrs()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	url := "http://" + DefaultServer.Addrs()[0].String()
+	open := make(chan error)
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		open <- err
+	}()
+	for DefaultServer.ActiveConnections() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The first signal stops accepting connections.
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("FindProcess(): %v", err)
+	}
+	p.Signal(os.Interrupt)
+	for len(DefaultServer.Addrs()) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-served:
+		t.Fatalf("ListenAndServeWithSignals() returned with an open connection")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// The second closes the open connection.
+	p.Signal(os.Interrupt)
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatalf("ListenAndServeWithSignals() didn't return after second signal")
+	}
+	close(release)
+	if err := <-open; err == nil {
+		t.Errorf("open request succeeded, wanted it to be closed")
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/icub3d/gop/algo"
+)
+
+// The layout of the integrity header: a magic number (4), the size of
+// the pages (4), the length of the buffer that was checked (8) and the
+// Merkle root of its pages (32). The rest is reserved.
+const (
+	integrityMagic      = 0x494e5447 // "INTG"
+	integrityPageSize   = 4096
+	integrityHeaderSize = 64
+)
+
+// ErrChecksum is returned when the checksum of a file doesn't match
+// its contents. This usually means it wasn't synced before the program
+// crashed.
+var ErrChecksum = errors.New("checksum doesn't match the contents")
+
+// checkIntegrity splits the header from the buffer and verifies the
+// checksum. If the file doesn't have a header yet, one is written.
+func (m *Mmap) checkIntegrity() error {
+	if len(m.Buf) < integrityHeaderSize {
+		return errors.New("file is too small for an integrity header")
+	}
+	m.header, m.Buf = m.Buf[:integrityHeaderSize], m.Buf[integrityHeaderSize:]
+	h := m.header
+	if binary.BigEndian.Uint32(h) != integrityMagic {
+		if !bytes.Equal(h, make([]byte, integrityHeaderSize)) {
+			return errors.New("file doesn't have an integrity header")
+		}
+		if !m.writable {
+			return errors.New("file doesn't have an integrity header and can't be written")
+		}
+		return m.Sync()
+	}
+	if binary.BigEndian.Uint32(h[4:]) != integrityPageSize {
+		return ErrChecksum
+	}
+	n := binary.BigEndian.Uint64(h[8:])
+	if n > uint64(len(m.Buf)) || !bytes.Equal(h[16:48], checksum(m.Buf[:n])) {
+		return ErrChecksum
+	}
+	if n != uint64(len(m.Buf)) && m.writable {
+		// The file has grown, so include the rest.
+		return m.Sync()
+	}
+	return nil
+}
+
+// writeChecksum updates the header with the checksum of the buffer.
+func (m *Mmap) writeChecksum() {
+	h := m.header
+	binary.BigEndian.PutUint32(h, integrityMagic)
+	binary.BigEndian.PutUint32(h[4:], integrityPageSize)
+	binary.BigEndian.PutUint64(h[8:], uint64(len(m.Buf)))
+	copy(h[16:48], checksum(m.Buf))
+}
+
+// checksum returns the Merkle root of the pages of b.
+func checksum(b []byte) []byte {
+	var pages [][]byte
+	for len(b) > integrityPageSize {
+		pages = append(pages, b[:integrityPageSize])
+		b = b[integrityPageSize:]
+	}
+	pages = append(pages, b)
+	mt := algo.NewMerkleTree(pages, sha256.New())
+	return mt.Root()
+}
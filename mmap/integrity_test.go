@@ -0,0 +1,86 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestIntegrity(t *testing.T) {
+	file, err := ioutil.TempFile("", "test_mmap_integrity")
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	name := file.Name()
+	defer os.Remove(name)
+	file.Close()
+
+	opts := Options{Perms: 0644, Size: 10000, Integrity: true}
+	m, err := NewWithOptions(name, opts)
+	if err != nil {
+		t.Fatalf("NewWithOptions(%v): %v", name, err)
+	}
+	if len(m.Buf) != 10000 {
+		t.Errorf("unexpected buffer length: %v", len(m.Buf))
+	}
+	copy(m.Buf[5000:], "Hello, world!")
+	if err := m.Sync(); err != nil {
+		t.Fatalf("Sync(): %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	// It's verified when it's opened again, even if it's read-only.
+	ro := Options{ReadOnly: true, Integrity: true}
+	m, err = NewWithOptions(name, ro)
+	if err != nil {
+		t.Fatalf("NewWithOptions(%v) read-only: %v", name, err)
+	}
+	if string(m.Buf[5000:5013]) != "Hello, world!" {
+		t.Errorf("unexpected buffer: %q", m.Buf[5000:5013])
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	// Growing it includes the new part in the checksum.
+	opts.Size = 20000
+	m, err = NewWithOptions(name, opts)
+	if err != nil {
+		t.Fatalf("NewWithOptions(%v) grown: %v", name, err)
+	}
+	m.Close()
+	if m, err = NewWithOptions(name, ro); err != nil {
+		t.Fatalf("NewWithOptions(%v) after growing: %v", name, err)
+	}
+	m.Close()
+
+	// Changes that weren't synced are detected.
+	m, err = NewWithOptions(name, opts)
+	if err != nil {
+		t.Fatalf("NewWithOptions(%v): %v", name, err)
+	}
+	copy(m.Buf[15000:], "torn")
+	m.Close()
+	if _, err = NewWithOptions(name, ro); err != ErrChecksum {
+		t.Errorf("NewWithOptions() of torn file = %v", err)
+	}
+
+	// Files without a header and regions aren't allowed.
+	if err := ioutil.WriteFile(name, []byte("not checked"), 0644); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	if _, err := NewWithOptions(name, Options{Size: 100, Integrity: true}); err == nil {
+		t.Errorf("NewWithOptions() of a file without a header didn't fail")
+	}
+	if _, err := NewWithOptions(name, Options{Offset: 5, Integrity: true}); err == nil {
+		t.Errorf("NewWithOptions() of a region didn't fail")
+	}
+}
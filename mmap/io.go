@@ -0,0 +1,89 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNegativeOffset is returned when reading, writing or seeking to an
+// offset before the start of the buffer.
+var ErrNegativeOffset = errors.New("negative offset")
+
+// The following make Mmap an io.ReaderAt, io.WriterAt,
+// io.ReadWriteSeeker and io.Closer. Offsets are relative to the start
+// of Buf. The buffer can't grow, so writing past its end writes what
+// fits and returns io.ErrShortWrite. Like the buffer itself, none of
+// these are safe to use concurrently.
+
+// ReadAt reads len(p) bytes from the buffer starting at off. It returns
+// io.EOF if fewer bytes are read because it reached the end.
+func (m *Mmap) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+	if off >= int64(len(m.Buf)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.Buf[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt writes p to the buffer starting at off.
+func (m *Mmap) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+	if off >= int64(len(m.Buf)) {
+		return 0, io.ErrShortWrite
+	}
+	n := copy(m.Buf[off:], p)
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// Read reads from the current offset and advances it.
+func (m *Mmap) Read(p []byte) (int, error) {
+	n, err := m.ReadAt(p, m.pos)
+	m.pos += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// Write writes to the current offset and advances it.
+func (m *Mmap) Write(p []byte) (int, error) {
+	n, err := m.WriteAt(p, m.pos)
+	m.pos += int64(n)
+	return n, err
+}
+
+// Seek sets the offset for the next Read or Write. Seeking past the end
+// is allowed but reads and writes there fail.
+func (m *Mmap) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += m.pos
+	case io.SeekEnd:
+		offset += int64(len(m.Buf))
+	default:
+		return m.pos, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return m.pos, ErrNegativeOffset
+	}
+	m.pos = offset
+	return offset, nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+var (
+	_ io.ReaderAt        = (*Mmap)(nil)
+	_ io.WriterAt        = (*Mmap)(nil)
+	_ io.ReadWriteSeeker = (*Mmap)(nil)
+	_ io.Closer          = (*Mmap)(nil)
+)
+
+func TestIO(t *testing.T) {
+	file, err := ioutil.TempFile("", "test_mmap")
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+	m, err := New(file.Name(), 0644, os.O_RDWR, 16, false)
+	if err != nil {
+		t.Fatalf("New(%v, 0644, os.O_RDWR, 16, false): %v", file.Name(), err)
+	}
+	defer m.Close()
+
+	// Writes.
+	if n, err := m.Write([]byte("Hello, ")); n != 7 || err != nil {
+		t.Errorf("Write() = %v, %v", n, err)
+	}
+	if n, err := m.Write([]byte("world! Goodbye!")); n != 9 || err != io.ErrShortWrite {
+		t.Errorf("Write() past the end = %v, %v", n, err)
+	}
+	if n, err := m.WriteAt([]byte("W"), 7); n != 1 || err != nil {
+		t.Errorf("WriteAt() = %v, %v", n, err)
+	}
+	if n, err := m.WriteAt([]byte("x"), 16); n != 0 || err != io.ErrShortWrite {
+		t.Errorf("WriteAt() at the end = %v, %v", n, err)
+	}
+	if _, err := m.WriteAt([]byte("x"), -1); err != ErrNegativeOffset {
+		t.Errorf("WriteAt() at negative offset = %v", err)
+	}
+
+	// Seeks.
+	tests := []struct {
+		offset int64
+		whence int
+		exp    int64
+		fails  bool
+	}{
+		{offset: 0, whence: io.SeekStart, exp: 0},
+		{offset: 7, whence: io.SeekCurrent, exp: 7},
+		{offset: -3, whence: io.SeekEnd, exp: 13},
+		{offset: -20, whence: io.SeekEnd, exp: 13, fails: true},
+		{offset: 0, whence: 7, exp: 13, fails: true},
+	}
+	for k, test := range tests {
+		o, err := m.Seek(test.offset, test.whence)
+		if o != test.exp || (err != nil) != test.fails {
+			t.Errorf("Test %v: Seek(%v, %v) = %v, %v", k, test.offset,
+				test.whence, o, err)
+		}
+	}
+
+	// Reads.
+	m.Seek(0, io.SeekStart)
+	b, err := ioutil.ReadAll(m)
+	if string(b) != "Hello, World! Go" || err != nil {
+		t.Errorf("ReadAll() = %q, %v", b, err)
+	}
+	p := make([]byte, 5)
+	if n, err := m.ReadAt(p, 7); n != 5 || err != nil || string(p) != "World" {
+		t.Errorf("ReadAt() = %v, %v, %q", n, err, p)
+	}
+	if n, err := m.ReadAt(p, 14); n != 2 || err != io.EOF {
+		t.Errorf("ReadAt() past the end = %v, %v", n, err)
+	}
+	if n, err := m.ReadAt(p, 16); n != 0 || err != io.EOF {
+		t.Errorf("ReadAt() at the end = %v, %v", n, err)
+	}
+	if _, err := m.ReadAt(p, -1); err != ErrNegativeOffset {
+		t.Errorf("ReadAt() at negative offset = %v", err)
+	}
+}
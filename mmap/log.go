@@ -0,0 +1,179 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"os"
+)
+
+// The size of the parts of a log record around the data: the length
+// (4) before it and a CRC32 of both (4) after it.
+const (
+	logHeaderSize = 4
+	logRecordSize = logHeaderSize + 4
+)
+
+// The smallest size of a log file.
+const minLogSize = 4096
+
+// ErrCorrupt is returned when reading a log record that is incomplete
+// or whose checksum doesn't match.
+var ErrCorrupt = errors.New("corrupt record")
+
+// ErrRecordTooLarge is returned by Append when the data is too large
+// for the length of a record.
+var ErrRecordTooLarge = errors.New("record is too large")
+
+// maxLogData is the most data a record can have. It's a variable so
+// the tests can lower it.
+var maxLogData int64 = math.MaxUint32
+
+// Log is an append-only log of records stored in a mapped file. Each
+// record is the length of the data, the data and a CRC32 of both. The
+// file grows as records are appended. When it's opened, the records
+// are checked and the log ends at the first one that is corrupt, so a
+// record torn by a crash is discarded. Like Mmap, it's not safe to use
+// concurrently.
+type Log struct {
+	m     *Mmap
+	name  string
+	perms os.FileMode
+	end   int64 // The offset after the last record.
+}
+
+// OpenLog opens the log in the given file, creating it if it doesn't
+// exist. The file is initially at least size bytes.
+func OpenLog(name string, perms os.FileMode, size int64) (*Log, error) {
+	if size < minLogSize {
+		size = minLogSize
+	}
+	m, err := New(name, perms, os.O_CREATE|os.O_RDWR, size, false)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{m: m, name: name, perms: perms}
+	for {
+		_, next, err := l.record(l.end)
+		if err != nil {
+			break
+		}
+		l.end = next
+	}
+	return l, nil
+}
+
+// Size returns the number of bytes used by the records. It's the
+// offset the next record will be appended at.
+func (l *Log) Size() int64 {
+	return l.end
+}
+
+// Append adds a record with the given data to the end of the log and
+// returns its offset. The file is grown if needed. The record isn't
+// durable until Sync is called. Records with 4GB of data or more
+// return ErrRecordTooLarge.
+func (l *Log) Append(data []byte) (int64, error) {
+	if int64(len(data)) > maxLogData {
+		return 0, ErrRecordTooLarge
+	}
+	need := l.end + int64(logRecordSize+len(data))
+	if need > int64(len(l.m.Buf)) {
+		if err := l.grow(need); err != nil {
+			return 0, err
+		}
+	}
+	off := l.end
+	b := l.m.Buf[off:need]
+	binary.BigEndian.PutUint32(b, uint32(len(data)))
+	copy(b[logHeaderSize:], data)
+	n := logHeaderSize + len(data)
+	binary.BigEndian.PutUint32(b[n:], crc32.ChecksumIEEE(b[:n]))
+	l.end = need
+	return off, nil
+}
+
+// Read returns a copy of the data of the record at the given offset
+// and the offset of the next record.
+func (l *Log) Read(off int64) ([]byte, int64, error) {
+	data, next, err := l.record(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	return append([]byte(nil), data...), next, nil
+}
+
+// Replay calls f with each record starting at the given offset, which
+// should be 0 or one returned by Append or Read. The data is part of
+// the mapping, so it's only valid until f returns. If f returns an
+// error, replaying stops and the error is returned.
+func (l *Log) Replay(off int64, f func(off int64, data []byte) error) error {
+	for off < l.end {
+		data, next, err := l.record(off)
+		if err != nil {
+			return err
+		}
+		if err := f(off, data); err != nil {
+			return err
+		}
+		off = next
+	}
+	return nil
+}
+
+// Sync ensures the appended records are written to disk.
+func (l *Log) Sync() error {
+	return l.m.Sync()
+}
+
+// Close closes the underlying mapping. The log should not be used
+// after this.
+func (l *Log) Close() error {
+	return l.m.Close()
+}
+
+// record returns the data of the record at the given offset and the
+// offset of the next record.
+func (l *Log) record(off int64) ([]byte, int64, error) {
+	if off < 0 {
+		return nil, 0, ErrNegativeOffset
+	}
+	buf := l.m.Buf
+	if off+logRecordSize > int64(len(buf)) {
+		return nil, 0, ErrCorrupt
+	}
+	next := off + logRecordSize + int64(binary.BigEndian.Uint32(buf[off:]))
+	if next > int64(len(buf)) {
+		return nil, 0, ErrCorrupt
+	}
+	n := next - 4
+	if crc32.ChecksumIEEE(buf[off:n]) != binary.BigEndian.Uint32(buf[n:]) {
+		return nil, 0, ErrCorrupt
+	}
+	return buf[off+logHeaderSize : n], next, nil
+}
+
+// grow remaps the file so it's at least need bytes. The size is at
+// least doubled so appends don't remap often. The larger file is
+// mapped before the old mapping is closed, so the log keeps the old
+// one if that fails.
+func (l *Log) grow(need int64) error {
+	size := 2 * int64(len(l.m.Buf))
+	if size < need {
+		size = need
+	}
+	m, err := New(l.name, l.perms, os.O_RDWR, size, false)
+	if err != nil {
+		return err
+	}
+	old := l.m
+	l.m = m
+	return old.Close()
+}
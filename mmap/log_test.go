@@ -0,0 +1,114 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	file, err := ioutil.TempFile("", "test_mmap_log")
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+
+	l, err := OpenLog(file.Name(), 0644, 0)
+	if err != nil {
+		t.Fatalf("OpenLog(%v, 0644, 0): %v", file.Name(), err)
+	}
+	big := bytes.Repeat([]byte("x"), 10000)
+	records := [][]byte{[]byte("one"), {}, big, []byte("four")}
+	var offs []int64
+	for _, r := range records {
+		off, err := l.Append(r)
+		if err != nil {
+			t.Fatalf("Append(): %v", err)
+		}
+		offs = append(offs, off)
+	}
+	if exp := []int64{0, 11, 19, 10027}; !reflect.DeepEqual(offs, exp) {
+		t.Errorf("expected offsets %v but got %v", exp, offs)
+	}
+
+	// Records too large for their length are rejected.
+	maxLogData = 10
+	if _, err := l.Append(big); err != ErrRecordTooLarge {
+		t.Errorf("Append() of too large record = %v", err)
+	}
+	maxLogData = math.MaxUint32
+	if err := l.Sync(); err != nil {
+		t.Fatalf("Sync(): %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	// Reopen it and check the records.
+	l, err = OpenLog(file.Name(), 0644, 0)
+	if err != nil {
+		t.Fatalf("OpenLog(%v, 0644, 0): %v", file.Name(), err)
+	}
+	if l.Size() != 10039 {
+		t.Errorf("Size() after reopening = %v", l.Size())
+	}
+	data, next, err := l.Read(offs[2])
+	if err != nil || next != offs[3] || !bytes.Equal(data, big) {
+		t.Errorf("Read(%v) = %v bytes, %v, %v", offs[2], len(data), next, err)
+	}
+	var got [][]byte
+	err = l.Replay(offs[1], func(off int64, data []byte) error {
+		got = append(got, append([]byte{}, data...))
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(got, records[1:]) {
+		t.Errorf("Replay(%v) = %v records, %v", offs[1], len(got), err)
+	}
+	stop := errors.New("stop")
+	if err := l.Replay(0, func(int64, []byte) error { return stop }); err != stop {
+		t.Errorf("Replay() didn't return the error from f: %v", err)
+	}
+	if _, _, err := l.Read(1); err != ErrCorrupt {
+		t.Errorf("Read() of a bad offset = %v", err)
+	}
+	if _, _, err := l.Read(-1); err != ErrNegativeOffset {
+		t.Errorf("Read() of a negative offset = %v", err)
+	}
+
+	// Tear the last record and make sure it's dropped.
+	l.m.Buf[offs[3]+5] = 'X'
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+	l, err = OpenLog(file.Name(), 0644, 0)
+	if err != nil {
+		t.Fatalf("OpenLog(%v, 0644, 0): %v", file.Name(), err)
+	}
+	defer l.Close()
+	if l.Size() != offs[3] {
+		t.Errorf("Size() after tearing the last record = %v", l.Size())
+	}
+	if off, err := l.Append([]byte("five")); off != offs[3] || err != nil {
+		t.Errorf("Append() after tearing = %v, %v", off, err)
+	}
+
+	// If it can't be grown, the old mapping is kept.
+	os.Remove(file.Name())
+	if _, err := l.Append(append(big, big...)); err == nil {
+		t.Errorf("Append() that grew a removed file succeeded")
+	}
+	if data, _, err := l.Read(offs[0]); err != nil || string(data) != "one" {
+		t.Errorf("Read(%v) after failing to grow = %q, %v", offs[0], data, err)
+	}
+}
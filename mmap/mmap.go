@@ -9,6 +9,8 @@
 package mmap
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"reflect"
 	"unsafe"
@@ -26,6 +28,71 @@ type Mmap struct {
 
 	// The byte array of the mmaped file.
 	Buf []byte
+
+	// Offset is where Buf starts in the file. It's only non-zero for
+	// regions (see NewRegion).
+	Offset int64
+
+	// pos is the offset in Buf used by Read, Write and Seek.
+	pos int64
+
+	// header is the integrity header if there is one (see
+	// Options.Integrity) and writable is true if the changes to the
+	// buffer are written to the file.
+	header   []byte
+	writable bool
+
+	// mapped is the entire mapping. It starts at a page boundary, so
+	// it may begin before Buf.
+	mapped []byte
+}
+
+// Options describe how a file is mapped.
+type Options struct {
+	// Perms are the permissions of the file if it's created.
+	Perms os.FileMode
+
+	// Size is the smallest size of the file. If it's smaller, it's
+	// increased to this size.
+	Size int64
+
+	// Offset and Length are the region of the file to map. The offset
+	// doesn't need to be a multiple of the page size. If Length > 0,
+	// then the file is increased to Offset + Length if it's smaller.
+	// Otherwise, the region extends to the end of the file.
+	Offset int64
+	Length int64
+
+	// ReadOnly opens the file read-only and maps it so it can only be
+	// read.
+	ReadOnly bool
+
+	// CopyOnWrite makes the mapping private. Changes to the buffer are
+	// never written to the file and aren't seen by other mappings of
+	// it. The file is opened read-only, so it can't be created or
+	// grown.
+	CopyOnWrite bool
+
+	// Exec maps the file so it can be executed.
+	Exec bool
+
+	// Preallocate allocates the space on disk for the file when it's
+	// grown instead of leaving it sparse, so writing to the buffer
+	// can't fail later because the disk is full.
+	Preallocate bool
+
+	// Populate reads the entire file into memory when it's mapped,
+	// so the first accesses of the buffer don't wait for the disk.
+	Populate bool
+
+	// Integrity stores a checksum of the buffer in a header at the
+	// start of the file. It's updated by Sync and verified when the
+	// file is mapped, so a file that was torn by a crash is detected
+	// (see ErrChecksum). The checksum is the root of a Merkle tree of
+	// the pages of the buffer, so both of them read the entire buffer.
+	// The header isn't part of the buffer and Size is the size of the
+	// buffer. It can't be used with a region.
+	Integrity bool
 }
 
 // New maps a new file. If size > 0, then the file is increased to the
@@ -33,54 +100,168 @@ type Mmap struct {
 // perms are passed when opening the file and determine how the mmap
 // will be opened. If private it true, then the map will be private.
 func New(name string, perms os.FileMode, flags int, size int64, private bool) (*Mmap, error) {
-	var err error
-	m := &Mmap{}
-	m.File, err = os.OpenFile(name, flags, os.FileMode(perms))
+	return newMmap(name, flags, Options{
+		Perms:       perms,
+		Size:        size,
+		ReadOnly:    readOnly(flags),
+		CopyOnWrite: private,
+	})
+}
+
+// NewRegion is like New but only maps length bytes of the file
+// starting at offset. This allows very large files to be mapped a
+// window at a time. The offset doesn't need to be a multiple of the
+// page size. If length > 0, then the file is increased to offset +
+// length if it's not already at least that size. Otherwise, the region
+// extends to the end of the file.
+func NewRegion(name string, perms os.FileMode, flags int, offset, length int64, private bool) (*Mmap, error) {
+	return newMmap(name, flags, Options{
+		Perms:       perms,
+		Offset:      offset,
+		Length:      length,
+		ReadOnly:    readOnly(flags),
+		CopyOnWrite: private,
+	})
+}
+
+// NewWithOptions maps the file as described by the options. Unless
+// it's read-only or copy-on-write, the file is created if it doesn't
+// exist.
+func NewWithOptions(name string, opts Options) (*Mmap, error) {
+	flags := os.O_CREATE | os.O_RDWR
+	if opts.ReadOnly || opts.CopyOnWrite {
+		flags = os.O_RDONLY
+	}
+	return newMmap(name, flags, opts)
+}
+
+// readOnly returns true if the flags open a file read-only.
+func readOnly(flags int) bool {
+	return flags&(os.O_WRONLY|os.O_RDWR) == 0
+}
+
+// newMmap opens the file with the given flags and maps it as described
+// by the options.
+func newMmap(name string, flags int, opts Options) (*Mmap, error) {
+	if opts.Offset < 0 {
+		return nil, fmt.Errorf("negative offset: %v", opts.Offset)
+	}
+	if opts.Integrity && (opts.Offset != 0 || opts.Length > 0) {
+		return nil, errors.New("integrity checking can't be used with a region")
+	}
+	size := opts.Size
+	if opts.Integrity && size > 0 {
+		size += integrityHeaderSize
+	}
+	if opts.Length > 0 && opts.Offset+opts.Length > size {
+		size = opts.Offset + opts.Length
+	}
+	f, len, err := open(name, opts.Perms, flags, size, opts.Preallocate)
 	if err != nil {
 		return nil, err
 	}
-	fi, err := m.File.Stat()
+	length := opts.Length
+	if length <= 0 {
+		length = len - opts.Offset
+	}
+	if length <= 0 {
+		f.Close()
+		return nil, fmt.Errorf("offset %v is at or beyond the end of the file", opts.Offset)
+	}
+
+	prot := unix.PROT_READ
+	if !opts.ReadOnly {
+		prot |= unix.PROT_WRITE
+	}
+	if opts.Exec {
+		prot |= unix.PROT_EXEC
+	}
+	t := unix.MAP_SHARED
+	if opts.CopyOnWrite {
+		t = unix.MAP_PRIVATE
+	}
+	m, err := mmap(f, prot, t, opts.Offset, length)
 	if err != nil {
-		m.File.Close()
 		return nil, err
 	}
+	m.writable = !opts.ReadOnly && !opts.CopyOnWrite
+	if opts.Integrity {
+		if err := m.checkIntegrity(); err != nil {
+			m.Close()
+			return nil, err
+		}
+	}
+	if opts.Populate {
+		if err := populate(m.mapped); err != nil {
+			m.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// open opens the file and increases it to size if it's smaller. If
+// preallocate is true, the space is allocated on disk as well. It
+// returns the file and its size.
+func open(name string, perms os.FileMode, flags int, size int64, preallocate bool) (*os.File, int64, error) {
+	f, err := os.OpenFile(name, flags, os.FileMode(perms))
+	if err != nil {
+		return nil, 0, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
 	len := fi.Size()
 	if size > 0 {
 		if fi.Size() < size {
-			err = m.File.Truncate(size)
+			if preallocate {
+				err = allocate(f, fi.Size(), size)
+			} else {
+				err = f.Truncate(size)
+			}
 			if err != nil {
-				m.File.Close()
-				return nil, err
+				f.Close()
+				return nil, 0, err
 			}
 			len = size
 		}
 	}
+	return f, len, nil
+}
 
-	mperms := 0
-	if flags&os.O_RDONLY != 0 || flags&os.O_RDWR != 0 {
-		mperms |= unix.PROT_READ
-	}
-	if flags&os.O_WRONLY != 0 || flags&os.O_RDWR != 0 {
-		mperms |= unix.PROT_WRITE
-	}
-
-	t := unix.MAP_SHARED
-	if private {
-		t = unix.MAP_PRIVATE
-	}
+// populate asks for the pages of the mapping to be read into memory.
+func populate(b []byte) error {
+	return unix.Madvise(b, unix.MADV_WILLNEED)
+}
 
-	m.Buf, err = unix.Mmap(int(m.File.Fd()), 0, int(len), mperms, t)
+// mmap maps length bytes of the file starting at offset with the given
+// protection and flags. The mapping itself starts at the page boundary
+// before the offset. The file is closed if an error occurs.
+func mmap(f *os.File, prot, flags int, offset, length int64) (*Mmap, error) {
+	start := offset &^ int64(os.Getpagesize()-1)
+	buf, err := unix.Mmap(int(f.Fd()), start, int(offset-start+length), prot, flags)
 	if err != nil {
-		m.File.Close()
+		f.Close()
 		return nil, err
 	}
-	return m, nil
+	return &Mmap{
+		File:   f,
+		Buf:    buf[offset-start:],
+		Offset: offset,
+		mapped: buf,
+	}, nil
 }
 
 // Sync ensures that any unwritten changes to the buffer are written
-// to disk. It will block until completed or an error occurs.
+// to disk. It will block until completed or an error occurs. If the
+// integrity of the file is checked, the checksum is updated first.
 func (m *Mmap) Sync() error {
-	sh := *(*reflect.SliceHeader)(unsafe.Pointer(&m.Buf))
+	if m.header != nil && m.writable {
+		m.writeChecksum()
+	}
+	sh := *(*reflect.SliceHeader)(unsafe.Pointer(&m.mapped))
 	_, _, err := unix.Syscall(unix.SYS_MSYNC,
 		sh.Data, uintptr(sh.Len), unix.MS_SYNC)
 	if err != 0 {
@@ -89,10 +270,30 @@ func (m *Mmap) Sync() error {
 	return nil
 }
 
+// Protect changes the protection of the mapping. For example, it can
+// be made read-only once it's initialized to guard against stray
+// writes and made writable again during updates. Accessing the buffer
+// in a way that isn't allowed causes a fault that crashes the program.
+// A shared mapping of a file can't be made writable unless the file
+// was opened for writing.
+func (m *Mmap) Protect(read, write, exec bool) error {
+	prot := unix.PROT_NONE
+	if read {
+		prot |= unix.PROT_READ
+	}
+	if write {
+		prot |= unix.PROT_WRITE
+	}
+	if exec {
+		prot |= unix.PROT_EXEC
+	}
+	return unix.Mprotect(m.mapped, prot)
+}
+
 // Close closes the associated mmap and file handles for this mmap. It
 // should not be used after this.
 func (m *Mmap) Close() error {
-	mErr := unix.Munmap(m.Buf)
+	mErr := unix.Munmap(m.mapped)
 	cErr := m.File.Close()
 	if mErr != nil {
 		return mErr
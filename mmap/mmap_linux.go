@@ -0,0 +1,19 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// allocate grows the file from size from to size to and allocates the
+// space on disk.
+func allocate(f *os.File, from, to int64) error {
+	return unix.Fallocate(int(f.Fd()), 0, from, to-from)
+}
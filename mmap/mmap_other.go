@@ -0,0 +1,31 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+//go:build !linux
+// +build !linux
+
+package mmap
+
+import (
+	"os"
+)
+
+// allocate grows the file from size from to size to and allocates the
+// space on disk. There's no fallocate here, so zeros are written.
+func allocate(f *os.File, from, to int64) error {
+	zeros := make([]byte, 64*1024)
+	for from < to {
+		n := int64(len(zeros))
+		if to-from < n {
+			n = to - from
+		}
+		if _, err := f.WriteAt(zeros[:n], from); err != nil {
+			return err
+		}
+		from += n
+	}
+	return nil
+}
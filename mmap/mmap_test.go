@@ -71,3 +71,171 @@ func TestMmap(t *testing.T) {
 		t.Fatalf("Close(): %v", err)
 	}
 }
+
+func TestNewRegion(t *testing.T) {
+	file, err := ioutil.TempFile("", "test_mmap")
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteAt([]byte("Hello, world!"), 5000); err != nil {
+		t.Fatalf("writing temp file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("closing temp file: %v", err)
+	}
+
+	// Map a region at an unaligned offset.
+	m, err := NewRegion(file.Name(), 0644, os.O_RDWR, 5000, 5, false)
+	if err != nil {
+		t.Fatalf("NewRegion(%v, 0644, os.O_RDWR, 5000, 5, false): %v", file.Name(), err)
+	}
+	if string(m.Buf) != "Hello" || m.Offset != 5000 {
+		t.Errorf("unexpected region: %q at %v", m.Buf, m.Offset)
+	}
+	copy(m.Buf, "HELLO")
+	if err := m.Sync(); err != nil {
+		t.Fatalf("Sync(): %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	// Map to the end of the file.
+	m, err = NewRegion(file.Name(), 0644, os.O_RDWR, 5007, 0, false)
+	if err != nil {
+		t.Fatalf("NewRegion(%v, 0644, os.O_RDWR, 5007, 0, false): %v", file.Name(), err)
+	}
+	if string(m.Buf) != "world!" {
+		t.Errorf("unexpected region to the end: %q", m.Buf)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	// Grow the file with a region past the end.
+	m, err = NewRegion(file.Name(), 0644, os.O_RDWR, 10000, 100, false)
+	if err != nil {
+		t.Fatalf("NewRegion(%v, 0644, os.O_RDWR, 10000, 100, false): %v", file.Name(), err)
+	}
+	if len(m.Buf) != 100 {
+		t.Errorf("unexpected region length: %v", len(m.Buf))
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+	b, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+	if len(b) != 10100 || string(b[5000:5013]) != "HELLO, world!" {
+		t.Errorf("unexpected file: %v bytes, %q", len(b), b[5000:5013])
+	}
+
+	// Bad offsets.
+	if _, err := NewRegion(file.Name(), 0644, os.O_RDWR, -1, 5, false); err == nil {
+		t.Errorf("NewRegion() with negative offset didn't fail")
+	}
+	if _, err := NewRegion(file.Name(), 0644, os.O_RDWR, 20000, 0, false); err == nil {
+		t.Errorf("NewRegion() past the end didn't fail")
+	}
+}
+
+func TestProtect(t *testing.T) {
+	file, err := ioutil.TempFile("", "test_mmap")
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+
+	m, err := New(file.Name(), 0644, os.O_RDWR, 4096, false)
+	if err != nil {
+		t.Fatalf("New(%v, 0644, os.O_RDWR, 4096, false): %v", file.Name(), err)
+	}
+	defer m.Close()
+	copy(m.Buf, "Hello")
+	if err := m.Protect(true, false, false); err != nil {
+		t.Fatalf("Protect(true, false, false): %v", err)
+	}
+	if string(m.Buf[:5]) != "Hello" {
+		t.Errorf("can't read after making read-only: %q", m.Buf[:5])
+	}
+	if err := m.Protect(true, true, false); err != nil {
+		t.Fatalf("Protect(true, true, false): %v", err)
+	}
+	copy(m.Buf, "HELLO")
+	if string(m.Buf[:5]) != "HELLO" {
+		t.Errorf("can't write after making writable: %q", m.Buf[:5])
+	}
+
+	// A shared mapping of a read-only file can't be made writable.
+	r, err := New(file.Name(), 0644, os.O_RDONLY, 0, false)
+	if err != nil {
+		t.Fatalf("New(%v, 0644, os.O_RDONLY, 0, false): %v", file.Name(), err)
+	}
+	defer r.Close()
+	if err := r.Protect(true, true, false); err == nil {
+		t.Errorf("Protect() made a read-only file writable")
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	file, err := ioutil.TempFile("", "test_mmap")
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	name := file.Name()
+	defer os.Remove(name)
+	file.Close()
+
+	// Create it preallocated and populated.
+	m, err := NewWithOptions(name, Options{Perms: 0644, Size: 8192,
+		Preallocate: true, Populate: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions(%v) preallocated: %v", name, err)
+	}
+	copy(m.Buf, "Hello, world!")
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+	if fi, err := os.Stat(name); err != nil || fi.Size() != 8192 {
+		t.Fatalf("Stat() of preallocated file = %v, %v", fi, err)
+	}
+
+	// Copy-on-write changes don't reach the file.
+	m, err = NewWithOptions(name, Options{CopyOnWrite: true, Offset: 7, Length: 5})
+	if err != nil {
+		t.Fatalf("NewWithOptions(%v) copy-on-write: %v", name, err)
+	}
+	if string(m.Buf) != "world" {
+		t.Errorf("unexpected copy-on-write region: %q", m.Buf)
+	}
+	copy(m.Buf, "WORLD")
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	// Read-only mappings can be read but not made writable.
+	m, err = NewWithOptions(name, Options{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions(%v) read-only: %v", name, err)
+	}
+	if string(m.Buf[:13]) != "Hello, world!" {
+		t.Errorf("unexpected read-only buffer: %q", m.Buf[:13])
+	}
+	if err := m.Protect(true, true, false); err == nil {
+		t.Errorf("read-only mapping made writable")
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	// Read-only files can't be grown or created.
+	if _, err := NewWithOptions(name, Options{ReadOnly: true, Size: 10000}); err == nil {
+		t.Errorf("NewWithOptions() grew a read-only file")
+	}
+	if _, err := NewWithOptions(name+".missing", Options{ReadOnly: true}); err == nil {
+		t.Errorf("NewWithOptions() created a read-only file")
+	}
+}
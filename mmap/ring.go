@@ -0,0 +1,250 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// The layout of the header of a ring file. The cursors are on their
+// own cache lines so the producers and the consumer don't contend.
+const (
+	ringMagic    = 0x52494e47 // "RING"
+	ringMagicOff = 0          // uint32
+	ringCapOff   = 8          // uint64
+	ringWriteOff = 64         // uint64, bytes ever pushed.
+	ringPushOff  = 72         // uint32, incremented by each push.
+	ringReadOff  = 128        // uint64, bytes ever popped.
+	ringPopOff   = 136        // uint32, incremented by each pop.
+	ringHeader   = 192
+)
+
+// Errors returned by a Ring.
+var (
+	ErrFull     = errors.New("ring is full")
+	ErrEmpty    = errors.New("ring is empty")
+	ErrTooLarge = errors.New("message is larger than the ring")
+)
+
+// Ring is a fixed-size queue of messages stored in a shared mapping of
+// a file, so it can be used to pass messages between processes. Use a
+// file in a memory file system like /dev/shm to avoid writing to disk.
+// (Anonymous mappings can't be used since Go programs can't fork
+// without exec.)
+//
+// Any number of producers in any number of processes can push to it
+// but there must only be one consumer popping from it. Pushes are
+// serialized with a lock on the file, which is released if a process
+// dies while holding it.
+type Ring struct {
+	// SingleProducer can be set if only one goroutine in one process
+	// ever pushes to the ring. The lock isn't taken then.
+	SingleProducer bool
+
+	m    *Mmap
+	data []byte // The part of the mapping with the messages.
+	cap  uint64
+
+	mu sync.Mutex // serializes the pushes in this process.
+
+	write, read     *uint64
+	pushSeq, popSeq *uint32
+}
+
+// NewRing opens the ring in the given file. If the file is new or
+// empty, it's created with room for capacity bytes of messages. Each
+// message uses 4 bytes more than its length. Otherwise, the ring in
+// the file is used and capacity must be 0 or match its capacity.
+func NewRing(name string, perms os.FileMode, capacity int) (*Ring, error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR, perms)
+	if err != nil {
+		return nil, err
+	}
+	// Creating the ring is done under the lock so two processes don't
+	// do it at once.
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	size := fi.Size()
+	if size == 0 {
+		if capacity <= 0 {
+			f.Close()
+			return nil, fmt.Errorf("invalid capacity: %v", capacity)
+		}
+		size = int64(ringHeader + capacity)
+		if err := f.Truncate(size); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if size < ringHeader {
+		f.Close()
+		return nil, fmt.Errorf("%v is not a ring", name)
+	}
+	m, err := mmap(f, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED, 0, size)
+	if err != nil {
+		return nil, err
+	}
+	b := m.Buf
+	if binary.LittleEndian.Uint32(b[ringMagicOff:]) == 0 {
+		binary.LittleEndian.PutUint64(b[ringCapOff:], uint64(size-ringHeader))
+		binary.LittleEndian.PutUint32(b[ringMagicOff:], ringMagic)
+	}
+	r := &Ring{
+		m:       m,
+		cap:     binary.LittleEndian.Uint64(b[ringCapOff:]),
+		write:   (*uint64)(unsafe.Pointer(&b[ringWriteOff])),
+		read:    (*uint64)(unsafe.Pointer(&b[ringReadOff])),
+		pushSeq: (*uint32)(unsafe.Pointer(&b[ringPushOff])),
+		popSeq:  (*uint32)(unsafe.Pointer(&b[ringPopOff])),
+	}
+	unix.Flock(int(f.Fd()), unix.LOCK_UN)
+	switch {
+	case binary.LittleEndian.Uint32(b[ringMagicOff:]) != ringMagic ||
+		r.cap != uint64(size-ringHeader):
+		r.m.Close()
+		return nil, fmt.Errorf("%v is not a ring", name)
+	case capacity > 0 && uint64(capacity) != r.cap:
+		r.m.Close()
+		return nil, fmt.Errorf("ring capacity is %v, not %v", r.cap, capacity)
+	}
+	r.data = b[ringHeader:]
+	return r, nil
+}
+
+// Cap returns the number of bytes of messages the ring can hold.
+func (r *Ring) Cap() int {
+	return int(r.cap)
+}
+
+// Len returns the number of bytes of messages in the ring.
+func (r *Ring) Len() int {
+	return int(atomic.LoadUint64(r.write) - atomic.LoadUint64(r.read))
+}
+
+// Push adds the message to the ring. It returns ErrFull if there isn't
+// room for it.
+func (r *Ring) Push(p []byte) error {
+	need := uint64(4 + len(p))
+	if need > r.cap {
+		return ErrTooLarge
+	}
+	if !r.SingleProducer {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if err := unix.Flock(int(r.m.File.Fd()), unix.LOCK_EX); err != nil {
+			return err
+		}
+		defer unix.Flock(int(r.m.File.Fd()), unix.LOCK_UN)
+	}
+	w := atomic.LoadUint64(r.write)
+	if w+need-atomic.LoadUint64(r.read) > r.cap {
+		return ErrFull
+	}
+	var l [4]byte
+	binary.LittleEndian.PutUint32(l[:], uint32(len(p)))
+	r.copyIn(w, l[:])
+	r.copyIn(w+4, p)
+	atomic.StoreUint64(r.write, w+need)
+	atomic.AddUint32(r.pushSeq, 1)
+	wake(r.pushSeq)
+	return nil
+}
+
+// Pop removes the next message from the ring and returns it. It
+// returns ErrEmpty if there are none.
+func (r *Ring) Pop() ([]byte, error) {
+	rd := atomic.LoadUint64(r.read)
+	if atomic.LoadUint64(r.write) == rd {
+		return nil, ErrEmpty
+	}
+	var l [4]byte
+	r.copyOut(rd, l[:])
+	p := make([]byte, binary.LittleEndian.Uint32(l[:]))
+	r.copyOut(rd+4, p)
+	atomic.StoreUint64(r.read, rd+uint64(4+len(p)))
+	atomic.AddUint32(r.popSeq, 1)
+	wake(r.popSeq)
+	return p, nil
+}
+
+// PushWait is like Push but it waits up to timeout for room in the
+// ring. If timeout is 0, it waits forever.
+func (r *Ring) PushWait(p []byte, timeout time.Duration) error {
+	return r.wait(r.popSeq, timeout, ErrFull, func() error {
+		return r.Push(p)
+	})
+}
+
+// PopWait is like Pop but it waits up to timeout for a message. If
+// timeout is 0, it waits forever.
+func (r *Ring) PopWait(timeout time.Duration) ([]byte, error) {
+	var p []byte
+	err := r.wait(r.pushSeq, timeout, ErrEmpty, func() error {
+		var err error
+		p, err = r.Pop()
+		return err
+	})
+	return p, err
+}
+
+// Close closes the underlying mapping. The ring should not be used
+// after this.
+func (r *Ring) Close() error {
+	return r.m.Close()
+}
+
+// wait calls f until it doesn't return retry or the timeout passes.
+// Between calls, it waits for seq to change.
+func (r *Ring) wait(seq *uint32, timeout time.Duration, retry error, f func() error) error {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		s := atomic.LoadUint32(seq)
+		if err := f(); err != retry {
+			return err
+		}
+		var d time.Duration
+		if timeout > 0 {
+			if d = time.Until(deadline); d <= 0 {
+				return retry
+			}
+		}
+		waitChange(seq, s, d)
+	}
+}
+
+// copyIn copies b into the ring at the given cursor, wrapping around
+// the end.
+func (r *Ring) copyIn(pos uint64, b []byte) {
+	n := copy(r.data[pos%r.cap:], b)
+	copy(r.data, b[n:])
+}
+
+// copyOut copies from the ring at the given cursor into b, wrapping
+// around the end.
+func (r *Ring) copyOut(pos uint64, b []byte) {
+	n := copy(b, r.data[pos%r.cap:])
+	copy(b[n:], r.data)
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"math"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// The futex operations. They are shared (not FUTEX_PRIVATE_FLAG) so
+// they work across processes.
+const (
+	futexWait = 0
+	futexWake = 1
+)
+
+// waitChange blocks until the value at addr may no longer be old or d
+// passes. If d is 0, it doesn't time out. It uses a futex on the shared
+// mapping.
+func waitChange(addr *uint32, old uint32, d time.Duration) {
+	var ts *unix.Timespec
+	if d > 0 {
+		t := unix.NsecToTimespec(int64(d))
+		ts = &t
+	}
+	// Any error (the value changed, we were interrupted or we timed
+	// out) is handled by the caller checking again.
+	unix.Syscall6(unix.SYS_FUTEX, uintptr(unsafe.Pointer(addr)),
+		futexWait, uintptr(old), uintptr(unsafe.Pointer(ts)), 0, 0)
+}
+
+// wake wakes all of the waiters on addr.
+func wake(addr *uint32) {
+	unix.Syscall6(unix.SYS_FUTEX, uintptr(unsafe.Pointer(addr)),
+		futexWake, uintptr(math.MaxInt32), 0, 0, 0)
+}
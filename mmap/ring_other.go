@@ -0,0 +1,34 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+//go:build !linux
+// +build !linux
+
+package mmap
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// The longest time waitChange sleeps before checking again.
+const maxPoll = time.Millisecond
+
+// waitChange blocks until the value at addr may no longer be old or d
+// passes. If d is 0, it doesn't time out. There's no futex here, so it
+// sleeps briefly.
+func waitChange(addr *uint32, old uint32, d time.Duration) {
+	if atomic.LoadUint32(addr) != old {
+		return
+	}
+	if d <= 0 || d > maxPoll {
+		d = maxPoll
+	}
+	time.Sleep(d)
+}
+
+// wake does nothing since the waiters poll.
+func wake(addr *uint32) {}
@@ -0,0 +1,138 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRing(t *testing.T) {
+	file, err := ioutil.TempFile("", "test_mmap_ring")
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+
+	if _, err := NewRing(file.Name(), 0644, 0); err == nil {
+		t.Errorf("NewRing() of a new ring without a capacity didn't fail")
+	}
+	r, err := NewRing(file.Name(), 0644, 16)
+	if err != nil {
+		t.Fatalf("NewRing(%v, 0644, 16): %v", file.Name(), err)
+	}
+	defer r.Close()
+	if _, err := r.Pop(); err != ErrEmpty {
+		t.Errorf("Pop() of empty ring = %v", err)
+	}
+	if err := r.Push(make([]byte, 13)); err != ErrTooLarge {
+		t.Errorf("Push() of large message = %v", err)
+	}
+
+	// Fill it up and wrap around a few times.
+	for x := 0; x < 5; x++ {
+		if err := r.Push([]byte("hello")); err != nil {
+			t.Fatalf("Push() %v: %v", x, err)
+		}
+		if err := r.Push([]byte("bye")); err != nil {
+			t.Fatalf("Push() %v: %v", x, err)
+		}
+		if err := r.Push([]byte("x")); err != ErrFull {
+			t.Fatalf("Push() %v of full ring = %v", x, err)
+		}
+		if r.Len() != 16 || r.Cap() != 16 {
+			t.Errorf("Len() = %v, Cap() = %v", r.Len(), r.Cap())
+		}
+		for _, exp := range []string{"hello", "bye"} {
+			if p, err := r.Pop(); err != nil || string(p) != exp {
+				t.Errorf("Pop() %v = %q, %v", x, p, err)
+			}
+		}
+		// Move the cursors so the next messages are split.
+		r.Push([]byte("a"))
+		r.Pop()
+	}
+
+	// Waiting times out.
+	if _, err := r.PopWait(10 * time.Millisecond); err != ErrEmpty {
+		t.Errorf("PopWait() of empty ring = %v", err)
+	}
+	r.Push(make([]byte, 12))
+	if err := r.PushWait([]byte("x"), 10*time.Millisecond); err != ErrFull {
+		t.Errorf("PushWait() of full ring = %v", err)
+	}
+	r.Pop()
+
+	// Opening it again uses the same ring.
+	if _, err := NewRing(file.Name(), 0644, 32); err == nil {
+		t.Errorf("NewRing() with a different capacity didn't fail")
+	}
+	o, err := NewRing(file.Name(), 0644, 0)
+	if err != nil {
+		t.Fatalf("NewRing(%v, 0644, 0): %v", file.Name(), err)
+	}
+	defer o.Close()
+	if o.Cap() != 16 {
+		t.Errorf("Cap() of reopened ring = %v", o.Cap())
+	}
+}
+
+func TestRingProducers(t *testing.T) {
+	file, err := ioutil.TempFile("", "test_mmap_ring")
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+	c, err := NewRing(file.Name(), 0644, 64)
+	if err != nil {
+		t.Fatalf("NewRing(%v, 0644, 64): %v", file.Name(), err)
+	}
+	defer c.Close()
+
+	// Each producer has its own mapping like it would in another
+	// process.
+	const producers, messages = 4, 200
+	var wg sync.WaitGroup
+	for x := 0; x < producers; x++ {
+		p, err := NewRing(file.Name(), 0644, 0)
+		if err != nil {
+			t.Fatalf("NewRing(%v, 0644, 0): %v", file.Name(), err)
+		}
+		defer p.Close()
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+			for y := 0; y < messages; y++ {
+				if err := p.PushWait([]byte(fmt.Sprintf("%v-%v", x, y)), 0); err != nil {
+					t.Errorf("PushWait(): %v", err)
+				}
+			}
+		}(x)
+	}
+
+	// The messages from each producer should be in order.
+	next := make([]int, producers)
+	for z := 0; z < producers*messages; z++ {
+		p, err := c.PopWait(5 * time.Second)
+		if err != nil {
+			t.Fatalf("PopWait() %v: %v", z, err)
+		}
+		var x, y int
+		fmt.Sscanf(string(p), "%d-%d", &x, &y)
+		if y != next[x] {
+			t.Errorf("expected %v-%v but got %s", x, next[x], p)
+		}
+		next[x] = y + 1
+	}
+	wg.Wait()
+}
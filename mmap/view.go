@@ -0,0 +1,85 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// Errors returned when making a view.
+var (
+	ErrAlignment = errors.New("offset isn't aligned for the type")
+	ErrPointers  = errors.New("type contains pointers")
+)
+
+// View returns n values of type T stored in the buffer starting at the
+// byte offset off. If n < 0, it includes as many as fit. The values are
+// the bytes of the buffer, so they are in the byte order of the
+// machine and changes to them change the buffer. T must be a fixed-size
+// type without pointers (e.g. numbers, arrays or structs of them) and
+// off must be aligned for it. The view shouldn't be used after the
+// mapping is closed.
+func View[T any](m *Mmap, off, n int) ([]T, error) {
+	var zero T
+	t := reflect.TypeOf(zero)
+	size := int(unsafe.Sizeof(zero))
+	switch {
+	case t == nil || hasPointers(t):
+		return nil, ErrPointers
+	case size == 0:
+		return nil, fmt.Errorf("type %v has no size", t)
+	case off < 0 || off > len(m.Buf):
+		return nil, fmt.Errorf("offset %v is out of range", off)
+	}
+	if n < 0 {
+		n = (len(m.Buf) - off) / size
+	}
+	if n > (len(m.Buf)-off)/size {
+		return nil, fmt.Errorf("%v values of %v at offset %v don't fit", n, t, off)
+	}
+	if n == 0 {
+		return []T{}, nil
+	}
+	p := unsafe.Pointer(&m.Buf[off])
+	if uintptr(p)%unsafe.Alignof(zero) != 0 {
+		return nil, ErrAlignment
+	}
+	return unsafe.Slice((*T)(p), n), nil
+}
+
+// Uint32s returns the entire buffer as a list of uint32s (see View).
+func (m *Mmap) Uint32s() ([]uint32, error) {
+	return View[uint32](m, 0, -1)
+}
+
+// Uint64s returns the entire buffer as a list of uint64s (see View).
+func (m *Mmap) Uint64s() ([]uint64, error) {
+	return View[uint64](m, 0, -1)
+}
+
+// hasPointers returns true if values of the type contain pointers,
+// which can't be stored in a mapping.
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Array:
+		return hasPointers(t.Elem())
+	case reflect.Struct:
+		for x := 0; x < t.NumField(); x++ {
+			if hasPointers(t.Field(x).Type) {
+				return true
+			}
+		}
+		return false
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Map, reflect.Slice,
+		reflect.String, reflect.Interface, reflect.Chan, reflect.Func:
+		return true
+	}
+	return false
+}
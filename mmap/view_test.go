@@ -0,0 +1,81 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package mmap
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"unsafe"
+)
+
+type entry struct {
+	ID     uint64
+	Offset uint32
+	Flags  [4]byte
+}
+
+func TestView(t *testing.T) {
+	file, err := ioutil.TempFile("", "test_mmap_view")
+	if err != nil {
+		t.Fatalf("creating temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+	m, err := New(file.Name(), 0644, os.O_RDWR, 4098, false)
+	if err != nil {
+		t.Fatalf("New(%v, 0644, os.O_RDWR, 4098, false): %v", file.Name(), err)
+	}
+	defer m.Close()
+
+	// Numbers.
+	u32, err := m.Uint32s()
+	if err != nil || len(u32) != 1024 {
+		t.Fatalf("Uint32s() = %v values, %v", len(u32), err)
+	}
+	u32[1] = 7
+	if v := *(*uint32)(unsafe.Pointer(&m.Buf[4])); v != 7 {
+		t.Errorf("uint32 not written to the buffer: %v", v)
+	}
+	u64, err := m.Uint64s()
+	if err != nil || len(u64) != 512 {
+		t.Fatalf("Uint64s() = %v values, %v", len(u64), err)
+	}
+
+	// Structs.
+	es, err := View[entry](m, 16, 2)
+	if err != nil || len(es) != 2 {
+		t.Fatalf("View[entry]() = %v values, %v", len(es), err)
+	}
+	es[1] = entry{ID: 1, Offset: 2, Flags: [4]byte{'a'}}
+	if e, _ := View[entry](m, 32, 1); e[0] != es[1] {
+		t.Errorf("unexpected entry: %v", e[0])
+	}
+	if e, err := View[entry](m, 4096, -1); err != nil || len(e) != 0 {
+		t.Errorf("View[entry]() at the end = %v, %v", e, err)
+	}
+
+	// Errors.
+	if _, err := View[uint64](m, 4, 1); err != ErrAlignment {
+		t.Errorf("View[uint64]() at unaligned offset = %v", err)
+	}
+	if _, err := View[entry](m, 4080, 2); err == nil {
+		t.Errorf("View[entry]() past the end didn't fail")
+	}
+	if _, err := View[uint32](m, -1, 1); err == nil {
+		t.Errorf("View[uint32]() at negative offset didn't fail")
+	}
+	if _, err := View[struct{ S string }](m, 0, 1); err != ErrPointers {
+		t.Errorf("View[] of type with pointers = %v", err)
+	}
+	if _, err := View[[2]*int](m, 0, 1); err != ErrPointers {
+		t.Errorf("View[] of array of pointers = %v", err)
+	}
+	if _, err := View[struct{}](m, 0, 1); err == nil {
+		t.Errorf("View[] of empty type didn't fail")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package nlock
+
+import "golang.org/x/net/context"
+
+// LockBackend is where a NamedLock created with NewWithBackend also
+// locks its names, so they can be shared with other NamedLocks, other
+// processes or other hosts. The NamedLock only calls it for a name
+// once it has locked the name itself, so it's called by at most one
+// goroutine of the NamedLock for each name at a time.
+type LockBackend interface {
+	// Acquire locks the name. If it's already locked, it waits until
+	// it's unlocked or the context is done. In that case, the
+	// context's error is returned. If the context is already done, the
+	// name should still be locked if that doesn't require waiting.
+	Acquire(ctx context.Context, name string) error
+
+	// Release unlocks the name. Nothing should happen if it isn't
+	// locked.
+	Release(name string) error
+}
+
+// NewWithBackend creates a new NamedLock that also locks the names in
+// the given backend. Its methods work the same as those of a NamedLock
+// created with New, except that the waits include the time spent
+// waiting for the backend and Lock and Unlock panic if the backend
+// fails.
+func NewWithBackend(b LockBackend) *NamedLock {
+	nl := New()
+	nl.b = b
+	return nl
+}
+
+// LocalBackend is a LockBackend that locks the names in this process.
+// It lets multiple NamedLocks, e.g. ones with different options, share
+// their names.
+type LocalBackend struct {
+	nl *NamedLock
+}
+
+// NewLocalBackend creates a new LocalBackend.
+func NewLocalBackend() *LocalBackend {
+	return &LocalBackend{nl: New()}
+}
+
+// Acquire locks the name.
+func (b *LocalBackend) Acquire(ctx context.Context, name string) error {
+	if ctx.Err() != nil {
+		if !b.nl.TryLock(name) {
+			return ctx.Err()
+		}
+		return nil
+	}
+	return b.nl.LockContext(ctx, name)
+}
+
+// Release unlocks the name.
+func (b *LocalBackend) Release(name string) error {
+	b.nl.Unlock(name)
+	return nil
+}
+
+// doneContext returns a context that's already done. It's given to the
+// backend when a name is only tried.
+func doneContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package nlock
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestSharedBackend(t *testing.T) {
+	// Unlocking a name this NamedLock doesn't hold leaves the other's
+	// lock alone.
+	b := NewLocalBackend()
+	a, c := NewWithBackend(b), NewWithBackend(b)
+	c.Lock("x")
+	a.Unlock("x")
+	if a.TryLock("x") {
+		t.Errorf("TryLock() succeeded after Unlock() of a name held by another")
+	}
+	c.Unlock("x")
+	if !a.TryLock("x") {
+		t.Errorf("TryLock() failed after the holder unlocked")
+	}
+	a.Unlock("x")
+}
+
+// failBackend fails to acquire and release names.
+type failBackend struct{}
+
+var errBackend = errors.New("backend failed")
+
+func (failBackend) Acquire(ctx context.Context, name string) error { return errBackend }
+func (failBackend) Release(name string) error                      { return errBackend }
+
+func TestBackendErrors(t *testing.T) {
+	nl := NewWithBackend(failBackend{})
+	if err := nl.LockContext(context.Background(), "a"); err != errBackend {
+		t.Errorf("LockContext() = %v, wanted %v", err, errBackend)
+	}
+	if nl.TryLock("a") {
+		t.Errorf("TryLock() succeeded")
+	}
+	if l := nl.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Lock() didn't panic")
+			}
+		}()
+		nl.Lock("a")
+	}()
+
+	// The name is unlocked in this process even if the backend fails.
+	nl = NewWithBackend(NewLocalBackend())
+	nl.Lock("a")
+	nl.b = failBackend{}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Unlock() didn't panic")
+			}
+		}()
+		nl.Unlock("a")
+	}()
+	if l := nl.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+}
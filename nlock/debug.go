@@ -0,0 +1,203 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package nlock
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Deadlock describes a cycle of goroutines waiting on each other that
+// was found in debug mode. Goroutines[i] is waiting for Names[i], which
+// is held by the next goroutine. The last name is held by the first
+// goroutine.
+type Deadlock struct {
+	Goroutines []int64
+	Names      []string
+}
+
+func (d *Deadlock) Error() string {
+	parts := make([]string, len(d.Names))
+	for x, name := range d.Names {
+		parts[x] = fmt.Sprintf("goroutine %v waits for %q held by goroutine %v",
+			d.Goroutines[x], name, d.Goroutines[(x+1)%len(d.Goroutines)])
+	}
+	return "nlock: deadlock: " + strings.Join(parts, ", ")
+}
+
+// Holder is a goroutine that holds a name in debug mode.
+type Holder struct {
+	Name      string
+	Goroutine int64
+	Since     time.Time
+}
+
+// debugState tracks the holders and waiters in debug mode.
+type debugState struct {
+	onDeadlock func(*Deadlock)
+	held       map[string][]Holder // The holders of each name.
+	waiting    map[int64]string    // What each goroutine waits for.
+}
+
+// Debug turns on debug mode. The goroutines holding each name are
+// tracked (see Holders). When a goroutine starts waiting for a name,
+// the goroutines are checked for a cycle where each waits for a name
+// held by the next. If one is found, onDeadlock is called with it
+// before waiting. If onDeadlock is nil, it panics with the *Deadlock
+// instead. Debug mode slows down locking, so it's meant for tracking
+// down problems. It must be turned on before the lock is used.
+func (nl *NamedLock) Debug(onDeadlock func(*Deadlock)) {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	nl.debug = &debugState{
+		onDeadlock: onDeadlock,
+		held:       map[string][]Holder{},
+		waiting:    map[int64]string{},
+	}
+}
+
+// Holders returns the holders of the names sorted by name and then by
+// when they got them. It returns nil unless debug mode is on.
+func (nl *NamedLock) Holders() []Holder {
+	if nl.debug == nil {
+		return nil
+	}
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	var hs []Holder
+	for _, h := range nl.debug.held {
+		hs = append(hs, h...)
+	}
+	sort.SliceStable(hs, func(i, j int) bool {
+		if hs[i].Name != hs[j].Name {
+			return hs[i].Name < hs[j].Name
+		}
+		return hs[i].Since.Before(hs[j].Since)
+	})
+	return hs
+}
+
+// debugWait records that the current goroutine is waiting for the name
+// and reports a deadlock if it causes one and block is true. It returns
+// the goroutine's id or 0 if debug mode isn't on.
+func (nl *NamedLock) debugWait(name string, block bool) int64 {
+	if nl.debug == nil {
+		return 0
+	}
+	g := goid()
+	nl.l.Lock()
+	nl.debug.waiting[g] = name
+	var d *Deadlock
+	if block {
+		d = nl.debug.cycle(g, name)
+	}
+	if d != nil && nl.debug.onDeadlock == nil {
+		delete(nl.debug.waiting, g)
+	}
+	f := nl.debug.onDeadlock
+	nl.l.Unlock()
+	if d != nil {
+		if f == nil {
+			panic(d)
+		}
+		f(d)
+	}
+	return g
+}
+
+// debugDone records that the goroutine stopped waiting for the name and
+// whether it got it.
+func (nl *NamedLock) debugDone(name string, g int64, locked bool) {
+	if nl.debug == nil {
+		return
+	}
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	delete(nl.debug.waiting, g)
+	if locked {
+		nl.debug.held[name] = append(nl.debug.held[name],
+			Holder{Name: name, Goroutine: g, Since: time.Now()})
+	}
+}
+
+// debugUnlock records that the name was unlocked. A name may be
+// unlocked by a goroutine other than the one that locked it, so the
+// current goroutine is only preferred. The caller must hold nl.l.
+func (nl *NamedLock) debugUnlock(name string) {
+	if nl.debug == nil {
+		return
+	}
+	hs := nl.debug.held[name]
+	x, g := 0, goid()
+	for y, h := range hs {
+		if h.Goroutine == g {
+			x = y
+			break
+		}
+	}
+	if len(hs) <= 1 {
+		delete(nl.debug.held, name)
+		return
+	}
+	nl.debug.held[name] = append(hs[:x:x], hs[x+1:]...)
+}
+
+// cycle returns the deadlock if g waiting for the name causes one.
+func (d *debugState) cycle(g int64, name string) *Deadlock {
+	holders, names := d.chain(g, name, map[int64]bool{})
+	if holders == nil {
+		return nil
+	}
+	return &Deadlock{
+		Goroutines: append([]int64{g}, holders[:len(holders)-1]...),
+		Names:      names,
+	}
+}
+
+// chain follows the holders of the name and the names they wait for
+// until it finds g. It returns the holders and names along the way:
+// holders[i] holds names[i] and waits for names[i+1]. The last holder
+// is g. If g isn't found, it returns nil.
+func (d *debugState) chain(g int64, name string, seen map[int64]bool) ([]int64, []string) {
+	for _, h := range d.held[name] {
+		if h.Goroutine == g {
+			return []int64{g}, []string{name}
+		}
+		if seen[h.Goroutine] {
+			continue
+		}
+		seen[h.Goroutine] = true
+		next, ok := d.waiting[h.Goroutine]
+		if !ok {
+			continue
+		}
+		if holders, names := d.chain(g, next, seen); holders != nil {
+			return append([]int64{h.Goroutine}, holders...),
+				append([]string{name}, names...)
+		}
+	}
+	return nil, nil
+}
+
+// goid returns the id of the current goroutine. Go doesn't expose it,
+// so it's read from the first line of the stack trace, which looks like
+// "goroutine 18 [running]:".
+func goid() int64 {
+	b := make([]byte, 64)
+	b = b[:runtime.Stack(b, false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
+}
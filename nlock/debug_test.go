@@ -0,0 +1,111 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package nlock
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugHolders(t *testing.T) {
+	nl := New()
+	if hs := nl.Holders(); hs != nil {
+		t.Errorf("Holders() without debug mode = %v", hs)
+	}
+	nl.Debug(nil)
+	start := time.Now()
+	nl.Lock("b")
+	nl.Lock("a")
+	if nl.TryLock("a") {
+		t.Fatalf("TryLock() of locked name succeeded")
+	}
+	hs := nl.Holders()
+	if len(hs) != 2 || hs[0].Name != "a" || hs[1].Name != "b" {
+		t.Fatalf("Holders() = %v", hs)
+	}
+	g := goid()
+	for _, h := range hs {
+		if h.Goroutine != g || h.Since.Before(start) {
+			t.Errorf("holder %+v, expected goroutine %v", h, g)
+		}
+	}
+
+	// Another goroutine can unlock it.
+	done := make(chan struct{})
+	go func() {
+		nl.Unlock("a")
+		close(done)
+	}()
+	<-done
+	nl.Unlock("b")
+	if hs := nl.Holders(); len(hs) != 0 {
+		t.Errorf("Holders() after Unlock() = %v", hs)
+	}
+}
+
+func TestDebugDeadlock(t *testing.T) {
+	nl := New()
+	found := make(chan *Deadlock, 1)
+	nl.Debug(func(d *Deadlock) { found <- d })
+
+	// Goroutine 1 holds a and waits for b while goroutine 2 holds b and
+	// waits for a.
+	g1 := make(chan int64)
+	nl.Lock("b")
+	go func() {
+		nl.Lock("a")
+		g1 <- goid()
+		nl.Lock("b")
+		nl.Unlock("b")
+		nl.Unlock("a")
+	}()
+	id := <-g1
+	// Wait for goroutine 1 to start waiting.
+	for {
+		nl.l.Lock()
+		_, waiting := nl.debug.waiting[id]
+		nl.l.Unlock()
+		if waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	err := nl.LockTimeout("a", 10*time.Millisecond)
+	if err != ErrTimeout {
+		t.Errorf("LockTimeout() = %v", err)
+	}
+	d := <-found
+	g := goid()
+	if len(d.Goroutines) != 2 || d.Goroutines[0] != g || d.Goroutines[1] != id ||
+		len(d.Names) != 2 || d.Names[0] != "a" || d.Names[1] != "b" {
+		t.Errorf("deadlock = %+v", d)
+	}
+	if !strings.Contains(d.Error(), `waits for "a" held by goroutine`) {
+		t.Errorf("Error() = %v", d.Error())
+	}
+	nl.Unlock("b")
+
+	// Locking a name again in the same goroutine panics without a
+	// callback.
+	self := New()
+	self.Debug(nil)
+	self.Lock("a")
+	func() {
+		defer func() {
+			d, ok := recover().(*Deadlock)
+			if !ok || len(d.Goroutines) != 1 || d.Names[0] != "a" {
+				t.Errorf("recover() = %v", d)
+			}
+		}()
+		self.Lock("a")
+	}()
+	self.Unlock("a")
+	if l := self.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+}
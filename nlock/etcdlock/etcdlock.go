@@ -0,0 +1,123 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+// Package etcdlock provides an nlock.LockBackend that locks the names
+// in etcd, so a NamedLock can coordinate processes on every host using
+// the same etcd key.
+package etcdlock
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/icub3d/gop/etcdutil"
+	"golang.org/x/net/context"
+)
+
+// ErrClosed is returned by Acquire when the EtcdUtil is closed.
+var ErrClosed = errors.New("closed")
+
+// holderID is the last number used in the ids of the holders of names
+// in this process.
+var holderID uint64
+
+// Backend is an nlock.LockBackend that locks the names in etcd, so they
+// are shared by the processes on every host using the same etcd key.
+// Each name is an election (see etcdutil.Elect) whose leader holds the
+// name. The leader refreshes the key every third of the ttl. If it
+// fails to, e.g. the host is partitioned from etcd, the key expires
+// and the name can be taken by another process while it's still
+// locked by this one. Locking a name that was just unlocked may take
+// up to a third of the ttl.
+type Backend struct {
+	u   *etcdutil.EtcdUtil
+	dir string
+	ttl time.Duration
+
+	mu   sync.Mutex
+	held map[string]*etcdutil.Election
+}
+
+// New creates a Backend that locks the names under prefix+dir with the
+// given ttl.
+func New(u *etcdutil.EtcdUtil, dir string, ttl time.Duration) *Backend {
+	return &Backend{
+		u:    u,
+		dir:  dir,
+		ttl:  ttl,
+		held: map[string]*etcdutil.Election{},
+	}
+}
+
+// Acquire locks the name by becoming the leader of its election. If the
+// context is already done, it still waits for the outcome of one
+// campaign, up to the ttl, so NamedLock.TryLock can block that long.
+func (b *Backend) Acquire(ctx context.Context, name string) error {
+	id := newHolderID()
+	e := b.u.Elect(b.key(name), id, b.ttl)
+	done := ctx.Done()
+	var try <-chan time.Time
+	if ctx.Err() != nil {
+		t := time.NewTimer(b.ttl)
+		defer t.Stop()
+		try, done = t.C, nil
+	}
+	for {
+		select {
+		case l, ok := <-e.Leaders():
+			if !ok {
+				return ErrClosed
+			}
+			if l == id {
+				b.mu.Lock()
+				b.held[name] = e
+				b.mu.Unlock()
+				return nil
+			}
+			if try != nil && l != "" {
+				e.Resign()
+				return ctx.Err()
+			}
+			continue
+		case <-try:
+		case <-done:
+		}
+		e.Resign()
+		return ctx.Err()
+	}
+}
+
+// Release unlocks the name by resigning from its election.
+func (b *Backend) Release(name string) error {
+	b.mu.Lock()
+	e, ok := b.held[name]
+	delete(b.held, name)
+	b.mu.Unlock()
+	if ok {
+		e.Resign()
+	}
+	return nil
+}
+
+// key returns the election key of the name. The name is escaped so it
+// can't refer to another directory.
+func (b *Backend) key(name string) string {
+	return strings.Join([]string{b.dir, url.QueryEscape(name)}, "/")
+}
+
+// newHolderID returns an id for a holder that's unique across
+// processes and hosts.
+func newHolderID() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%v:%v:%v:%v", host, os.Getpid(),
+		time.Now().UnixNano(), atomic.AddUint64(&holderID, 1))
+}
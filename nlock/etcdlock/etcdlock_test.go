@@ -0,0 +1,46 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package etcdlock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/icub3d/gop/etcdutil"
+	"github.com/icub3d/gop/etcdutil/etcdutiltest"
+	"github.com/icub3d/gop/nlock"
+	"github.com/icub3d/gop/nlock/nlocktest"
+	"golang.org/x/net/context"
+)
+
+func TestBackend(t *testing.T) {
+	f := etcdutiltest.NewFake()
+	u := etcdutil.NewFromClient(f, "/nlock")
+	a := New(u, "locks", 30*time.Millisecond)
+	b := New(u, "locks", 30*time.Millisecond)
+	nlocktest.CheckBackend(t, a, b)
+
+	// The names are escaped.
+	nl := nlock.NewWithBackend(a)
+	nl.Lock("b/../c")
+	if _, _, err := u.GetForUpdate("locks/b%2F..%2Fc"); err != nil {
+		t.Errorf("lock key not found: %v", err)
+	}
+	nl.Unlock("b/../c")
+
+	// Closing the EtcdUtil stops waiting.
+	nl.Lock("a")
+	done := make(chan error)
+	go func() {
+		done <- b.Acquire(context.Background(), "a")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	u.Close()
+	if err := <-done; err != ErrClosed {
+		t.Errorf("Acquire() after Close() = %v, wanted %v", err, ErrClosed)
+	}
+}
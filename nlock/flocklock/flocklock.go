@@ -0,0 +1,43 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+// Package flocklock provides an nlock.LockBackend that locks the names
+// with lock files, so a NamedLock can coordinate the processes on a
+// host.
+package flocklock
+
+import (
+	"github.com/icub3d/gop/flock"
+	"golang.org/x/net/context"
+)
+
+// Backend is an nlock.LockBackend that locks the names with lock files
+// in a directory (see flock.NamedLock), so they are shared by the
+// processes on a host using the same directory.
+type Backend struct {
+	nl *flock.NamedLock
+}
+
+// New creates a Backend with the lock files in the given directory,
+// creating it if needed.
+func New(dir string) (*Backend, error) {
+	nl, err := flock.NewNamedLock(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{nl: nl}, nil
+}
+
+// Acquire locks the name's file.
+func (b *Backend) Acquire(ctx context.Context, name string) error {
+	return b.nl.LockContext(ctx, name)
+}
+
+// Release unlocks the name's file.
+func (b *Backend) Release(name string) error {
+	b.nl.Unlock(name)
+	return nil
+}
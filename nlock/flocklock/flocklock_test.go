@@ -0,0 +1,30 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package flocklock
+
+import (
+	"os"
+	"testing"
+
+	"github.com/icub3d/gop/nlock/nlocktest"
+)
+
+func TestBackend(t *testing.T) {
+	dir := "/tmp/flocklock_test"
+	defer os.RemoveAll(dir)
+
+	// Each backend opens its own files, like another process would.
+	a, err := New(dir)
+	if err != nil {
+		t.Fatalf("New(%q): %v", dir, err)
+	}
+	b, err := New(dir)
+	if err != nil {
+		t.Fatalf("New(%q): %v", dir, err)
+	}
+	nlocktest.CheckBackend(t, a, b)
+}
@@ -13,45 +13,298 @@
 // the file names. One goroutine can acquire a lock by name and then
 // be sure that it's work on that file won't be interrupted by other
 // files.
+//
+// The names are locked within the process unless the NamedLock is
+// created with a LockBackend. Then they are also locked in the backend,
+// e.g. a directory of lock files (see the flocklock package) or etcd
+// (see the etcdlock package), so the same code can coordinate
+// processes and hosts.
 package nlock
 
-import "sync"
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ErrTimeout is returned by LockTimeout when the name couldn't be
+// locked in time.
+var ErrTimeout = errors.New("timeout")
+
+// errLocked is returned by lock when it's trying the lock and the name
+// is locked.
+var errLocked = errors.New("locked")
 
 // NamedLock is used for creating mutex locks by name. It is
-// instantiated with the New() function.
+// instantiated with the New() function. A name only uses memory while
+// it's locked or being waited on.
 type NamedLock struct {
-	l sync.Mutex
-	m map[string]*sync.Mutex
+	l     sync.Mutex
+	m     map[string]*entry
+	n     int                   // The number of holders a name can have.
+	fair  bool                  // Whether waiters are queued in order.
+	debug *debugState           // The state of the debug mode if it's on.
+	stats map[string]*nameStats // The statistics if they're collected.
+	b     LockBackend           // Where the names are also locked if set.
+}
+
+// entry is the lock of a name. There is a value in the channel for
+// each holder of the lock. refs is the number of goroutines holding or
+// waiting for it. The entry is removed when it's 0. If the lock is
+// fair, the waiters are queued and the first is handed the lock by
+// closing its channel. The queue is only used when the lock is full.
+type entry struct {
+	ch    chan struct{}
+	refs  int
+	queue []chan struct{}
 }
 
 // New creates a new Named lock.
 func New() *NamedLock {
 	return &NamedLock{
-		m: map[string]*sync.Mutex{},
+		m: map[string]*entry{},
+		n: 1,
 	}
 }
 
+// Fair makes waiters get the names in the order they started waiting
+// for them. Otherwise, there is no guarantee about the order and a
+// goroutine that repeatedly locks a name can starve others. An unlocked
+// name is handed directly to the next waiter, so fairness costs some
+// throughput. It must be called before the lock is used.
+func (nl *NamedLock) Fair() {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	nl.fair = true
+}
+
 // Lock locks the given name. If name is already locked, it blocks
-// until the mutex is available.
+// until the mutex is available. It panics if the name can't be locked
+// in the backend.
 func (nl *NamedLock) Lock(name string) {
-	nl.l.Lock()
-	l, ok := nl.m[name]
-	if !ok {
-		l = &sync.Mutex{}
-		nl.m[name] = l
+	if err := nl.lock(context.Background(), name, false); err != nil {
+		panic(fmt.Sprintf("nlock: locking %q: %v", name, err))
 	}
-	nl.l.Unlock()
-	l.Lock()
+}
+
+// TryLock locks the given name if it isn't already locked and returns
+// true if it got the lock. It never waits for the name to be unlocked,
+// but a backend may block while it checks the name, e.g. for a round
+// trip to etcd.
+func (nl *NamedLock) TryLock(name string) bool {
+	return nl.lock(context.Background(), name, true) == nil
+}
+
+// LockTimeout locks the given name. If it's already locked, it waits
+// up to d for it. If it can't be locked in that time, the returned
+// error is ErrTimeout.
+func (nl *NamedLock) LockTimeout(name string, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	err := nl.lock(ctx, name, false)
+	if err == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return err
+}
+
+// LockContext locks the given name. If it's already locked, it waits
+// until it's unlocked or the context is done. In that case, the
+// context's error is returned. If the name can't be locked in the
+// backend, the backend's error is returned.
+func (nl *NamedLock) LockContext(ctx context.Context, name string) error {
+	return nl.lock(ctx, name, false)
 }
 
 // Unlock unlocks the given name. It is a run-time error if the name
-// is not locked when Unlock is called.
+// is not locked when Unlock is called, except that nothing happens if
+// no goroutine is waiting for it either. The name is only released in
+// the backend if this NamedLock holds it, since the backend may be
+// shared. It panics if the name can't be released in the backend,
+// after unlocking it in this process.
 func (nl *NamedLock) Unlock(name string) {
+	var err error
+	if nl.b != nil && nl.holds(name) {
+		err = nl.b.Release(name)
+	}
+	nl.unlockLocal(name)
+	if err != nil {
+		panic(fmt.Sprintf("nlock: releasing %q: %v", name, err))
+	}
+}
+
+// holds returns whether the name is locked in this NamedLock.
+func (nl *NamedLock) holds(name string) bool {
 	nl.l.Lock()
 	defer nl.l.Unlock()
-	l, ok := nl.m[name]
+	e, ok := nl.m[name]
+	return ok && len(e.ch) > 0
+}
+
+// unlockLocal unlocks the name in this process.
+func (nl *NamedLock) unlockLocal(name string) {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	e, ok := nl.m[name]
 	if !ok {
 		return
 	}
-	l.Unlock()
+	nl.unlock(name, e)
+	nl.debugUnlock(name)
+	nl.statsUnlock(name)
+	nl.release(name, e)
+}
+
+// WithLock locks the given name, calls fn and unlocks it, even if fn
+// panics.
+func (nl *NamedLock) WithLock(name string, fn func()) {
+	nl.Lock(name)
+	defer nl.Unlock(name)
+	fn()
+}
+
+// WithLockErr is like WithLock but returns the error from fn.
+func (nl *NamedLock) WithLockErr(name string, fn func() error) error {
+	nl.Lock(name)
+	defer nl.Unlock(name)
+	return fn()
+}
+
+// Len returns the number of names that are locked or being waited on.
+func (nl *NamedLock) Len() int {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	return len(nl.m)
+}
+
+// lock locks the given name unless the context is done first. If try
+// is true, it doesn't wait at all. The name is locked in the backend
+// after it's locked in this process. It returns nil if it got the lock.
+func (nl *NamedLock) lock(ctx context.Context, name string, try bool) error {
+	g := nl.debugWait(name, !try)
+	start := time.Now()
+	e := nl.acquire(name)
+	ok := true
+	if nl.fair {
+		ok = nl.lockFair(ctx, name, e, try)
+	} else if try {
+		select {
+		case e.ch <- struct{}{}:
+		default:
+			ok = false
+		}
+	} else {
+		select {
+		case e.ch <- struct{}{}:
+		case <-ctx.Done():
+			ok = false
+		}
+	}
+	var err error
+	if !ok {
+		err = ctx.Err()
+		if try {
+			err = errLocked
+		}
+	} else if nl.b != nil {
+		if try {
+			ctx = doneContext()
+		}
+		err = nl.b.Acquire(ctx, name)
+	}
+	if err != nil {
+		nl.l.Lock()
+		if ok {
+			nl.unlock(name, e)
+		}
+		nl.release(name, e)
+		nl.l.Unlock()
+	}
+	nl.debugDone(name, g, err == nil)
+	nl.statsDone(name, start, err == nil)
+	return err
+}
+
+// lockFair is lock for a fair lock. The caller must have a reference
+// to the entry.
+func (nl *NamedLock) lockFair(ctx context.Context, name string, e *entry, try bool) bool {
+	nl.l.Lock()
+	if len(e.queue) == 0 {
+		select {
+		case e.ch <- struct{}{}:
+			nl.l.Unlock()
+			return true
+		default:
+		}
+	}
+	if try {
+		nl.l.Unlock()
+		return false
+	}
+	t := make(chan struct{})
+	e.queue = append(e.queue, t)
+	nl.l.Unlock()
+
+	select {
+	case <-t:
+		return true
+	case <-ctx.Done():
+	}
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	select {
+	case <-t:
+		// It was handed the lock while giving up, so it's passed on.
+		nl.unlock(name, e)
+	default:
+		for x, q := range e.queue {
+			if q == t {
+				e.queue = append(e.queue[:x], e.queue[x+1:]...)
+				break
+			}
+		}
+	}
+	return false
+}
+
+// unlock hands the lock of the entry to the first waiter in its queue
+// or frees a place in it. The caller must hold nl.l.
+func (nl *NamedLock) unlock(name string, e *entry) {
+	if len(e.queue) > 0 {
+		close(e.queue[0])
+		e.queue = e.queue[1:]
+		return
+	}
+	select {
+	case <-e.ch:
+	default:
+		panic("nlock: unlock of unlocked name " + name)
+	}
+}
+
+// acquire returns the entry for the name, creating it if needed, and
+// adds a reference to it.
+func (nl *NamedLock) acquire(name string) *entry {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	e, ok := nl.m[name]
+	if !ok {
+		e = &entry{ch: make(chan struct{}, nl.n)}
+		nl.m[name] = e
+	}
+	e.refs++
+	nl.statsWait(name)
+	return e
+}
+
+// release removes a reference to the entry and removes it once there
+// are none. The caller must hold nl.l.
+func (nl *NamedLock) release(name string, e *entry) {
+	e.refs--
+	if e.refs == 0 {
+		delete(nl.m, name)
+	}
 }
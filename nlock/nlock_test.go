@@ -6,7 +6,16 @@
 
 package nlock
 
-import "testing"
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
 
 func TestNamedLock(t *testing.T) {
 	nl := New()
@@ -18,3 +27,225 @@ func TestNamedLock(t *testing.T) {
 	nl.Unlock("b")
 	nl.Unlock("c")
 }
+
+func TestNamedLockCleanup(t *testing.T) {
+	nl := New()
+	nl.Lock("a")
+	nl.Lock("b")
+	if l := nl.Len(); l != 2 {
+		t.Errorf("Len() = %v, wanted 2", l)
+	}
+
+	// Waiters keep the name.
+	locked := make(chan struct{})
+	go func() {
+		nl.Lock("a")
+		close(locked)
+	}()
+	nl.Unlock("b")
+	nl.Unlock("a")
+	<-locked
+	if l := nl.Len(); l != 1 {
+		t.Errorf("Len() = %v, wanted 1", l)
+	}
+	nl.Unlock("a")
+	if l := nl.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+
+	// Many names don't leak.
+	var wg sync.WaitGroup
+	for x := 0; x < 100; x++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+			name := strconv.Itoa(x % 10)
+			nl.Lock(name)
+			nl.Unlock(name)
+		}(x)
+	}
+	wg.Wait()
+	if l := nl.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	nl := New()
+	if !nl.TryLock("a") {
+		t.Fatalf("TryLock() of unlocked name failed")
+	}
+	if nl.TryLock("a") {
+		t.Errorf("TryLock() of locked name succeeded")
+	}
+	if !nl.TryLock("b") {
+		t.Errorf("TryLock() of another name failed")
+	}
+	nl.Unlock("b")
+	if l := nl.Len(); l != 1 {
+		t.Errorf("Len() = %v, wanted 1", l)
+	}
+	nl.Unlock("a")
+	if !nl.TryLock("a") {
+		t.Errorf("TryLock() after Unlock() failed")
+	}
+	nl.Unlock("a")
+}
+
+func TestLockTimeout(t *testing.T) {
+	nl := New()
+	if err := nl.LockTimeout("a", time.Millisecond); err != nil {
+		t.Fatalf("LockTimeout() of unlocked name: %v", err)
+	}
+	start := time.Now()
+	if err := nl.LockTimeout("a", 20*time.Millisecond); err != ErrTimeout {
+		t.Errorf("LockTimeout() of locked name = %v", err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("LockTimeout() returned after %v", d)
+	}
+	if l := nl.Len(); l != 1 {
+		t.Errorf("Len() = %v, wanted 1", l)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		nl.Unlock("a")
+	}()
+	if err := nl.LockTimeout("a", time.Second); err != nil {
+		t.Errorf("LockTimeout() after Unlock(): %v", err)
+	}
+	nl.Unlock("a")
+	if l := nl.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+}
+
+func TestLockContext(t *testing.T) {
+	nl := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := nl.LockContext(ctx, "a"); err != nil {
+		t.Fatalf("LockContext() of unlocked name: %v", err)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if err := nl.LockContext(ctx, "a"); err != context.Canceled {
+		t.Errorf("LockContext() of locked name = %v", err)
+	}
+	if l := nl.Len(); l != 1 {
+		t.Errorf("Len() = %v, wanted 1", l)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		nl.Unlock("a")
+	}()
+	if err := nl.LockContext(context.Background(), "a"); err != nil {
+		t.Errorf("LockContext() after Unlock(): %v", err)
+	}
+	nl.Unlock("a")
+}
+
+func TestWithLock(t *testing.T) {
+	nl := New()
+	nl.WithLock("a", func() {
+		if nl.TryLock("a") {
+			t.Errorf("name not locked in WithLock()")
+		}
+	})
+	ferr := errors.New("fn failed")
+	err := nl.WithLockErr("a", func() error {
+		if nl.TryLock("a") {
+			t.Errorf("name not locked in WithLockErr()")
+		}
+		return ferr
+	})
+	if err != ferr {
+		t.Errorf("WithLockErr() = %v", err)
+	}
+
+	// It's unlocked if fn panics.
+	func() {
+		defer func() {
+			if r := recover(); r != "oops" {
+				t.Errorf("recover() = %v", r)
+			}
+		}()
+		nl.WithLock("a", func() { panic("oops") })
+	}()
+	if l := nl.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+}
+
+func TestFair(t *testing.T) {
+	nl := New()
+	nl.Fair()
+	queued := func(n int) {
+		for {
+			nl.l.Lock()
+			l := 0
+			if e, ok := nl.m["a"]; ok {
+				l = len(e.queue)
+			}
+			nl.l.Unlock()
+			if l == n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// The holder can't take the name back from a waiter.
+	nl.Lock("a")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		nl.Lock("a")
+		nl.Unlock("a")
+	}()
+	queued(1)
+	nl.Unlock("a")
+	if nl.TryLock("a") {
+		t.Errorf("TryLock() took the name from a waiter")
+		nl.Unlock("a")
+	}
+	<-done
+
+	// Waiters get the name in order, skipping those that gave up.
+	nl.Lock("a")
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for x := 0; x < 5; x++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+			if x == 2 {
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				defer cancel()
+				if err := nl.LockContext(ctx, "a"); err != context.DeadlineExceeded {
+					t.Errorf("LockContext() = %v", err)
+				}
+				return
+			}
+			nl.Lock("a")
+			mu.Lock()
+			order = append(order, x)
+			mu.Unlock()
+			nl.Unlock("a")
+		}(x)
+		queued(x + 1)
+	}
+	queued(4)
+	nl.Unlock("a")
+	wg.Wait()
+	if !reflect.DeepEqual(order, []int{0, 1, 3, 4}) {
+		t.Errorf("order = %v, wanted [0 1 3 4]", order)
+	}
+	if l := nl.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+}
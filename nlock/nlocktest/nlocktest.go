@@ -0,0 +1,76 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+// Package nlocktest provides helpers for testing implementations of
+// nlock.LockBackend. For example:
+//
+//	func TestBackend(t *testing.T) {
+//		nlocktest.CheckBackend(t, NewBackend(addr), NewBackend(addr))
+//	}
+package nlocktest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/icub3d/gop/nlock"
+)
+
+// CheckBackend checks that two NamedLocks using backends that share
+// their names, e.g. two connections to the same lock server, exclude
+// each other. The names "a" and "b" are used.
+func CheckBackend(t *testing.T, a, b nlock.LockBackend) {
+	nla, nlb := nlock.NewWithBackend(a), nlock.NewWithBackend(b)
+	nla.Lock("a")
+	if nlb.TryLock("a") {
+		t.Errorf("TryLock() of name locked in the backend succeeded")
+	}
+	if !nlb.TryLock("b") {
+		t.Errorf("TryLock() of free name failed")
+	}
+	nlb.Unlock("b")
+	if err := nlb.LockTimeout("a", 20*time.Millisecond); err != nlock.ErrTimeout {
+		t.Errorf("LockTimeout() = %v, wanted %v", err, nlock.ErrTimeout)
+	}
+	nla.Unlock("a")
+	if err := nlb.LockTimeout("a", 5*time.Second); err != nil {
+		t.Errorf("LockTimeout() after Unlock(): %v", err)
+	}
+	nlb.Unlock("a")
+	if la, lb := nla.Len(), nlb.Len(); la != 0 || lb != 0 {
+		t.Errorf("Len() = %v and %v, wanted 0", la, lb)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders int
+	)
+	for x := 0; x < 6; x++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+			nl := nla
+			if x%2 == 1 {
+				nl = nlb
+			}
+			nl.WithLock("a", func() {
+				mu.Lock()
+				holders++
+				if holders > 1 {
+					t.Errorf("%v holders of the lock", holders)
+				}
+				mu.Unlock()
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				holders--
+				mu.Unlock()
+			})
+		}(x)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,18 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package nlocktest
+
+import (
+	"testing"
+
+	"github.com/icub3d/gop/nlock"
+)
+
+func TestCheckBackend(t *testing.T) {
+	b := nlock.NewLocalBackend()
+	CheckBackend(t, b, b)
+}
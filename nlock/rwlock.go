@@ -0,0 +1,95 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package nlock
+
+import "sync"
+
+// NamedRWLock is like NamedLock but each name is a reader/writer lock
+// (see sync.RWMutex). Many goroutines can read lock a name at the same
+// time but only one can lock it. It is instantiated with the NewRW()
+// function.
+type NamedRWLock struct {
+	l sync.Mutex
+	m map[string]*rwEntry
+}
+
+// rwEntry is the lock of a name. refs is the number of goroutines
+// holding or waiting for it. The entry is removed when it's 0.
+type rwEntry struct {
+	mu   sync.RWMutex
+	refs int
+}
+
+// NewRW creates a new NamedRWLock.
+func NewRW() *NamedRWLock {
+	return &NamedRWLock{
+		m: map[string]*rwEntry{},
+	}
+}
+
+// Lock locks the given name for writing. If name is already locked for
+// reading or writing, it blocks until the lock is available.
+func (nl *NamedRWLock) Lock(name string) {
+	nl.acquire(name).mu.Lock()
+}
+
+// Unlock unlocks the given name for writing. It is a run-time error if
+// the name is not locked for writing when Unlock is called, except that
+// nothing happens if no goroutine is waiting for it either.
+func (nl *NamedRWLock) Unlock(name string) {
+	nl.release(name, func(e *rwEntry) { e.mu.Unlock() })
+}
+
+// RLock locks the given name for reading. If name is locked for
+// writing, it blocks until the lock is available.
+func (nl *NamedRWLock) RLock(name string) {
+	nl.acquire(name).mu.RLock()
+}
+
+// RUnlock undoes a single RLock of the given name. It is a run-time
+// error if the name is not locked for reading when RUnlock is called,
+// except that nothing happens if no goroutine is waiting for it either.
+func (nl *NamedRWLock) RUnlock(name string) {
+	nl.release(name, func(e *rwEntry) { e.mu.RUnlock() })
+}
+
+// Len returns the number of names that are locked or being waited on.
+func (nl *NamedRWLock) Len() int {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	return len(nl.m)
+}
+
+// acquire returns the entry for the name, creating it if needed, and
+// adds a reference to it.
+func (nl *NamedRWLock) acquire(name string) *rwEntry {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	e, ok := nl.m[name]
+	if !ok {
+		e = &rwEntry{}
+		nl.m[name] = e
+	}
+	e.refs++
+	return e
+}
+
+// release unlocks the entry for the name with the given function and
+// removes a reference to it. The entry is removed once there are none.
+func (nl *NamedRWLock) release(name string, unlock func(*rwEntry)) {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	e, ok := nl.m[name]
+	if !ok {
+		return
+	}
+	unlock(e)
+	e.refs--
+	if e.refs == 0 {
+		delete(nl.m, name)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package nlock
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNamedRWLock(t *testing.T) {
+	nl := NewRW()
+
+	// Readers don't block each other.
+	nl.RLock("a")
+	nl.RLock("a")
+	nl.Lock("b")
+	if l := nl.Len(); l != 2 {
+		t.Errorf("Len() = %v, wanted 2", l)
+	}
+	nl.Unlock("c")
+
+	// A writer waits for the readers.
+	locked := make(chan struct{})
+	go func() {
+		nl.Lock("a")
+		close(locked)
+	}()
+	nl.RUnlock("a")
+	select {
+	case <-locked:
+		t.Fatalf("Lock() didn't wait for a reader")
+	case <-time.After(20 * time.Millisecond):
+	}
+	nl.RUnlock("a")
+	<-locked
+	nl.Unlock("a")
+	nl.Unlock("b")
+	if l := nl.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+
+	// Writers are exclusive.
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		readers int
+		writers int
+	)
+	check := func(w int) {
+		mu.Lock()
+		readers += 1 - w
+		writers += w
+		if writers > 1 || (writers == 1 && readers > 0) {
+			t.Errorf("%v readers and %v writers", readers, writers)
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		readers -= 1 - w
+		writers -= w
+		mu.Unlock()
+	}
+	for x := 0; x < 20; x++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+			if x%4 == 0 {
+				nl.Lock("a")
+				check(1)
+				nl.Unlock("a")
+				return
+			}
+			nl.RLock("a")
+			check(0)
+			nl.RUnlock("a")
+		}(x)
+	}
+	wg.Wait()
+	if l := nl.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package nlock
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// NamedSemaphore is like NamedLock but up to n goroutines can hold each
+// name at the same time. For example, it can limit the number of
+// concurrent operations of each user. It is instantiated with the
+// NewSemaphore() function.
+type NamedSemaphore struct {
+	nl *NamedLock
+}
+
+// NewSemaphore creates a new NamedSemaphore where each name has n
+// slots. It panics if n < 1.
+func NewSemaphore(n int) *NamedSemaphore {
+	if n < 1 {
+		panic(fmt.Sprintf("nlock: invalid semaphore size %v", n))
+	}
+	nl := New()
+	nl.n = n
+	return &NamedSemaphore{nl: nl}
+}
+
+// Fair makes waiters get the slots of the names in the order they
+// started waiting for them (see NamedLock.Fair). It must be called
+// before the semaphore is used.
+func (s *NamedSemaphore) Fair() {
+	s.nl.Fair()
+}
+
+// Acquire takes one of the slots of the given name. If they are all
+// taken, it blocks until one is released.
+func (s *NamedSemaphore) Acquire(name string) {
+	s.nl.Lock(name)
+}
+
+// TryAcquire takes one of the slots of the given name if one is free.
+// It never blocks and returns true if it got one.
+func (s *NamedSemaphore) TryAcquire(name string) bool {
+	return s.nl.TryLock(name)
+}
+
+// AcquireContext takes one of the slots of the given name. If they are
+// all taken, it waits until one is released or the context is done. In
+// that case, the context's error is returned.
+func (s *NamedSemaphore) AcquireContext(ctx context.Context, name string) error {
+	return s.nl.LockContext(ctx, name)
+}
+
+// Release releases a slot of the given name. It is a run-time error if
+// no slot is taken when Release is called, except that nothing happens
+// if no goroutine is waiting for one either.
+func (s *NamedSemaphore) Release(name string) {
+	s.nl.Unlock(name)
+}
+
+// Len returns the number of names that have slots taken or being
+// waited on.
+func (s *NamedSemaphore) Len() int {
+	return s.nl.Len()
+}
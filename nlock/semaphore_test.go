@@ -0,0 +1,77 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package nlock
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestNamedSemaphore(t *testing.T) {
+	s := NewSemaphore(3)
+	for x := 0; x < 3; x++ {
+		if !s.TryAcquire("a") {
+			t.Fatalf("TryAcquire() %v failed", x)
+		}
+	}
+	if s.TryAcquire("a") {
+		t.Errorf("TryAcquire() of full name succeeded")
+	}
+	s.Acquire("b")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.AcquireContext(ctx, "a"); err != context.DeadlineExceeded {
+		t.Errorf("AcquireContext() of full name = %v", err)
+	}
+	s.Release("a")
+	if err := s.AcquireContext(context.Background(), "a"); err != nil {
+		t.Errorf("AcquireContext() after Release() = %v", err)
+	}
+	for x := 0; x < 3; x++ {
+		s.Release("a")
+	}
+	s.Release("b")
+	if l := s.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+
+	// No more than 3 hold a name at once.
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders int
+	)
+	for x := 0; x < 20; x++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Acquire("a")
+			mu.Lock()
+			holders++
+			if holders > 3 {
+				t.Errorf("%v holders", holders)
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			s.Release("a")
+		}()
+	}
+	wg.Wait()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewSemaphore(0) didn't panic")
+		}
+	}()
+	NewSemaphore(0)
+}
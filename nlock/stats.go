@@ -0,0 +1,160 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package nlock
+
+import (
+	"expvar"
+	"time"
+)
+
+// Stats are the statistics of a name collected after CollectStats is
+// called.
+type Stats struct {
+	// Acquisitions is the number of times the name was locked.
+	Acquisitions uint64
+
+	// Waiters is the number of goroutines waiting for the name now.
+	Waiters int
+
+	// TotalWait and MaxWait are the total and longest times spent
+	// waiting for the name. Waits that timed out are included.
+	TotalWait time.Duration
+	MaxWait   time.Duration
+
+	// TotalHold and MaxHold are the total and longest times the name
+	// was held.
+	TotalHold time.Duration
+	MaxHold   time.Duration
+}
+
+// nameStats are the statistics of a name and the times it was locked
+// by its current holders from the oldest to the newest.
+type nameStats struct {
+	Stats
+	held []time.Time
+}
+
+// CollectStats turns on collecting statistics for each name (see
+// Stats). Unlike the locks, the statistics of a name are kept after
+// it's unlocked until ResetStats is called. It must be called before
+// the lock is used.
+func (nl *NamedLock) CollectStats() {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	nl.stats = map[string]*nameStats{}
+}
+
+// Stats returns the statistics of the given name. They are empty
+// unless CollectStats was called.
+func (nl *NamedLock) Stats(name string) Stats {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	if s, ok := nl.stats[name]; ok {
+		return s.Stats
+	}
+	return Stats{}
+}
+
+// Snapshot returns the statistics of all of the names. It's nil unless
+// CollectStats was called.
+func (nl *NamedLock) Snapshot() map[string]Stats {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	if nl.stats == nil {
+		return nil
+	}
+	m := make(map[string]Stats, len(nl.stats))
+	for name, s := range nl.stats {
+		m[name] = s.Stats
+	}
+	return m
+}
+
+// ResetStats forgets the statistics of the names that aren't locked or
+// being waited on and clears the rest.
+func (nl *NamedLock) ResetStats() {
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	for name, s := range nl.stats {
+		if _, ok := nl.m[name]; !ok {
+			delete(nl.stats, name)
+			continue
+		}
+		s.Stats = Stats{Waiters: s.Waiters}
+	}
+}
+
+// Publish publishes the snapshot of the statistics as an expvar with
+// the given name. Like expvar.Publish, it panics if the name is already
+// used.
+func (nl *NamedLock) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return nl.Snapshot()
+	}))
+}
+
+// statsWait records that a goroutine started waiting for the name. The
+// caller must hold nl.l.
+func (nl *NamedLock) statsWait(name string) {
+	if nl.stats == nil {
+		return
+	}
+	s, ok := nl.stats[name]
+	if !ok {
+		s = &nameStats{}
+		nl.stats[name] = s
+	}
+	s.Waiters++
+}
+
+// statsDone records that a goroutine stopped waiting for the name after
+// it started at the given time and whether it got it.
+func (nl *NamedLock) statsDone(name string, start time.Time, locked bool) {
+	if nl.stats == nil {
+		return
+	}
+	now := time.Now()
+	nl.l.Lock()
+	defer nl.l.Unlock()
+	s, ok := nl.stats[name]
+	if !ok {
+		// The statistics were reset.
+		s = &nameStats{}
+		nl.stats[name] = s
+	}
+	if s.Waiters > 0 {
+		s.Waiters--
+	}
+	wait := now.Sub(start)
+	s.TotalWait += wait
+	if wait > s.MaxWait {
+		s.MaxWait = wait
+	}
+	if locked {
+		s.Acquisitions++
+		s.held = append(s.held, now)
+	}
+}
+
+// statsUnlock records that the name was unlocked. For a semaphore, the
+// oldest holder is assumed to be the one releasing it. The caller must
+// hold nl.l.
+func (nl *NamedLock) statsUnlock(name string) {
+	if nl.stats == nil {
+		return
+	}
+	s, ok := nl.stats[name]
+	if !ok || len(s.held) == 0 {
+		return
+	}
+	hold := time.Since(s.held[0])
+	s.held = s.held[1:]
+	s.TotalHold += hold
+	if hold > s.MaxHold {
+		s.MaxHold = hold
+	}
+}
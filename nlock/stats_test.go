@@ -0,0 +1,91 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package nlock
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	nl := New()
+	nl.Lock("a")
+	nl.Unlock("a")
+	if s := nl.Snapshot(); s != nil {
+		t.Errorf("Snapshot() without CollectStats() = %v", s)
+	}
+
+	nl.CollectStats()
+	nl.Lock("a")
+	locked := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		nl.Lock("a")
+		close(locked)
+		nl.Unlock("a")
+	}()
+	for nl.Stats("a").Waiters != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	nl.Unlock("a")
+	<-done
+	if nl.TryLock("b") != true || nl.TryLock("b") != false {
+		t.Fatalf("TryLock() of b didn't succeed once")
+	}
+	nl.Unlock("b")
+
+	a := nl.Stats("a")
+	if a.Acquisitions != 2 || a.Waiters != 0 {
+		t.Errorf("Stats(a) = %+v, wanted 2 acquisitions and no waiters", a)
+	}
+	if a.MaxWait < 10*time.Millisecond || a.TotalWait < a.MaxWait {
+		t.Errorf("Stats(a) wait = %v total, %v max, wanted at least 10ms",
+			a.TotalWait, a.MaxWait)
+	}
+	if a.MaxHold < 10*time.Millisecond || a.TotalHold < a.MaxHold {
+		t.Errorf("Stats(a) hold = %v total, %v max, wanted at least 10ms",
+			a.TotalHold, a.MaxHold)
+	}
+	if b := nl.Stats("b"); b.Acquisitions != 1 {
+		t.Errorf("Stats(b) = %+v, wanted 1 acquisition", b)
+	}
+	if s := nl.Snapshot(); len(s) != 2 || s["a"] != a {
+		t.Errorf("Snapshot() = %v", s)
+	}
+	if l := nl.Len(); l != 0 {
+		t.Errorf("Len() = %v, wanted 0", l)
+	}
+
+	// Publish as an expvar. Vars can't be unpublished, so it's only
+	// checked the first time the test is run.
+	if expvar.Get("nlock_test_stats") == nil {
+		nl.Publish("nlock_test_stats")
+		var m map[string]Stats
+		v := expvar.Get("nlock_test_stats").String()
+		if err := json.Unmarshal([]byte(v), &m); err != nil {
+			t.Fatalf("unmarshaling expvar: %v", err)
+		}
+		if m["a"] != a {
+			t.Errorf("expvar a = %+v, wanted %+v", m["a"], a)
+		}
+	}
+
+	// Only the held names are kept on reset.
+	nl.Lock("c")
+	nl.ResetStats()
+	if s := nl.Snapshot(); len(s) != 1 || s["c"] != (Stats{}) {
+		t.Errorf("Snapshot() after ResetStats() = %v", s)
+	}
+	nl.Unlock("c")
+	if c := nl.Stats("c"); c.Acquisitions != 0 || c.MaxHold == 0 {
+		t.Errorf("Stats(c) = %+v, wanted only the hold", c)
+	}
+}
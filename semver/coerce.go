@@ -0,0 +1,81 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Coerce makes a best effort to get a semantic version from a string
+// that isn't one, like "1.2.3.4", "release-2021.04", "1.2" or "v2rc1".
+// The version starts at the first number and has up to three numbers
+// separated by dots. Missing numbers are 0. What follows becomes the
+// pre-release version and build metadata if it's valid for them, so
+// "v2rc1" is v2.0.0-rc1. The returned bool is true if the conversion
+// was lossy: part of the string was dropped (other than a leading v) or
+// a number had leading zeros. If there is no number, the error is
+// ErrParse.
+func Coerce(s string) (SemanticVersion, bool, error) {
+	var v SemanticVersion
+	i := strings.IndexAny(s, "0123456789")
+	if i < 0 {
+		return v, false, ErrParse
+	}
+	prefix := s[:i]
+	lossy := prefix != "" && prefix != "v" && prefix != "V"
+	rest := s[i:]
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for x := 0; ; x++ {
+		end := len(rest) - len(strings.TrimLeft(rest, "0123456789"))
+		part := rest[:end]
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			// It's too large.
+			return SemanticVersion{}, false, ErrParse
+		}
+		if x < len(nums) {
+			*nums[x] = n
+		} else {
+			lossy = true
+		}
+		if len(part) > 1 && part[0] == '0' {
+			lossy = true
+		}
+		rest = rest[end:]
+		if len(rest) < 2 || rest[0] != '.' || rest[1] < '0' || rest[1] > '9' {
+			break
+		}
+		rest = rest[1:]
+	}
+	if rest == "" {
+		return v, lossy, nil
+	}
+
+	// What's left may be a pre-release version and build metadata.
+	if rest[0] == '-' || rest[0] == '.' {
+		rest = rest[1:]
+		if rest == "" {
+			return v, true, nil
+		}
+	}
+	pre, build := rest, ""
+	if x := strings.Index(rest, "+"); x >= 0 {
+		pre, build = rest[:x], rest[x+1:]
+	}
+	if pre != "" && validIdentifiers(pre, true) {
+		v.Prerelease = pre
+	} else if pre != "" {
+		lossy = true
+	}
+	if build != "" && validIdentifiers(build, false) {
+		v.Build = build
+	} else if build != "" || strings.HasSuffix(rest, "+") {
+		lossy = true
+	}
+	return v, lossy, nil
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import "testing"
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected string
+		lossy    bool
+		err      error
+	}{
+		{s: "v1.2.3", expected: "v1.2.3"},
+		{s: "1.2.3-rc.1+b5", expected: "v1.2.3-rc.1+b5"},
+		{s: "1.2", expected: "v1.2.0"},
+		{s: "V7", expected: "v7.0.0"},
+		{s: "v2rc1", expected: "v2.0.0-rc1"},
+		{s: "1.2.3.4", expected: "v1.2.3", lossy: true},
+		{s: "1.2.3.4-beta", expected: "v1.2.3-beta", lossy: true},
+		{s: "release-2021.04", expected: "v2021.4.0", lossy: true},
+		{s: "go1.5.2", expected: "v1.5.2", lossy: true},
+		{s: "1.2.", expected: "v1.2.0", lossy: true},
+		{s: "1.2_beta", expected: "v1.2.0", lossy: true},
+		{s: "1.0-rc.01", expected: "v1.0.0", lossy: true},
+		{s: "1.0+a+b", expected: "v1.0.0", lossy: true},
+		{s: "1.0+", expected: "v1.0.0", lossy: true},
+		{s: "latest", err: ErrParse},
+		{s: "", err: ErrParse},
+		{s: "99999999999999999999", err: ErrParse},
+	}
+	for i, test := range tests {
+		v, lossy, err := Coerce(test.s)
+		if err != test.err {
+			t.Errorf("Test %v: Coerce(%q) returned error %v, wanted %v", i,
+				test.s, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if v.String() != test.expected || lossy != test.lossy {
+			t.Errorf("Test %v: Coerce(%q) = %v, %v, wanted %v, %v", i, test.s,
+				v, lossy, test.expected, test.lossy)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Compare returns -1 if v has a lower precedence than o, 1 if it has a
+// higher one and 0 if they are equal. The major, minor and patch
+// versions are compared in that order. A version with a pre-release
+// version has a lower precedence than one without. Pre-release versions
+// are compared by their identifiers (see comparePrerelease). The build
+// metadata is ignored.
+func (v SemanticVersion) Compare(o SemanticVersion) int {
+	if c := compareInt(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// Less returns true if v has a lower precedence than o.
+func (v SemanticVersion) Less(o SemanticVersion) bool {
+	return v.Compare(o) < 0
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparePrerelease compares the dot separated identifiers of the
+// pre-release versions from left to right. Numeric identifiers are
+// compared numerically and others in ASCII order. Numeric identifiers
+// are lower than others. If all of them are equal, the one with more
+// identifiers is higher.
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for x := 0; x < len(as) && x < len(bs); x++ {
+		an, aErr := strconv.ParseUint(as[x], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[x], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[x], bs[x]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInt(len(as), len(bs))
+}
+
+// Versions is a list of versions that can be sorted by precedence.
+type Versions []SemanticVersion
+
+func (vs Versions) Len() int           { return len(vs) }
+func (vs Versions) Less(i, j int) bool { return vs[i].Less(vs[j]) }
+func (vs Versions) Swap(i, j int)      { vs[i], vs[j] = vs[j], vs[i] }
+
+// Sort sorts the versions from the lowest precedence to the highest.
+// Versions with equal precedence keep their order.
+func Sort(vs []SemanticVersion) {
+	sort.Stable(Versions(vs))
+}
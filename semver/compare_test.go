@@ -0,0 +1,81 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func ExampleSort() {
+	var vs []SemanticVersion
+	for _, s := range []string{"v1.0.0", "v1.0.0-rc.1", "v0.9.12", "v1.0.0-beta"} {
+		v, _ := New(s)
+		vs = append(vs, v)
+	}
+	Sort(vs)
+	fmt.Println(vs)
+
+	// Output:
+	// [v0.9.12 v1.0.0-beta v1.0.0-rc.1 v1.0.0]
+}
+
+func TestSemanticVersionCompare(t *testing.T) {
+	// This is the order from the spec with a few more.
+	ordered := []string{
+		"v0.9.9",
+		"v1.0.0-alpha",
+		"v1.0.0-alpha.1",
+		"v1.0.0-alpha.beta",
+		"v1.0.0-beta",
+		"v1.0.0-beta.2",
+		"v1.0.0-beta.11",
+		"v1.0.0-rc.1",
+		"v1.0.0",
+		"v1.0.1",
+		"v1.2.0",
+		"v1.10.0",
+		"v2.0.0",
+	}
+	for i, a := range ordered {
+		va, err := New(a)
+		if err != nil {
+			t.Fatalf("New(%v): %v", a, err)
+		}
+		for j, b := range ordered {
+			vb, _ := New(b)
+			exp := compareInt(i, j)
+			if c := va.Compare(vb); c != exp {
+				t.Errorf("%v.Compare(%v) = %v, wanted %v", a, b, c, exp)
+			}
+			if l := va.Less(vb); l != (exp < 0) {
+				t.Errorf("%v.Less(%v) = %v, wanted %v", a, b, l, exp < 0)
+			}
+		}
+	}
+
+	// The build metadata is ignored.
+	a, _ := New("v1.0.0-rc.1+build.1")
+	b, _ := New("v1.0.0-rc.1+build.2")
+	if c := a.Compare(b); c != 0 {
+		t.Errorf("%v.Compare(%v) = %v, wanted 0", a, b, c)
+	}
+}
+
+func TestSort(t *testing.T) {
+	var vs, exp []SemanticVersion
+	for _, s := range []string{"v1.0.0+b", "v0.1.0", "v1.0.0+a", "v1.0.0-rc.1"} {
+		v, _ := New(s)
+		vs = append(vs, v)
+	}
+	exp = []SemanticVersion{vs[1], vs[3], vs[0], vs[2]}
+	Sort(vs)
+	if !reflect.DeepEqual(vs, exp) {
+		t.Errorf("Sort() = %v, wanted %v", vs, exp)
+	}
+}
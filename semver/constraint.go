@@ -0,0 +1,347 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrConstraint is returned when NewConstraint is unable to parse the
+// given string into a constraint.
+var ErrConstraint = errors.New("unable to parse given string into a version constraint")
+
+// Constraint is a set of versions, like ">=v1.2.0 <v1.5.0 || >=v2.0.0".
+// It's created with NewConstraint. The zero value matches nothing.
+type Constraint struct {
+	// The versions are those in any of the ranges.
+	ranges []versionRange
+}
+
+// versionRange is the versions between two bounds.
+type versionRange struct {
+	lo, hi bound
+}
+
+// bound is one end of a range. If unbounded is true, there is no limit
+// on that end.
+type bound struct {
+	v         SemanticVersion
+	inclusive bool
+	unbounded bool
+}
+
+// NewConstraint creates a constraint from the given string. It's a list
+// of alternatives separated by "||". A version matches if it matches any
+// of them. Each alternative is a list of comparisons separated by
+// spaces or commas and a version matches if it matches all of them. The
+// comparisons are:
+//
+//	v1.2.3, =v1.2.3  exactly v1.2.3
+//	>v1.2.3          after v1.2.3 (also >=, < and <=)
+//	^v1.2.3          compatible with v1.2.3: >=v1.2.3 <v2.0.0
+//	~v1.2.3          the same minor version: >=v1.2.3 <v1.3.0
+//	*                any version
+//
+// The leading v of the versions is optional. The build metadata is
+// ignored when comparing versions, as in Compare.
+func NewConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, alt := range strings.Split(s, "||") {
+		r := versionRange{lo: bound{unbounded: true}, hi: bound{unbounded: true}}
+		fields := strings.Fields(strings.Replace(alt, ",", " ", -1))
+		if len(fields) == 0 {
+			return Constraint{}, ErrConstraint
+		}
+		for x := 0; x < len(fields); x++ {
+			f := fields[x]
+			// Allow a space between the operator and the version.
+			if strings.Trim(f, "<>=^~") == "" && f != "" && x+1 < len(fields) {
+				x++
+				f += fields[x]
+			}
+			cr, err := parseComparison(f)
+			if err != nil {
+				return Constraint{}, err
+			}
+			r = r.intersect(cr)
+		}
+		c.ranges = append(c.ranges, r)
+	}
+	return c.normalize(), nil
+}
+
+// parseComparison parses a single comparison into the range of versions
+// it matches.
+func parseComparison(s string) (versionRange, error) {
+	open := bound{unbounded: true}
+	if s == "*" {
+		return versionRange{lo: open, hi: open}, nil
+	}
+	vs := strings.TrimLeft(s, "<>=^~")
+	op := s[:len(s)-len(vs)]
+	if !strings.HasPrefix(vs, "v") {
+		vs = "v" + vs
+	}
+	v, err := New(vs)
+	if err != nil {
+		return versionRange{}, ErrConstraint
+	}
+	switch op {
+	case "", "=":
+		return versionRange{lo: bound{v: v, inclusive: true},
+			hi: bound{v: v, inclusive: true}}, nil
+	case ">":
+		return versionRange{lo: bound{v: v}, hi: open}, nil
+	case ">=":
+		return versionRange{lo: bound{v: v, inclusive: true}, hi: open}, nil
+	case "<":
+		return versionRange{lo: open, hi: bound{v: v}}, nil
+	case "<=":
+		return versionRange{lo: open, hi: bound{v: v, inclusive: true}}, nil
+	case "^":
+		return versionRange{lo: bound{v: v, inclusive: true},
+			hi: bound{v: SemanticVersion{Major: v.Major + 1}}}, nil
+	case "~":
+		return versionRange{lo: bound{v: v, inclusive: true},
+			hi: bound{v: SemanticVersion{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	}
+	return versionRange{}, ErrConstraint
+}
+
+// Check returns true if the version matches the constraint. Pre-release
+// versions are compared like any other, so v2.0.0-rc.1 matches
+// "<v2.0.0".
+func (c Constraint) Check(v SemanticVersion) bool {
+	for _, r := range c.ranges {
+		if r.contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersect returns a constraint matching the versions that match both
+// c and o.
+func (c Constraint) Intersect(o Constraint) Constraint {
+	var n Constraint
+	for _, r := range c.ranges {
+		for _, or := range o.ranges {
+			n.ranges = append(n.ranges, r.intersect(or))
+		}
+	}
+	return n.normalize()
+}
+
+// Union returns a constraint matching the versions that match either c
+// or o.
+func (c Constraint) Union(o Constraint) Constraint {
+	var n Constraint
+	n.ranges = append(n.ranges, c.ranges...)
+	n.ranges = append(n.ranges, o.ranges...)
+	return n.normalize()
+}
+
+// IsEmpty returns true if no version matches the constraint, like
+// ">v2.0.0 <v1.0.0".
+func (c Constraint) IsEmpty() bool {
+	return len(c.ranges) == 0
+}
+
+// String returns the constraint in the form NewConstraint parses. It's
+// normalized: each alternative is given by its bounds, so "^v1.2.3" is
+// ">=v1.2.3 <v2.0.0", and the alternatives are sorted and don't
+// overlap. Equal constraints have the same string. An empty constraint
+// is ">v0.0.0 <v0.0.0".
+func (c Constraint) String() string {
+	if c.IsEmpty() {
+		return ">v0.0.0 <v0.0.0"
+	}
+	alts := make([]string, len(c.ranges))
+	for x, r := range c.ranges {
+		alts[x] = r.String()
+	}
+	return strings.Join(alts, " || ")
+}
+
+func (r versionRange) String() string {
+	switch {
+	case r.lo.unbounded && r.hi.unbounded:
+		return "*"
+	case !r.lo.unbounded && !r.hi.unbounded && r.lo.inclusive &&
+		r.hi.inclusive && r.lo.v.Compare(r.hi.v) == 0:
+		return "=" + r.lo.v.String()
+	}
+	var parts []string
+	if !r.lo.unbounded {
+		op := ">"
+		if r.lo.inclusive {
+			op = ">="
+		}
+		parts = append(parts, op+r.lo.v.String())
+	}
+	if !r.hi.unbounded {
+		op := "<"
+		if r.hi.inclusive {
+			op = "<="
+		}
+		parts = append(parts, op+r.hi.v.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// contains returns true if the version is in the range.
+func (r versionRange) contains(v SemanticVersion) bool {
+	if !r.lo.unbounded {
+		c := v.Compare(r.lo.v)
+		if c < 0 || (c == 0 && !r.lo.inclusive) {
+			return false
+		}
+	}
+	if !r.hi.unbounded {
+		c := v.Compare(r.hi.v)
+		if c > 0 || (c == 0 && !r.hi.inclusive) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize removes the empty ranges and merges the ones that overlap
+// or touch, leaving them sorted.
+func (c Constraint) normalize() Constraint {
+	var rs []versionRange
+	for _, r := range c.ranges {
+		if !r.empty() {
+			rs = append(rs, r)
+		}
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		return compareLower(rs[i].lo, rs[j].lo) < 0
+	})
+	var n Constraint
+	for _, r := range rs {
+		last := len(n.ranges) - 1
+		if last >= 0 && n.ranges[last].touches(r) {
+			if compareUpper(r.hi, n.ranges[last].hi) > 0 {
+				n.ranges[last].hi = r.hi
+			}
+			continue
+		}
+		n.ranges = append(n.ranges, r)
+	}
+	return n
+}
+
+// compareLower compares two lower bounds. The lower one matches more
+// versions.
+func compareLower(a, b bound) int {
+	switch {
+	case a.unbounded && b.unbounded:
+		return 0
+	case a.unbounded:
+		return -1
+	case b.unbounded:
+		return 1
+	}
+	if c := a.v.Compare(b.v); c != 0 {
+		return c
+	}
+	switch {
+	case a.inclusive == b.inclusive:
+		return 0
+	case a.inclusive:
+		return -1
+	}
+	return 1
+}
+
+// compareUpper compares two upper bounds. The higher one matches more
+// versions.
+func compareUpper(a, b bound) int {
+	switch {
+	case a.unbounded && b.unbounded:
+		return 0
+	case a.unbounded:
+		return 1
+	case b.unbounded:
+		return -1
+	}
+	if c := a.v.Compare(b.v); c != 0 {
+		return c
+	}
+	switch {
+	case a.inclusive == b.inclusive:
+		return 0
+	case a.inclusive:
+		return 1
+	}
+	return -1
+}
+
+// empty returns true if no version is in the range.
+func (r versionRange) empty() bool {
+	if r.lo.unbounded || r.hi.unbounded {
+		return false
+	}
+	c := r.lo.v.Compare(r.hi.v)
+	return c > 0 || (c == 0 && !(r.lo.inclusive && r.hi.inclusive))
+}
+
+// touches returns true if o, which doesn't start before r, overlaps r
+// or starts where r ends, so they can be merged.
+func (r versionRange) touches(o versionRange) bool {
+	if r.hi.unbounded || o.lo.unbounded {
+		return true
+	}
+	c := r.hi.v.Compare(o.lo.v)
+	return c > 0 || (c == 0 && (r.hi.inclusive || o.lo.inclusive))
+}
+
+// intersect returns the versions in both ranges. The result may be
+// empty.
+func (r versionRange) intersect(o versionRange) versionRange {
+	lo, hi := r.lo, r.hi
+	if !o.lo.unbounded && (lo.unbounded || o.lo.v.Compare(lo.v) > 0 ||
+		(o.lo.v.Compare(lo.v) == 0 && !o.lo.inclusive)) {
+		lo = o.lo
+	}
+	if !o.hi.unbounded && (hi.unbounded || o.hi.v.Compare(hi.v) < 0 ||
+		(o.hi.v.Compare(hi.v) == 0 && !o.hi.inclusive)) {
+		hi = o.hi
+	}
+	return versionRange{lo: lo, hi: hi}
+}
+
+// MaxSatisfying returns the highest of the versions that matches the
+// constraint. Pre-release versions are only considered if prerelease is
+// true. If none of them match, it returns false.
+func MaxSatisfying(vs []SemanticVersion, c Constraint, prerelease bool) (SemanticVersion, bool) {
+	return satisfying(vs, c, prerelease, 1)
+}
+
+// MinSatisfying is like MaxSatisfying but returns the lowest version.
+func MinSatisfying(vs []SemanticVersion, c Constraint, prerelease bool) (SemanticVersion, bool) {
+	return satisfying(vs, c, prerelease, -1)
+}
+
+// satisfying returns the matching version that compares as better to
+// the rest of them.
+func satisfying(vs []SemanticVersion, c Constraint, prerelease bool, better int) (SemanticVersion, bool) {
+	var best SemanticVersion
+	found := false
+	for _, v := range vs {
+		if (!prerelease && v.Prerelease != "") || !c.Check(v) {
+			continue
+		}
+		if !found || v.Compare(best) == better {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
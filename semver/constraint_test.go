@@ -0,0 +1,224 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import (
+	"fmt"
+	"testing"
+)
+
+// versions parses the given versions for tests. They must be valid.
+func versions(ss ...string) []SemanticVersion {
+	vs := make([]SemanticVersion, len(ss))
+	for x, s := range ss {
+		v, err := New(s)
+		if err != nil {
+			panic(err)
+		}
+		vs[x] = v
+	}
+	return vs
+}
+
+func ExampleMaxSatisfying() {
+	vs := versions("v1.2.0", "v1.4.1", "v1.5.0-rc.1", "v2.0.0")
+	c, _ := NewConstraint("^v1.2.0")
+	v, _ := MaxSatisfying(vs, c, false)
+	fmt.Println(v)
+	v, _ = MaxSatisfying(vs, c, true)
+	fmt.Println(v)
+
+	// Output:
+	// v1.4.1
+	// v1.5.0-rc.1
+}
+
+func TestNewConstraint(t *testing.T) {
+	tests := []struct {
+		c        string
+		expected string
+		yes, no  []string
+		err      error
+	}{
+		{
+			c:        "v1.2.3",
+			expected: "=v1.2.3",
+			yes:      []string{"v1.2.3", "v1.2.3+build"},
+			no:       []string{"v1.2.4", "v1.2.3-rc.1"},
+		},
+		{
+			c:        ">= 1.2, <v1.5.0",
+			expected: ">=v1.2.0 <v1.5.0",
+			yes:      []string{"v1.2.0", "v1.4.9", "v1.5.0-rc.1"},
+			no:       []string{"v1.1.9", "v1.5.0", "v1.2.0-rc.1"},
+		},
+		{
+			c:        ">v1.0.0 <=v2.0.0 >=v1.5.0",
+			expected: ">=v1.5.0 <=v2.0.0",
+			yes:      []string{"v1.5.0", "v2.0.0"},
+			no:       []string{"v1.4.0", "v2.0.1"},
+		},
+		{
+			c:        "^v1.2.3 || ~v2.1.0",
+			expected: ">=v1.2.3 <v2.0.0 || >=v2.1.0 <v2.2.0",
+			yes:      []string{"v1.2.3", "v1.9.0", "v2.1.5"},
+			no:       []string{"v1.2.2", "v2.0.0", "v2.2.0"},
+		},
+		{
+			c:        "*",
+			expected: "*",
+			yes:      []string{"v0.0.0", "v9.9.9-rc"},
+		},
+		{c: "", err: ErrConstraint},
+		{c: "v1 ||", err: ErrConstraint},
+		{c: ">=", err: ErrConstraint},
+		{c: "!=v1.2.3", err: ErrConstraint},
+		{c: ">=v1.a", err: ErrConstraint},
+	}
+
+	for i, test := range tests {
+		c, err := NewConstraint(test.c)
+		if err != test.err {
+			t.Errorf("Test %v: NewConstraint(%v) returned error %v, wanted %v", i,
+				test.c, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if s := c.String(); s != test.expected {
+			t.Errorf("Test %v: String() = %v, wanted %v", i, s, test.expected)
+		}
+		for _, v := range versions(test.yes...) {
+			if !c.Check(v) {
+				t.Errorf("Test %v: %v doesn't match %v", i, v, test.c)
+			}
+		}
+		for _, v := range versions(test.no...) {
+			if c.Check(v) {
+				t.Errorf("Test %v: %v matches %v", i, v, test.c)
+			}
+		}
+	}
+	if (Constraint{}).Check(SemanticVersion{}) {
+		t.Errorf("zero constraint matches")
+	}
+}
+
+func TestSatisfying(t *testing.T) {
+	vs := versions("v1.4.1", "v1.2.0", "v2.0.0-rc.1", "v1.9.0-beta", "v2.0.0", "v1.2.0+b")
+	tests := []struct {
+		c          string
+		prerelease bool
+		max, min   string
+	}{
+		{c: "^v1.0.0", max: "v1.4.1", min: "v1.2.0"},
+		{c: "^v1.0.0", prerelease: true, max: "v2.0.0-rc.1", min: "v1.2.0"},
+		{c: ">v1.4.1", max: "v2.0.0", min: "v2.0.0"},
+		{c: ">v1.4.1", prerelease: true, max: "v2.0.0", min: "v1.9.0-beta"},
+		{c: ">v2.0.0"},
+	}
+	for i, test := range tests {
+		c, err := NewConstraint(test.c)
+		if err != nil {
+			t.Fatalf("Test %v: NewConstraint(%v): %v", i, test.c, err)
+		}
+		max, ok := MaxSatisfying(vs, c, test.prerelease)
+		if ok != (test.max != "") || (ok && max.String() != test.max) {
+			t.Errorf("Test %v: MaxSatisfying() = %v, %v, wanted %v", i, max, ok, test.max)
+		}
+		min, ok := MinSatisfying(vs, c, test.prerelease)
+		if ok != (test.min != "") || (ok && min.String() != test.min) {
+			t.Errorf("Test %v: MinSatisfying() = %v, %v, wanted %v", i, min, ok, test.min)
+		}
+	}
+}
+
+func TestConstraintAlgebra(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		intersect string
+		union     string
+	}{
+		{
+			a:         "^v1.2.0",
+			b:         ">=v1.4.0 <v3.0.0",
+			intersect: ">=v1.4.0 <v2.0.0",
+			union:     ">=v1.2.0 <v3.0.0",
+		},
+		{
+			a:         "^v1.0.0",
+			b:         "^v2.0.0",
+			intersect: ">v0.0.0 <v0.0.0",
+			union:     ">=v1.0.0 <v3.0.0",
+		},
+		{
+			a:         "<v1.0.0 || >v2.0.0",
+			b:         "v1.0.0",
+			intersect: ">v0.0.0 <v0.0.0",
+			union:     "<=v1.0.0 || >v2.0.0",
+		},
+		{
+			a:         "~v1.2.0 || ~v1.5.0",
+			b:         ">=v1.2.5 <=v1.5.1",
+			intersect: ">=v1.2.5 <v1.3.0 || >=v1.5.0 <=v1.5.1",
+			union:     ">=v1.2.0 <v1.6.0",
+		},
+		{
+			a:         "<v1.0.0",
+			b:         ">v1.0.0",
+			intersect: ">v0.0.0 <v0.0.0",
+			union:     "<v1.0.0 || >v1.0.0",
+		},
+		{
+			a:         "*",
+			b:         "v1.0.0 || v0.5.0",
+			intersect: "=v0.5.0 || =v1.0.0",
+			union:     "*",
+		},
+	}
+	for i, test := range tests {
+		a, err := NewConstraint(test.a)
+		if err != nil {
+			t.Fatalf("Test %v: NewConstraint(%v): %v", i, test.a, err)
+		}
+		b, err := NewConstraint(test.b)
+		if err != nil {
+			t.Fatalf("Test %v: NewConstraint(%v): %v", i, test.b, err)
+		}
+		for _, c := range []struct {
+			name     string
+			got, rev Constraint
+			expected string
+		}{
+			{"Intersect", a.Intersect(b), b.Intersect(a), test.intersect},
+			{"Union", a.Union(b), b.Union(a), test.union},
+		} {
+			if s := c.got.String(); s != c.expected || c.rev.String() != s {
+				t.Errorf("Test %v: %v() = %v (reversed %v), wanted %v", i,
+					c.name, s, c.rev, c.expected)
+			}
+			// The string parses to an equal constraint.
+			p, err := NewConstraint(c.got.String())
+			if err != nil || p.String() != c.expected {
+				t.Errorf("Test %v: %v() doesn't round trip: %v, %v", i,
+					c.name, p, err)
+			}
+			if empty := c.got.IsEmpty(); empty != (c.expected == ">v0.0.0 <v0.0.0") {
+				t.Errorf("Test %v: %v().IsEmpty() = %v", i, c.name, empty)
+			}
+		}
+	}
+
+	c, _ := NewConstraint(">v2.0.0 <v1.0.0 || >=v1.0.0 <=v1.0.0")
+	if c.IsEmpty() || c.String() != "=v1.0.0" {
+		t.Errorf("NewConstraint() didn't normalize: %v", c)
+	}
+	if !(Constraint{}).IsEmpty() {
+		t.Errorf("zero constraint isn't empty")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+// Change is the most significant part of a version that differs
+// between two versions.
+type Change int
+
+// The kinds of changes between versions from the least significant to
+// the most.
+const (
+	None Change = iota
+	Build
+	Prerelease
+	Patch
+	Minor
+	Major
+)
+
+func (c Change) String() string {
+	switch c {
+	case None:
+		return "none"
+	case Build:
+		return "build"
+	case Prerelease:
+		return "prerelease"
+	case Patch:
+		return "patch"
+	case Minor:
+		return "minor"
+	case Major:
+		return "major"
+	}
+	return "unknown"
+}
+
+// Diff returns the most significant part of the version that differs
+// between a and b. It doesn't matter which of them is higher.
+func Diff(a, b SemanticVersion) Change {
+	switch {
+	case a.Major != b.Major:
+		return Major
+	case a.Minor != b.Minor:
+		return Minor
+	case a.Patch != b.Patch:
+		return Patch
+	case a.Prerelease != b.Prerelease:
+		return Prerelease
+	case a.Build != b.Build:
+		return Build
+	}
+	return None
+}
+
+// IsBreaking returns true if going between a and b may break
+// compatibility. It does when the major version changes. While the
+// major version is 0, a change of the minor version does as well, and
+// so does a change of the patch version while the minor version is
+// also 0.
+func IsBreaking(a, b SemanticVersion) bool {
+	switch Diff(a, b) {
+	case Major:
+		return true
+	case Minor:
+		return a.Major == 0
+	case Patch:
+		return a.Major == 0 && a.Minor == 0
+	}
+	return false
+}
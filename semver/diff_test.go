@@ -0,0 +1,42 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		change   Change
+		breaking bool
+	}{
+		{a: "v1.2.3", b: "v2.0.0", change: Major, breaking: true},
+		{a: "v2.0.0", b: "v1.2.3", change: Major, breaking: true},
+		{a: "v1.2.3", b: "v1.3.0", change: Minor},
+		{a: "v1.2.3", b: "v1.2.4-rc.1", change: Patch},
+		{a: "v1.2.3-rc.1", b: "v1.2.3", change: Prerelease},
+		{a: "v1.2.3+a", b: "v1.2.3+b", change: Build},
+		{a: "v1.2.3+a", b: "v1.2.3+a", change: None},
+		{a: "v0.2.3", b: "v0.3.0", change: Minor, breaking: true},
+		{a: "v0.2.3", b: "v0.2.4", change: Patch},
+		{a: "v0.0.3", b: "v0.0.4", change: Patch, breaking: true},
+	}
+	for i, test := range tests {
+		vs := versions(test.a, test.b)
+		if c := Diff(vs[0], vs[1]); c != test.change {
+			t.Errorf("Test %v: Diff(%v, %v) = %v, wanted %v", i, test.a, test.b,
+				c, test.change)
+		}
+		if b := IsBreaking(vs[0], vs[1]); b != test.breaking {
+			t.Errorf("Test %v: IsBreaking(%v, %v) = %v, wanted %v", i, test.a,
+				test.b, b, test.breaking)
+		}
+	}
+	if s := Change(9).String(); s != "unknown" {
+		t.Errorf("String() of unknown change = %v", s)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalText implements encoding.TextMarshaler. The text is the same
+// as String.
+func (v SemanticVersion) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. The text is parsed
+// with New.
+func (v *SemanticVersion) UnmarshalText(text []byte) error {
+	nv, err := New(string(text))
+	if err != nil {
+		return err
+	}
+	*v = nv
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. The version is a JSON string.
+func (v SemanticVersion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The version must be a
+// JSON string.
+func (v *SemanticVersion) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return v.UnmarshalText([]byte(s))
+}
+
+// Value implements driver.Valuer. The version is stored as a string.
+func (v SemanticVersion) Value() (driver.Value, error) {
+	return v.String(), nil
+}
+
+// Scan implements sql.Scanner. The value must be a string or a []byte.
+func (v *SemanticVersion) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case string:
+		return v.UnmarshalText([]byte(s))
+	case []byte:
+		return v.UnmarshalText(s)
+	}
+	return fmt.Errorf("can't scan %T into a semantic version", src)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	type config struct {
+		Min  SemanticVersion  `json:"min"`
+		Max  *SemanticVersion `json:"max"`
+		Seen []SemanticVersion
+	}
+	in := `{"min":"v1.2.3-rc.1+b5","max":"v2.0.0","Seen":["v1.0.0","v1.1.0"]}`
+	var c config
+	if err := json.Unmarshal([]byte(in), &c); err != nil {
+		t.Fatalf("Unmarshal(): %v", err)
+	}
+	exp := SemanticVersion{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", Build: "b5"}
+	if c.Min != exp || c.Max == nil || c.Max.Major != 2 || len(c.Seen) != 2 {
+		t.Errorf("Unmarshal() = %+v", c)
+	}
+	out, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("Marshal(): %v", err)
+	}
+	if string(out) != in {
+		t.Errorf("Marshal() = %s, wanted %s", out, in)
+	}
+
+	for _, bad := range []string{`{"min":"1.2.3"}`, `{"min":1}`} {
+		if err := json.Unmarshal([]byte(bad), &c); err == nil {
+			t.Errorf("Unmarshal(%s) didn't fail", bad)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	type config struct {
+		Version SemanticVersion `xml:"version,attr"`
+	}
+	in := `<config version="v1.2.3"></config>`
+	var c config
+	if err := xml.Unmarshal([]byte(in), &c); err != nil {
+		t.Fatalf("Unmarshal(): %v", err)
+	}
+	if c.Version != (SemanticVersion{Major: 1, Minor: 2, Patch: 3}) {
+		t.Errorf("Unmarshal() = %v", c.Version)
+	}
+	out, err := xml.Marshal(c)
+	if err != nil {
+		t.Fatalf("Marshal(): %v", err)
+	}
+	if string(out) != in {
+		t.Errorf("Marshal() = %s, wanted %s", out, in)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	v := SemanticVersion{Major: 1, Minor: 2, Patch: 3, Build: "b"}
+	dv, err := v.Value()
+	if err != nil || dv != "v1.2.3+b" {
+		t.Errorf("Value() = %v, %v", dv, err)
+	}
+	tests := []struct {
+		src interface{}
+		ok  bool
+	}{
+		{src: "v1.2.3+b", ok: true},
+		{src: []byte("v1.2.3+b"), ok: true},
+		{src: "bad"},
+		{src: int64(1)},
+		{src: nil},
+	}
+	for i, test := range tests {
+		var s SemanticVersion
+		err := s.Scan(test.src)
+		if (err == nil) != test.ok {
+			t.Errorf("Test %v: Scan(%v) returned error %v", i, test.src, err)
+		}
+		if test.ok && s != v {
+			t.Errorf("Test %v: Scan(%v) = %v, wanted %v", i, test.src, s, v)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+// Set implements flag.Value. The version is parsed with New, so a
+// version can be given as a flag:
+//
+//	min := semver.SemanticVersion{Major: 1}
+//	flag.Var(&min, "min-version", "the lowest version allowed")
+func (v *SemanticVersion) Set(s string) error {
+	return v.UnmarshalText([]byte(s))
+}
+
+// Type returns the name of the type of the flag for pflag.Value.
+func (v *SemanticVersion) Type() string {
+	return "version"
+}
+
+// Set implements flag.Value. The constraint is parsed with
+// NewConstraint.
+func (c *Constraint) Set(s string) error {
+	nc, err := NewConstraint(s)
+	if err != nil {
+		return err
+	}
+	*c = nc
+	return nil
+}
+
+// Type returns the name of the type of the flag for pflag.Value.
+func (c *Constraint) Type() string {
+	return "constraint"
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	// These are what pflag.Value needs in addition to flag.Value.
+	var _ interface {
+		flag.Value
+		Type() string
+	} = &SemanticVersion{}
+	var _ interface {
+		flag.Value
+		Type() string
+	} = &Constraint{}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	min := SemanticVersion{Major: 1}
+	var c Constraint
+	fs.Var(&min, "min-version", "")
+	fs.Var(&c, "constraint", "")
+	if f := fs.Lookup("min-version"); f.DefValue != "v1.0.0" {
+		t.Errorf("default value = %v", f.DefValue)
+	}
+	err := fs.Parse([]string{"-min-version", "v1.2.3-rc.1", "-constraint", "^1.2 || ~2.0"})
+	if err != nil {
+		t.Fatalf("Parse(): %v", err)
+	}
+	if min.String() != "v1.2.3-rc.1" {
+		t.Errorf("min-version = %v", min)
+	}
+	if s := c.String(); s != ">=v1.2.0 <v2.1.0" {
+		t.Errorf("constraint = %v", s)
+	}
+	if min.Type() != "version" || c.Type() != "constraint" {
+		t.Errorf("Type() = %v, %v", min.Type(), c.Type())
+	}
+
+	for _, args := range [][]string{{"-min-version", "1.2"}, {"-constraint", "!v1"}} {
+		if err := fs.Parse(args); err == nil {
+			t.Errorf("Parse(%v) didn't fail", args)
+		}
+	}
+}
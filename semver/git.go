@@ -0,0 +1,91 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GitDescribe is the output of git describe parsed by FromGitDescribe.
+type GitDescribe struct {
+	// Base is the version of the tag.
+	Base SemanticVersion
+
+	// Ahead is the number of commits since the tag and Hash is the
+	// abbreviated hash of the commit described. Hash is empty if git
+	// describe only printed the tag.
+	Ahead int
+	Hash  string
+
+	// Dirty is true if the working tree had changes (--dirty).
+	Dirty bool
+
+	// Version is a version for the commit described. If it's ahead of
+	// the tag, the patch version is increased and the pre-release
+	// version is dev.<ahead>, so it comes after the tag and before the
+	// next release (e.g. v1.4.2-14-g6f3c1b2 is v1.4.3-dev.14). If the
+	// tag is a pre-release, .dev.<ahead> is added to it instead. The
+	// hash and dirty flag are the build metadata (e.g. +g6f3c1b2.dirty).
+	Version SemanticVersion
+}
+
+// describeSuffix matches the part git describe adds after the tag.
+var describeSuffix = regexp.MustCompile(`-([0-9]+)-g([0-9a-f]+)$`)
+
+// FromGitDescribe parses the output of git describe --tags, like
+// "v1.4.2-14-g6f3c1b2-dirty". The tag must be a semantic version. The
+// leading v is optional.
+func FromGitDescribe(s string) (GitDescribe, error) {
+	var d GitDescribe
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "-dirty") {
+		d.Dirty = true
+		s = strings.TrimSuffix(s, "-dirty")
+	}
+	if m := describeSuffix.FindStringSubmatch(s); m != nil {
+		ahead, err := strconv.Atoi(m[1])
+		if err != nil {
+			return GitDescribe{}, ErrParse
+		}
+		d.Ahead, d.Hash = ahead, m[2]
+		s = s[:len(s)-len(m[0])]
+	}
+	if !strings.HasPrefix(s, "v") {
+		s = "v" + s
+	}
+	base, err := New(s)
+	if err != nil {
+		return GitDescribe{}, err
+	}
+	d.Base = base
+
+	v := base
+	if d.Ahead > 0 {
+		dev := "dev." + strconv.Itoa(d.Ahead)
+		if v.Prerelease == "" {
+			v.Patch++
+			v.Prerelease = dev
+		} else {
+			v.Prerelease += "." + dev
+		}
+	}
+	var build []string
+	if v.Build != "" {
+		build = append(build, v.Build)
+	}
+	if d.Hash != "" {
+		build = append(build, "g"+d.Hash)
+	}
+	if d.Dirty {
+		build = append(build, "dirty")
+	}
+	v.Build = strings.Join(build, ".")
+	d.Version = v
+	return d, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import "testing"
+
+func TestFromGitDescribe(t *testing.T) {
+	tests := []struct {
+		s       string
+		base    string
+		ahead   int
+		hash    string
+		dirty   bool
+		version string
+		err     error
+	}{
+		{
+			s:       "v1.4.2-14-g6f3c1b2-dirty\n",
+			base:    "v1.4.2",
+			ahead:   14,
+			hash:    "6f3c1b2",
+			dirty:   true,
+			version: "v1.4.3-dev.14+g6f3c1b2.dirty",
+		},
+		{
+			s:       "v1.4.2-0-g6f3c1b2",
+			base:    "v1.4.2",
+			hash:    "6f3c1b2",
+			version: "v1.4.2+g6f3c1b2",
+		},
+		{
+			s:       "v1.4.2",
+			base:    "v1.4.2",
+			version: "v1.4.2",
+		},
+		{
+			s:       "1.4.2-dirty",
+			base:    "v1.4.2",
+			dirty:   true,
+			version: "v1.4.2+dirty",
+		},
+		{
+			s:       "v1.5.0-rc.1+b7-3-gabc123",
+			base:    "v1.5.0-rc.1+b7",
+			ahead:   3,
+			hash:    "abc123",
+			version: "v1.5.0-rc.1.dev.3+b7.gabc123",
+		},
+		{s: "release-3-gabc123", err: ErrParse},
+		{s: "", err: ErrParse},
+	}
+	for i, test := range tests {
+		d, err := FromGitDescribe(test.s)
+		if err != test.err {
+			t.Errorf("Test %v: FromGitDescribe(%q) returned error %v, wanted %v",
+				i, test.s, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if d.Base.String() != test.base || d.Ahead != test.ahead ||
+			d.Hash != test.hash || d.Dirty != test.dirty ||
+			d.Version.String() != test.version {
+			t.Errorf("Test %v: FromGitDescribe(%q) = %+v", i, test.s, d)
+		}
+		if d.Ahead > 0 && !d.Base.Less(d.Version) {
+			t.Errorf("Test %v: %v isn't after %v", i, d.Version, d.Base)
+		}
+	}
+}
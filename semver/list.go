@@ -0,0 +1,85 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import "sort"
+
+// VersionList is a sorted list of versions without duplicates. Versions
+// are duplicates if they are the same, including the build metadata.
+// Versions with the same precedence but different build metadata are
+// kept in the order they were added. The zero value is an empty list.
+type VersionList struct {
+	vs []SemanticVersion
+}
+
+// NewVersionList creates a list with the given versions.
+func NewVersionList(vs ...SemanticVersion) *VersionList {
+	l := &VersionList{}
+	for _, v := range vs {
+		l.Add(v)
+	}
+	return l
+}
+
+// Add adds the version to the list in order. It returns false if the
+// version was already in it.
+func (l *VersionList) Add(v SemanticVersion) bool {
+	i := sort.Search(len(l.vs), func(i int) bool {
+		return l.vs[i].Compare(v) > 0
+	})
+	for x := i - 1; x >= 0 && l.vs[x].Compare(v) == 0; x-- {
+		if l.vs[x] == v {
+			return false
+		}
+	}
+	l.vs = append(l.vs, SemanticVersion{})
+	copy(l.vs[i+1:], l.vs[i:])
+	l.vs[i] = v
+	return true
+}
+
+// Len returns the number of versions in the list.
+func (l *VersionList) Len() int {
+	return len(l.vs)
+}
+
+// Versions returns a copy of the versions from the lowest to the
+// highest.
+func (l *VersionList) Versions() []SemanticVersion {
+	return append([]SemanticVersion(nil), l.vs...)
+}
+
+// Latest returns the highest version. It returns false if the list is
+// empty.
+func (l *VersionList) Latest() (SemanticVersion, bool) {
+	if len(l.vs) == 0 {
+		return SemanticVersion{}, false
+	}
+	return l.vs[len(l.vs)-1], true
+}
+
+// LatestStable is like Latest but pre-release versions are skipped.
+func (l *VersionList) LatestStable() (SemanticVersion, bool) {
+	for x := len(l.vs) - 1; x >= 0; x-- {
+		if l.vs[x].Prerelease == "" {
+			return l.vs[x], true
+		}
+	}
+	return SemanticVersion{}, false
+}
+
+// Filter returns a new list with the versions that match the
+// constraint.
+func (l *VersionList) Filter(c Constraint) *VersionList {
+	n := &VersionList{}
+	for _, v := range l.vs {
+		if c.Check(v) {
+			n.vs = append(n.vs, v)
+		}
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package semver
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestVersionList(t *testing.T) {
+	var l VersionList
+	if _, ok := l.Latest(); ok {
+		t.Errorf("Latest() of empty list succeeded")
+	}
+	if _, ok := l.LatestStable(); ok {
+		t.Errorf("LatestStable() of empty list succeeded")
+	}
+
+	vs := versions("v1.2.0", "v2.0.0-rc.1", "v1.0.0+b", "v1.10.0", "v1.0.0+a",
+		"v0.9.0")
+	for _, v := range vs {
+		if !l.Add(v) {
+			t.Errorf("Add(%v) returned false", v)
+		}
+	}
+	for _, v := range versions("v1.2.0", "v1.0.0+a", "v2.0.0-rc.1") {
+		if l.Add(v) {
+			t.Errorf("Add(%v) of duplicate returned true", v)
+		}
+	}
+	exp := "[v0.9.0 v1.0.0+b v1.0.0+a v1.2.0 v1.10.0 v2.0.0-rc.1]"
+	if s := fmt.Sprint(l.Versions()); s != exp || l.Len() != 6 {
+		t.Errorf("Versions() = %v (%v), wanted %v", s, l.Len(), exp)
+	}
+	if v, ok := l.Latest(); !ok || v.String() != "v2.0.0-rc.1" {
+		t.Errorf("Latest() = %v, %v", v, ok)
+	}
+	if v, ok := l.LatestStable(); !ok || v.String() != "v1.10.0" {
+		t.Errorf("LatestStable() = %v, %v", v, ok)
+	}
+
+	c, _ := NewConstraint("~v1.0.0 || >=v1.10.0")
+	f := l.Filter(c)
+	exp = "[v1.0.0+b v1.0.0+a v1.10.0 v2.0.0-rc.1]"
+	if s := fmt.Sprint(f.Versions()); s != exp {
+		t.Errorf("Filter() = %v, wanted %v", s, exp)
+	}
+	if l.Len() != 6 {
+		t.Errorf("Filter() changed the list")
+	}
+
+	n := NewVersionList(vs[0], vs[1], vs[0])
+	if s := fmt.Sprint(n.Versions()); s != "[v1.2.0 v2.0.0-rc.1]" {
+		t.Errorf("NewVersionList() = %v", s)
+	}
+}
@@ -26,21 +26,48 @@ type SemanticVersion struct {
 	Major int
 	Minor int
 	Patch int
+
+	// Prerelease is the pre-release version without the leading hyphen
+	// (e.g. "rc.1") or empty if there is none.
+	Prerelease string
+
+	// Build is the build metadata without the leading plus (e.g.
+	// "build5") or empty if there is none.
+	Build string
 }
 
-// New creates a new semantic version from the given string.
+// New creates a new semantic version from the given string. The string
+// must start with a v. A pre-release version and build metadata may
+// follow the version (e.g. "v1.2.3-rc.1+build5").
 func New(v string) (SemanticVersion, error) {
 	nv := SemanticVersion{}
 	// Verify it starts with a v.
-	if v[:1] != "v" {
+	if !strings.HasPrefix(v, "v") {
 		return nv, ErrParse
 	}
+	v = v[1:]
+	// Pull off the build metadata and then the pre-release version.
+	// The build metadata may contain hyphens, so it goes first.
+	if i := strings.Index(v, "+"); i >= 0 {
+		nv.Build = v[i+1:]
+		if !validIdentifiers(nv.Build, false) {
+			return SemanticVersion{}, ErrParse
+		}
+		v = v[:i]
+	}
+	if i := strings.Index(v, "-"); i >= 0 {
+		nv.Prerelease = v[i+1:]
+		if !validIdentifiers(nv.Prerelease, true) {
+			return SemanticVersion{}, ErrParse
+		}
+		v = v[:i]
+	}
 	// Split it out by it constituent parts, parse it, and then set the
 	// right value.
-	for i, part := range strings.Split(v[1:], ".") {
+	for i, part := range strings.Split(v, ".") {
 		n, err := strconv.Atoi(part)
 		if err != nil {
-			return nv, ErrParse
+			return SemanticVersion{}, ErrParse
 		}
 		switch i {
 		case 0:
@@ -56,6 +83,31 @@ func New(v string) (SemanticVersion, error) {
 	return nv, nil
 }
 
+// validIdentifiers returns true if s is a list of dot separated
+// identifiers made up of ASCII alphanumerics and hyphens. If
+// prerelease is true, numeric identifiers can't have leading zeros.
+func validIdentifiers(s string, prerelease bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		numeric := true
+		for _, c := range id {
+			switch {
+			case c >= '0' && c <= '9':
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-':
+				numeric = false
+			default:
+				return false
+			}
+		}
+		if prerelease && numeric && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
 // GreaterEqual returns true if v is greater than or equal to o.
 func (v SemanticVersion) GreaterEqual(o SemanticVersion) bool {
 	if o.Major > v.Major {
@@ -73,7 +125,26 @@ func (v SemanticVersion) Compatible(o SemanticVersion) bool {
 	return v.Major == o.Major && v.GreaterEqual(o)
 }
 
+// CompatibleStrict is like Compatible but follows the spec for
+// versions before 1.0.0, which make no compatibility promises. While
+// the major version is 0, v is only compatible with o if the minor
+// versions are the same as well, like ^0.y in other tools. The versions
+// are compared by precedence (see Compare).
+func (v SemanticVersion) CompatibleStrict(o SemanticVersion) bool {
+	if v.Major != o.Major || (v.Major == 0 && v.Minor != o.Minor) {
+		return false
+	}
+	return v.Compare(o) >= 0
+}
+
 // String returns the version as a string.
 func (v SemanticVersion) String() string {
-	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	s := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
 }
@@ -43,23 +43,68 @@ func TestNewSemanticVersion(t *testing.T) {
 		// Test a valid version.
 		{
 			v:        "v1.2.3",
-			expected: SemanticVersion{1, 2, 3},
+			expected: SemanticVersion{Major: 1, Minor: 2, Patch: 3},
 		},
 		// Test a valid version with just major.
 		{
 			v:        "v3",
-			expected: SemanticVersion{3, 0, 0},
+			expected: SemanticVersion{Major: 3, Minor: 0, Patch: 0},
 		},
 		// Test a valid version with major.minor.
 		{
 			v:        "v4.5",
-			expected: SemanticVersion{4, 5, 0},
+			expected: SemanticVersion{Major: 4, Minor: 5, Patch: 0},
+		},
+		// Test a pre-release version and build metadata.
+		{
+			v: "v1.2.3-rc.1+build5",
+			expected: SemanticVersion{Major: 1, Minor: 2, Patch: 3,
+				Prerelease: "rc.1", Build: "build5"},
+		},
+		{
+			v: "v1.2.3-alpha-2.0a",
+			expected: SemanticVersion{Major: 1, Minor: 2, Patch: 3,
+				Prerelease: "alpha-2.0a"},
+		},
+		{
+			v: "v1.2.3+build-7.001",
+			expected: SemanticVersion{Major: 1, Minor: 2, Patch: 3,
+				Build: "build-7.001"},
 		},
 		// Test a string that doesn't start with a v.
 		{
 			v:   "1.2.3",
 			err: ErrParse,
 		},
+		{
+			v:   "",
+			err: ErrParse,
+		},
+		// Test bad pre-release versions and build metadata.
+		{
+			v:   "v1.2.3-",
+			err: ErrParse,
+		},
+		{
+			v:   "v1.2.3-rc..1",
+			err: ErrParse,
+		},
+		{
+			v:   "v1.2.3-rc.01",
+			err: ErrParse,
+		},
+		{
+			v:   "v1.2.3-rc_1",
+			err: ErrParse,
+		},
+		{
+			v:   "v1.2.3+",
+			err: ErrParse,
+		},
+		{
+			v:   "v1.2.3+a+b",
+			err: ErrParse,
+		},
 		// Test a bad major version.
 		{
 			v:   "va.2.3",
@@ -98,37 +143,37 @@ func TestSemanticVersionGreaterEqual(t *testing.T) {
 	}{
 		// Test a bunch of true values.
 		{
-			v:        SemanticVersion{1, 2, 3},
-			o:        SemanticVersion{1, 2, 3},
+			v:        SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o:        SemanticVersion{Major: 1, Minor: 2, Patch: 3},
 			expected: true,
 		},
 		{
-			v:        SemanticVersion{1, 2, 3},
-			o:        SemanticVersion{1, 2, 2},
+			v:        SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o:        SemanticVersion{Major: 1, Minor: 2, Patch: 2},
 			expected: true,
 		},
 		{
-			v:        SemanticVersion{1, 2, 3},
-			o:        SemanticVersion{1, 1, 3},
+			v:        SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o:        SemanticVersion{Major: 1, Minor: 1, Patch: 3},
 			expected: true,
 		},
 		{
-			v:        SemanticVersion{1, 2, 3},
-			o:        SemanticVersion{0, 2, 3},
+			v:        SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o:        SemanticVersion{Major: 0, Minor: 2, Patch: 3},
 			expected: true,
 		},
 		// Test a bunch of false values.
 		{
-			v: SemanticVersion{1, 2, 3},
-			o: SemanticVersion{2, 2, 3},
+			v: SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o: SemanticVersion{Major: 2, Minor: 2, Patch: 3},
 		},
 		{
-			v: SemanticVersion{1, 2, 3},
-			o: SemanticVersion{1, 3, 3},
+			v: SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o: SemanticVersion{Major: 1, Minor: 3, Patch: 3},
 		},
 		{
-			v: SemanticVersion{1, 2, 3},
-			o: SemanticVersion{1, 2, 4},
+			v: SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o: SemanticVersion{Major: 1, Minor: 2, Patch: 4},
 		},
 	}
 
@@ -148,37 +193,37 @@ func TestSemanticVersionCompatible(t *testing.T) {
 	}{
 		// Test a bunch of true values.
 		{
-			v:        SemanticVersion{1, 2, 3},
-			o:        SemanticVersion{1, 2, 3},
+			v:        SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o:        SemanticVersion{Major: 1, Minor: 2, Patch: 3},
 			expected: true,
 		},
 		{
-			v:        SemanticVersion{1, 2, 3},
-			o:        SemanticVersion{1, 2, 2},
+			v:        SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o:        SemanticVersion{Major: 1, Minor: 2, Patch: 2},
 			expected: true,
 		},
 		{
-			v:        SemanticVersion{1, 2, 3},
-			o:        SemanticVersion{1, 1, 3},
+			v:        SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o:        SemanticVersion{Major: 1, Minor: 1, Patch: 3},
 			expected: true,
 		},
 		{
-			v:        SemanticVersion{1, 2, 3},
-			o:        SemanticVersion{1, 0, 0},
+			v:        SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o:        SemanticVersion{Major: 1, Minor: 0, Patch: 0},
 			expected: true,
 		},
 		// Test a bunch of false values.
 		{
-			v: SemanticVersion{1, 2, 3},
-			o: SemanticVersion{2, 2, 3},
+			v: SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o: SemanticVersion{Major: 2, Minor: 2, Patch: 3},
 		},
 		{
-			v: SemanticVersion{1, 2, 3},
-			o: SemanticVersion{1, 3, 3},
+			v: SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o: SemanticVersion{Major: 1, Minor: 3, Patch: 3},
 		},
 		{
-			v: SemanticVersion{1, 2, 3},
-			o: SemanticVersion{1, 2, 4},
+			v: SemanticVersion{Major: 1, Minor: 2, Patch: 3},
+			o: SemanticVersion{Major: 1, Minor: 2, Patch: 4},
 		},
 	}
 
@@ -191,23 +236,57 @@ func TestSemanticVersionCompatible(t *testing.T) {
 	}
 }
 
+func TestSemanticVersionCompatibleStrict(t *testing.T) {
+	tests := []struct {
+		v, o     string
+		expected bool
+	}{
+		{v: "v1.2.3", o: "v1.0.0", expected: true},
+		{v: "v1.3.0", o: "v1.2.5", expected: true},
+		{v: "v1.2.3", o: "v1.2.3-rc.1", expected: true},
+		{v: "v0.1.5", o: "v0.1.2", expected: true},
+		{v: "v1.2.3-rc.1", o: "v1.2.3"},
+		{v: "v1.0.0", o: "v1.2.3"},
+		{v: "v2.0.0", o: "v1.2.3"},
+		{v: "v0.3.0", o: "v0.1.0"},
+		{v: "v1.0.0", o: "v0.1.0"},
+	}
+
+	for i, test := range tests {
+		vs := versions(test.v, test.o)
+		if result := vs[0].CompatibleStrict(vs[1]); result != test.expected {
+			t.Errorf("Test %v: %v.CompatibleStrict(%v) = %v, wanted %v", i,
+				test.v, test.o, result, test.expected)
+		}
+	}
+}
+
 func TestSemanticVersionString(t *testing.T) {
 	tests := []struct {
 		v        SemanticVersion
 		expected string
 	}{
 		{
-			v:        SemanticVersion{1, 2, 3},
+			v:        SemanticVersion{Major: 1, Minor: 2, Patch: 3},
 			expected: "v1.2.3",
 		},
 		{
-			v:        SemanticVersion{1, 2, 0},
+			v:        SemanticVersion{Major: 1, Minor: 2, Patch: 0},
 			expected: "v1.2.0",
 		},
 		{
-			v:        SemanticVersion{1, 0, 0},
+			v:        SemanticVersion{Major: 1, Minor: 0, Patch: 0},
 			expected: "v1.0.0",
 		},
+		{
+			v: SemanticVersion{Major: 1, Minor: 0, Patch: 0,
+				Prerelease: "rc.1", Build: "build5"},
+			expected: "v1.0.0-rc.1+build5",
+		},
+		{
+			v:        SemanticVersion{Major: 1, Minor: 0, Patch: 0, Build: "b"},
+			expected: "v1.0.0+b",
+		},
 	}
 
 	for i, test := range tests {
@@ -0,0 +1,78 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package signalhandler
+
+import (
+	"os"
+	"time"
+)
+
+// Logger is used to log what the signal handler is doing. *log.Logger
+// satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// EventType is the type of an Event.
+type EventType int
+
+// These are the types of events.
+const (
+	// EventReceived is sent when a signal is received.
+	EventReceived EventType = iota
+
+	// EventDispatched is sent once the functions for a signal have
+	// been called.
+	EventDispatched
+
+	// EventHandlerError is sent when a function panics or times out.
+	EventHandlerError
+)
+
+var eventTypes = []string{"received", "dispatched", "handler error"}
+
+func (t EventType) String() string {
+	if t < 0 || int(t) >= len(eventTypes) {
+		return "unknown"
+	}
+	return eventTypes[t]
+}
+
+// Event describes something that happened in the signal handler.
+type Event struct {
+	Type   EventType
+	Signal os.Signal
+	Time   time.Time
+
+	// Handlers is the number of functions called for an
+	// EventDispatched. Coalesced calls that were skipped aren't
+	// counted.
+	Handlers int
+
+	// Err is the error of an EventHandlerError.
+	Err error
+}
+
+// eventBuffer is the size of the events channel.
+const eventBuffer = 100
+
+// emit sends the event without blocking. It's dropped if the channel
+// is full.
+func (h *handler) emit(e Event) {
+	e.Time = time.Now()
+	select {
+	case h.events <- e:
+	default:
+	}
+}
+
+// printf logs to the logger if there is one.
+func (h *handler) printf(format string, v ...interface{}) {
+	if h.logger != nil {
+		h.logger.Printf(format, v...)
+	}
+}
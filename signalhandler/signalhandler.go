@@ -10,19 +10,14 @@
 package signalhandler
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
-)
-
-// These are common signals. You can find more in the packages os
-// and syscall.
-const (
-	SigHup  = syscall.SIGHUP  // Reload the config.
-	SigUsr1 = syscall.SIGUSR1 // Reopen the logs.
-	SigTerm = syscall.SIGTERM // gracefully die.
-	SigKill = syscall.SIGKILL // bad day.
+	"time"
 )
 
 // SignalHandler is the interface used for handling incoming signals
@@ -31,34 +26,279 @@ type SignalHandler interface {
 	// Watch registers the given function when the given signal is
 	// called. Multiple functions can be registered to a signal and if
 	// the same function is registered multiple times, it will be called
-	// multiple times.
+	// multiple times. The functions of a signal are called one at a
+	// time in the order they were registered and all of them return
+	// before the next signal is handled.
 	Watch(os.Signal, func())
 
+	// WatchOnce is like Watch but the function is only called for the
+	// first delivery of the signal. It's then unregistered. The signal
+	// is still caught afterwards even if nothing else is watching it.
+	WatchOnce(os.Signal, func())
+
+	// WatchWithOptions is like Watch but the function is called as
+	// described by the options.
+	WatchWithOptions(os.Signal, func(), Options)
+
+	// WatchDefault registers the given function for any signal that
+	// has no functions registered for it. Once it's called, all
+	// signals are caught, including ones the runtime uses internally
+	// like SIGURG, so it should ignore the ones it doesn't care about.
+	// Only one default function is kept; later calls replace it.
+	WatchDefault(func(os.Signal))
+
+	// OnError registers the function that is given the errors of the
+	// watched functions: a *PanicError if one panics or ErrTimeout if
+	// one doesn't return in time (see Options.Timeout). Only one is
+	// kept; later calls replace it. Without one, the errors are logged
+	// to the default logger.
+	OnError(func(os.Signal, error))
+
+	// Events returns the channel on which events are sent. It's
+	// buffered and events are dropped when it's full, so reading from
+	// it is optional.
+	Events() <-chan Event
+
+	// Stats returns the statistics of the given signal.
+	Stats(os.Signal) Stats
+
+	// LastReceived returns when the given signal was last received or
+	// the zero time if it never was.
+	LastReceived(os.Signal) time.Time
+
 	// Stop stops watching for incoming signals.
 	Stop()
 }
 
+// Options change how a watched function is called.
+type Options struct {
+	// Priority orders the functions of a signal. Functions with a
+	// higher priority are called first. Those with the same priority
+	// are called in the order they were registered. Watch uses a
+	// priority of 0.
+	Priority int
+
+	// Async calls the function in its own goroutine. The other
+	// functions and signals don't wait for it to return.
+	Async bool
+
+	// Once unregisters the function after it's called (see
+	// WatchOnce).
+	Once bool
+
+	// Coalesce combines a burst of the signal into a single call of the
+	// function. A burst ends once the signal hasn't been received for
+	// this long. Zero disables coalescing.
+	Coalesce time.Duration
+
+	// Edge is when the function is called for a coalesced burst.
+	Edge Edge
+
+	// Timeout is how long the function is waited on before the next
+	// one is called. ErrTimeout is reported if it takes longer but it
+	// isn't stopped. Zero waits forever.
+	Timeout time.Duration
+}
+
+// ErrTimeout is reported when a watched function doesn't return before
+// its timeout.
+var ErrTimeout = errors.New("signal handler timed out")
+
+// PanicError is reported when a watched function panics. Value is the
+// value that was given to panic().
+type PanicError struct {
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("signal handler panicked: %v", e.Value)
+}
+
+// Edge is when the function is called for a burst of signals (see
+// Options.Coalesce).
+type Edge int
+
+// These are the edges of a burst.
+const (
+	// Trailing calls the function once the burst is over.
+	Trailing Edge = iota
+
+	// Leading calls the function for the first signal of a burst and
+	// ignores the rest.
+	Leading
+
+	// BothEdges calls the function for the first signal of a burst and
+	// again once it's over if there was more than one signal.
+	BothEdges
+)
+
 // Handler is our implementation of the SignalHandler interface.
 type handler struct {
 	// Incoming is the incoming channel.
 	incoming chan os.Signal
 
-	// funcs is map of functions mapped to signals.
-	funcs map[os.Signal][]func()
+	// funcs is map of functions mapped to signals. mu protects it.
+	mu    sync.Mutex
+	funcs map[os.Signal][]*registration
+	def   func(os.Signal)        // The default function, also protected by mu.
+	onErr func(os.Signal, error) // The error function, also protected by mu.
+	stats map[os.Signal]*Stats   // The statistics, also protected by mu.
+
+	// trailing receives the coalesced functions to call once their
+	// burst is over.
+	trailing chan *registration
+
+	// logger is where messages go if it's not nil.
+	logger Logger
+
+	// events is where events are sent.
+	events chan Event
 
 	// We'll use this to stop the goroutine that's waiting on signals.
 	stop chan struct{}
 }
 
+// registration is a function registered for a signal.
+type registration struct {
+	sig  os.Signal
+	f    func()
+	opts Options
+
+	// These track the current burst if the signal is coalesced. They
+	// are protected by the handler's mu.
+	burst   bool      // A burst is in progress.
+	last    time.Time // When the last signal of the burst came in.
+	pending bool      // The function should be called when it's over.
+}
+
 func (h *handler) Watch(sig os.Signal, f func()) {
-	if funcs, ok := h.funcs[sig]; !ok {
-		h.funcs[sig] = []func(){f}
-	} else {
-		h.funcs[sig] = append(funcs, f)
+	h.WatchWithOptions(sig, f, Options{})
+}
+
+func (h *handler) WatchOnce(sig os.Signal, f func()) {
+	h.WatchWithOptions(sig, f, Options{Once: true})
+}
+
+func (h *handler) WatchWithOptions(sig os.Signal, f func(), opts Options) {
+	h.mu.Lock()
+	// Insert it after all of the ones with the same or a higher
+	// priority.
+	regs := h.funcs[sig]
+	x := 0
+	for x < len(regs) && regs[x].opts.Priority >= opts.Priority {
+		x++
 	}
+	regs = append(regs, nil)
+	copy(regs[x+1:], regs[x:])
+	regs[x] = &registration{sig: sig, f: f, opts: opts}
+	h.funcs[sig] = regs
+	h.mu.Unlock()
 	signal.Notify(h.incoming, sig)
 }
 
+func (h *handler) WatchDefault(f func(os.Signal)) {
+	h.mu.Lock()
+	h.def = f
+	h.mu.Unlock()
+	signal.Notify(h.incoming)
+}
+
+func (h *handler) OnError(f func(os.Signal, error)) {
+	h.mu.Lock()
+	h.onErr = f
+	h.mu.Unlock()
+}
+
+func (h *handler) Events() <-chan Event {
+	return h.events
+}
+
+// report gives the error to the error function or logs it.
+func (h *handler) report(sig os.Signal, err error) {
+	h.emit(Event{Type: EventHandlerError, Signal: sig, Err: err})
+	h.mu.Lock()
+	onErr := h.onErr
+	h.mu.Unlock()
+	if onErr != nil {
+		onErr(sig, err)
+	} else if h.logger != nil {
+		h.logger.Printf("[signalhandler] %v: %v", sig, err)
+	} else {
+		log.Printf("[signalhandler] %v: %v", sig, err)
+	}
+}
+
+// Reraise restores the default behavior of the signal and sends it to
+// this process again. It's useful in a default function (see
+// WatchDefault) for signals that should still do what they normally
+// do, like terminating the program. The signal is no longer caught
+// anywhere in the program afterwards. On Windows, where a process
+// can't send itself signals, the program exits if the signal would
+// normally terminate it.
+func Reraise(sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("unsupported signal: %v", sig)
+	}
+	signal.Reset(sig)
+	return raise(s)
+}
+
+// take returns the registrations for the signal and unregisters the
+// ones that should only be called once.
+func (h *handler) take(sig os.Signal) []*registration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	regs := h.funcs[sig]
+	var keep []*registration
+	for _, r := range regs {
+		if !r.opts.Once {
+			keep = append(keep, r)
+		}
+	}
+	h.funcs[sig] = keep
+	return regs
+}
+
+// coalesce updates the burst of the coalesced registration and
+// returns true if the function should be called now.
+func (h *handler) coalesce(r *registration) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r.last = time.Now()
+	if r.burst {
+		r.pending = r.opts.Edge != Leading
+		return false
+	}
+	r.burst = true
+	r.pending = r.opts.Edge == Trailing
+	time.AfterFunc(r.opts.Coalesce, func() { h.burstOver(r) })
+	return r.opts.Edge != Trailing
+}
+
+// burstOver is called when the burst of the registration may be over.
+// If it is and the function should be called, it's sent to the main
+// loop.
+func (h *handler) burstOver(r *registration) {
+	h.mu.Lock()
+	if d := r.opts.Coalesce - time.Since(r.last); d > 0 {
+		// More signals came in, so wait for them to stop.
+		time.AfterFunc(d, func() { h.burstOver(r) })
+		h.mu.Unlock()
+		return
+	}
+	pending := r.pending
+	r.burst = false
+	r.pending = false
+	h.mu.Unlock()
+	if pending {
+		select {
+		case h.trailing <- r:
+		case <-h.stop:
+		}
+	}
+}
+
 func (h *handler) Stop() {
 	signal.Stop(h.incoming)
 	close(h.stop)
@@ -70,28 +310,107 @@ func (h *handler) listen() {
 	for {
 		select {
 		case sig := <-h.incoming:
-			funcs, ok := h.funcs[sig]
-			fmt.Println(sig, len(funcs))
-			if ok {
-				// Call all the registered functions.
-				for _, f := range funcs {
-					f()
-				}
-			}
+			h.dispatch(sig)
+		case r := <-h.trailing:
+			h.call(r)
+			h.stat(r.sig, func(s *Stats) { s.Handled++ })
+			h.emit(Event{Type: EventDispatched, Signal: r.sig, Handlers: 1})
 		case <-h.stop:
 			return
 		}
 	}
 }
 
+// dispatch calls the registered functions for the signal.
+func (h *handler) dispatch(sig os.Signal) {
+	regs := h.take(sig)
+	h.printf("[signalhandler] received %v, %v functions registered", sig, len(regs))
+	h.emit(Event{Type: EventReceived, Signal: sig})
+	h.stat(sig, func(s *Stats) {
+		s.Received++
+		s.LastReceived = time.Now()
+	})
+	called, coalesced := 0, 0
+	if len(regs) == 0 {
+		h.mu.Lock()
+		def := h.def
+		h.mu.Unlock()
+		if def != nil {
+			h.run(sig, func() { def(sig) })
+			called++
+		}
+	}
+	// Call all the registered functions. They are called without the
+	// lock so they can register more.
+	for _, r := range regs {
+		if r.opts.Coalesce > 0 && !h.coalesce(r) {
+			coalesced++
+			continue
+		}
+		h.call(r)
+		called++
+	}
+	h.stat(sig, func(s *Stats) {
+		s.Handled += uint64(called)
+		s.Coalesced += uint64(coalesced)
+	})
+	h.emit(Event{Type: EventDispatched, Signal: sig, Handlers: called})
+}
+
+// call calls the registered function. It waits for it to return unless
+// it's asynchronous or takes longer than its timeout.
+func (h *handler) call(r *registration) {
+	if r.opts.Async {
+		go h.run(r.sig, r.f)
+		return
+	}
+	if r.opts.Timeout <= 0 {
+		h.run(r.sig, r.f)
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		h.run(r.sig, r.f)
+		close(done)
+	}()
+	t := time.NewTimer(r.opts.Timeout)
+	defer t.Stop()
+	select {
+	case <-done:
+	case <-t.C:
+		h.report(r.sig, ErrTimeout)
+	}
+}
+
+// run calls the function for the signal and reports a panic.
+func (h *handler) run(sig os.Signal, f func()) {
+	defer func() {
+		if v := recover(); v != nil {
+			h.report(sig, &PanicError{Value: v})
+		}
+	}()
+	f()
+}
+
 // New create a new signal handler which is listening for
 // signal. Calls to Watch() will add functions when signals come down
 // the pipe. Stop() should be called when you are done listening for
 // signals.
 func New() SignalHandler {
+	return NewWithLogger(nil)
+}
+
+// NewWithLogger is like New but what the signal handler does is logged
+// to the given logger. If it's nil, nothing is logged except the errors
+// of the functions when there is no error function (see OnError).
+func NewWithLogger(l Logger) SignalHandler {
 	h := &handler{
 		incoming: make(chan os.Signal, 20),
-		funcs:    make(map[os.Signal][]func()),
+		funcs:    make(map[os.Signal][]*registration),
+		trailing: make(chan *registration),
+		logger:   l,
+		events:   make(chan Event, eventBuffer),
+		stats:    make(map[os.Signal]*Stats),
 		stop:     make(chan struct{}),
 	}
 	go h.listen()
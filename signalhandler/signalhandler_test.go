@@ -4,16 +4,22 @@
 // found in the LICENSE file in the root of the repository or at
 // https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
 
+//go:build !windows
+// +build !windows
+
 package signalhandler
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
 	"testing"
+	"time"
 )
 
 func ExampleSignalHandler() {
@@ -40,7 +46,6 @@ func ExampleSignalHandler() {
 	h.Stop()
 
 	// Output:
-	// hangup 1
 	// reloading config
 }
 
@@ -138,3 +143,241 @@ func TestSignalHandler(t *testing.T) {
 		h.Stop()
 	}
 }
+
+func TestWatchOnce(t *testing.T) {
+	h := New()
+	defer h.Stop()
+	out := make(chan string, 10)
+	// This is the usual pattern: the first signal starts a shutdown and
+	// the next ones force it.
+	h.WatchOnce(SigHup, func() {
+		out <- "a"
+		h.Watch(SigHup, func() { out <- "b" })
+	})
+	var res string
+	for x := 0; x < 3; x++ {
+		syscall.Kill(os.Getpid(), SigHup)
+		res += <-out
+	}
+	if res != "abb" {
+		t.Errorf("expected output abb but got %v", res)
+	}
+}
+
+func TestWatchWithOptions(t *testing.T) {
+	h := New()
+	defer h.Stop()
+	out := make(chan string, 10)
+	w := func(s string) func() {
+		return func() { out <- s }
+	}
+	block := make(chan struct{})
+	h.Watch(SigUsr1, w("a"))
+	h.WatchWithOptions(SigUsr1, w("b"), Options{Priority: 10})
+	h.WatchWithOptions(SigUsr1, w("c"), Options{Priority: -1, Once: true})
+	h.WatchWithOptions(SigUsr1, w("d"), Options{Priority: 10})
+	h.WatchWithOptions(SigUsr1, func() {
+		// This doesn't stop the others from being called.
+		<-block
+		out <- "e"
+	}, Options{Priority: 20, Async: true})
+	h.Watch(SigUsr1, w("f"))
+
+	var res string
+	syscall.Kill(os.Getpid(), SigUsr1)
+	for x := 0; x < 5; x++ {
+		res += <-out
+	}
+	close(block)
+	res += <-out
+	if exp := "bdafce"; res != exp {
+		t.Errorf("expected output %v but got %v", exp, res)
+	}
+
+	// The once function is gone and the async one can be called at any
+	// point now.
+	res = ""
+	syscall.Kill(os.Getpid(), SigUsr1)
+	for x := 0; x < 5; x++ {
+		if s := <-out; s != "e" {
+			res += s
+		}
+	}
+	if exp := "bdaf"; res != exp {
+		t.Errorf("expected output %v but got %v", exp, res)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	tests := []struct {
+		edge    Edge
+		signals int
+		now     int // The calls expected during the burst.
+		after   int // The calls expected after it.
+	}{
+		{edge: Trailing, signals: 3, now: 0, after: 1},
+		{edge: Leading, signals: 3, now: 1, after: 0},
+		{edge: BothEdges, signals: 3, now: 1, after: 1},
+		{edge: BothEdges, signals: 1, now: 1, after: 0},
+	}
+	for k, test := range tests {
+		h := New().(*handler)
+		calls := make(chan struct{}, 10)
+		h.WatchWithOptions(SigHup, func() { calls <- struct{}{} },
+			Options{Coalesce: 20 * time.Millisecond, Edge: test.edge})
+		// We dispatch directly so the signals all land in the burst.
+		for x := 0; x < test.signals; x++ {
+			h.dispatch(SigHup)
+		}
+		if n := len(calls); n != test.now {
+			t.Errorf("Test %v: expected %v calls during burst but got %v",
+				k, test.now, n)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if n := len(calls) - test.now; n != test.after {
+			t.Errorf("Test %v: expected %v calls after burst but got %v",
+				k, test.after, n)
+		}
+		h.Stop()
+	}
+}
+
+func TestWatchDefault(t *testing.T) {
+	h := New()
+	defer h.Stop()
+	got := make(chan os.Signal, 10)
+	h.Watch(SigHup, func() { got <- SigHup })
+	h.WatchDefault(func(sig os.Signal) {
+		// The runtime sends some signals itself.
+		if sig == syscall.SIGUSR2 {
+			got <- sig
+		}
+	})
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	if sig := <-got; sig != syscall.SIGUSR2 {
+		t.Errorf("default function got %v", sig)
+	}
+	syscall.Kill(os.Getpid(), SigHup)
+	if sig := <-got; sig != SigHup {
+		t.Errorf("registered function not called, got %v", sig)
+	}
+}
+
+type fakeSignal struct{}
+
+func (s fakeSignal) String() string { return "fake" }
+func (s fakeSignal) Signal()        {}
+
+func TestReraise(t *testing.T) {
+	// SIGWINCH is ignored by default, so reraising it is harmless.
+	if err := Reraise(syscall.SIGWINCH); err != nil {
+		t.Errorf("Reraise() failed: %v", err)
+	}
+	// Give it time to be delivered so it doesn't show up in the other
+	// tests.
+	time.Sleep(10 * time.Millisecond)
+	if err := Reraise(fakeSignal{}); err == nil {
+		t.Errorf("Reraise() of unsupported signal didn't fail")
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h := New().(*handler)
+	defer h.Stop()
+	var errs []error
+	h.OnError(func(sig os.Signal, err error) {
+		if sig != SigHup {
+			t.Errorf("error reported for %v", sig)
+		}
+		errs = append(errs, err)
+	})
+	block := make(chan struct{})
+	defer close(block)
+	var called bool
+	h.Watch(SigHup, func() { panic("oops") })
+	h.WatchWithOptions(SigHup, func() { <-block },
+		Options{Timeout: 10 * time.Millisecond})
+	h.Watch(SigHup, func() { called = true })
+
+	// We dispatch directly so the errors are reported before it
+	// returns.
+	h.dispatch(SigHup)
+	if !called {
+		t.Errorf("function after panic and timeout wasn't called")
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors but got %v", errs)
+	}
+	if pe, ok := errs[0].(*PanicError); !ok || pe.Value != "oops" {
+		t.Errorf("expected panic error but got %v", errs[0])
+	}
+	if errs[1] != ErrTimeout {
+		t.Errorf("expected timeout error but got %v", errs[1])
+	}
+}
+
+func TestLoggerAndEvents(t *testing.T) {
+	b := &bytes.Buffer{}
+	h := NewWithLogger(log.New(b, "", 0)).(*handler)
+	defer h.Stop()
+	h.Watch(SigHup, func() {})
+	h.Watch(SigHup, func() { panic("oops") })
+	h.dispatch(SigHup)
+
+	exp := []Event{
+		{Type: EventReceived, Signal: SigHup},
+		{Type: EventHandlerError, Signal: SigHup, Err: &PanicError{Value: "oops"}},
+		{Type: EventDispatched, Signal: SigHup, Handlers: 2},
+	}
+	for k, e := range exp {
+		got := <-h.Events()
+		if got.Time.IsZero() {
+			t.Errorf("Event %v: time not set", k)
+		}
+		got.Time = time.Time{}
+		if fmt.Sprint(got) != fmt.Sprint(e) {
+			t.Errorf("Event %v: expected %v but got %v", k, e, got)
+		}
+	}
+	logs := "[signalhandler] received hangup, 2 functions registered\n" +
+		"[signalhandler] hangup: signal handler panicked: oops\n"
+	if b.String() != logs {
+		t.Errorf("expected logs %q but got %q", logs, b.String())
+	}
+
+	// Events are dropped rather than blocking.
+	for x := 0; x < eventBuffer+1; x++ {
+		h.emit(Event{Type: EventHandlerError, Err: errors.New("x")})
+	}
+	if len(h.events) != eventBuffer {
+		t.Errorf("expected a full events channel but got %v", len(h.events))
+	}
+	if s := EventType(7).String(); s != "unknown" {
+		t.Errorf("String() of unknown type = %v", s)
+	}
+}
+
+func TestStats(t *testing.T) {
+	h := New().(*handler)
+	defer h.Stop()
+	if s := h.Stats(SigHup); s != (Stats{}) {
+		t.Errorf("Stats() before any signals = %v", s)
+	}
+	h.Watch(SigHup, func() {})
+	h.WatchWithOptions(SigHup, func() {},
+		Options{Coalesce: time.Hour, Edge: Leading})
+	start := time.Now()
+	h.dispatch(SigHup)
+	h.dispatch(SigHup)
+	h.dispatch(SigUsr1)
+	s := h.Stats(SigHup)
+	if s.Received != 2 || s.Handled != 3 || s.Coalesced != 1 {
+		t.Errorf("Stats() = %v", s)
+	}
+	if l := h.LastReceived(SigHup); l.Before(start) || l != s.LastReceived {
+		t.Errorf("LastReceived() = %v", l)
+	}
+	if s := h.Stats(SigUsr1); s.Received != 1 || s.Handled != 0 {
+		t.Errorf("Stats() of unwatched signal = %v", s)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+//go:build !windows
+// +build !windows
+
+package signalhandler
+
+import (
+	"os"
+	"syscall"
+)
+
+// These are common signals. You can find more in the packages os
+// and syscall.
+const (
+	SigInt  = syscall.SIGINT  // Interrupt (Ctrl-C).
+	SigHup  = syscall.SIGHUP  // Reload the config.
+	SigUsr1 = syscall.SIGUSR1 // Reopen the logs.
+	SigTerm = syscall.SIGTERM // gracefully die.
+	SigKill = syscall.SIGKILL // bad day.
+)
+
+// raise sends the signal to this process.
+func raise(s syscall.Signal) error {
+	return syscall.Kill(os.Getpid(), s)
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package signalhandler
+
+import (
+	"os"
+	"syscall"
+)
+
+// These are common signals. You can find more in the packages os
+// and syscall. Windows doesn't have signals, so os/signal delivers
+// SigInt for the Ctrl-C and Ctrl-Break console events and SigTerm
+// when the console is closed or the user logs off or shuts down.
+// SigHup and SigUsr1 are never delivered, but they can still be
+// watched so the same code works everywhere.
+const (
+	SigInt  = syscall.SIGINT       // Interrupt (Ctrl-C).
+	SigHup  = syscall.SIGHUP       // Reload the config.
+	SigUsr1 = syscall.Signal(0x1e) // Reopen the logs.
+	SigTerm = syscall.SIGTERM      // gracefully die.
+	SigKill = syscall.SIGKILL      // bad day.
+)
+
+// raise does what the signal would normally do. Windows processes can't
+// send themselves signals, so the program exits for the ones that would
+// terminate it.
+func raise(s syscall.Signal) error {
+	switch s {
+	case SigInt, SigHup, SigTerm, SigKill:
+		os.Exit(2)
+	}
+	return nil
+}
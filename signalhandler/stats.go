@@ -0,0 +1,56 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package signalhandler
+
+import (
+	"os"
+	"time"
+)
+
+// Stats are the statistics of a signal.
+type Stats struct {
+	// Received is the number of times the signal was received.
+	Received uint64
+
+	// Handled is the number of calls to the functions for the signal,
+	// including the default function.
+	Handled uint64
+
+	// Coalesced is the number of calls that were skipped because they
+	// were part of a burst (see Options.Coalesce).
+	Coalesced uint64
+
+	// LastReceived is when the signal was last received. It's the zero
+	// time if it never was.
+	LastReceived time.Time
+}
+
+func (h *handler) Stats(sig os.Signal) Stats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.stats[sig]; ok {
+		return *s
+	}
+	return Stats{}
+}
+
+func (h *handler) LastReceived(sig os.Signal) time.Time {
+	return h.Stats(sig).LastReceived
+}
+
+// stat calls f with the statistics of the signal while holding the
+// lock.
+func (h *handler) stat(sig os.Signal, f func(s *Stats)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.stats[sig]
+	if !ok {
+		s = &Stats{}
+		h.stats[sig] = s
+	}
+	f(s)
+}
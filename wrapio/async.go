@@ -0,0 +1,128 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"io"
+	"sync"
+)
+
+// asyncOp is a write or, if flushed isn't nil, a flush queued on an
+// AsyncWriter.
+type asyncOp struct {
+	p       []byte
+	flushed chan struct{} // Closed once the writes before it are done.
+}
+
+// AsyncWriter is an io.WriteCloser that writes in the background.
+// Instantiate it with NewAsyncWriter.
+type AsyncWriter struct {
+	w      io.Writer
+	ops    chan asyncOp
+	done   chan struct{} // Closed when the background writes stop.
+	closed bool
+
+	mu  sync.Mutex // protects the following.
+	err error      // The first error from writing.
+}
+
+// NewAsyncWriter returns an AsyncWriter that writes what's written to it
+// to the given io.Writer on its own goroutine. Up to bufSize writes are
+// queued, so Write only waits for the io.Writer when the queue is full.
+// This keeps slow writers, like the network or a disk, from slowing
+// down the code writing to them.
+//
+// Since the writes happen later, an error from the io.Writer is returned
+// by the Write, Flush or Close after it happened and nothing else is
+// written. Close must be called to write out what's queued and stop the
+// goroutine. It doesn't close the io.Writer. If w is nil, nil is
+// returned.
+func NewAsyncWriter(bufSize int, w io.Writer) *AsyncWriter {
+	if w == nil {
+		return nil
+	}
+	if bufSize < 0 {
+		bufSize = 0
+	}
+	a := &AsyncWriter{
+		w:    w,
+		ops:  make(chan asyncOp, bufSize),
+		done: make(chan struct{}),
+	}
+	go a.write()
+	return a
+}
+
+// write performs the queued operations until the queue is closed.
+func (a *AsyncWriter) write() {
+	defer close(a.done)
+	for op := range a.ops {
+		if op.flushed != nil {
+			close(op.flushed)
+			continue
+		}
+		if a.error() != nil {
+			continue
+		}
+		n, err := a.w.Write(op.p)
+		if err == nil && n < len(op.p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			a.mu.Lock()
+			a.err = err
+			a.mu.Unlock()
+		}
+	}
+}
+
+// error returns the first error from writing.
+func (a *AsyncWriter) error() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// Write implements the io.Writer interface. The data is copied, so p can
+// be reused once it returns.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	if a.closed {
+		return 0, ErrClosed
+	}
+	if err := a.error(); err != nil {
+		return 0, err
+	}
+	if len(p) < 1 {
+		return 0, nil
+	}
+	a.ops <- asyncOp{p: append([]byte(nil), p...)}
+	return len(p), nil
+}
+
+// Flush waits until the queued writes are written and returns the first
+// error from writing.
+func (a *AsyncWriter) Flush() error {
+	if a.closed {
+		return ErrClosed
+	}
+	flushed := make(chan struct{})
+	a.ops <- asyncOp{flushed: flushed}
+	<-flushed
+	return a.error()
+}
+
+// Close writes out the queued writes, stops the background goroutine and
+// returns the first error from writing.
+func (a *AsyncWriter) Close() error {
+	if a.closed {
+		return ErrClosed
+	}
+	a.closed = true
+	close(a.ops)
+	<-a.done
+	return a.error()
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/icub3d/gop/wrapio/iotestutil"
+)
+
+func TestAsyncWriter(t *testing.T) {
+	if NewAsyncWriter(1, nil) != nil {
+		t.Errorf("NewAsyncWriter() with nil writer wasn't nil")
+	}
+
+	// Writes are queued until the writer is released.
+	g := newGateWriter()
+	a := NewAsyncWriter(2, g)
+	p := []byte("ab")
+	if n, err := a.Write(p); n != 2 || err != nil {
+		t.Errorf("Write() = %v, %v", n, err)
+	}
+	<-g.entered
+	copy(p, "cd") // p can be reused.
+	if n, err := a.Write(p); n != 2 || err != nil {
+		t.Errorf("Write() = %v, %v", n, err)
+	}
+	if g.buf.Len() != 0 {
+		t.Errorf("wrote %q before it was released", g.buf.String())
+	}
+	close(g.release)
+	if err := a.Flush(); err != nil {
+		t.Errorf("Flush(): %v", err)
+	}
+	if g.buf.String() != "abcd" {
+		t.Errorf("wrote %q after Flush(), wanted abcd", g.buf.String())
+	}
+	a.Write([]byte("ef"))
+	if err := a.Close(); err != nil {
+		t.Errorf("Close(): %v", err)
+	}
+	if g.buf.String() != "abcdef" {
+		t.Errorf("wrote %q after Close(), wanted abcdef", g.buf.String())
+	}
+	if _, err := a.Write([]byte("x")); err != ErrClosed {
+		t.Errorf("Write() after Close() = %v", err)
+	}
+	if err := a.Flush(); err != ErrClosed {
+		t.Errorf("Flush() after Close() = %v", err)
+	}
+	if err := a.Close(); err != ErrClosed {
+		t.Errorf("second Close() = %v", err)
+	}
+
+	// Errors are returned by the calls after they happen.
+	werr := errors.New("failed")
+	a = NewAsyncWriter(10, iotestutil.NewErrorWriter(0, werr, ioutil.Discard))
+	if _, err := a.Write([]byte("hello")); err != nil {
+		t.Errorf("first Write() = %v", err)
+	}
+	if err := a.Flush(); err != werr {
+		t.Errorf("Flush() = %v, wanted %v", err, werr)
+	}
+	if _, err := a.Write([]byte("hello")); err != werr {
+		t.Errorf("Write() after error = %v, wanted %v", err, werr)
+	}
+	if err := a.Close(); err != werr {
+		t.Errorf("Close() = %v, wanted %v", err, werr)
+	}
+
+	// A short write is an error.
+	a = NewAsyncWriter(0, iotestutil.NewShortWriter(1, ioutil.Discard))
+	a.Write([]byte("hello"))
+	if err := a.Close(); err != io.ErrShortWrite {
+		t.Errorf("Close() after short write = %v, wanted %v", err, io.ErrShortWrite)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"io"
+
+	"golang.org/x/net/context"
+)
+
+type ctxio struct {
+	ctx context.Context
+	r   io.Reader
+	w   io.Writer
+}
+
+// Read implements the io.Reader interface.
+func (c *ctxio) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// Write implements the io.Writer interface.
+func (c *ctxio) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
+
+// NewContextReader returns an io.Reader that wraps the given io.Reader
+// and returns the context's error from Read() once the context is
+// done. This stops loops like io.Copy between reads. A Read() that's
+// already blocked on the given reader isn't interrupted though. If
+// either of the parameters are nil, nil is returned.
+func NewContextReader(ctx context.Context, r io.Reader) io.Reader {
+	if ctx == nil || r == nil {
+		return nil
+	}
+	return &ctxio{ctx: ctx, r: r}
+}
+
+// NewContextWriter is like NewContextReader but returns the context's
+// error from Write() once the context is done. If either of the
+// parameters are nil, nil is returned.
+func NewContextWriter(ctx context.Context, w io.Writer) io.Writer {
+	if ctx == nil || w == nil {
+		return nil
+	}
+	return &ctxio{ctx: ctx, w: w}
+}
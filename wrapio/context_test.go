@@ -0,0 +1,60 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/icub3d/gop/wrapio/iotestutil"
+	"golang.org/x/net/context"
+)
+
+func TestContextReader(t *testing.T) {
+	if NewContextReader(context.Background(), nil) != nil {
+		t.Errorf("NewContextReader() with nil reader wasn't nil")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewContextReader(ctx, strings.NewReader("abcdef"))
+	p := make([]byte, 3)
+	if n, err := r.Read(p); n != 3 || err != nil || string(p) != "abc" {
+		t.Errorf("Read() = %v, %v, %q", n, err, p)
+	}
+	cancel()
+	if n, err := r.Read(p); n != 0 || err != context.Canceled {
+		t.Errorf("Read() after cancel() = %v, %v", n, err)
+	}
+
+	// A copy stops once the context is done.
+	ctx, cancel = context.WithCancel(context.Background())
+	w := NewFuncWriter(func(p []byte) { cancel() }, &bytes.Buffer{})
+	r = NewContextReader(ctx, iotestutil.NewShortReader(2, strings.NewReader("abcdef")))
+	if n, err := io.Copy(w, r); n != 2 || err != context.Canceled {
+		t.Errorf("io.Copy() = %v, %v, wanted 2, %v", n, err, context.Canceled)
+	}
+}
+
+func TestContextWriter(t *testing.T) {
+	if NewContextWriter(nil, &bytes.Buffer{}) != nil {
+		t.Errorf("NewContextWriter() with nil context wasn't nil")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &bytes.Buffer{}
+	w := NewContextWriter(ctx, b)
+	if n, err := w.Write([]byte("abc")); n != 3 || err != nil {
+		t.Errorf("Write() = %v, %v", n, err)
+	}
+	cancel()
+	if n, err := w.Write([]byte("def")); n != 0 || err != context.Canceled {
+		t.Errorf("Write() after cancel() = %v, %v", n, err)
+	}
+	if b.String() != "abc" {
+		t.Errorf("wrote %q, wanted abc", b)
+	}
+}
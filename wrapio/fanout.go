@@ -0,0 +1,189 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrSlowWriter is returned by a FanOutWriter with the FanOutError
+// policy when one of its writers' buffer is full.
+var ErrSlowWriter = errors.New("slow writer")
+
+// FanOutPolicy is what a FanOutWriter does when one of its writers'
+// buffer is full.
+type FanOutPolicy int
+
+// These are the policies of a FanOutWriter.
+const (
+	// FanOutBlock waits until there is room in the buffer, so the
+	// slowest writer sets the pace.
+	FanOutBlock FanOutPolicy = iota
+
+	// FanOutDrop doesn't write the data to the slow writer. The number
+	// of writes dropped is available from Dropped.
+	FanOutDrop
+
+	// FanOutError fails the slow writer with ErrSlowWriter. The
+	// writers before it may already have been given the data.
+	FanOutError
+)
+
+// FanOutWriter is an io.WriteCloser that writes to several writers
+// concurrently. Instantiate it with NewFanOutWriter.
+type FanOutWriter struct {
+	// Policy is what it does when a writer's buffer is full. The
+	// default is FanOutBlock. It should be set before the first Write.
+	Policy FanOutPolicy
+
+	chs    []chan []byte // The buffers of the writers.
+	wg     sync.WaitGroup
+	closed bool
+
+	mu      sync.Mutex // protects the following.
+	errs    []error    // The errors of the writers.
+	err     error      // The first error of a writer.
+	dropped []int64    // The number of writes dropped for each writer.
+}
+
+// NewFanOutWriter returns a FanOutWriter that writes what's written to
+// it to each of the given writers. Each writer is written to by its own
+// goroutine and has a buffer of up to the given number of writes, so
+// Write only waits for the slow writers, depending on the Policy, once
+// their buffer is full. This is useful for writing the same data to
+// destinations of different speeds, like a disk and the network.
+//
+// Since the writes happen later, the error of a writer is returned by
+// the Write or Close after it happened. Like TeeFailFast, once a writer
+// fails, every Write returns the error without writing anything. The
+// other writers still write what's in their buffer. Close must be
+// called to wait for the buffers to be written. It doesn't close the
+// writers.
+func NewFanOutWriter(buffer int, ws ...io.Writer) *FanOutWriter {
+	if buffer < 0 {
+		buffer = 0
+	}
+	f := &FanOutWriter{
+		errs:    make([]error, len(ws)),
+		dropped: make([]int64, len(ws)),
+	}
+	for x, w := range ws {
+		ch := make(chan []byte, buffer)
+		f.chs = append(f.chs, ch)
+		f.wg.Add(1)
+		go f.write(x, w, ch)
+	}
+	return f
+}
+
+// write writes the data sent on ch to the x-th writer until ch is
+// closed.
+func (f *FanOutWriter) write(x int, w io.Writer, ch chan []byte) {
+	defer f.wg.Done()
+	for p := range ch {
+		if f.failed(x) {
+			continue
+		}
+		n, err := w.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			f.fail(x, err)
+		}
+	}
+}
+
+// fail records the error of the x-th writer.
+func (f *FanOutWriter) fail(x int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errs[x] = err
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+// failed returns whether the x-th writer failed.
+func (f *FanOutWriter) failed(x int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.errs[x] != nil
+}
+
+// error returns the first error of a writer.
+func (f *FanOutWriter) error() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// Write implements the io.Writer interface. The data is copied, so p can
+// be reused once it returns.
+func (f *FanOutWriter) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, ErrClosed
+	}
+	if err := f.error(); err != nil {
+		return 0, err
+	}
+	if len(p) < 1 {
+		return 0, nil
+	}
+	b := append([]byte(nil), p...)
+	for x, ch := range f.chs {
+		if f.Policy == FanOutBlock {
+			ch <- b
+			continue
+		}
+		select {
+		case ch <- b:
+			continue
+		default:
+		}
+		if f.Policy == FanOutError {
+			f.fail(x, ErrSlowWriter)
+			return 0, ErrSlowWriter
+		}
+		f.mu.Lock()
+		f.dropped[x]++
+		f.mu.Unlock()
+	}
+	return len(p), nil
+}
+
+// Close waits for the buffered data to be written and returns the first
+// error of a writer.
+func (f *FanOutWriter) Close() error {
+	if f.closed {
+		return ErrClosed
+	}
+	f.closed = true
+	for _, ch := range f.chs {
+		close(ch)
+	}
+	f.wg.Wait()
+	return f.error()
+}
+
+// Errs returns the errors of the writers in the order they were given.
+// The error of a writer that hasn't failed is nil.
+func (f *FanOutWriter) Errs() []error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]error(nil), f.errs...)
+}
+
+// Dropped returns the number of writes that were dropped for each of
+// the writers in the order they were given.
+func (f *FanOutWriter) Dropped() []int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int64(nil), f.dropped...)
+}
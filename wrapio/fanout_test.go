@@ -0,0 +1,142 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/icub3d/gop/wrapio/iotestutil"
+)
+
+// gateWriter blocks writes until release is closed. It signals entered
+// when a write starts.
+type gateWriter struct {
+	entered chan struct{}
+	release chan struct{}
+	buf     bytes.Buffer
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{
+		entered: make(chan struct{}, 100),
+		release: make(chan struct{}),
+	}
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	g.entered <- struct{}{}
+	<-g.release
+	return g.buf.Write(p)
+}
+
+func TestFanOutWriter(t *testing.T) {
+	tests := []struct {
+		policy  FanOutPolicy
+		err     error  // The error of the third write.
+		wrote   string // What the slow writer gets.
+		dropped []int64
+		errs    []error
+	}{
+		{
+			policy:  FanOutBlock,
+			wrote:   "abc",
+			dropped: []int64{0},
+			errs:    []error{nil},
+		},
+		{
+			policy:  FanOutDrop,
+			wrote:   "ab",
+			dropped: []int64{1},
+			errs:    []error{nil},
+		},
+		{
+			policy:  FanOutError,
+			err:     ErrSlowWriter,
+			wrote:   "a",
+			dropped: []int64{0},
+			errs:    []error{ErrSlowWriter},
+		},
+	}
+	for k, test := range tests {
+		slow := newGateWriter()
+		f := NewFanOutWriter(1, slow)
+		f.Policy = test.policy
+
+		// The slow writer is stuck writing a while b is buffered.
+		f.Write([]byte("a"))
+		<-slow.entered
+		f.Write([]byte("b"))
+		if test.policy == FanOutBlock {
+			// The third write waits for the slow writer.
+			go close(slow.release)
+		}
+		if n, err := f.Write([]byte("c")); err != test.err {
+			t.Errorf("Test %v: third Write() = %v, %v, wanted %v", k, n, err, test.err)
+		}
+		if test.err != nil {
+			if _, err := f.Write([]byte("d")); err != test.err {
+				t.Errorf("Test %v: Write() after error = %v, wanted %v", k, err, test.err)
+			}
+		}
+		if test.policy != FanOutBlock {
+			close(slow.release)
+		}
+		if err := f.Close(); err != test.err {
+			t.Errorf("Test %v: Close() = %v, wanted %v", k, err, test.err)
+		}
+		if slow.buf.String() != test.wrote {
+			t.Errorf("Test %v: wrote %q, wanted %q", k, slow.buf.String(), test.wrote)
+		}
+		if d := f.Dropped(); !reflect.DeepEqual(d, test.dropped) {
+			t.Errorf("Test %v: Dropped() = %v, wanted %v", k, d, test.dropped)
+		}
+		if e := f.Errs(); !reflect.DeepEqual(e, test.errs) {
+			t.Errorf("Test %v: Errs() = %v, wanted %v", k, e, test.errs)
+		}
+		if _, err := f.Write([]byte("e")); err != ErrClosed {
+			t.Errorf("Test %v: Write() after Close() = %v", k, err)
+		}
+		if err := f.Close(); err != ErrClosed {
+			t.Errorf("Test %v: second Close() = %v", k, err)
+		}
+	}
+
+	// Every writer gets all of the data.
+	a, b := &bytes.Buffer{}, &bytes.Buffer{}
+	f := NewFanOutWriter(0, a, b)
+	for _, s := range []string{"hello", ", ", "world"} {
+		if _, err := f.Write([]byte(s)); err != nil {
+			t.Errorf("Write(%q): %v", s, err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("Close(): %v", err)
+	}
+	if a.String() != "hello, world" || b.String() != "hello, world" {
+		t.Errorf("wrote %q and %q", a.String(), b.String())
+	}
+
+	// A writer's error is returned by Close but the others keep
+	// writing what they were given.
+	werr := errors.New("failed")
+	a = &bytes.Buffer{}
+	f = NewFanOutWriter(10, a, iotestutil.NewErrorWriter(0, werr, ioutil.Discard))
+	f.Write([]byte("hello"))
+	if err := f.Close(); err != werr {
+		t.Errorf("Close() = %v, wanted %v", err, werr)
+	}
+	if e := f.Errs(); e[0] != nil || e[1] != werr {
+		t.Errorf("Errs() = %v", e)
+	}
+	if a.String() != "hello" {
+		t.Errorf("wrote %q, wanted hello", a.String())
+	}
+}
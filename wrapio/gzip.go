@@ -0,0 +1,126 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClosed is returned when a wrapper is used after it's closed.
+var ErrClosed = errors.New("closed")
+
+var (
+	// gzipWriters are the unused gzip writers for each level offset by
+	// gzip.HuffmanOnly.
+	gzipWriters [gzip.BestCompression - gzip.HuffmanOnly + 1]sync.Pool
+
+	// gzipReaders are the unused gzip readers.
+	gzipReaders sync.Pool
+)
+
+type gzipWriter struct {
+	z     *gzip.Writer // nil once closed.
+	level int
+}
+
+// Write implements the io.Writer interface.
+func (g *gzipWriter) Write(p []byte) (int, error) {
+	if g.z == nil {
+		return 0, ErrClosed
+	}
+	return g.z.Write(p)
+}
+
+// Close implements the io.Closer interface. It flushes the compressed
+// data and writes the gzip footer but doesn't close the underlying
+// io.Writer.
+func (g *gzipWriter) Close() error {
+	if g.z == nil {
+		return ErrClosed
+	}
+	err := g.z.Close()
+	gzipWriters[g.level-gzip.HuffmanOnly].Put(g.z)
+	g.z = nil
+	return err
+}
+
+// NewGzipWriter returns an io.WriteCloser that gzips what's written to
+// it at the given level (see compress/gzip) to the given io.Writer. It
+// must be closed to finish the stream. Closing it returns its encoder
+// to a pool that later writers reuse, which saves allocating one for
+// each stream, e.g. for each response of an HTTP server. If w is nil or
+// the level is invalid, nil is returned.
+func NewGzipWriter(level int, w io.Writer) io.WriteCloser {
+	if w == nil || level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return nil
+	}
+	var z *gzip.Writer
+	if v := gzipWriters[level-gzip.HuffmanOnly].Get(); v != nil {
+		z = v.(*gzip.Writer)
+		z.Reset(w)
+	} else {
+		z, _ = gzip.NewWriterLevel(w, level)
+	}
+	return &gzipWriter{z: z, level: level}
+}
+
+type gunzip struct {
+	r   io.Reader
+	z   *gzip.Reader
+	err error // The error to return from Read().
+}
+
+// Read implements the io.Reader interface.
+func (g *gunzip) Read(p []byte) (int, error) {
+	if g.err != nil {
+		return 0, g.err
+	}
+	if g.z == nil {
+		z, _ := gzipReaders.Get().(*gzip.Reader)
+		if z == nil {
+			z = new(gzip.Reader)
+		}
+		if err := z.Reset(g.r); err != nil {
+			gzipReaders.Put(z)
+			g.err = err
+			return 0, err
+		}
+		g.z = z
+	}
+	return g.z.Read(p)
+}
+
+// Close implements the io.Closer interface. It doesn't close the
+// underlying io.Reader.
+func (g *gunzip) Close() error {
+	if g.err == ErrClosed {
+		return ErrClosed
+	}
+	var err error
+	if g.z != nil {
+		err = g.z.Close()
+		gzipReaders.Put(g.z)
+		g.z = nil
+	}
+	g.err = ErrClosed
+	return err
+}
+
+// NewGunzipReader returns an io.ReadCloser that reads the gzipped data
+// from the given io.Reader and returns it uncompressed. The gzip header
+// is read by the first Read(), so an invalid header is reported there.
+// Like NewGzipWriter, closing it returns its decoder to a pool that
+// later readers reuse. If r is nil, nil is returned.
+func NewGunzipReader(r io.Reader) io.ReadCloser {
+	if r == nil {
+		return nil
+	}
+	return &gunzip{r: r}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestGzip(t *testing.T) {
+	if NewGzipWriter(gzip.DefaultCompression, nil) != nil {
+		t.Errorf("NewGzipWriter() with nil writer wasn't nil")
+	}
+	if NewGzipWriter(gzip.BestCompression+1, &bytes.Buffer{}) != nil {
+		t.Errorf("NewGzipWriter() with invalid level wasn't nil")
+	}
+	if NewGunzipReader(nil) != nil {
+		t.Errorf("NewGunzipReader() with nil reader wasn't nil")
+	}
+
+	// Round trip each level twice so pooled encoders are reused.
+	data := strings.Repeat("hello, world! ", 1000)
+	for level := gzip.HuffmanOnly; level <= gzip.BestCompression; level++ {
+		for x := 0; x < 2; x++ {
+			buf := &bytes.Buffer{}
+			w := NewGzipWriter(level, buf)
+			if _, err := w.Write([]byte(data)); err != nil {
+				t.Fatalf("level %v: Write(): %v", level, err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("level %v: Close(): %v", level, err)
+			}
+			if _, err := w.Write([]byte("x")); err != ErrClosed {
+				t.Errorf("level %v: Write() after Close() = %v", level, err)
+			}
+			if err := w.Close(); err != ErrClosed {
+				t.Errorf("level %v: second Close() = %v", level, err)
+			}
+
+			// It's readable by compress/gzip.
+			z, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("level %v: gzip.NewReader(): %v", level, err)
+			}
+			if b, err := ioutil.ReadAll(z); err != nil || string(b) != data {
+				t.Errorf("level %v: gzip read %v bytes, %v", level, len(b), err)
+			}
+
+			r := NewGunzipReader(buf)
+			if b, err := ioutil.ReadAll(r); err != nil || string(b) != data {
+				t.Errorf("level %v: gunzip read %v bytes, %v", level, len(b), err)
+			}
+			if err := r.Close(); err != nil {
+				t.Errorf("level %v: gunzip Close(): %v", level, err)
+			}
+			if _, err := r.Read(make([]byte, 1)); err != ErrClosed {
+				t.Errorf("level %v: Read() after Close() = %v", level, err)
+			}
+		}
+	}
+
+	// An invalid header is reported by Read().
+	r := NewGunzipReader(strings.NewReader("this isn't gzipped data"))
+	if _, err := r.Read(make([]byte, 1)); err != gzip.ErrHeader {
+		t.Errorf("Read() of invalid data = %v, wanted %v", err, gzip.ErrHeader)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() after invalid data = %v", err)
+	}
+
+	// Closing before reading anything works.
+	if err := NewGunzipReader(strings.NewReader("")).Close(); err != nil {
+		t.Errorf("Close() before Read() = %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+// Package iotestutil provides io.Readers and io.Writers that inject
+// faults for testing code that reads and writes streams, like the
+// wrappers of wrapio. It complements testing/iotest with readers and
+// writers that fail after a number of bytes, return short reads and
+// writes, and are slow. For example, to test that a pipeline handles a
+// connection dropping midway:
+//
+//	r := iotestutil.NewErrorReader(100, io.ErrUnexpectedEOF, f)
+//	err := process(iotestutil.NewShortReader(10, r))
+package iotestutil
+
+import (
+	"io"
+	"time"
+)
+
+type errorReader struct {
+	r   io.Reader
+	n   int64 // The number of bytes left before failing.
+	err error
+}
+
+// Read implements the io.Reader interface.
+func (e *errorReader) Read(p []byte) (int, error) {
+	if e.n <= 0 {
+		return 0, e.err
+	}
+	if int64(len(p)) > e.n {
+		p = p[:e.n]
+	}
+	n, err := e.r.Read(p)
+	e.n -= int64(n)
+	if err == nil && e.n <= 0 {
+		err = e.err
+	}
+	return n, err
+}
+
+// NewErrorReader returns an io.Reader that reads the first n bytes of
+// the given io.Reader and then fails with the given error. The Read()
+// that reaches n bytes returns the error with the data and every Read()
+// after that returns it as well. Errors from r before that, like
+// io.EOF, are returned as is. If r is nil, nil is returned.
+func NewErrorReader(n int64, err error, r io.Reader) io.Reader {
+	if r == nil {
+		return nil
+	}
+	return &errorReader{r: r, n: n, err: err}
+}
+
+type errorWriter struct {
+	w   io.Writer
+	n   int64 // The number of bytes left before failing.
+	err error
+}
+
+// Write implements the io.Writer interface.
+func (e *errorWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= e.n {
+		n, err := e.w.Write(p)
+		e.n -= int64(n)
+		return n, err
+	}
+	n, err := e.w.Write(p[:e.n])
+	e.n -= int64(n)
+	if err == nil {
+		err = e.err
+	}
+	return n, err
+}
+
+// NewErrorWriter returns an io.Writer that writes the first n bytes to
+// the given io.Writer and then fails with the given error. The Write()
+// that goes over n bytes writes what fits and returns the error. Every
+// Write() after that returns it without writing anything unless it's
+// empty. If w is nil, nil is returned.
+func NewErrorWriter(n int64, err error, w io.Writer) io.Writer {
+	if w == nil {
+		return nil
+	}
+	if n < 0 {
+		n = 0
+	}
+	return &errorWriter{w: w, n: n, err: err}
+}
+
+type shortReader struct {
+	r   io.Reader
+	max int
+}
+
+// Read implements the io.Reader interface.
+func (s *shortReader) Read(p []byte) (int, error) {
+	if len(p) > s.max {
+		p = p[:s.max]
+	}
+	return s.r.Read(p)
+}
+
+// NewShortReader returns an io.Reader that reads at most max bytes at a
+// time from the given io.Reader, no matter how big the buffer it's
+// given is. It's iotest.OneByteReader and iotest.HalfReader for any
+// size. If r is nil or max is less than 1, nil is returned.
+func NewShortReader(max int, r io.Reader) io.Reader {
+	if r == nil || max < 1 {
+		return nil
+	}
+	return &shortReader{r: r, max: max}
+}
+
+type shortWriter struct {
+	w   io.Writer
+	max int
+}
+
+// Write implements the io.Writer interface.
+func (s *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > s.max {
+		p = p[:s.max]
+	}
+	return s.w.Write(p)
+}
+
+// NewShortWriter returns an io.Writer that writes at most max bytes of
+// each Write() to the given io.Writer. It returns the number of bytes
+// written without an error, which breaks the io.Writer contract on
+// purpose to test that callers catch short writes. If w is nil or max
+// is negative, nil is returned.
+func NewShortWriter(max int, w io.Writer) io.Writer {
+	if w == nil || max < 0 {
+		return nil
+	}
+	return &shortWriter{w: w, max: max}
+}
+
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+// Read implements the io.Reader interface.
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.r.Read(p)
+}
+
+// NewSlowReader returns an io.Reader that waits for delay before each
+// Read() from the given io.Reader. If r is nil, nil is returned.
+func NewSlowReader(delay time.Duration, r io.Reader) io.Reader {
+	if r == nil {
+		return nil
+	}
+	return &slowReader{r: r, delay: delay}
+}
+
+type slowWriter struct {
+	w     io.Writer
+	delay time.Duration
+}
+
+// Write implements the io.Writer interface.
+func (s *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.w.Write(p)
+}
+
+// NewSlowWriter returns an io.Writer that waits for delay before each
+// Write() to the given io.Writer. If w is nil, nil is returned.
+func NewSlowWriter(delay time.Duration, w io.Writer) io.Writer {
+	if w == nil {
+		return nil
+	}
+	return &slowWriter{w: w, delay: delay}
+}
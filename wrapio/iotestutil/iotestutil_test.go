@@ -0,0 +1,120 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package iotestutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test")
+
+func TestNil(t *testing.T) {
+	if NewErrorReader(1, errTest, nil) != nil ||
+		NewErrorWriter(1, errTest, nil) != nil ||
+		NewShortReader(1, nil) != nil ||
+		NewShortReader(0, strings.NewReader("")) != nil ||
+		NewShortWriter(1, nil) != nil ||
+		NewShortWriter(-1, ioutil.Discard) != nil ||
+		NewSlowReader(0, nil) != nil ||
+		NewSlowWriter(0, nil) != nil {
+		t.Errorf("a constructor with invalid parameters didn't return nil")
+	}
+}
+
+func TestErrorReader(t *testing.T) {
+	r := NewErrorReader(5, errTest, strings.NewReader("hello, world"))
+	p := make([]byte, 3)
+	if n, err := r.Read(p); n != 3 || err != nil {
+		t.Errorf("first Read() = %v, %v", n, err)
+	}
+	if n, err := r.Read(p); n != 2 || err != errTest || string(p[:n]) != "lo" {
+		t.Errorf("second Read() = %q, %v", p[:n], err)
+	}
+	if n, err := r.Read(p); n != 0 || err != errTest {
+		t.Errorf("third Read() = %v, %v", n, err)
+	}
+
+	// The reader's own errors come first.
+	b, err := ioutil.ReadAll(NewErrorReader(10, errTest, strings.NewReader("hi")))
+	if string(b) != "hi" || err != nil {
+		t.Errorf("ReadAll() of short data = %q, %v", b, err)
+	}
+}
+
+func TestErrorWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewErrorWriter(5, errTest, buf)
+	if n, err := w.Write([]byte("abc")); n != 3 || err != nil {
+		t.Errorf("first Write() = %v, %v", n, err)
+	}
+	if n, err := w.Write([]byte("defg")); n != 2 || err != errTest {
+		t.Errorf("second Write() = %v, %v", n, err)
+	}
+	if n, err := w.Write([]byte("h")); n != 0 || err != errTest {
+		t.Errorf("third Write() = %v, %v", n, err)
+	}
+	if buf.String() != "abcde" {
+		t.Errorf("wrote %q, wanted abcde", buf.String())
+	}
+}
+
+func TestShortReader(t *testing.T) {
+	r := NewShortReader(4, strings.NewReader("hello, world"))
+	p := make([]byte, 10)
+	if n, err := r.Read(p); n != 4 || err != nil || string(p[:n]) != "hell" {
+		t.Errorf("Read() = %q, %v", p[:n], err)
+	}
+	if b, err := ioutil.ReadAll(r); string(b) != "o, world" || err != nil {
+		t.Errorf("ReadAll() = %q, %v", b, err)
+	}
+}
+
+func TestShortWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewShortWriter(2, buf)
+	if n, err := w.Write([]byte("abc")); n != 2 || err != nil {
+		t.Errorf("Write() = %v, %v", n, err)
+	}
+	if n, err := w.Write([]byte("d")); n != 1 || err != nil {
+		t.Errorf("Write() = %v, %v", n, err)
+	}
+	if buf.String() != "abd" {
+		t.Errorf("wrote %q, wanted abd", buf.String())
+	}
+	if _, err := io.Copy(NewShortWriter(1, ioutil.Discard),
+		strings.NewReader("abc")); err != io.ErrShortWrite {
+		t.Errorf("io.Copy() = %v, wanted %v", err, io.ErrShortWrite)
+	}
+}
+
+func TestSlow(t *testing.T) {
+	const delay = 10 * time.Millisecond
+	start := time.Now()
+	r := NewSlowReader(delay, NewShortReader(2, strings.NewReader("abcd")))
+	if b, err := ioutil.ReadAll(r); string(b) != "abcd" || err != nil {
+		t.Errorf("ReadAll() = %q, %v", b, err)
+	}
+	// There are two reads with data and one for io.EOF.
+	if d := time.Since(start); d < 3*delay {
+		t.Errorf("reading took %v, wanted at least %v", d, 3*delay)
+	}
+
+	start = time.Now()
+	buf := &bytes.Buffer{}
+	w := NewSlowWriter(delay, buf)
+	w.Write([]byte("ab"))
+	w.Write([]byte("cd"))
+	if d := time.Since(start); d < 2*delay || buf.String() != "abcd" {
+		t.Errorf("writing %q took %v, wanted at least %v", buf.String(), d, 2*delay)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrLimitExceeded is returned by a limit writer when more than its
+// limit is written to it.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+type limit struct {
+	w io.Writer
+	n int64 // The number of bytes that can still be written.
+}
+
+// Write implements the io.Writer interface.
+func (l *limit) Write(p []byte) (int, error) {
+	if int64(len(p)) <= l.n {
+		n, err := l.w.Write(p)
+		l.n -= int64(n)
+		return n, err
+	}
+	n, err := l.w.Write(p[:l.n])
+	l.n -= int64(n)
+	if err == nil {
+		err = ErrLimitExceeded
+	}
+	return n, err
+}
+
+// NewLimitWriter returns an io.Writer that writes at most n bytes to the
+// given io.Writer. It's the io.LimitReader for writes. A Write() that
+// goes over the limit writes what fits and returns ErrLimitExceeded.
+// Every Write() after that returns it without writing anything unless
+// it's empty. If w is nil, nil is returned.
+func NewLimitWriter(n int64, w io.Writer) io.Writer {
+	if w == nil {
+		return nil
+	}
+	if n < 0 {
+		n = 0
+	}
+	return &limit{w: w, n: n}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/icub3d/gop/wrapio/iotestutil"
+)
+
+func TestLimitWriter(t *testing.T) {
+	if NewLimitWriter(10, nil) != nil {
+		t.Errorf("NewLimitWriter() with nil writer wasn't nil")
+	}
+	tests := []struct {
+		n      int64
+		writes []string
+		ns     []int
+		errs   []error
+		result string
+	}{
+		// Under the limit.
+		{10, []string{"abc", "def"}, []int{3, 3}, []error{nil, nil}, "abcdef"},
+		// Exactly at the limit.
+		{6, []string{"abc", "def", ""}, []int{3, 3, 0}, []error{nil, nil, nil}, "abcdef"},
+		// Over the limit.
+		{5, []string{"abc", "def", "g"}, []int{3, 2, 0},
+			[]error{nil, ErrLimitExceeded, ErrLimitExceeded}, "abcde"},
+		// Negative limits write nothing.
+		{-1, []string{"abc"}, []int{0}, []error{ErrLimitExceeded}, ""},
+	}
+	for x, test := range tests {
+		b := &bytes.Buffer{}
+		w := NewLimitWriter(test.n, b)
+		for y, s := range test.writes {
+			n, err := w.Write([]byte(s))
+			if n != test.ns[y] || err != test.errs[y] {
+				t.Errorf("Test %v: Write(%q) = %v, %v, wanted %v, %v", x, s, n, err,
+					test.ns[y], test.errs[y])
+			}
+		}
+		if b.String() != test.result {
+			t.Errorf("Test %v: wrote %q, wanted %q", x, b, test.result)
+		}
+	}
+
+	// The limit applies to io.Copy and the writer's errors are returned.
+	b := &bytes.Buffer{}
+	if n, err := io.Copy(NewLimitWriter(4, b), strings.NewReader("abcdef")); n != 4 ||
+		err != ErrLimitExceeded {
+		t.Errorf("io.Copy() = %v, %v", n, err)
+	}
+	werr := errors.New("write failed")
+	if _, err := NewLimitWriter(1, iotestutil.NewErrorWriter(0, werr, ioutil.Discard)).Write([]byte("ab")); err != werr {
+		t.Errorf("Write() = %v, wanted %v", err, werr)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Progress is the progress of a transfer reported by a progress reader
+// or writer.
+type Progress struct {
+	Bytes     int64         // The number of bytes transferred.
+	Total     int64         // The expected total or 0 if it's unknown.
+	Percent   float64       // The percent of the total transferred.
+	Elapsed   time.Duration // The time since the transfer started.
+	Remaining time.Duration // The estimated time left.
+	Done      bool          // Whether the transfer is over.
+}
+
+// String implements the fmt.Stringer interface.
+func (p Progress) String() string {
+	if p.Total <= 0 {
+		return fmt.Sprintf("%d bytes in %v", p.Bytes, p.Elapsed)
+	}
+	return fmt.Sprintf("%d/%d bytes (%.1f%%) in %v, %v left", p.Bytes,
+		p.Total, p.Percent, p.Elapsed, p.Remaining)
+}
+
+type progress struct {
+	r        io.Reader
+	w        io.Writer
+	total    int64
+	interval time.Duration
+	cb       func(Progress)
+	n        int64
+	start    time.Time // When the first call was made.
+	last     time.Time // When cb was last called.
+	done     bool
+}
+
+// Read implements the io.Reader interface.
+func (p *progress) Read(b []byte) (int, error) {
+	p.begin()
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	p.report(err != nil)
+	return n, err
+}
+
+// Write implements the io.Writer interface.
+func (p *progress) Write(b []byte) (int, error) {
+	p.begin()
+	n, err := p.w.Write(b)
+	p.n += int64(n)
+	p.report(err != nil || (p.total > 0 && p.n >= p.total))
+	return n, err
+}
+
+// begin starts the clock on the first call.
+func (p *progress) begin() {
+	if p.start.IsZero() {
+		p.start = time.Now()
+		p.last = p.start
+	}
+}
+
+// report calls the callback if the interval has passed or the transfer
+// is over. It's only called once after the transfer is over.
+func (p *progress) report(done bool) {
+	if p.done {
+		return
+	}
+	now := time.Now()
+	if !done && now.Sub(p.last) < p.interval {
+		return
+	}
+	p.done, p.last = done, now
+	pr := Progress{
+		Bytes:   p.n,
+		Total:   p.total,
+		Elapsed: now.Sub(p.start),
+		Done:    done,
+	}
+	if p.total > 0 {
+		pr.Percent = float64(p.n) / float64(p.total) * 100
+		if p.n > 0 && p.n < p.total {
+			pr.Remaining = time.Duration(float64(pr.Elapsed) *
+				float64(p.total-p.n) / float64(p.n))
+		}
+	}
+	p.cb(pr)
+}
+
+// NewProgressReader returns an io.Reader that wraps the given io.Reader
+// and calls cb with the progress of the reading. It's called after a
+// Read() when at least interval has passed since the last call and
+// once more when Read() returns an error, including io.EOF. total is
+// the expected number of bytes, which is used to calculate the percent
+// and the remaining time. It may be 0 if it's unknown. If either cb or
+// r is nil, nil is returned.
+func NewProgressReader(total int64, interval time.Duration,
+	cb func(Progress), r io.Reader) io.Reader {
+	if cb == nil || r == nil {
+		return nil
+	}
+	return &progress{r: r, total: total, interval: interval, cb: cb}
+}
+
+// NewProgressWriter is like NewProgressReader but for the Write()
+// operations. Since it can't tell when writing is done, the last call is
+// made when total bytes have been written or Write() returns an error.
+// If either cb or w is nil, nil is returned.
+func NewProgressWriter(total int64, interval time.Duration,
+	cb func(Progress), w io.Writer) io.Writer {
+	if cb == nil || w == nil {
+		return nil
+	}
+	return &progress{w: w, total: total, interval: interval, cb: cb}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/icub3d/gop/wrapio/iotestutil"
+)
+
+func TestProgressReader(t *testing.T) {
+	if NewProgressReader(0, 0, nil, strings.NewReader("")) != nil {
+		t.Errorf("NewProgressReader() with nil callback wasn't nil")
+	}
+	data := strings.Repeat("a", 100)
+	var ps []Progress
+	r := NewProgressReader(100, 0, func(p Progress) { ps = append(ps, p) },
+		iotestutil.NewShortReader(25, strings.NewReader(data)))
+	if _, err := ioutil.ReadAll(r); err != nil {
+		t.Fatalf("ReadAll(): %v", err)
+	}
+	// There is one per Read() including the one returning io.EOF.
+	if len(ps) != 5 {
+		t.Fatalf("got %v progress reports, wanted 5: %v", len(ps), ps)
+	}
+	for x, p := range ps[:4] {
+		n := int64(x+1) * 25
+		if p.Bytes != n || p.Total != 100 || p.Percent != float64(n) || p.Done {
+			t.Errorf("report %v = %+v", x, p)
+		}
+	}
+	if p := ps[4]; !p.Done || p.Bytes != 100 || p.Remaining != 0 {
+		t.Errorf("last report = %+v", p)
+	}
+	if ps[0].Remaining < 0 || ps[0].Elapsed < 0 {
+		t.Errorf("first report has negative times: %+v", ps[0])
+	}
+
+	// With a long interval, only the end is reported.
+	ps = nil
+	r = NewProgressReader(0, time.Hour, func(p Progress) { ps = append(ps, p) },
+		iotestutil.NewShortReader(25, strings.NewReader(data)))
+	ioutil.ReadAll(r)
+	if len(ps) != 1 || !ps[0].Done || ps[0].Bytes != 100 || ps[0].Percent != 0 {
+		t.Errorf("reports with unknown total = %v", ps)
+	}
+	if s := ps[0].String(); !strings.HasPrefix(s, "100 bytes in ") {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestProgressWriter(t *testing.T) {
+	if NewProgressWriter(0, 0, func(Progress) {}, nil) != nil {
+		t.Errorf("NewProgressWriter() with nil writer wasn't nil")
+	}
+	var ps []Progress
+	w := NewProgressWriter(10, time.Hour, func(p Progress) { ps = append(ps, p) },
+		ioutil.Discard)
+	for x := 0; x < 5; x++ {
+		w.Write([]byte("ab"))
+	}
+	if len(ps) != 1 || !ps[0].Done || ps[0].Percent != 100 {
+		t.Errorf("reports = %v", ps)
+	}
+	if s := ps[0].String(); !strings.HasPrefix(s, "10/10 bytes (100.0%) in ") {
+		t.Errorf("String() = %q", s)
+	}
+
+	// An error ends the transfer.
+	ps = nil
+	werr := errors.New("write failed")
+	w = NewProgressWriter(10, time.Hour, func(p Progress) { ps = append(ps, p) },
+		iotestutil.NewErrorWriter(0, werr, ioutil.Discard))
+	if _, err := w.Write([]byte("ab")); err != werr {
+		t.Errorf("Write() = %v, wanted %v", err, werr)
+	}
+	if len(ps) != 1 || !ps[0].Done || ps[0].Bytes != 0 {
+		t.Errorf("reports after error = %v", ps)
+	}
+}
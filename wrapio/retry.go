@@ -0,0 +1,80 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"io"
+	"time"
+)
+
+// sleep waits between retries. This is for testing.
+var sleep = time.Sleep
+
+type retry struct {
+	r       io.Reader // The current reader or nil if it failed.
+	reopen  func(offset int64) (io.Reader, error)
+	retries int
+	backoff time.Duration
+	off     int64 // The number of bytes read.
+	tries   int   // The number of retries since the last successful read.
+	err     error // The last error.
+}
+
+// Read implements the io.Reader interface.
+func (r *retry) Read(p []byte) (int, error) {
+	for {
+		if r.r != nil {
+			n, err := r.r.Read(p)
+			r.off += int64(n)
+			if n > 0 {
+				r.tries = 0
+			}
+			if err == nil || err == io.EOF {
+				return n, err
+			}
+			r.err = err
+			if c, ok := r.r.(io.Closer); ok {
+				c.Close()
+			}
+			r.r = nil
+			if n > 0 {
+				// The reader is reopened on the next call.
+				return n, nil
+			}
+		}
+		if r.tries >= r.retries {
+			return 0, r.err
+		}
+		r.tries++
+		sleep(r.backoff << uint(r.tries-1))
+		nr, err := r.reopen(r.off)
+		if err != nil {
+			r.err = err
+			continue
+		}
+		r.r = nr
+	}
+}
+
+// NewRetryReader returns an io.Reader that reads from the given
+// io.Reader and recovers from its errors by reopening it. When a Read()
+// fails with an error other than io.EOF, reopen is called with the
+// number of bytes read so far and reading continues from the reader it
+// returns. This suits sources that can be read from an offset, like
+// HTTP servers that support range requests or files. The reader is
+// reopened up to retries times in a row. The first time, it waits for
+// backoff beforehand and the wait doubles each time after that. The
+// count starts over once data is read. If the retries run out, the
+// last error is returned. A failed reader is closed if it's an
+// io.Closer. If either r or reopen is nil, nil is returned.
+func NewRetryReader(r io.Reader, retries int, backoff time.Duration,
+	reopen func(offset int64) (io.Reader, error)) io.Reader {
+	if r == nil || reopen == nil {
+		return nil
+	}
+	return &retry{r: r, reopen: reopen, retries: retries, backoff: backoff}
+}
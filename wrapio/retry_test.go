@@ -0,0 +1,112 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flaky reads from data starting at an offset and fails after n bytes.
+type flaky struct {
+	data   string
+	off    int64
+	n      int
+	closed bool
+}
+
+var errFlaky = errors.New("flaky")
+
+func (f *flaky) Read(p []byte) (int, error) {
+	if f.n == 0 {
+		return 0, errFlaky
+	}
+	if len(p) > f.n {
+		p = p[:f.n]
+	}
+	n, err := strings.NewReader(f.data[f.off:]).Read(p)
+	f.off += int64(n)
+	f.n -= n
+	return n, err
+}
+
+func (f *flaky) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestRetryReader(t *testing.T) {
+	if NewRetryReader(strings.NewReader(""), 1, 0, nil) != nil {
+		t.Errorf("NewRetryReader() with nil reopen wasn't nil")
+	}
+	var waits []time.Duration
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	defer func() { sleep = time.Sleep }()
+
+	// Each reader fails after 10 bytes and every other reopen fails.
+	data := strings.Repeat("abcdefghij", 5) + "xyz"
+	var (
+		readers []*flaky
+		offsets []int64
+		reopens int
+	)
+	first := &flaky{data: data, n: 10}
+	readers = append(readers, first)
+	r := NewRetryReader(first, 2, time.Millisecond, func(off int64) (io.Reader, error) {
+		reopens++
+		if reopens%2 == 1 {
+			return nil, errors.New("reopen failed")
+		}
+		offsets = append(offsets, off)
+		f := &flaky{data: data, off: off, n: 10}
+		readers = append(readers, f)
+		return f, nil
+	})
+	b, err := ioutil.ReadAll(r)
+	if err != nil || string(b) != data {
+		t.Fatalf("ReadAll() = %q, %v", b, err)
+	}
+	if !reflect.DeepEqual(offsets, []int64{10, 20, 30, 40, 50}) {
+		t.Errorf("reopened at %v", offsets)
+	}
+	for x, f := range readers[:len(readers)-1] {
+		if !f.closed {
+			t.Errorf("reader %v wasn't closed", x)
+		}
+	}
+	// The backoff doubles for the second try in a row.
+	if len(waits) != 10 || waits[0] != time.Millisecond || waits[1] != 2*time.Millisecond {
+		t.Errorf("waits = %v", waits)
+	}
+
+	// The last error is returned once the retries run out.
+	rerr := errors.New("reopen failed")
+	r = NewRetryReader(&flaky{data: data, n: 5}, 3, 0, func(off int64) (io.Reader, error) {
+		return nil, rerr
+	})
+	b, err = ioutil.ReadAll(r)
+	if err != rerr || string(b) != data[:5] {
+		t.Errorf("ReadAll() = %q, %v, wanted %q, %v", b, err, data[:5], rerr)
+	}
+	if _, err := r.Read(make([]byte, 1)); err != rerr {
+		t.Errorf("Read() after failing = %v, wanted %v", err, rerr)
+	}
+
+	// Without retries, the error is returned right away.
+	r = NewRetryReader(&flaky{data: data}, 0, 0, func(off int64) (io.Reader, error) {
+		t.Errorf("reopened without retries")
+		return nil, nil
+	})
+	if _, err := r.Read(make([]byte, 1)); err != errFlaky {
+		t.Errorf("Read() = %v, wanted %v", err, errFlaky)
+	}
+}
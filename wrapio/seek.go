@@ -0,0 +1,53 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import "io"
+
+type seek struct {
+	r    io.Reader // The wrapped reader.
+	rs   io.ReadSeeker
+	wrap func(io.Reader) io.Reader
+}
+
+// Read implements the io.Reader interface.
+func (s *seek) Read(p []byte) (int, error) {
+	return s.r.Read(p)
+}
+
+// Seek implements the io.Seeker interface.
+func (s *seek) Seek(offset int64, whence int) (int64, error) {
+	n, err := s.rs.Seek(offset, whence)
+	if err != nil {
+		return n, err
+	}
+	s.r = s.wrap(s.rs)
+	return n, nil
+}
+
+// NewSeekPreservingReader returns the io.Reader that wrap returns for
+// the given io.Reader, e.g. one of the readers from this package. If r
+// is an io.Seeker, the returned io.Reader is one as well, so wrapping a
+// file doesn't keep it from being used with things like
+// http.ServeContent. Each Seek() seeks r and calls wrap again to start
+// over with a new wrapper, since the state of the old one, like a
+// hash, no longer matches the data. If either of the parameters are
+// nil or wrap returns nil, nil is returned.
+func NewSeekPreservingReader(wrap func(io.Reader) io.Reader, r io.Reader) io.Reader {
+	if wrap == nil || r == nil {
+		return nil
+	}
+	wr := wrap(r)
+	if wr == nil {
+		return nil
+	}
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		return wr
+	}
+	return &seek{r: wr, rs: rs, wrap: wrap}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeekPreservingReader(t *testing.T) {
+	if NewSeekPreservingReader(nil, strings.NewReader("")) != nil {
+		t.Errorf("nil wrap didn't return nil")
+	}
+	if NewSeekPreservingReader(func(r io.Reader) io.Reader { return r }, nil) != nil {
+		t.Errorf("nil reader didn't return nil")
+	}
+	if NewSeekPreservingReader(func(r io.Reader) io.Reader { return nil },
+		strings.NewReader("")) != nil {
+		t.Errorf("nil wrapper didn't return nil")
+	}
+
+	// Each wrapper counts what it reads.
+	var counts []int
+	wrap := func(r io.Reader) io.Reader {
+		counts = append(counts, 0)
+		x := len(counts) - 1
+		return NewFuncReader(func(p []byte) { counts[x] += len(p) }, r)
+	}
+
+	// Without an io.Seeker, it's just the wrapper.
+	r := NewSeekPreservingReader(wrap, bytes.NewBufferString("hello"))
+	if _, ok := r.(io.Seeker); ok {
+		t.Errorf("wrapper of a bytes.Buffer is an io.Seeker")
+	}
+
+	counts = nil
+	r = NewSeekPreservingReader(wrap, strings.NewReader("hello, world"))
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		t.Fatalf("wrapper of a strings.Reader isn't an io.Seeker")
+	}
+	p := make([]byte, 5)
+	if n, err := rs.Read(p); n != 5 || err != nil || string(p) != "hello" {
+		t.Errorf("Read() = %q, %v, %v", p[:n], n, err)
+	}
+	if n, err := rs.Seek(-5, io.SeekEnd); n != 7 || err != nil {
+		t.Errorf("Seek() = %v, %v, wanted 7, nil", n, err)
+	}
+	if b, err := ioutil.ReadAll(rs); err != nil || string(b) != "world" {
+		t.Errorf("ReadAll() after Seek() = %q, %v", b, err)
+	}
+	if len(counts) != 2 || counts[0] != 5 || counts[1] != 5 {
+		t.Errorf("counts = %v, wanted [5 5]", counts)
+	}
+	if _, err := rs.Seek(-1, io.SeekStart); err == nil {
+		t.Errorf("Seek() to a negative position succeeded")
+	}
+	if len(counts) != 2 {
+		t.Errorf("failed Seek() made a new wrapper")
+	}
+
+	// It can be served with ranges.
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Range", "bytes=7-")
+	http.ServeContent(rec, req, "hello.txt", time.Time{}, rs)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "world" {
+		t.Errorf("ServeContent() = %v %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"math/bits"
+	"time"
+)
+
+// RateWindow is how far back Stats looks to calculate the rate.
+const RateWindow = 10 * time.Second
+
+// rateSeconds is RateWindow in seconds.
+const rateSeconds = int64(RateWindow / time.Second)
+
+// Histogram counts values in buckets that double in size. Bucket 0
+// counts the values less than 1. Bucket x counts the values from
+// 2^(x-1) to 2^x-1. The last bucket also counts anything bigger.
+type Histogram [32]int
+
+// add counts the value.
+func (h *Histogram) add(v int64) {
+	x := 0
+	if v > 0 {
+		x = bits.Len64(uint64(v))
+	}
+	if x >= len(h) {
+		x = len(h) - 1
+	}
+	h[x]++
+}
+
+// Bucket returns the smallest and largest values counted by the given
+// bucket.
+func (h *Histogram) Bucket(x int) (lo, hi int64) {
+	switch {
+	case x == 0:
+		return 0, 0
+	case x == len(h)-1:
+		return 1 << uint(x-1), 1<<63 - 1
+	}
+	return 1 << uint(x-1), 1<<uint(x) - 1
+}
+
+// StatsSnapshot is a copy of the values of Stats at a point in time.
+type StatsSnapshot struct {
+	Total     int
+	Average   float64
+	Calls     int
+	Min       int
+	Max       int
+	Sizes     Histogram
+	Latencies Histogram
+
+	// Rate is the average number of bytes per second over the last
+	// RateWindow.
+	Rate float64
+}
+
+// Snapshot returns a copy of the statistics. It can be used without
+// locking.
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.Lock()
+	defer s.Unlock()
+	return StatsSnapshot{
+		Total:     s.Total,
+		Average:   s.Average,
+		Calls:     s.Calls,
+		Min:       s.Min,
+		Max:       s.Max,
+		Sizes:     s.Sizes,
+		Latencies: s.Latencies,
+		Rate:      s.rate.rate(time.Now()),
+	}
+}
+
+// rateWindow tracks the bytes of each of the last rateSeconds seconds.
+// The bytes of a second are at the second modulo rateSeconds.
+type rateWindow struct {
+	bytes [rateSeconds]int64
+	secs  [rateSeconds]int64 // The second of each of the bytes.
+}
+
+// add adds n bytes at the given time.
+func (r *rateWindow) add(now time.Time, n int) {
+	sec := now.Unix()
+	x := sec % rateSeconds
+	if r.secs[x] != sec {
+		r.secs[x], r.bytes[x] = sec, 0
+	}
+	r.bytes[x] += int64(n)
+}
+
+// rate returns the bytes per second in the window ending at the given
+// time.
+func (r *rateWindow) rate(now time.Time) float64 {
+	sec := now.Unix()
+	var total int64
+	for x, s := range r.secs {
+		if sec-s < rateSeconds {
+			total += r.bytes[x]
+		}
+	}
+	return float64(total) / float64(rateSeconds)
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/icub3d/gop/wrapio/iotestutil"
+)
+
+func TestStatsSnapshot(t *testing.T) {
+	data := strings.Repeat("a", 100)
+	s, r := NewStatsReader(iotestutil.NewShortReader(30, strings.NewReader(data)))
+	io.Copy(ioutil.Discard, r)
+	ss := s.Snapshot()
+	// The reads are 30, 30, 30 and 10 bytes.
+	if ss.Total != 100 || ss.Calls != 4 || ss.Min != 10 || ss.Max != 30 {
+		t.Errorf("Snapshot() = %+v", ss)
+	}
+	var sizes Histogram
+	sizes[4], sizes[5] = 1, 3
+	if ss.Sizes != sizes {
+		t.Errorf("Sizes = %v, wanted %v", ss.Sizes, sizes)
+	}
+	calls := 0
+	for _, c := range ss.Latencies {
+		calls += c
+	}
+	if calls != 4 {
+		t.Errorf("Latencies has %v calls, wanted 4", calls)
+	}
+	if ss.Rate != 10 {
+		t.Errorf("Rate = %v, wanted 10", ss.Rate)
+	}
+
+	// The snapshot is a copy.
+	s.Total = 0
+	if ss.Total != 100 {
+		t.Errorf("snapshot changed with the stats")
+	}
+
+	s, w := NewStatsWriter(ioutil.Discard)
+	w.Write(make([]byte, 5000))
+	if ss := s.Snapshot(); ss.Min != 5000 || ss.Max != 5000 || ss.Sizes[13] != 1 {
+		t.Errorf("Snapshot() of writer = %+v", ss)
+	}
+	if _, r := NewStatsReader(nil); r != nil {
+		t.Errorf("NewStatsReader(nil) didn't return a nil reader")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var h Histogram
+	for _, v := range []int64{-1, 0, 1, 2, 3, 4, 1 << 40} {
+		h.add(v)
+	}
+	var exp Histogram
+	exp[0], exp[1], exp[2], exp[3], exp[31] = 2, 1, 2, 1, 1
+	if h != exp {
+		t.Errorf("histogram = %v, wanted %v", h, exp)
+	}
+	tests := []struct {
+		x      int
+		lo, hi int64
+	}{
+		{0, 0, 0},
+		{1, 1, 1},
+		{3, 4, 7},
+		{31, 1 << 30, 1<<63 - 1},
+	}
+	for _, test := range tests {
+		if lo, hi := h.Bucket(test.x); lo != test.lo || hi != test.hi {
+			t.Errorf("Bucket(%v) = %v, %v, wanted %v, %v", test.x, lo, hi,
+				test.lo, test.hi)
+		}
+	}
+}
+
+func TestRateWindow(t *testing.T) {
+	var r rateWindow
+	now := time.Unix(1000, 0)
+	for x := 0; x < 20; x++ {
+		r.add(now.Add(time.Duration(x)*time.Second), 10)
+	}
+	// Only the last 10 seconds count.
+	r.add(now.Add(19*time.Second), 100)
+	if rate := r.rate(now.Add(19 * time.Second)); rate != 20 {
+		t.Errorf("rate() = %v, wanted 20", rate)
+	}
+	if rate := r.rate(now.Add(time.Minute)); rate != 0 {
+		t.Errorf("rate() after a minute = %v, wanted 0", rate)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import "io"
+
+// TeePolicy is what a TeeReader does when one of its writers fails.
+type TeePolicy int
+
+// These are the policies of a TeeReader.
+const (
+	// TeeFailFast returns the error of the first writer that fails
+	// from Read. It's returned from every Read after that as well.
+	TeeFailFast TeePolicy = iota
+
+	// TeeBestEffort stops writing to the writers that fail but keeps
+	// reading and writing to the others. The errors are available
+	// from Errs.
+	TeeBestEffort
+)
+
+// TeeReader is an io.Reader that writes what it reads to several
+// writers. Instantiate it with NewMultiTeeReader.
+type TeeReader struct {
+	// Policy is what it does when a writer fails. The default is
+	// TeeFailFast.
+	Policy TeePolicy
+
+	r    io.Reader
+	ws   []io.Writer
+	errs []error // The errors of the writers.
+	err  error   // The error returned by Read with TeeFailFast.
+}
+
+// NewMultiTeeReader returns a TeeReader that writes to the given writers
+// what it reads from the given reader. The data is written to each
+// writer in order before Read returns. Like io.TeeReader, there is no
+// internal buffering, so the writers must accept all of the data. A
+// short write is treated as an io.ErrShortWrite error. If r is nil, nil
+// is returned.
+func NewMultiTeeReader(r io.Reader, ws ...io.Writer) *TeeReader {
+	if r == nil {
+		return nil
+	}
+	return &TeeReader{r: r, ws: ws, errs: make([]error, len(ws))}
+}
+
+// Read implements the io.Reader interface.
+func (t *TeeReader) Read(p []byte) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+	n, err := t.r.Read(p)
+	if n < 1 {
+		return n, err
+	}
+	for x, w := range t.ws {
+		if t.errs[x] != nil {
+			continue
+		}
+		wn, werr := w.Write(p[:n])
+		if werr == nil && wn < n {
+			werr = io.ErrShortWrite
+		}
+		if werr == nil {
+			continue
+		}
+		t.errs[x] = werr
+		if t.Policy == TeeFailFast {
+			t.err = werr
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// Errs returns the errors of the writers in the order they were given.
+// The error of a writer that hasn't failed is nil.
+func (t *TeeReader) Errs() []error {
+	return append([]error(nil), t.errs...)
+}
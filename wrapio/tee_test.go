@@ -0,0 +1,80 @@
+// Copyright (c) 2015 Joshua Marsh. All rights reserved.
+//
+// Use of this source code is governed by the MIT license that can be
+// found in the LICENSE file in the root of the repository or at
+// https://raw.githubusercontent.com/icub3d/gop/master/LICENSE.
+
+package wrapio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/icub3d/gop/wrapio/iotestutil"
+)
+
+func TestMultiTeeReader(t *testing.T) {
+	if NewMultiTeeReader(nil) != nil {
+		t.Errorf("NewMultiTeeReader(nil) wasn't nil")
+	}
+	data := "This is the sample data that we are going to test with."
+	werr := errors.New("write failed")
+	tests := []struct {
+		policy TeePolicy
+		failAt int // The byte at which the second writer fails.
+		read   string
+		err    error
+		second string
+		third  string
+		errs   []error
+	}{
+		// Everything succeeds.
+		{TeeFailFast, 1000, data, nil, data, data, []error{nil, nil, nil}},
+		// The second writer fails and stops the reading.
+		{TeeFailFast, 10, data[:16], werr, data[:10], "", []error{nil, werr, nil}},
+		// The second writer fails but the others get everything.
+		{TeeBestEffort, 10, data, nil, data[:10], data, []error{nil, werr, nil}},
+	}
+	for x, test := range tests {
+		first, second, third := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+		tr := NewMultiTeeReader(iotestutil.NewShortReader(8, strings.NewReader(data)),
+			first, iotestutil.NewErrorWriter(int64(test.failAt), werr, second), third)
+		tr.Policy = test.policy
+		read, err := ioutil.ReadAll(tr)
+		if string(read) != test.read || err != test.err {
+			t.Errorf("Test %v: ReadAll() = %q, %v, wanted %q, %v", x, read, err,
+				test.read, test.err)
+		}
+		if first.String() != test.read {
+			t.Errorf("Test %v: first writer got %q, wanted %q", x, first, test.read)
+		}
+		if second.String() != test.second {
+			t.Errorf("Test %v: second writer got %q, wanted %q", x, second, test.second)
+		}
+		if test.third != "" && third.String() != test.third {
+			t.Errorf("Test %v: third writer got %q, wanted %q", x, third, test.third)
+		}
+		if errs := tr.Errs(); !reflect.DeepEqual(errs, test.errs) {
+			t.Errorf("Test %v: Errs() = %v, wanted %v", x, errs, test.errs)
+		}
+	}
+
+	// Short writes are errors.
+	tr := NewMultiTeeReader(strings.NewReader(data), iotestutil.NewShortWriter(1, ioutil.Discard))
+	if _, err := ioutil.ReadAll(tr); err != io.ErrShortWrite {
+		t.Errorf("ReadAll() with short writer = %v, wanted %v", err, io.ErrShortWrite)
+	}
+
+	// The read errors are returned.
+	tr = NewMultiTeeReader(iotest.TimeoutReader(strings.NewReader(data)), ioutil.Discard)
+	tr.Read(make([]byte, 10))
+	if _, err := tr.Read(make([]byte, 10)); err != iotest.ErrTimeout {
+		t.Errorf("Read() = %v, wanted %v", err, iotest.ErrTimeout)
+	}
+}
@@ -14,10 +14,12 @@
 package wrapio
 
 import (
+	"bytes"
 	"fmt"
 	"hash"
 	"io"
 	"sync"
+	"time"
 )
 
 // Wrap implements the io.Closer, io.Reader, and io.Writer interface.
@@ -101,12 +103,19 @@ func NewHashWriter(h hash.Hash, w io.Writer) io.Writer {
 // Stats maintains the statistics about the I/O. It is updated with
 // each read/write operation. If you are accessing the values, you
 // should Lock() before accessing them and Unlock() after you are done
-// to prevent possible race conditions.
+// to prevent possible race conditions, or use Snapshot().
 type Stats struct {
 	sync.Mutex
 	Total   int     // The total number of bytes that have passed through.
 	Average float64 // The average number of bytes read or written per call.
 	Calls   int     // The number of calls made to Read or Write.
+
+	Min       int       // The fewest bytes read or written by a call.
+	Max       int       // The most bytes read or written by a call.
+	Sizes     Histogram // The number of calls by the bytes read or written.
+	Latencies Histogram // The number of calls by their microseconds.
+
+	rate rateWindow // The bytes of the recent seconds.
 }
 
 // String implements the fmt.Stringer interface.
@@ -115,12 +124,45 @@ func (s *Stats) String() string {
 		s.Total, s.Average, s.Calls)
 }
 
-func (s *Stats) update(p []byte) {
+func (s *Stats) update(n int, d time.Duration) {
 	s.Lock()
 	defer s.Unlock()
-	s.Total += len(p)
+	s.Total += n
 	s.Calls++
 	s.Average = float64(s.Total / s.Calls)
+	if s.Calls == 1 || n < s.Min {
+		s.Min = n
+	}
+	if n > s.Max {
+		s.Max = n
+	}
+	s.Sizes.add(int64(n))
+	s.Latencies.add(int64(d / time.Microsecond))
+	s.rate.add(time.Now(), n)
+}
+
+type stats struct {
+	s *Stats
+	r io.Reader
+	w io.Writer
+}
+
+// Read implements the io.Reader interface.
+func (s *stats) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := s.r.Read(p)
+	if n > 0 {
+		s.s.update(n, time.Since(start))
+	}
+	return n, err
+}
+
+// Write implements the io.Writer interface.
+func (s *stats) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := s.w.Write(p)
+	s.s.update(len(p), time.Since(start))
+	return n, err
 }
 
 // NewStatsReader returns an io.Reader that wraps the given io.Reader
@@ -129,7 +171,10 @@ func (s *Stats) update(p []byte) {
 // are nil, nil is returned.
 func NewStatsReader(r io.Reader) (*Stats, io.Reader) {
 	s := &Stats{}
-	return s, NewFuncReader(s.update, r)
+	if r == nil {
+		return s, nil
+	}
+	return s, &stats{s: s, r: r}
 }
 
 // NewStatsWriter returns an io.Writer that wraps the given io.Writer
@@ -138,16 +183,20 @@ func NewStatsReader(r io.Reader) (*Stats, io.Reader) {
 // are nil, nil is returned.
 func NewStatsWriter(w io.Writer) (*Stats, io.Writer) {
 	s := &Stats{}
-	return s, NewFuncWriter(s.update, w)
+	if w == nil {
+		return s, nil
+	}
+	return s, &stats{s: s, w: w}
 }
 
 type block struct {
-	r    io.Reader
-	w    io.Writer
-	size int
-	buf  []byte
-	err  error // The non-nil error from the last Read().
-
+	r     io.Reader
+	w     io.Writer
+	size  int
+	buf   []byte
+	err   error // The non-nil error from the last Read().
+	delim bool  // Whether to also write out up to d.
+	d     byte
 }
 
 // Read implements the io.Reader interface.
@@ -193,6 +242,12 @@ func (b *block) Write(p []byte) (int, error) {
 	b.buf = append(b.buf, p...)
 	// Write out any whole blocks.
 	l := (len(b.buf) / b.size) * b.size
+	// Also write out any records ending in the delimiter.
+	if b.delim {
+		if i := bytes.LastIndexByte(b.buf, b.d); i+1 > l {
+			l = i + 1
+		}
+	}
 	if l > 0 {
 		n, err := b.w.Write(b.buf[:l])
 		// Move the unwritten portion to the beginning of the buffer and
@@ -254,6 +309,19 @@ func NewBlockWriter(size int, w io.Writer) io.WriteCloser {
 	return &block{w: w, size: size}
 }
 
+// NewDelimBlockWriter returns a writer like NewBlockWriter that also
+// writes out the data up to and including the last delimiter when it
+// appears, even if it doesn't complete a block. This keeps complete
+// records, e.g. lines ending in '\n', from being held while the data
+// not ending in the delimiter is still sent in blocks that are a
+// multiple of size.
+func NewDelimBlockWriter(size int, delim byte, w io.Writer) io.WriteCloser {
+	if w == nil || size < 1 {
+		return nil
+	}
+	return &block{w: w, size: size, delim: true, d: delim}
+}
+
 // Last implements the io.Closer, io.Reader, and io.Writer interface.
 type last struct {
 	handler func([]byte) []byte
@@ -19,6 +19,8 @@ import (
 	"strings"
 	"testing"
 	"testing/iotest"
+
+	"github.com/icub3d/gop/wrapio/iotestutil"
 )
 
 func ExampleNewFuncReader() {
@@ -295,7 +297,7 @@ func TestBlockWriter(t *testing.T) {
 		t.Errorf("zero size didn't return nil.")
 	}
 	// Test with the error writer.
-	e := ew{err: fmt.Errorf("i did it")}
+	e := iotestutil.NewErrorWriter(0, fmt.Errorf("i did it"), ioutil.Discard)
 	w := NewBlockWriter(1, e)
 	for x := 0; x < 2; x++ {
 		n, err := w.Write([]byte("test"))
@@ -310,8 +312,46 @@ func TestBlockWriter(t *testing.T) {
 	}
 }
 
+func TestDelimBlockWriter(t *testing.T) {
+	if NewDelimBlockWriter(1, '\n', nil) != nil {
+		t.Errorf("nil io.Writer didn't return nil.")
+	}
+	if NewDelimBlockWriter(0, '\n', &bytes.Buffer{}) != nil {
+		t.Errorf("zero size didn't return nil.")
+	}
+	tests := []struct {
+		write string
+		read  string
+	}{
+		// A complete record is written before the block is full.
+		{write: "ab\n", read: "ab\n"},
+		// A partial record is held.
+		{write: "cd", read: ""},
+		// Whole blocks are still written.
+		{write: "efghi", read: "cdef"},
+		// Everything up to the delimiter is written.
+		{write: "k\nlm", read: "ghik\n"},
+	}
+	bw := new(bytes.Buffer)
+	w := NewDelimBlockWriter(4, '\n', bw)
+	for k, test := range tests {
+		n, err := w.Write([]byte(test.write))
+		if n != len(test.write) || err != nil {
+			t.Errorf("Test %v: Write() = %v, %v", k, n, err)
+		}
+		if bw.String() != test.read {
+			t.Errorf("Test %v: wrote %q, wanted %q", k, bw.String(), test.read)
+		}
+		bw.Reset()
+	}
+	w.Close()
+	if bw.String() != "lm" {
+		t.Errorf("Close() wrote %q, wanted %q", bw.String(), "lm")
+	}
+}
+
 func TestBlockReaderFunctional(t *testing.T) {
-	if NewBlockReader(0, er{}) != nil {
+	if NewBlockReader(0, strings.NewReader("")) != nil {
 		t.Errorf("zero reader size didn't return nil")
 	}
 	if NewBlockReader(1, nil) != nil {
@@ -408,11 +448,7 @@ func TestBlockReaderUnitTest(t *testing.T) {
 			p:        make([]byte, 5),
 			expected: []byte{48, 49, 50, 51},
 			block: block{
-				r: er{
-					data: []byte("34567"),
-					n:    5,
-					err:  nil,
-				},
+				r:    strings.NewReader("34567"),
 				buf:  []byte("012"),
 				size: 4,
 				err:  nil,
@@ -426,11 +462,7 @@ func TestBlockReaderUnitTest(t *testing.T) {
 			p:        make([]byte, 5),
 			expected: []byte{},
 			block: block{
-				r: er{
-					data: []byte(""),
-					n:    0,
-					err:  nil,
-				},
+				r:    iotestutil.NewErrorReader(0, nil, strings.NewReader("")),
 				buf:  []byte("012"),
 				size: 4,
 				err:  nil,
@@ -579,11 +611,7 @@ func TestLastFuncReader(t *testing.T) {
 			expected: [][]byte{
 				[]byte("100"),
 			},
-			data: &er{
-				data: []byte("1"),
-				n:    1,
-				err:  io.EOF,
-			},
+			data: iotestutil.NewErrorReader(1, io.EOF, strings.NewReader("1")),
 			f: func(p []byte) []byte {
 				for len(p) < 3 {
 					p = append(p, 48)
@@ -814,11 +842,11 @@ func TestWrapN(t *testing.T) {
 			errWhen: 13,
 			errSend: io.EOF,
 			c:       2,
-			expC:    5,
+			expC:    1,
 			n:       5,
-			w:       17,
+			w:       13,
 			err:     io.EOF,
-			result:  "345\r\n67890\r\n12345",
+			result:  "345\r\n67890\r\n1",
 		},
 		// Return an error while writing delim.
 		{
@@ -829,14 +857,18 @@ func TestWrapN(t *testing.T) {
 			c:       2,
 			expC:    0,
 			n:       5,
-			w:       12,
+			w:       11,
 			err:     io.EOF,
-			result:  "345\r\n67890\r\n",
+			result:  "345\r\n67890\r",
 		},
 	}
 	for k, test := range tests {
 		buf := &bytes.Buffer{}
-		w := NewWrapN(test.n, test.delim, &eww{w: buf, n: test.errWhen, err: test.errSend})
+		var ew io.Writer = buf
+		if test.errSend != nil {
+			ew = iotestutil.NewErrorWriter(int64(test.errWhen), test.errSend, buf)
+		}
+		w := NewWrapN(test.n, test.delim, ew)
 		w.(*wrapn).c = test.c
 		n, err := w.Write(test.data)
 		if test.w != n {
@@ -998,7 +1030,7 @@ func TestUnwrapN(t *testing.T) {
 
 	for k, test := range tests {
 		buf := make([]byte, test.size)
-		r := NewUnwrapN(test.n, test.delim, &er{data: test.data, n: test.errSize, err: test.errSent})
+		r := NewUnwrapN(test.n, test.delim, iotestutil.NewErrorReader(int64(test.errSize), test.errSent, bytes.NewReader(test.data)))
 		r.(*unwrapn).leftover = test.leftover
 		r.(*unwrapn).inDelim = test.inDelim
 		n, err := r.Read(buf)
@@ -1050,44 +1082,3 @@ func TestUnwrapNMulti(t *testing.T) {
 		t.Fatalf("Final multi-read failed: %v %v %v", n, err, string(res))
 	}
 }
-
-// Err is a helper for testing writers that need to error after
-// writing n bytes.
-type eww struct {
-	w   io.Writer
-	n   int
-	c   int
-	err error
-}
-
-func (w *eww) Write(data []byte) (int, error) {
-	n, err := w.w.Write(data)
-	w.c += n
-	if w.err != nil && w.c >= w.n {
-		return n, w.err
-	}
-	return n, err
-}
-
-// Er is a helper for testing reads. It always writes the given data
-// to p and returns the given values.
-type er struct {
-	data []byte
-	n    int
-	err  error
-}
-
-func (e er) Read(p []byte) (int, error) {
-	copy(p, e.data)
-	return e.n, e.err
-}
-
-// Ew is a helper for testing the writers that need to error out. Any
-// call to Write() will produce the err.
-type ew struct {
-	err error
-}
-
-func (e ew) Write(p []byte) (int, error) {
-	return 0, e.err
-}